@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/exports"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/validator"
+)
+
+func (app *application) saveBudget(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Category       string    `json:"category"`
+		FiscalYear     int       `json:"fiscalYear"`
+		Amount         float64   `json:"amount"`
+		MonthlyPhasing []float64 `json:"monthlyPhasing"`
+		AlertThreshold *float64  `json:"alertThreshold"`
+	}
+
+	err := app.readJSON(w, r, &input)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	budget := &models.Budget{
+		OrganizationId: user.OrganizationId,
+		Category:       input.Category,
+		FiscalYear:     input.FiscalYear,
+		Amount:         input.Amount,
+		MonthlyPhasing: input.MonthlyPhasing,
+		AlertThreshold: 90,
+	}
+
+	if input.AlertThreshold != nil {
+		budget.AlertThreshold = *input.AlertThreshold
+	}
+
+	v := validator.New()
+
+	data.ValidateBudget(v, budget)
+
+	if !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	id, err := app.budgetModel.SaveBudget(user, budget)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"message": "budget saved", "id": id})
+}
+
+func (app *application) getBudgets(w http.ResponseWriter, r *http.Request) {
+	year := app.readIntParam(r.URL.Query(), "year", time.Now().Year())
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	budgets, err := app.budgetModel.GetBudgets(app.contextGetUser(r).OrganizationId, year)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.Budgets(budgets, year))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": budgets})
+}
+
+func (app *application) getBudgetVsActual(w http.ResponseWriter, r *http.Request) {
+	year, month := app.readBudgetPeriod(r.URL.Query())
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := app.budgetModel.GetBudgetVsActual(app.contextGetUser(r).OrganizationId, year, month)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.BudgetVsActual(report, year, month))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": report})
+}
+
+func (app *application) getBudgetAlerts(w http.ResponseWriter, r *http.Request) {
+	year, month := app.readBudgetPeriod(r.URL.Query())
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	alerts, err := app.budgetModel.GetBudgetAlerts(app.contextGetUser(r).OrganizationId, year, month)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		doc := exports.BudgetVsActual(alerts, year, month)
+		doc.Name = "budget-alerts"
+		app.writeDocument(w, r, exporter, doc)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": alerts})
+}
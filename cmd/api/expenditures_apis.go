@@ -0,0 +1,335 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/exports"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/models/postgres"
+	"github.com/VaudKK/CAS/pkg/validator"
+	"github.com/VaudKK/CAS/utils"
+	"github.com/gorilla/mux"
+)
+
+type expenditureInput struct {
+	Payee         string  `json:"payee"`
+	Amount        float64 `json:"amount"`
+	Category      string  `json:"category"`
+	PaymentMethod string  `json:"paymentMethod"`
+	VoucherNo     string  `json:"voucherNo"`
+	Description   string  `json:"description"`
+	Date          string  `json:"date"`
+}
+
+func (input *expenditureInput) toModel(organizationId int) *models.Expenditure {
+	return &models.Expenditure{
+		Payee:          input.Payee,
+		Amount:         input.Amount,
+		Category:       input.Category,
+		PaymentMethod:  strings.ToUpper(input.PaymentMethod),
+		VoucherNo:      input.VoucherNo,
+		Description:    input.Description,
+		Date:           input.Date,
+		OrganizationId: organizationId,
+	}
+}
+
+func (app *application) addExpenditure(w http.ResponseWriter, r *http.Request) {
+	var input expenditureInput
+
+	err := app.readJSON(w, r, &input)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	expenditure := input.toModel(user.OrganizationId)
+
+	v := validator.New()
+
+	data.ValidateExpenditure(v, expenditure)
+
+	if !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	id, err := app.expenditureModel.SaveExpenditure(user, expenditure)
+
+	if err != nil {
+		if errors.Is(err, postgres.ErrPeriodClosed) {
+			app.writeJSONError(w, http.StatusConflict, err)
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"message": "successfully added expenditure", "id": id})
+}
+
+func (app *application) getExpenditures(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	page := app.readIntParam(qs, "page", 1)
+	size := app.readIntParam(qs, "size", 10)
+
+	pageable := utils.Pageable{
+		Page:   page,
+		Size:   size,
+		OffSet: page * size,
+	}
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	expenditures, pageInfo, err := app.expenditureModel.GetExpenditures(app.contextGetUser(r).OrganizationId, pageable)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.Expenditures(expenditures, time.Time{}, time.Time{}))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": expenditures, "pageInfo": pageInfo})
+}
+
+func (app *application) updateExpenditure(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	var input expenditureInput
+
+	err = app.readJSON(w, r, &input)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	expenditure := input.toModel(user.OrganizationId)
+
+	v := validator.New()
+
+	data.ValidateExpenditure(v, expenditure)
+
+	if !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	_, err = app.expenditureModel.UpdateExpenditure(user, id, expenditure)
+
+	if err != nil {
+		if errors.Is(err, postgres.ErrPeriodClosed) {
+			app.writeJSONError(w, http.StatusConflict, err)
+			return
+		}
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "updated successfully"})
+}
+
+func (app *application) approveExpenditure(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	err = app.expenditureModel.ApproveExpenditure(app.contextGetUser(r), id)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "expenditure approved"})
+}
+
+func (app *application) uploadExpenditureDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	err = r.ParseMultipartForm(10 << 20) // limit upload to 10MB
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	file, handler, err := r.FormFile("document")
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("missing file or field name 'document'"))
+		return
+	}
+
+	defer file.Close()
+
+	fileData, err := io.ReadAll(file)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	document := &models.ExpenditureDocument{
+		Name:        handler.Filename,
+		ContentType: http.DetectContentType(fileData),
+		Data:        fileData,
+	}
+
+	err = app.expenditureModel.SaveDocument(app.contextGetUser(r), id, document)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusNotFound, err)
+		default:
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "document uploaded successfully"})
+}
+
+func (app *application) getExpenditureDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	document, err := app.expenditureModel.GetDocument(app.contextGetUser(r).OrganizationId, id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusNotFound, err)
+		default:
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", document.ContentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(document.Name))
+	w.Write(document.Data)
+}
+
+func (app *application) searchExpenditures(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	page := app.readIntParam(qs, "page", 1)
+	size := app.readIntParam(qs, "size", 10)
+	dateFrom, hasFrom := app.readDateParam(qs, "from")
+	dateTo, hasTo := app.readDateParam(qs, "to")
+	exact := qs.Get("exact")
+	searchTerm := qs.Get("terms")
+
+	if searchTerm == "" && !hasFrom {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("missing query params, specify search term or both from and to dates"))
+		return
+	}
+
+	if !hasTo {
+		dateTo = time.Time{}
+	}
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pageable := utils.Pageable{
+		Page:   page,
+		Size:   size,
+		OffSet: page * size,
+	}
+
+	if exporter != nil {
+		pageable.Size = math.MaxInt
+		pageable.Page = 0
+		pageable.OffSet = 0
+	}
+
+	expenditures, pageInfo, err := app.expenditureModel.SearchExpenditures(app.contextGetUser(r).OrganizationId,
+		searchTerm, exact == "true", dateFrom, dateTo, pageable)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.Expenditures(expenditures, dateFrom, dateTo))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": expenditures, "pageInfo": pageInfo})
+}
+
+func (app *application) getFundBalances(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	dateFrom, _ := app.readDateParam(qs, "from")
+	dateTo, hasTo := app.readDateParam(qs, "to")
+
+	if !hasTo {
+		dateTo = time.Time{}
+	}
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	balances, err := app.expenditureModel.GetFundBalances(app.contextGetUser(r).OrganizationId, dateFrom, dateTo)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.FundBalances(balances, dateFrom, dateTo))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": balances})
+}
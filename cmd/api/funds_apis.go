@@ -2,13 +2,19 @@ package main
 
 import (
 	"errors"
-	"math"
+	"fmt"
 	"net/http"
-	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/exports"
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/imports/importers"
 	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/models/postgres"
+	"github.com/VaudKK/CAS/pkg/validator"
 	"github.com/VaudKK/CAS/utils"
 	"github.com/gorilla/mux"
 )
@@ -16,10 +22,11 @@ import (
 func (app *application) addContribution(w http.ResponseWriter, r *http.Request) {
 
 	var input struct {
-		Contributor string             `json:"contributor"`
-		Date        string             `json:"date"`
-		Total       float64            `json:"total"`
-		BreakDown   map[string]float64 `json:"breakDown"`
+		Contributor   string             `json:"contributor"`
+		Date          string             `json:"date"`
+		Total         float64            `json:"total"`
+		BreakDown     map[string]float64 `json:"breakDown"`
+		PaymentMethod string             `json:"paymentMethod"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -35,12 +42,26 @@ func (app *application) addContribution(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	paymentMethod := strings.ToUpper(input.PaymentMethod)
+
+	if paymentMethod == "" {
+		paymentMethod = "CASH"
+	}
+
+	if !validator.In(paymentMethod, data.PaymentMethods...) {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("paymentMethod must be one of CASH, CHEQUE, BANK TRANSFER or MPESA"))
+		return
+	}
+
+	user := app.contextGetUser(r)
+
 	contributions := []models.Fund{{
 		Contributor:    input.Contributor,
 		Date:           t.Format("2006-01-02"),
 		Total:          input.Total,
 		BreakDown:      input.BreakDown,
-		OrganizationId: 1,
+		PaymentMethod:  paymentMethod,
+		OrganizationId: user.OrganizationId,
 	}}
 
 	if !app.fundsModel.ValidateTotalAndBreakDown(input.Total, input.BreakDown) {
@@ -48,14 +69,30 @@ func (app *application) addContribution(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = app.fundsModel.SaveContributions(app.contextGetUser(r), contributions)
+	duplicates, err := app.fundsModel.FindDuplicates(contributions[0].OrganizationId, contributions)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = app.fundsModel.SaveContributions(user, contributions)
 
 	if err != nil {
 		app.writeJSONError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	app.writeJSON(w, http.StatusCreated, envelope{"message": "successfully added contribution"})
+	response := envelope{"message": "successfully added contribution"}
+
+	// the contribution is saved either way, likely duplicates are only reported
+	if duplicate, ok := duplicates[0]; ok {
+		response["warning"] = fmt.Sprintf("possible duplicate of contribution %d with the same contributor, date and total",
+			duplicate.MatchedId)
+		response["duplicates"] = []*imports.Duplicate{duplicate}
+	}
+
+	app.writeJSON(w, http.StatusCreated, response)
 
 }
 
@@ -70,13 +107,33 @@ func (app *application) getContributions(w http.ResponseWriter, r *http.Request)
 		OffSet: page * size,
 	}
 
-	contributions, pageInfo, err := app.fundsModel.GetContributions(1, pageable)
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	charts, err := app.readCharts(qs)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	contributions, pageInfo, err := app.fundsModel.GetContributions(app.contextGetUser(r).OrganizationId, pageable)
 
 	if err != nil {
 		app.writeJSONError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.Contributions(contributions, app.fundsModel.GetCategories(), time.Time{}, time.Time{},
+			data.SelectCharts(charts)))
+		return
+	}
+
 	app.writeJSON(w, http.StatusOK, envelope{"data": contributions, "pageInfo": pageInfo})
 }
 
@@ -110,10 +167,17 @@ func (app *application) updateContribution(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	_, err = app.fundsModel.UpdateContribution(id, &input)
+	_, err = app.fundsModel.UpdateContribution(app.contextGetUser(r), id, &input)
 
 	if err != nil {
-		app.writeJSONError(w, http.StatusInternalServerError, err)
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusNotFound, err)
+		case errors.Is(err, postgres.ErrPeriodClosed):
+			app.writeJSONError(w, http.StatusConflict, err)
+		default:
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+		}
 		return
 	}
 
@@ -127,6 +191,13 @@ func (app *application) getMonthlyStats(w http.ResponseWriter, r *http.Request)
 	year := app.readIntParam(qs, "year", int(time.Now().Year()))
 	month := app.readIntParam(qs, "month", int(time.Now().Month()))
 
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	stats, err := app.fundsModel.GetMonthlyStatistics(year, month, 1)
 
 	if err != nil {
@@ -134,18 +205,34 @@ func (app *application) getMonthlyStats(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.MonthlyStats(stats, year, month))
+		return
+	}
+
 	app.writeJSON(w, http.StatusOK, stats)
 }
 
-func (app *application) getStatisticalVariance(w http.ResponseWriter, _ *http.Request) {
+func (app *application) getStatisticalVariance(w http.ResponseWriter, r *http.Request) {
+	exporter, err := app.readExporter(r)
 
-	stats, err := app.fundsModel.GetMonthlyVariance([]string{"LCB", "COMB. OFFERING", "BUILDING CHURCH FUNDS"})
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	stats, err := app.fundsModel.GetMonthlyVariance(data.VarianceCategories)
 
 	if err != nil {
 		app.writeJSONError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.Variance(stats))
+		return
+	}
+
 	app.writeJSON(w, http.StatusOK, stats)
 }
 
@@ -161,8 +248,6 @@ func (app *application) search(w http.ResponseWriter, r *http.Request) {
 	size := app.readIntParam(qs, "size", 10)
 	dateFrom, hasFrom := app.readDateParam(qs, "from")
 	dateTo, hasTo := app.readDateParam(qs, "to")
-	generateExcel := qs.Get("generateExcel")
-	generatePdf := qs.Get("generatePdf")
 	exact := qs.Get("exact")
 
 	pageable := utils.Pageable{
@@ -171,118 +256,262 @@ func (app *application) search(w http.ResponseWriter, r *http.Request) {
 		OffSet: page * size,
 	}
 
-	if generateExcel == "true" || generatePdf == "true" {
-		pageable.Size = math.MaxInt
-		pageable.Page = 0
-		pageable.OffSet = 0
-	}
-
 	searchTerm := qs.Get("terms")
 
-	var contributions []*models.Fund
-	var pageInfo utils.PageInfo
-	var err error
-
-	if searchTerm != "" {
-		contributions, pageInfo, err = app.fundsModel.FullTextSearch(searchTerm, exact == "true", dateFrom, dateTo, pageable)
-	} else if hasFrom && hasTo {
-		contributions, pageInfo, err = app.fundsModel.SearchByDateRange(dateFrom, dateTo, pageable)
-	} else if hasFrom && !hasTo {
-		contributions, pageInfo, err = app.fundsModel.SearchByDateRange(dateFrom, time.Time{}, pageable)
-	} else {
+	if searchTerm == "" && !hasFrom {
 		app.writeJSONError(w, http.StatusBadRequest, errors.New("missing query params, specify search term or both from and to dates"))
 		return
 	}
 
+	exporter, err := app.readExporter(r)
+
 	if err != nil {
-		app.writeJSONError(w, http.StatusInternalServerError, err)
+		app.writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if generatePdf == "true" {
-		file, err := app.fundsModel.GeneratePdfFile(contributions, dateFrom, dateTo)
-		if err != nil {
-			app.writeJSONError(w, http.StatusInternalServerError, err)
-			return
-		}
+	charts, err := app.readCharts(qs)
 
-		w.Header().Set("Content-Type", "application/pdf")
-		w.Header().Set("Content-Disposition", "attachment; filename=contributions.pdf")
-		w.Write(file)
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if generateExcel == "true" {
-		file, err := app.fundsModel.GenerateExcelFile(contributions)
+	if exporter != nil {
+		report := &models.Report{Type: data.ReportContributions, Format: exporter.Extension(),
+			Parameters: models.ReportParameters{Terms: searchTerm, Exact: exact == "true", Charts: charts}}
+
+		if hasFrom {
+			report.Parameters.From = dateFrom.Format("2006-01-02")
+		}
+
+		if hasTo {
+			report.Parameters.To = dateTo.Format("2006-01-02")
+		}
+
+		id, err := app.enqueueReport(app.contextGetUser(r), report)
+
 		if err != nil {
 			app.writeJSONError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-		w.Header().Set("Content-Disposition", "attachment; filename=contributions.xlsx")
-		w.Write(file)
+		app.writeJSON(w, http.StatusAccepted, envelope{"message": "report queued", "id": id})
+		return
+	}
+
+	var contributions []*models.Fund
+	var pageInfo utils.PageInfo
+
+	organizationId := app.contextGetUser(r).OrganizationId
+
+	if searchTerm != "" {
+		contributions, pageInfo, err = app.fundsModel.FullTextSearch(organizationId, searchTerm, exact == "true", dateFrom, dateTo, pageable)
+	} else if hasTo {
+		contributions, pageInfo, err = app.fundsModel.SearchByDateRange(organizationId, dateFrom, dateTo, pageable)
+	} else {
+		contributions, pageInfo, err = app.fundsModel.SearchByDateRange(organizationId, dateFrom, time.Time{}, pageable)
+	}
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	app.writeJSON(w, http.StatusOK, envelope{"data": contributions, "pageInfo": pageInfo})
 }
 
+// getSummary returns the treasurer's cash statement for the from and to dates, or
+// for a calendar month when year and month are given instead
 func (app *application) getSummary(w http.ResponseWriter, r *http.Request) {
 	qs := r.URL.Query()
 
 	startDate, hasFrom := app.readDateParam(qs, "from")
 	endDate, _ := app.readDateParam(qs, "to")
 
+	if !hasFrom && qs.Get("month") != "" {
+		year := app.readIntParam(qs, "year", time.Now().Year())
+		month := app.readIntParam(qs, "month", int(time.Now().Month()))
+
+		startDate = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		endDate = startDate.AddDate(0, 1, -1)
+		hasFrom = true
+	}
+
 	if !hasFrom {
 		app.writeJSONError(w, http.StatusBadRequest, errors.New("missing from request param"))
 		return
 	}
 
-	file, err := app.fundsModel.GetSummary(startDate, endDate, 1)
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	charts, err := app.readCharts(qs)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// the summary has always been a workbook unless json is asked for by name
+	if exporter == nil && qs.Get("format") != "json" {
+		exporter, _ = app.exporters.Lookup("xlsx")
+	}
+
+	statement, err := app.fundsModel.GetCashStatement(startDate, endDate, app.contextGetUser(r).OrganizationId)
 	if err != nil {
 		app.writeJSONError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	w.Header().Set("Content-Disposition", "attachment; filename=contributions-summary.xlsx")
-	w.Write(file)
+	if exporter == nil {
+		app.writeJSON(w, http.StatusOK, statement)
+		return
+	}
+
+	app.writeDocument(w, r, exporter, exports.CashStatement(statement, data.SelectCharts(charts)))
 }
 
 func (app *application) upload(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(10 << 20) // limit upload to 10MB
+	upload, values, err := app.readUpload(w, r, "document")
 
 	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		if errors.As(err, &maxBytesError) {
+			app.writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("file must not be larger than %d MB", maxUploadSize>>20))
+			return
+		}
 		app.writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	file, handler, err := r.FormFile("document")
+	keep := false
+
+	defer func() {
+		if !keep {
+			upload.Remove()
+		}
+	}()
+
+	user := app.contextGetUser(r)
+
+	profile, err := app.readImportProfile(values.Get("profileId"), user.OrganizationId)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("import profile not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job := &models.ImportJob{
+		FileName:        upload.Name,
+		DuplicateAction: strings.ToUpper(values.Get("onDuplicate")),
+	}
+
+	if profile != nil {
+		job.ProfileId = profile.ID
+	}
+
+	if job.DuplicateAction == "" {
+		job.DuplicateAction = imports.DuplicateSkip
+	}
+
+	if !validator.In(job.DuplicateAction, imports.DuplicateActions...) {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("onDuplicate must be one of SKIP, UPDATE or FAIL"))
+		return
+	}
+
+	if r.URL.Query().Get("preview") == "true" {
+		preview, err := app.fundsModel.PreviewFile(user, upload, job, profile)
+
+		if err != nil {
+			if errors.Is(err, importers.ErrCsvDate) {
+				app.writeJSONError(w, http.StatusUnprocessableEntity, err)
+				return
+			}
+			app.writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		keep = true
+
+		app.writeJSON(w, http.StatusOK, preview)
+		return
+	}
+
+	err = app.fundsModel.ValidateFile(user, upload, job, profile)
+
+	if err != nil {
+		if errors.Is(err, importers.ErrCsvDate) {
+			app.writeJSONError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if errors.Is(err, importers.ErrUnsupportedFormat) {
+			app.writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("file has already been uploaded or could not be saved"))
+		return
+	}
+
+	keep = true
+
+	jobId, err := app.enqueueImport(user, job)
 
 	if err != nil {
-		app.writeJSONError(w, http.StatusBadRequest, errors.New("missing file or field name 'document'"))
+		app.writeJSONError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	fileName := handler.Filename
+	app.writeJSON(w, http.StatusAccepted, envelope{"message": "file queued for import", "id": job.ID, "jobId": jobId})
+}
 
-	if filepath.Ext(fileName) != ".xlsx" {
-		app.writeJSONError(w, http.StatusBadRequest, errors.New("invalid file type, expected xlsx excel file"))
+func (app *application) confirmImport(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PreviewToken string `json:"previewToken"`
+	}
+
+	err := app.readJSON(w, r, &input)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	data, err := app.fundsModel.ValidateFile(file, fileName)
+	if input.PreviewToken == "" {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("previewToken is required"))
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	job, err := app.importJobModel.ConfirmPreview(user, input.PreviewToken)
 
 	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("preview not found or expired"))
+			return
+		}
 		app.writeJSONError(w, http.StatusBadRequest, errors.New("file has already been uploaded or could not be saved"))
 		return
 	}
 
-	go app.fundsModel.ProcessExcelFile(app.contextGetUser(r), data, fileName)
+	jobId, err := app.enqueueImport(user, job)
 
-	app.writeJSON(w, http.StatusOK, map[string]string{"message": "file uploaded successfully"})
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
 
+	app.writeJSON(w, http.StatusAccepted, envelope{"message": "file queued for import", "id": job.ID, "jobId": jobId})
 }
 
 func (app *application) getCategories(w http.ResponseWriter, r *http.Request) {
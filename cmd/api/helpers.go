@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +10,14 @@ import (
 	"net/url"
 	"runtime/debug"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/exports"
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/validator"
 	"github.com/VaudKK/CAS/utils"
 )
 
@@ -26,7 +33,7 @@ func (app *application) writeJSONError(w http.ResponseWriter, httpStatus int, er
 	utils.GetLoggerInstance().ErrorLog.Output(2, trace)
 
 	errResponse := ErrorResponse{
-		ErrorMessage: err.Error(),
+		ErrorMessage: responseLanguage(w).Message(err.Error()),
 	}
 
 	js, err := json.Marshal(errResponse)
@@ -48,7 +55,7 @@ func (app *application) writeJSON(w http.ResponseWriter, httpStatus int, body an
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.WriteHeader(httpStatus)
 
-	js, err := json.Marshal(body)
+	js, err := json.Marshal(localize(responseLanguage(w), httpStatus, body))
 
 	if err != nil {
 		return err
@@ -59,6 +66,50 @@ func (app *application) writeJSON(w http.ResponseWriter, httpStatus int, body an
 	return nil
 }
 
+// responseLanguage is the printer of the language the response is written in, which
+// the authenticate middleware chooses
+func responseLanguage(w http.ResponseWriter) *i18n.Printer {
+	return i18n.For(w.Header().Get("Content-Language"))
+}
+
+// localize translates the messages of a response: validation errors, and the message
+// or error message of an envelope
+func localize(p *i18n.Printer, httpStatus int, body any) any {
+	switch b := body.(type) {
+	case map[string]string:
+		if httpStatus < http.StatusBadRequest {
+			return body
+		}
+
+		translated := make(map[string]string, len(b))
+
+		for key, message := range b {
+			translated[key] = p.Message(message)
+		}
+
+		return translated
+	case envelope:
+		translated := make(envelope, len(b))
+
+		for key, value := range b {
+			if message, ok := value.(string); ok && (key == "message" || key == "errorMessage") {
+				value = p.Message(message)
+			}
+			translated[key] = value
+		}
+
+		return translated
+	case ErrorResponse:
+		if message, ok := b.ErrorMessage.(string); ok {
+			b.ErrorMessage = p.Message(message)
+		}
+
+		return b
+	}
+
+	return body
+}
+
 func (app *application) writeUnauthorizedJSON(w http.ResponseWriter, r *http.Request) {
 	app.writeJSON(w, http.StatusUnauthorized, ErrorResponse{
 		ErrorMessage: "Unauthorized",
@@ -149,3 +200,140 @@ func (app *application) readDateParam(values url.Values, key string) (time.Time,
 
 	return t, true
 }
+
+// readCharts reads the charts report option, a comma separated list of pie, columns
+// and trend or none. No option means every chart.
+func (app *application) readCharts(values url.Values) ([]string, error) {
+	if values.Get("charts") == "" {
+		return nil, nil
+	}
+
+	names := strings.Split(values.Get("charts"), ",")
+
+	for i := range names {
+		names[i] = strings.ToLower(strings.TrimSpace(names[i]))
+	}
+
+	v := validator.New()
+
+	if data.ValidateCharts(v, names); !v.Valid() {
+		return nil, errors.New("charts " + v.Errors["charts"])
+	}
+
+	return names, nil
+}
+
+// readBudgetPeriod reads the fiscal year and the month the report runs up to,
+// defaulting to the current year to date
+func (app *application) readBudgetPeriod(values url.Values) (int, int) {
+	now := time.Now()
+
+	year := app.readIntParam(values, "year", now.Year())
+	month := app.readIntParam(values, "month", int(now.Month()))
+
+	if year != now.Year() && values.Get("month") == "" {
+		month = 12
+	}
+
+	if month < 1 || month > 12 {
+		month = 12
+	}
+
+	return year, month
+}
+
+// maxUploadSize limits imported files. Multi-year archives run to around 100MB.
+const maxUploadSize = 256 << 20
+
+// readUpload streams a multipart request, spooling the file in the named field to the
+// upload directory instead of holding it in memory. The other fields are returned as
+// form values.
+func (app *application) readUpload(w http.ResponseWriter, r *http.Request, field string) (*imports.Upload, url.Values, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	reader, err := r.MultipartReader()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := url.Values{}
+	var upload *imports.Upload
+
+	for {
+		part, err := reader.NextPart()
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err == nil && part.FormName() == field && part.FileName() != "" && upload == nil {
+			upload, err = imports.Spool(app.configuration.uploadDir, part.FileName(), part)
+		} else if err == nil {
+			var value []byte
+			value, err = io.ReadAll(io.LimitReader(part, 1<<10))
+			values.Add(part.FormName(), string(value))
+		}
+
+		if err != nil {
+			if upload != nil {
+				upload.Remove()
+			}
+			return nil, nil, err
+		}
+	}
+
+	if upload == nil {
+		return nil, nil, fmt.Errorf("missing file or field name '%s'", field)
+	}
+
+	return upload, values, nil
+}
+
+// readExporter negotiates the format of a list or report from the format query value
+// or the Accept header. The older generatePdf and generateExcel flags still work. A
+// nil exporter means the response is json.
+func (app *application) readExporter(r *http.Request) (exports.Exporter, error) {
+	qs := r.URL.Query()
+	format := qs.Get("format")
+
+	if format == "" {
+		if qs.Get("generatePdf") == "true" {
+			format = "pdf"
+		} else if qs.Get("generateExcel") == "true" {
+			format = "xlsx"
+		}
+	}
+
+	exporter, err := app.exporters.Negotiate(format, r.Header.Get("Accept"))
+
+	if err != nil {
+		return nil, fmt.Errorf("format must be one of json, %s", strings.Join(app.exporters.Formats(), ", "))
+	}
+
+	return exporter, nil
+}
+
+// writeDocument renders a document and sends it as an attachment
+func (app *application) writeDocument(w http.ResponseWriter, r *http.Request, exporter exports.Exporter, doc *exports.Document) {
+	var buf bytes.Buffer
+
+	user := app.contextGetUser(r)
+
+	doc.OrganizationId = user.OrganizationId
+	doc.Locale = user.Locale
+
+	if err := exporter.Export(&buf, doc); err != nil {
+		if errors.Is(err, exports.ErrNoJournal) || errors.Is(err, exports.ErrUnsupportedDocument) {
+			app.writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", exporter.MediaType())
+	w.Header().Set("Content-Disposition", "attachment; filename="+doc.Name+"."+exporter.Extension())
+	w.Header().Set("Vary", "Accept")
+	w.Write(buf.Bytes())
+}
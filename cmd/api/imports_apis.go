@@ -0,0 +1,392 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/exports"
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/imports/importers"
+	"github.com/VaudKK/CAS/pkg/models/postgres"
+	"github.com/VaudKK/CAS/pkg/validator"
+	"github.com/VaudKK/CAS/utils"
+	"github.com/gorilla/mux"
+)
+
+func (app *application) getImports(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	page := app.readIntParam(qs, "page", 1)
+	size := app.readIntParam(qs, "size", 10)
+
+	pageable := utils.Pageable{
+		Page:   page,
+		Size:   size,
+		OffSet: page * size,
+	}
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	jobs, pageInfo, err := app.importJobModel.GetImports(app.contextGetUser(r).OrganizationId, pageable)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.Imports(jobs))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": jobs, "pageInfo": pageInfo})
+}
+
+func (app *application) getImport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	job, err := app.importJobModel.GetImport(app.contextGetUser(r).OrganizationId, id)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("import not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, job)
+}
+
+func (app *application) deleteImport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	removed, err := app.fundsModel.RollbackImport(app.contextGetUser(r), id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusNotFound, errors.New("import not found"))
+		case errors.Is(err, postgres.ErrImportNotSucceeded), errors.Is(err, postgres.ErrImportUpdatedRows),
+			errors.Is(err, postgres.ErrPeriodClosed):
+			app.writeJSONError(w, http.StatusConflict, err)
+		default:
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "import rolled back", "removed": removed})
+}
+
+func (app *application) downloadImportFile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	fileName, file, size, err := app.importJobModel.OpenFile(app.contextGetUser(r).OrganizationId, id)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("file not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	defer file.Close()
+
+	format, _ := importers.DetectFormat(file, size)
+
+	w.Header().Set("Content-Type", importers.ContentType(format))
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(fileName))
+	http.ServeContent(w, r, fileName, time.Time{}, file)
+}
+
+func (app *application) createImportProfile(w http.ResponseWriter, r *http.Request) {
+	var profile imports.Profile
+
+	err := app.readJSON(w, r, &profile)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateImportProfile(v, &profile)
+
+	if !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	id, err := app.importProfileModel.SaveProfile(app.contextGetUser(r), &profile)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"message": "successfully saved import profile", "id": id})
+}
+
+func (app *application) getImportProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := app.importProfileModel.GetProfiles(app.contextGetUser(r).OrganizationId)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, profiles)
+}
+
+func (app *application) getImportProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	profile, err := app.importProfileModel.GetProfile(app.contextGetUser(r).OrganizationId, id)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("import profile not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, profile)
+}
+
+// readImportProfile loads the profile named by the profileId form value. No profile
+// means the default layout.
+func (app *application) readImportProfile(value string, organizationId int) (*imports.Profile, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.Atoi(value)
+
+	if err != nil {
+		return nil, errors.New("profileId must be a positive INTEGER")
+	}
+
+	return app.importProfileModel.GetProfile(organizationId, id)
+}
+
+// replaceImport previews a corrected file for an earlier import. Nothing changes until
+// the preview is confirmed.
+func (app *application) replaceImport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	upload, values, err := app.readUpload(w, r, "document")
+
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		if errors.As(err, &maxBytesError) {
+			app.writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("file must not be larger than %d MB", maxUploadSize>>20))
+			return
+		}
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	keep := false
+
+	defer func() {
+		if !keep {
+			upload.Remove()
+		}
+	}()
+
+	user := app.contextGetUser(r)
+
+	profile, err := app.readImportProfile(values.Get("profileId"), user.OrganizationId)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("import profile not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	replacement, err := app.fundsModel.PreviewReplacement(user, id, upload, profile)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusNotFound, errors.New("import not found"))
+		case errors.Is(err, postgres.ErrImportNotReplaceable):
+			app.writeJSONError(w, http.StatusConflict, err)
+		case errors.Is(err, importers.ErrCsvDate):
+			app.writeJSONError(w, http.StatusUnprocessableEntity, err)
+		default:
+			app.writeJSONError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	keep = true
+
+	app.writeJSON(w, http.StatusOK, replacement)
+}
+
+// confirmReplaceImport applies a previewed replacement
+func (app *application) confirmReplaceImport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	var input struct {
+		PreviewToken string `json:"previewToken"`
+	}
+
+	if err = app.readJSON(w, r, &input); err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if input.PreviewToken == "" {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("previewToken is required"))
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	version, err := app.importJobModel.GetPendingVersion(user.OrganizationId, id, input.PreviewToken)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("preview not found or expired"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var profile *imports.Profile
+
+	if version.ProfileId != 0 {
+		profile, err = app.importProfileModel.GetProfile(user.OrganizationId, version.ProfileId)
+
+		if err != nil {
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	version, err = app.fundsModel.ApplyReplacement(user, version, profile)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusNotFound, errors.New("preview not found or expired"))
+		case errors.Is(err, postgres.ErrImportNotReplaceable), errors.Is(err, postgres.ErrReplacementStale),
+			errors.Is(err, postgres.ErrPeriodClosed), errors.Is(err, postgres.ErrTotalsMismatch):
+			app.writeJSONError(w, http.StatusConflict, err)
+		default:
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "import replaced", "version": version})
+}
+
+func (app *application) getImportVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	versions, err := app.importJobModel.GetVersions(app.contextGetUser(r).OrganizationId, id)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("import not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, versions)
+}
+
+func (app *application) downloadImportVersionFile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	fileName, file, size, err := app.importJobModel.OpenVersionFile(app.contextGetUser(r).OrganizationId, id, version)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("file not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	defer file.Close()
+
+	format, _ := importers.DetectFormat(file, size)
+
+	w.Header().Set("Content-Type", importers.ContentType(format))
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(fileName))
+	http.ServeContent(w, r, fileName, time.Time{}, file)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/jobs"
+	"github.com/VaudKK/CAS/pkg/mailer"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/models/postgres"
+	"github.com/VaudKK/CAS/utils"
+)
+
+type importPayload struct {
+	ImportId int `json:"importId"`
+	UserId   int `json:"userId"`
+}
+
+func (app *application) registerJobHandlers() {
+	app.jobPool.Register(data.JobImport, app.runImport)
+	app.jobPool.OnBury(data.JobImport, app.buryImport)
+	app.jobPool.Register(data.JobReport, app.runReport)
+	app.jobPool.Register(data.JobMail, app.runMail)
+	app.jobPool.Register(data.JobDelivery, app.runDelivery)
+}
+
+// enqueueImport hands a queued import to the workers. The import is failed if the job
+// cannot be created so that the file can be uploaded again.
+func (app *application) enqueueImport(user *models.User, job *models.ImportJob) (int, error) {
+	id, err := app.jobModel.Enqueue(user, data.JobImport, importPayload{ImportId: job.ID, UserId: user.ID})
+
+	if err != nil {
+		app.failImport(job.ID, err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// runImport saves the rows of an uploaded file. A file that cannot be read or does
+// not validate fails the import and is not retried; other errors are retried, and
+// buryImport fails the import once the pool gives up on the job. An import that is
+// no longer queued or running has already been handled by an earlier attempt.
+func (app *application) runImport(ctx context.Context, job *models.Job) (*jobs.Result, error) {
+	var payload importPayload
+
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		app.failImport(payload.ImportId, err)
+		return nil, jobs.Permanent(err)
+	}
+
+	user, err := app.userModel.GetUserID(payload.UserId)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			err = fmt.Errorf("user %d not found", payload.UserId)
+			app.failImport(payload.ImportId, err)
+			return nil, jobs.Permanent(err)
+		}
+		return nil, err
+	}
+
+	importJob, err := app.importJobModel.GetImport(user.OrganizationId, payload.ImportId)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			err = fmt.Errorf("import %d not found", payload.ImportId)
+			app.failImport(payload.ImportId, err)
+			return nil, jobs.Permanent(err)
+		}
+		return nil, err
+	}
+
+	if importJob.Status != data.ImportQueued && importJob.Status != data.ImportRunning {
+		return &jobs.Result{Data: envelope{"importId": importJob.ID, "status": importJob.Status}}, nil
+	}
+
+	var profile *imports.Profile
+
+	if importJob.ProfileId != 0 {
+		profile, err = app.importProfileModel.GetProfile(user.OrganizationId, importJob.ProfileId)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = app.fundsModel.ProcessFile(user, importJob, profile); err != nil {
+		if postgres.IsInvalidImport(err) {
+			return nil, jobs.Permanent(err)
+		}
+
+		return nil, err
+	}
+
+	return &jobs.Result{Data: envelope{"importId": importJob.ID, "status": data.ImportSucceeded}}, nil
+}
+
+// buryImport fails the import of a job the pool has given up on, whether its
+// attempts ran out or it had no handler, so that the import does not stay queued
+// or running and the same file can be uploaded again
+func (app *application) buryImport(job *models.Job, cause error) {
+	var payload importPayload
+
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		utils.GetLoggerInstance().ErrorLog.Printf("Error while reading the import of job %d: %v", job.ID, err)
+		return
+	}
+
+	app.failImport(payload.ImportId, cause)
+}
+
+// failImport fails an import that is still queued or running. A payload too broken
+// to name its import has nothing to fail.
+func (app *application) failImport(id int, cause error) {
+	if id == 0 {
+		return
+	}
+
+	if err := app.importJobModel.FailImport(id, &imports.Progress{}, cause); err != nil {
+		utils.GetLoggerInstance().ErrorLog.Printf("Error while failing import %d: %v", id, err)
+	}
+}
+
+func (app *application) runMail(ctx context.Context, job *models.Job) (*jobs.Result, error) {
+	var message mailer.Message
+
+	if err := json.Unmarshal(job.Payload, &message); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	if err := app.mailer.Send(message); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/models/postgres"
+	"github.com/VaudKK/CAS/utils"
+	"github.com/gorilla/mux"
+)
+
+func (app *application) getJobs(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	page := app.readIntParam(qs, "page", 1)
+	size := app.readIntParam(qs, "size", 10)
+
+	pageable := utils.Pageable{
+		Page:   page,
+		Size:   size,
+		OffSet: page * size,
+	}
+
+	jobs, pageInfo, err := app.jobModel.GetJobs(app.contextGetUser(r).OrganizationId, pageable)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": jobs, "pageInfo": pageInfo})
+}
+
+func (app *application) getJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	job, err := app.jobModel.GetJob(app.contextGetUser(r).OrganizationId, id)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("job not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, job)
+}
+
+// retryJob puts a dead job back on the queue
+func (app *application) retryJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	err = app.jobModel.Requeue(app.contextGetUser(r).OrganizationId, id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusNotFound, errors.New("job not found"))
+		case errors.Is(err, postgres.ErrJobNotDead):
+			app.writeJSONError(w, http.StatusConflict, err)
+		default:
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusAccepted, envelope{"message": "job queued", "id": id})
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/exports"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/models/postgres"
+	"github.com/VaudKK/CAS/pkg/validator"
+	"github.com/VaudKK/CAS/utils"
+	"github.com/gorilla/mux"
+)
+
+func (app *application) getAccounts(w http.ResponseWriter, r *http.Request) {
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	accounts, err := app.ledgerModel.GetAccounts(app.contextGetUser(r).OrganizationId)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.Accounts(accounts))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": accounts})
+}
+
+func (app *application) createAccount(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code     string `json:"code"`
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		Category string `json:"category"`
+	}
+
+	err := app.readJSON(w, r, &input)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	account := &models.Account{
+		Code:     input.Code,
+		Name:     input.Name,
+		Type:     input.Type,
+		Category: input.Category,
+	}
+
+	v := validator.New()
+
+	data.ValidateAccount(v, account)
+
+	if !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	id, err := app.ledgerModel.CreateAccount(app.contextGetUser(r), account)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"message": "account created", "id": id})
+}
+
+func (app *application) getJournalEntries(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	page := app.readIntParam(qs, "page", 0)
+	size := app.readIntParam(qs, "size", 50)
+	accountId := app.readIntParam(qs, "account", 0)
+	dateFrom, hasFrom := app.readDateParam(qs, "from")
+	dateTo, hasTo := app.readDateParam(qs, "to")
+
+	if !hasFrom {
+		dateFrom = time.Time{}
+	}
+
+	if !hasTo {
+		dateTo = time.Time{}
+	}
+
+	pageable := utils.Pageable{
+		Page:   page,
+		Size:   size,
+		OffSet: page * size,
+	}
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	entries, pageInfo, err := app.ledgerModel.GetEntries(app.contextGetUser(r).OrganizationId, dateFrom, dateTo, accountId, pageable)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.JournalEntries(entries, dateFrom, dateTo))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": entries, "pageInfo": pageInfo})
+}
+
+func (app *application) reverseJournalEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	date := r.URL.Query().Get("date")
+
+	if date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			app.writeJSONError(w, http.StatusBadRequest, errors.New("date must be in the format YYYY-MM-DD"))
+			return
+		}
+	}
+
+	reversalId, err := app.ledgerModel.ReverseEntry(app.contextGetUser(r), id, date)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusNotFound, err)
+		case errors.Is(err, postgres.ErrAlreadyReversed), errors.Is(err, postgres.ErrPeriodClosed):
+			app.writeJSONError(w, http.StatusConflict, err)
+		default:
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"message": "entry reversed", "id": reversalId})
+}
+
+func (app *application) postTransfer(w http.ResponseWriter, r *http.Request) {
+	var input models.Transfer
+
+	err := app.readJSON(w, r, &input)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateTransfer(v, &input)
+
+	if !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	id, err := app.ledgerModel.PostTransfer(app.contextGetUser(r), &input)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusBadRequest, errors.New("unknown account"))
+		case errors.Is(err, postgres.ErrMixedTransfer):
+			app.writeJSONError(w, http.StatusBadRequest, err)
+		case errors.Is(err, postgres.ErrPeriodClosed):
+			app.writeJSONError(w, http.StatusConflict, err)
+		default:
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"message": "transfer posted", "id": id})
+}
+
+func (app *application) checkLedgerIntegrity(w http.ResponseWriter, r *http.Request) {
+	imbalances, err := app.ledgerModel.CheckIntegrity(app.contextGetUser(r).OrganizationId)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"balanced": len(imbalances) == 0, "data": imbalances})
+}
+
+func (app *application) postUnposted(w http.ResponseWriter, r *http.Request) {
+	posted, err := app.ledgerModel.PostUnposted(app.contextGetUser(r))
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "ledger backfilled", "posted": posted})
+}
+
+func (app *application) closePeriod(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PeriodEnd string `json:"periodEnd"`
+	}
+
+	err := app.readJSON(w, r, &input)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := time.Parse("2006-01-02", input.PeriodEnd); err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("periodEnd must be in the format YYYY-MM-DD"))
+		return
+	}
+
+	id, err := app.ledgerModel.ClosePeriod(app.contextGetUser(r), input.PeriodEnd)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"message": "period closed", "id": id})
+}
+
+func (app *application) getClosedPeriods(w http.ResponseWriter, r *http.Request) {
+	periods, err := app.ledgerModel.GetClosedPeriods(app.contextGetUser(r).OrganizationId)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": periods})
+}
+
+// getJournal returns the contributions and expenditures of a period as double entry.
+// Asked for as journal.csv, iif or xero.csv it is the file to import into an
+// accounting package, with the categories under the organization's mapped accounts.
+func (app *application) getJournal(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	dateFrom, hasFrom := app.readDateParam(qs, "from")
+	dateTo, hasTo := app.readDateParam(qs, "to")
+
+	if !hasFrom {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("from must be a date in the format YYYY-MM-DD"))
+		return
+	}
+
+	if !hasTo {
+		dateTo = time.Time{}
+	}
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	doc, err := app.journalDocument(app.contextGetUser(r).OrganizationId, dateFrom, dateTo)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, doc)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": doc.Journal})
+}
+
+// journalDocument loads the contributions and expenditures of a period for the
+// journal, the same way the contribution and expenditure searches do
+func (app *application) journalDocument(organizationId int, dateFrom, dateTo time.Time) (*exports.Document, error) {
+	pageable := utils.Pageable{Page: 0, Size: math.MaxInt, OffSet: 0}
+
+	contributions, _, err := app.fundsModel.SearchByDateRange(organizationId, dateFrom, dateTo, pageable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	expenditures, _, err := app.expenditureModel.SearchExpenditures(organizationId, "", false, dateFrom, dateTo, pageable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return exports.Journal(contributions, expenditures, app.fundsModel.GetCategories(), dateFrom, dateTo), nil
+}
+
+func (app *application) getAccountMappings(w http.ResponseWriter, r *http.Request) {
+	mappings, err := app.ledgerModel.GetAccountMappings(app.contextGetUser(r).OrganizationId)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": mappings})
+}
+
+// saveAccountMapping maps a category, or cash or bank, to an account of the
+// organization's accounting package for the journal exports
+func (app *application) saveAccountMapping(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		AccountType string `json:"accountType"`
+		Category    string `json:"category"`
+		Code        string `json:"code"`
+		Name        string `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	mapping := &models.AccountMapping{
+		AccountType: strings.ToUpper(strings.TrimSpace(input.AccountType)),
+		Category:    strings.TrimSpace(input.Category),
+		Code:        strings.TrimSpace(input.Code),
+		Name:        strings.TrimSpace(input.Name),
+	}
+
+	v := validator.New()
+
+	data.ValidateAccountMapping(v, mapping)
+
+	if !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	id, err := app.ledgerModel.SaveAccountMapping(app.contextGetUser(r), mapping)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "account mapping saved", "id": id})
+}
+
+func (app *application) deleteAccountMapping(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	if err = app.ledgerModel.DeleteAccountMapping(app.contextGetUser(r).OrganizationId, id); err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, err)
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "account mapping deleted"})
+}
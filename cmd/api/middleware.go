@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/i18n"
 )
 
 func (app *application) recoverPanic(next http.Handler) http.Handler {
@@ -25,6 +26,11 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Authorization")
+		w.Header().Add("Vary", "Accept-Language")
+
+		// responses are in the language the client asks for until the user is known,
+		// whose own choice is used from then on
+		w.Header().Set("Content-Language", i18n.Match(r.Header.Get("Accept-Language")))
 
 		authorizationHeader := r.Header.Get("Authorization")
 
@@ -64,6 +70,8 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		w.Header().Set("Content-Language", i18n.For(user.Locale).Locale())
+
 		r = app.contextSetUser(r, user)
 
 		next.ServeHTTP(w, r)
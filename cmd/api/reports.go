@@ -0,0 +1,493 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/exports"
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/VaudKK/CAS/pkg/jobs"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/models/postgres"
+	"github.com/VaudKK/CAS/pkg/storage"
+	"github.com/VaudKK/CAS/pkg/validator"
+	"github.com/VaudKK/CAS/utils"
+	"github.com/gorilla/mux"
+)
+
+// reportPayload names the report a job generates. What goes into the report is kept
+// on the report itself.
+type reportPayload struct {
+	ReportId int `json:"reportId"`
+}
+
+// requestReport queues a report for generation. The response carries the report id
+// to poll for its status and download link.
+func (app *application) requestReport(w http.ResponseWriter, r *http.Request) {
+	report := &models.Report{}
+
+	if err := app.readJSON(w, r, report); err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if exporter, ok := app.exporters.Lookup(report.Format); ok {
+		report.Format = exporter.Extension()
+	}
+
+	if locale, ok := i18n.Normalize(report.Parameters.Locale); ok {
+		report.Parameters.Locale = locale
+	}
+
+	v := validator.New()
+
+	if data.ValidateReport(v, report, app.exporters.Formats()); !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	id, err := app.enqueueReport(app.contextGetUser(r), report)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusAccepted, envelope{"message": "report queued", "id": id})
+}
+
+// enqueueReport saves a report request and hands it to the workers. The report is
+// written in the user's language unless it asks for another, and is failed if the job
+// cannot be created so that it is not left queued forever.
+func (app *application) enqueueReport(user *models.User, report *models.Report) (int, error) {
+	if report.Parameters.Locale == "" {
+		report.Parameters.Locale = user.Locale
+	}
+
+	id, err := app.reportModel.Create(user, report)
+
+	if err != nil {
+		return 0, err
+	}
+
+	jobId, err := app.jobModel.Enqueue(user, data.JobReport, reportPayload{ReportId: id})
+
+	if err == nil {
+		err = app.reportModel.SetJob(id, jobId)
+	}
+
+	if err != nil {
+		if failErr := app.reportModel.Fail(id, err); failErr != nil {
+			utils.GetLoggerInstance().ErrorLog.Printf("Error while failing report %d: %v", id, failErr)
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (app *application) getReports(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	page := app.readIntParam(qs, "page", 1)
+	size := app.readIntParam(qs, "size", 10)
+
+	pageable := utils.Pageable{
+		Page:   page,
+		Size:   size,
+		OffSet: page * size,
+	}
+
+	reports, pageInfo, err := app.reportModel.GetReports(app.contextGetUser(r).OrganizationId, pageable)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, report := range reports {
+		app.signReport(report)
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": reports, "pageInfo": pageInfo})
+}
+
+// getReport returns the status of a report, with a download link once it is ready
+func (app *application) getReport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	report, err := app.reportModel.GetReport(app.contextGetUser(r).OrganizationId, id)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("report not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.signReport(report)
+
+	app.writeJSON(w, http.StatusOK, report)
+}
+
+// downloadReport serves the file of a ready report. The route is open so the link can
+// be handed to a browser or mail client; the signature stands in for the login.
+func (app *application) downloadReport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	qs := r.URL.Query()
+
+	if err = app.signer.Verify(r.URL.Path, qs.Get("expires"), qs.Get("signature")); err != nil {
+		app.writeJSONError(w, http.StatusForbidden, err)
+		return
+	}
+
+	report, err := app.reportModel.GetReportByID(id)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("report not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if report.Status != data.ReportReady {
+		app.writeJSONError(w, http.StatusGone, errors.New("report is not available for download"))
+		return
+	}
+
+	file, err := app.storage.Open(report.StorageKey)
+
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			app.writeJSONError(w, http.StatusGone, errors.New("report is not available for download"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	defer file.Close()
+
+	w.Header().Set("Content-Type", report.ContentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(report.FileName))
+	http.ServeContent(w, r, report.FileName, *report.CompletedAt, file)
+}
+
+// signReport sets the download link of a ready report. The link expires with the file.
+func (app *application) signReport(report *models.Report) {
+	if report.Status != data.ReportReady || report.ExpiresAt == nil {
+		return
+	}
+
+	path := fmt.Sprintf("/api/v1/reports/%d/download", report.ID)
+	report.DownloadURL = path + "?" + app.signer.Sign(path, *report.ExpiresAt)
+}
+
+// runReport generates the file of a requested report and puts it in storage. The
+// report is failed once the job will not be tried again.
+func (app *application) runReport(ctx context.Context, job *models.Job) (*jobs.Result, error) {
+	var payload reportPayload
+
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	report, err := app.reportModel.GetReportByID(payload.ReportId)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			return nil, jobs.Permanent(fmt.Errorf("report %d not found", payload.ReportId))
+		}
+		return nil, err
+	}
+
+	started, err := app.reportModel.Start(report.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !started {
+		return &jobs.Result{Data: envelope{"reportId": report.ID, "status": report.Status}}, nil
+	}
+
+	if err = app.generateReport(report); err != nil {
+		var recordErr error
+
+		if ctx.Err() == nil && (jobs.IsPermanent(err) || job.Attempts >= job.MaxAttempts) {
+			recordErr = app.reportModel.Fail(report.ID, err)
+		} else {
+			recordErr = app.reportModel.RecordError(report.ID, err)
+		}
+
+		if recordErr != nil {
+			utils.GetLoggerInstance().ErrorLog.Printf("Error while recording the failure of report %d: %v", report.ID, recordErr)
+		}
+		return nil, err
+	}
+
+	return &jobs.Result{Data: envelope{"reportId": report.ID, "status": data.ReportReady}}, nil
+}
+
+// generateReport exports a report straight into storage and marks it ready
+func (app *application) generateReport(report *models.Report) error {
+	exporter, ok := app.exporters.Lookup(report.Format)
+
+	if !ok {
+		return jobs.Permanent(fmt.Errorf("unknown report format %q", report.Format))
+	}
+
+	doc, err := app.reportDocument(report, exporter)
+
+	if err != nil {
+		return err
+	}
+
+	doc.OrganizationId = report.OrganizationId
+	doc.Locale = report.Parameters.Locale
+
+	fileName := doc.Name + "." + exporter.Extension()
+	key := fmt.Sprintf("reports/%d/%d/%s", report.OrganizationId, report.ID, fileName)
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(exporter.Export(writer, doc))
+	}()
+
+	size, err := app.storage.Put(key, reader)
+	reader.CloseWithError(err)
+
+	if err != nil {
+		if errors.Is(err, exports.ErrNoJournal) || errors.Is(err, exports.ErrUnsupportedDocument) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+
+	expiresAt := time.Now().Add(app.configuration.reports.ttl)
+
+	if err = app.reportModel.Complete(report.ID, key, fileName, exporter.MediaType(), size, expiresAt); err != nil {
+		app.storage.Delete(key)
+		return err
+	}
+
+	return nil
+}
+
+// reportDocument loads the data of a report the same way its list or statement
+// endpoint does. Contribution workbooks are written from an open query rather than
+// loaded first, as they can run to tens of thousands of rows.
+func (app *application) reportDocument(report *models.Report, exporter exports.Exporter) (*exports.Document, error) {
+	params := report.Parameters
+	organizationId := report.OrganizationId
+
+	// the dates were validated when the report was requested; a missing date is zero
+	// as it is for the list endpoints
+	dateFrom, _ := time.Parse("2006-01-02", params.From)
+	dateTo, _ := time.Parse("2006-01-02", params.To)
+
+	var err error
+
+	pageable := utils.Pageable{Page: 0, Size: math.MaxInt, OffSet: 0}
+	charts := data.SelectCharts(params.Charts)
+
+	switch report.Type {
+	case data.ReportContributions:
+		if _, ok := exporter.(*exports.ExcelExporter); ok {
+			rows, err := app.fundsModel.QueryContributions(organizationId, params.Terms, params.Exact, dateFrom, dateTo)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return exports.ContributionRows(rows, app.fundsModel.GetCategories(), charts), nil
+		}
+
+		var contributions []*models.Fund
+
+		if params.Terms != "" {
+			contributions, _, err = app.fundsModel.FullTextSearch(organizationId, params.Terms, params.Exact, dateFrom, dateTo, pageable)
+		} else {
+			contributions, _, err = app.fundsModel.SearchByDateRange(organizationId, dateFrom, dateTo, pageable)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return exports.Contributions(contributions, app.fundsModel.GetCategories(), dateFrom, dateTo, charts), nil
+	case data.ReportExpenditures:
+		expenditures, _, err := app.expenditureModel.SearchExpenditures(organizationId, params.Terms, params.Exact,
+			dateFrom, dateTo, pageable)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return exports.Expenditures(expenditures, dateFrom, dateTo), nil
+	case data.ReportCashStatement:
+		statement, err := app.fundsModel.GetCashStatement(dateFrom, dateTo, organizationId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return exports.CashStatement(statement, charts), nil
+	case data.ReportFundBalances:
+		balances, err := app.expenditureModel.GetFundBalances(organizationId, dateFrom, dateTo)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return exports.FundBalances(balances, dateFrom, dateTo), nil
+	case data.ReportBudgetVsActual:
+		now := time.Now()
+		year, month := params.Year, params.Month
+
+		if year == 0 {
+			year = now.Year()
+		}
+
+		if month == 0 {
+			month = 12
+
+			if year == now.Year() {
+				month = int(now.Month())
+			}
+		}
+
+		budgetReport, err := app.budgetModel.GetBudgetVsActual(organizationId, year, month)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return exports.BudgetVsActual(budgetReport, year, month), nil
+	case data.ReportVariance:
+		variance, err := app.fundsModel.GetMonthlyVariance(data.VarianceCategories)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return exports.Variance(variance), nil
+	case data.ReportJournal:
+		return app.journalDocument(organizationId, dateFrom, dateTo)
+	case data.ReportAnnual:
+		year := params.Year
+
+		if year == 0 {
+			year = time.Now().Year()
+		}
+
+		annualReport, err := app.fundsModel.GetAnnualReport(organizationId, year)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return exports.AnnualReport(annualReport), nil
+	}
+
+	if dateTo.IsZero() {
+		dateTo = time.Now().Truncate(24 * time.Hour)
+	}
+
+	period := postgres.NewStatementPeriod(dateFrom, dateTo, params.Comparative == nil || *params.Comparative)
+
+	switch report.Type {
+	case data.ReportTrialBalance:
+		statement, err := app.statementModel.GetTrialBalance(organizationId, period)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return exports.TrialBalance(statement), nil
+	case data.ReportIncomeStatement:
+		statement, err := app.statementModel.GetIncomeStatement(organizationId, period)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return exports.IncomeStatement(statement), nil
+	case data.ReportFundBalanceSheet:
+		statement, err := app.statementModel.GetFundBalanceSheet(organizationId, period)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return exports.FundBalanceSheet(statement), nil
+	}
+
+	return nil, jobs.Permanent(fmt.Errorf("unknown report type %q", report.Type))
+}
+
+// expireReports removes the files of reports past their expiry until the context is
+// cancelled
+func (app *application) expireReports(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				reports, err := app.reportModel.Expired(100)
+
+				if err != nil {
+					utils.GetLoggerInstance().ErrorLog.Printf("Error while listing expired reports: %v", err)
+					break
+				}
+
+				expired := 0
+
+				for _, report := range reports {
+					if err = app.storage.Delete(report.StorageKey); err != nil {
+						utils.GetLoggerInstance().ErrorLog.Printf("Error while deleting the file of report %d: %v", report.ID, err)
+						continue
+					}
+
+					if err = app.reportModel.MarkExpired(report.ID); err != nil {
+						utils.GetLoggerInstance().ErrorLog.Printf("Error while expiring report %d: %v", report.ID, err)
+						continue
+					}
+
+					expired++
+				}
+
+				if expired < 100 {
+					break
+				}
+			}
+		}
+	}
+}
@@ -12,6 +12,7 @@ func (app *application) routes() http.Handler {
 
 	// contributions
 	subRouter.Handle("/contributions/import", app.requiresAuthenticatedUser(app.upload)).Methods("POST")
+	subRouter.Handle("/contributions/import/confirm", app.requiresAuthenticatedUser(app.confirmImport)).Methods("POST")
 	subRouter.Handle("/contributions", app.requiresAuthenticatedUser(app.addContribution)).Methods("POST")
 	subRouter.Handle("/contributions", app.requiresAuthenticatedUser(app.getContributions)).Methods("GET")
 	subRouter.Handle("/contributions/search", app.requiresAuthenticatedUser(app.search)).Methods("GET")
@@ -21,6 +22,79 @@ func (app *application) routes() http.Handler {
 	subRouter.Handle("/contributions/{id}", app.requiresAuthenticatedUser(app.updateContribution)).Methods("PUT")
 	subRouter.Handle("/contributions/summary", app.requiresAuthenticatedUser(app.getSummary)).Methods("GET")
 
+	// budgets
+	subRouter.Handle("/budgets", app.requiresAuthenticatedUser(app.saveBudget)).Methods("POST")
+	subRouter.Handle("/budgets", app.requiresAuthenticatedUser(app.getBudgets)).Methods("GET")
+	subRouter.Handle("/budgets/actual", app.requiresAuthenticatedUser(app.getBudgetVsActual)).Methods("GET")
+	subRouter.Handle("/budgets/alerts", app.requiresAuthenticatedUser(app.getBudgetAlerts)).Methods("GET")
+
+	// expenditures
+	subRouter.Handle("/expenditures", app.requiresAuthenticatedUser(app.addExpenditure)).Methods("POST")
+	subRouter.Handle("/expenditures", app.requiresAuthenticatedUser(app.getExpenditures)).Methods("GET")
+	subRouter.Handle("/expenditures/search", app.requiresAuthenticatedUser(app.searchExpenditures)).Methods("GET")
+	subRouter.Handle("/expenditures/balances", app.requiresAuthenticatedUser(app.getFundBalances)).Methods("GET")
+	subRouter.Handle("/expenditures/{id}", app.requiresAuthenticatedUser(app.updateExpenditure)).Methods("PUT")
+	subRouter.Handle("/expenditures/{id}/approve", app.requiresAuthenticatedUser(app.approveExpenditure)).Methods("POST")
+	subRouter.Handle("/expenditures/{id}/document", app.requiresAuthenticatedUser(app.uploadExpenditureDocument)).Methods("POST")
+	subRouter.Handle("/expenditures/{id}/document", app.requiresAuthenticatedUser(app.getExpenditureDocument)).Methods("GET")
+
+	// ledger
+	subRouter.Handle("/ledger/accounts", app.requiresAuthenticatedUser(app.getAccounts)).Methods("GET")
+	subRouter.Handle("/ledger/accounts", app.requiresAuthenticatedUser(app.createAccount)).Methods("POST")
+	subRouter.Handle("/ledger/entries", app.requiresAuthenticatedUser(app.getJournalEntries)).Methods("GET")
+	subRouter.Handle("/ledger/entries/{id}/reverse", app.requiresAuthenticatedUser(app.reverseJournalEntry)).Methods("POST")
+	subRouter.Handle("/ledger/transfers", app.requiresAuthenticatedUser(app.postTransfer)).Methods("POST")
+	subRouter.Handle("/ledger/integrity", app.requiresAuthenticatedUser(app.checkLedgerIntegrity)).Methods("GET")
+	subRouter.Handle("/ledger/backfill", app.requiresAuthenticatedUser(app.postUnposted)).Methods("POST")
+	subRouter.Handle("/ledger/periods", app.requiresAuthenticatedUser(app.getClosedPeriods)).Methods("GET")
+	subRouter.Handle("/ledger/periods", app.requiresAuthenticatedUser(app.closePeriod)).Methods("POST")
+	subRouter.Handle("/ledger/journal", app.requiresAuthenticatedUser(app.getJournal)).Methods("GET")
+	subRouter.Handle("/ledger/account-mappings", app.requiresAuthenticatedUser(app.getAccountMappings)).Methods("GET")
+	subRouter.Handle("/ledger/account-mappings", app.requiresAuthenticatedUser(app.saveAccountMapping)).Methods("PUT")
+	subRouter.Handle("/ledger/account-mappings/{id}", app.requiresAuthenticatedUser(app.deleteAccountMapping)).Methods("DELETE")
+
+	// statements
+	subRouter.Handle("/statements/trial-balance", app.requiresAuthenticatedUser(app.getTrialBalance)).Methods("GET")
+	subRouter.Handle("/statements/income", app.requiresAuthenticatedUser(app.getIncomeStatement)).Methods("GET")
+	subRouter.Handle("/statements/fund-balances", app.requiresAuthenticatedUser(app.getFundBalanceSheet)).Methods("GET")
+	subRouter.Handle("/statements/annual", app.requiresAuthenticatedUser(app.getAnnualReport)).Methods("GET")
+
+	// imports
+	subRouter.Handle("/imports", app.requiresAuthenticatedUser(app.getImports)).Methods("GET")
+	subRouter.Handle("/imports/profiles", app.requiresAuthenticatedUser(app.createImportProfile)).Methods("POST")
+	subRouter.Handle("/imports/profiles", app.requiresAuthenticatedUser(app.getImportProfiles)).Methods("GET")
+	subRouter.Handle("/imports/profiles/{id}", app.requiresAuthenticatedUser(app.getImportProfile)).Methods("GET")
+	subRouter.Handle("/imports/{id}", app.requiresAuthenticatedUser(app.getImport)).Methods("GET")
+	subRouter.Handle("/imports/{id}", app.requiresAuthenticatedUser(app.deleteImport)).Methods("DELETE")
+	subRouter.Handle("/imports/{id}/file", app.requiresAuthenticatedUser(app.downloadImportFile)).Methods("GET")
+	subRouter.Handle("/imports/{id}/versions", app.requiresAuthenticatedUser(app.replaceImport)).Methods("POST")
+	subRouter.Handle("/imports/{id}/versions", app.requiresAuthenticatedUser(app.getImportVersions)).Methods("GET")
+	subRouter.Handle("/imports/{id}/versions/confirm", app.requiresAuthenticatedUser(app.confirmReplaceImport)).Methods("POST")
+	subRouter.Handle("/imports/{id}/versions/{version}/file", app.requiresAuthenticatedUser(app.downloadImportVersionFile)).Methods("GET")
+
+	// jobs
+	subRouter.Handle("/jobs", app.requiresAuthenticatedUser(app.getJobs)).Methods("GET")
+	subRouter.Handle("/jobs/{id}", app.requiresAuthenticatedUser(app.getJob)).Methods("GET")
+	subRouter.Handle("/jobs/{id}/retry", app.requiresAuthenticatedUser(app.retryJob)).Methods("POST")
+
+	// reports
+	subRouter.Handle("/reports", app.requiresAuthenticatedUser(app.requestReport)).Methods("POST")
+	subRouter.Handle("/reports", app.requiresAuthenticatedUser(app.getReports)).Methods("GET")
+	subRouter.Handle("/reports/subscriptions", app.requiresAuthenticatedUser(app.createSubscription)).Methods("POST")
+	subRouter.Handle("/reports/subscriptions", app.requiresAuthenticatedUser(app.getSubscriptions)).Methods("GET")
+	subRouter.Handle("/reports/subscriptions/{id}", app.requiresAuthenticatedUser(app.getSubscription)).Methods("GET")
+	subRouter.Handle("/reports/subscriptions/{id}", app.requiresAuthenticatedUser(app.updateSubscription)).Methods("PUT")
+	subRouter.Handle("/reports/subscriptions/{id}", app.requiresAuthenticatedUser(app.deleteSubscription)).Methods("DELETE")
+	subRouter.Handle("/reports/subscriptions/{id}/deliveries", app.requiresAuthenticatedUser(app.getDeliveries)).Methods("GET")
+	subRouter.Handle("/reports/subscriptions/{id}/deliveries", app.requiresAuthenticatedUser(app.sendSubscription)).Methods("POST")
+	subRouter.Handle("/reports/deliveries/{id}/retry", app.requiresAuthenticatedUser(app.retryDelivery)).Methods("POST")
+	subRouter.Handle("/reports/{id}", app.requiresAuthenticatedUser(app.getReport)).Methods("GET")
+	subRouter.HandleFunc("/reports/{id}/download", app.downloadReport).Methods("GET")
+
+	// settings
+	subRouter.Handle("/settings/locale", app.requiresAuthenticatedUser(app.updateLocale)).Methods("PUT")
+	subRouter.Handle("/settings/organization/locale", app.requiresAuthenticatedUser(app.updateOrganizationLocale)).Methods("PUT")
+
 	// user
 	subRouter.HandleFunc("/auth/signup", app.createUser).Methods("POST")
 	subRouter.HandleFunc("/auth/login", app.issueToken).Methods("POST")
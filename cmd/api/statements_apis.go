@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/exports"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/models/postgres"
+	"github.com/VaudKK/CAS/pkg/validator"
+)
+
+// readStatementPeriod reads the from and to dates of a statement. Comparative
+// columns for the same period a year earlier are included unless comparative=false.
+func (app *application) readStatementPeriod(r *http.Request, requireFrom bool) (models.StatementPeriod, error) {
+	qs := r.URL.Query()
+
+	dateFrom, hasFrom := app.readDateParam(qs, "from")
+	dateTo, hasTo := app.readDateParam(qs, "to")
+
+	if requireFrom && !hasFrom {
+		return models.StatementPeriod{}, errors.New("missing from request param")
+	}
+
+	if !hasTo {
+		dateTo = time.Now().Truncate(24 * time.Hour)
+	}
+
+	if hasFrom && dateTo.Before(dateFrom) {
+		return models.StatementPeriod{}, errors.New("to date must not be before from date")
+	}
+
+	return postgres.NewStatementPeriod(dateFrom, dateTo, qs.Get("comparative") != "false"), nil
+}
+
+func (app *application) getTrialBalance(w http.ResponseWriter, r *http.Request) {
+	period, err := app.readStatementPeriod(r, false)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := app.statementModel.GetTrialBalance(app.contextGetUser(r).OrganizationId, period)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.TrialBalance(report))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, report)
+}
+
+func (app *application) getIncomeStatement(w http.ResponseWriter, r *http.Request) {
+	period, err := app.readStatementPeriod(r, true)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := app.statementModel.GetIncomeStatement(app.contextGetUser(r).OrganizationId, period)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.IncomeStatement(report))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, report)
+}
+
+func (app *application) getFundBalanceSheet(w http.ResponseWriter, r *http.Request) {
+	period, err := app.readStatementPeriod(r, true)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := app.statementModel.GetFundBalanceSheet(app.contextGetUser(r).OrganizationId, period)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.FundBalanceSheet(report))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, report)
+}
+
+// getAnnualReport returns the annual report of a year, the current one unless
+// ?year= is given. The workbook and the PDF carry the whole pack.
+func (app *application) getAnnualReport(w http.ResponseWriter, r *http.Request) {
+	year := app.readIntParam(r.URL.Query(), "year", time.Now().Year())
+
+	v := validator.New()
+
+	if data.ValidateAnnualYear(v, year); !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	exporter, err := app.readExporter(r)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := app.fundsModel.GetAnnualReport(app.contextGetUser(r).OrganizationId, year)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if exporter != nil {
+		app.writeDocument(w, r, exporter, exports.AnnualReport(report))
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/exports"
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/VaudKK/CAS/pkg/jobs"
+	"github.com/VaudKK/CAS/pkg/mailer"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/models/postgres"
+	"github.com/VaudKK/CAS/pkg/schedule"
+	"github.com/VaudKK/CAS/pkg/validator"
+	"github.com/VaudKK/CAS/utils"
+	"github.com/gorilla/mux"
+)
+
+// deliveryPayload names the delivery a job sends
+type deliveryPayload struct {
+	DeliveryId int `json:"deliveryId"`
+}
+
+type subscriptionInput struct {
+	Name       string   `json:"name"`
+	ReportType string   `json:"reportType"`
+	Period     string   `json:"period"`
+	Format     string   `json:"format"`
+	Recipients []string `json:"recipients"`
+	Cron       string   `json:"cron"`
+	Charts     []string `json:"charts"`
+	Active     *bool    `json:"active"`
+}
+
+// toModel tidies the input into a subscription. Subscriptions are active unless
+// switched off.
+func (input *subscriptionInput) toModel(app *application) *models.ReportSubscription {
+	subscription := &models.ReportSubscription{
+		Name:       strings.TrimSpace(input.Name),
+		ReportType: input.ReportType,
+		Period:     input.Period,
+		Format:     input.Format,
+		Cron:       strings.TrimSpace(input.Cron),
+		Charts:     input.Charts,
+		Active:     input.Active == nil || *input.Active,
+	}
+
+	if exporter, ok := app.exporters.Lookup(input.Format); ok {
+		subscription.Format = exporter.Extension()
+	}
+
+	for _, recipient := range input.Recipients {
+		subscription.Recipients = append(subscription.Recipients, strings.ToLower(strings.TrimSpace(recipient)))
+	}
+
+	return subscription
+}
+
+// readSubscription reads and checks a subscription from the request body, writing
+// the response itself when it is not valid
+func (app *application) readSubscription(w http.ResponseWriter, r *http.Request) (*models.ReportSubscription, bool) {
+	var input subscriptionInput
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	subscription := input.toModel(app)
+
+	v := validator.New()
+
+	if data.ValidateSubscription(v, subscription, app.exporters.Formats()); !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return nil, false
+	}
+
+	return subscription, true
+}
+
+func (app *application) createSubscription(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := app.readSubscription(w, r)
+
+	if !ok {
+		return
+	}
+
+	id, err := app.subscriptionModel.Create(app.contextGetUser(r), subscription, app.nextRun(subscription))
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"message": "successfully saved report subscription", "id": id})
+}
+
+func (app *application) getSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := app.subscriptionModel.GetSubscriptions(app.contextGetUser(r).OrganizationId)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, subscriptions)
+}
+
+func (app *application) getSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	subscription, err := app.subscriptionModel.GetSubscription(app.contextGetUser(r).OrganizationId, id)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("report subscription not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, subscription)
+}
+
+func (app *application) updateSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	subscription, ok := app.readSubscription(w, r)
+
+	if !ok {
+		return
+	}
+
+	err = app.subscriptionModel.Update(app.contextGetUser(r), id, subscription, app.nextRun(subscription))
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("report subscription not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "updated successfully"})
+}
+
+func (app *application) deleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	if err = app.subscriptionModel.Delete(app.contextGetUser(r).OrganizationId, id); err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("report subscription not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "deleted successfully"})
+}
+
+// getDeliveries pages through the log of reports sent for a subscription
+func (app *application) getDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	qs := r.URL.Query()
+	page := app.readIntParam(qs, "page", 1)
+	size := app.readIntParam(qs, "size", 10)
+
+	pageable := utils.Pageable{
+		Page:   page,
+		Size:   size,
+		OffSet: page * size,
+	}
+
+	deliveries, pageInfo, err := app.subscriptionModel.GetDeliveries(app.contextGetUser(r).OrganizationId, id, pageable)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"data": deliveries, "pageInfo": pageInfo})
+}
+
+// sendSubscription sends the report of a subscription now, for the period it would
+// cover if it fell due now. The schedule is left as it is.
+func (app *application) sendSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	subscription, err := app.subscriptionModel.GetSubscription(user.OrganizationId, id)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			app.writeJSONError(w, http.StatusNotFound, errors.New("report subscription not found"))
+			return
+		}
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	deliveryId, err := app.deliverSubscription(user, subscription, time.Now())
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusAccepted, envelope{"message": "report delivery queued", "id": deliveryId})
+}
+
+// retryDelivery sends a failed delivery again once its job has given up
+func (app *application) retryDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, errors.New("path parameter must be a positive INTEGER"))
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	delivery, err := app.subscriptionModel.RetryDelivery(user.OrganizationId, id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusNotFound, errors.New("report delivery not found"))
+		case errors.Is(err, postgres.ErrDeliveryNotFailed):
+			app.writeJSONError(w, http.StatusConflict, err)
+		default:
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if err = app.enqueueDelivery(user, delivery.ID); err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusAccepted, envelope{"message": "report delivery queued", "id": delivery.ID})
+}
+
+// nextRun is when a subscription next falls due from now, in the timezone the
+// schedules are kept in. The cron expression has been validated.
+func (app *application) nextRun(subscription *models.ReportSubscription) time.Time {
+	cron, err := schedule.Parse(subscription.Cron)
+
+	if err != nil {
+		return time.Time{}
+	}
+
+	return cron.Next(time.Now().In(app.location))
+}
+
+// deliverSubscription logs a delivery of the subscription's report for the period
+// ending at scheduledFor and hands it to the workers. A nil user stands for the
+// scheduler. It returns 0 when the delivery was already logged.
+func (app *application) deliverSubscription(user *models.User, subscription *models.ReportSubscription,
+	scheduledFor time.Time) (int, error) {
+	from, to := data.SubscriptionPeriod(subscription.Period, scheduledFor.In(app.location))
+
+	createdBy := ""
+
+	if user != nil {
+		createdBy = fmt.Sprint(user.ID)
+	}
+
+	id, err := app.subscriptionModel.CreateDelivery(subscription, scheduledFor, from, to, createdBy)
+
+	if err != nil || id == 0 {
+		return id, err
+	}
+
+	if err = app.enqueueDelivery(user, id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// enqueueDelivery hands a delivery to the workers. The delivery is failed if the job
+// cannot be created so that it can be retried from the log.
+func (app *application) enqueueDelivery(user *models.User, id int) error {
+	jobId, err := app.jobModel.Enqueue(user, data.JobDelivery, deliveryPayload{DeliveryId: id})
+
+	if err == nil {
+		err = app.subscriptionModel.SetDeliveryJob(id, jobId)
+	}
+
+	if err != nil {
+		if failErr := app.subscriptionModel.FailDelivery(id, err); failErr != nil {
+			utils.GetLoggerInstance().ErrorLog.Printf("Error while failing report delivery %d: %v", id, failErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// scheduleReports sends the reports of subscriptions as they fall due until the
+// context is cancelled. A subscription that fell due more than once while the server
+// was down is sent once, for its latest run.
+func (app *application) scheduleReports(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			app.runDueSubscriptions(time.Now())
+		}
+	}
+}
+
+func (app *application) runDueSubscriptions(now time.Time) {
+	subscriptions, err := app.subscriptionModel.Due(now, 100)
+
+	if err != nil {
+		utils.GetLoggerInstance().ErrorLog.Printf("Error while listing due report subscriptions: %v", err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		var next time.Time
+
+		// a schedule that no longer parses is stopped rather than found due every tick
+		if cron, err := schedule.Parse(subscription.Cron); err == nil {
+			next = cron.Next(now.In(app.location))
+		} else {
+			utils.GetLoggerInstance().ErrorLog.Printf("Stopping report subscription %d: %v", subscription.ID, err)
+		}
+
+		scheduledFor := *subscription.NextRunAt
+
+		claimed, err := app.subscriptionModel.Advance(subscription.ID, scheduledFor, next)
+
+		if err != nil {
+			utils.GetLoggerInstance().ErrorLog.Printf("Error while advancing report subscription %d: %v", subscription.ID, err)
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		if _, err = app.deliverSubscription(nil, subscription, scheduledFor); err != nil {
+			utils.GetLoggerInstance().ErrorLog.Printf("Error while delivering report subscription %d: %v", subscription.ID, err)
+		}
+	}
+}
+
+// runDelivery generates the report of a delivery and emails it to the recipients as
+// an attachment. Failed attempts are retried by the pool; the delivery is failed once
+// the job will not be tried again.
+func (app *application) runDelivery(ctx context.Context, job *models.Job) (*jobs.Result, error) {
+	var payload deliveryPayload
+
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	delivery, err := app.subscriptionModel.GetDelivery(payload.DeliveryId)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			return nil, jobs.Permanent(fmt.Errorf("report delivery %d not found", payload.DeliveryId))
+		}
+		return nil, err
+	}
+
+	started, err := app.subscriptionModel.StartDelivery(delivery.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !started {
+		return &jobs.Result{Data: envelope{"deliveryId": delivery.ID, "status": delivery.Status}}, nil
+	}
+
+	fileName, err := app.sendDelivery(delivery)
+
+	if err != nil {
+		var recordErr error
+
+		if ctx.Err() == nil && (jobs.IsPermanent(err) || job.Attempts >= job.MaxAttempts) {
+			recordErr = app.subscriptionModel.FailDelivery(delivery.ID, err)
+		} else {
+			recordErr = app.subscriptionModel.RecordDeliveryError(delivery.ID, err)
+		}
+
+		if recordErr != nil {
+			utils.GetLoggerInstance().ErrorLog.Printf("Error while recording the failure of report delivery %d: %v",
+				delivery.ID, recordErr)
+		}
+		return nil, err
+	}
+
+	if err = app.subscriptionModel.CompleteDelivery(delivery.ID, fileName); err != nil {
+		return nil, err
+	}
+
+	return &jobs.Result{Data: envelope{"deliveryId": delivery.ID, "status": data.DeliverySent}}, nil
+}
+
+// sendDelivery exports the subscription's report for the period of the delivery and
+// mails it, returning the name of the attached file
+func (app *application) sendDelivery(delivery *models.ReportDelivery) (string, error) {
+	subscription, err := app.subscriptionModel.GetSubscriptionByID(delivery.SubscriptionId)
+
+	if err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			return "", jobs.Permanent(fmt.Errorf("report subscription %d not found", delivery.SubscriptionId))
+		}
+		return "", err
+	}
+
+	exporter, ok := app.exporters.Lookup(subscription.Format)
+
+	if !ok {
+		return "", jobs.Permanent(fmt.Errorf("unknown report format %q", subscription.Format))
+	}
+
+	// subscriptions are sent to a list of addresses rather than to users, so they are
+	// written in the language of the organization
+	locale, err := app.userModel.OrganizationLocale(subscription.OrganizationId)
+
+	if err != nil && !errors.Is(err, data.ErrorNoRecords) {
+		return "", err
+	}
+
+	p := i18n.For(locale)
+
+	to, _ := time.Parse("2006-01-02", delivery.PeriodTo)
+
+	report := &models.Report{
+		OrganizationId: subscription.OrganizationId,
+		Type:           subscription.ReportType,
+		Format:         subscription.Format,
+		Parameters: models.ReportParameters{
+			From:   delivery.PeriodFrom,
+			To:     delivery.PeriodTo,
+			Year:   to.Year(),
+			Month:  int(to.Month()),
+			Charts: subscription.Charts,
+			Locale: p.Locale(),
+		},
+	}
+
+	doc, err := app.reportDocument(report, exporter)
+
+	if err != nil {
+		return "", err
+	}
+
+	doc.OrganizationId = report.OrganizationId
+	doc.Locale = p.Locale()
+
+	file := new(bytes.Buffer)
+
+	if err = exporter.Export(file, doc); err != nil {
+		if errors.Is(err, exports.ErrNoJournal) || errors.Is(err, exports.ErrUnsupportedDocument) {
+			return "", jobs.Permanent(err)
+		}
+		return "", err
+	}
+
+	fileName := doc.Name + "." + exporter.Extension()
+	title := strings.ReplaceAll(doc.Name, "-", " ")
+
+	if doc.Table != nil {
+		title = p.T(doc.Table.Title, doc.Table.TitleArgs...)
+	}
+
+	period := periodLabel(p, delivery.PeriodFrom, delivery.PeriodTo)
+
+	err = app.mailer.SendAttachments(delivery.OrganizationId, delivery.Recipients, p.Locale(), "scheduled_report.tmpl", map[string]any{
+		"Name":     subscription.Name,
+		"Title":    title,
+		"Period":   period,
+		"FileName": fileName,
+	}, mailer.Attachment{FileName: fileName, ContentType: exporter.MediaType(), Data: file.Bytes()})
+
+	if err != nil {
+		return "", err
+	}
+
+	return fileName, nil
+}
+
+// periodLabel writes the dates of a period, YYYY-MM-DD, the way they read in an email
+func periodLabel(p *i18n.Printer, from, to string) string {
+	fromDate, fromErr := time.Parse("2006-01-02", from)
+	toDate, toErr := time.Parse("2006-01-02", to)
+
+	if fromErr != nil || toErr != nil {
+		return p.T("%s to %s", from, to)
+	}
+
+	if fromDate.Equal(toDate) {
+		return p.Date(fromDate)
+	}
+
+	return p.T("%s to %s", fromDate, toDate)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/i18n"
 	"github.com/VaudKK/CAS/pkg/models"
 	"github.com/VaudKK/CAS/pkg/validator"
 )
@@ -70,7 +71,15 @@ func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.mailer.Send(input.Email, "user_welcome.tmpl", nil)
+	// a new user reads in the language of their organization until they choose one
+	locale, err := app.userModel.OrganizationLocale(newUser.OrganizationId)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = app.jobModel.EnqueueMail(newUser.OrganizationId, input.Email, locale, "user_welcome.tmpl", nil)
 
 	if err != nil {
 		app.writeJSONError(w, http.StatusInternalServerError, err)
@@ -190,3 +199,78 @@ func (app *application) changePassword(w http.ResponseWriter, r *http.Request) {
 
 	app.writeJSON(w, http.StatusOK, envelope{"message": "password changed successfully"})
 }
+
+// updateLocale chooses the language of the user's reports, emails and API responses.
+// An empty locale goes back to that of their organization.
+func (app *application) updateLocale(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Locale string `json:"locale"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	locale, _ := i18n.Normalize(input.Locale)
+
+	if locale != "" {
+		v := validator.New()
+
+		if data.ValidateLocale(v, locale); !v.Valid() {
+			app.writeJSON(w, http.StatusBadRequest, v.Errors)
+			return
+		}
+	}
+
+	if err := app.userModel.SetLocale(user.ID, locale); err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	user, err := app.userModel.GetUserID(user.ID)
+
+	if err != nil {
+		app.writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Language", user.Locale)
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "locale updated", "locale": user.Locale})
+}
+
+// updateOrganizationLocale chooses the language of the organization, used by its
+// users who have not chosen one and for its scheduled reports
+func (app *application) updateOrganizationLocale(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Locale string `json:"locale"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	locale, _ := i18n.Normalize(input.Locale)
+
+	v := validator.New()
+
+	if data.ValidateLocale(v, locale); !v.Valid() {
+		app.writeJSON(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	if err := app.userModel.SetOrganizationLocale(app.contextGetUser(r).OrganizationId, locale); err != nil {
+		switch {
+		case errors.Is(err, data.ErrorNoRecords):
+			app.writeJSONError(w, http.StatusNotFound, err)
+		default:
+			app.writeJSONError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "locale updated", "locale": locale})
+}
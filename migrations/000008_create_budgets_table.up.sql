@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS budgets (
+    id bigserial primary key,
+    organization_id bigint not null,
+    category text not null,
+    fiscal_year integer not null,
+    amount numeric(20, 2) not null,
+    monthly_phasing jsonb null,
+    alert_threshold numeric(5, 2) default 90 not null,
+    created_at timestamp with time zone default now() not null,
+    modified_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null,
+    modified_by varchar(1000) null,
+    UNIQUE (organization_id, category, fiscal_year)
+);
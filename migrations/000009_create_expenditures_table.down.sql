@@ -0,0 +1,7 @@
+DO $$
+DECLARE
+    new_table_name text;
+BEGIN
+    new_table_name := 'expenditures_dropped_' || to_char(CURRENT_TIMESTAMP, 'YYYYMMDD_HH24MI_SS');
+    EXECUTE 'ALTER TABLE expenditures RENAME TO ' || new_table_name;
+END $$;
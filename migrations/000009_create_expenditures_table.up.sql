@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS expenditures (
+    id bigserial primary key,
+    payee text not null,
+    amount numeric(20, 2) not null,
+    category text not null,
+    payment_method varchar(50) not null,
+    voucher_no varchar(200) not null,
+    organization_id bigint not null,
+    expenditure_date date not null,
+    description text null,
+    document bytea null,
+    document_name text null,
+    document_type varchar(200) null,
+    approved_by bigint null,
+    approved_at timestamp with time zone null,
+    created_at timestamp with time zone default now() not null,
+    modified_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null,
+    modified_by varchar(1000) null,
+    UNIQUE (organization_id, voucher_no)
+);
+
+CREATE INDEX IF NOT EXISTS expenditures_payee_idx ON expenditures USING GIN (to_tsvector('simple', payee));
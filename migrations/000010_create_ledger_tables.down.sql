@@ -0,0 +1,9 @@
+DO $$
+DECLARE
+    suffix text;
+BEGIN
+    suffix := '_dropped_' || to_char(CURRENT_TIMESTAMP, 'YYYYMMDD_HH24MI_SS');
+    EXECUTE 'ALTER TABLE journal_lines RENAME TO journal_lines' || suffix;
+    EXECUTE 'ALTER TABLE journal_entries RENAME TO journal_entries' || suffix;
+    EXECUTE 'ALTER TABLE accounts RENAME TO accounts' || suffix;
+END $$;
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS accounts (
+    id bigserial primary key,
+    organization_id bigint not null,
+    code varchar(50) not null,
+    name text not null,
+    account_type varchar(50) not null,
+    category text null,
+    created_at timestamp with time zone default now() not null,
+    modified_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null,
+    modified_by varchar(1000) null,
+    UNIQUE (organization_id, code)
+);
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id bigserial primary key,
+    organization_id bigint not null,
+    entry_date date not null,
+    description text not null,
+    source_type varchar(50) not null,
+    source_id bigint null,
+    reverses_id bigint null references journal_entries(id),
+    created_at timestamp with time zone default now() not null,
+    modified_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null,
+    modified_by varchar(1000) null
+);
+
+CREATE INDEX IF NOT EXISTS journal_entries_source_idx ON journal_entries (source_type, source_id);
+
+CREATE TABLE IF NOT EXISTS journal_lines (
+    id bigserial primary key,
+    journal_entry_id bigint not null references journal_entries(id),
+    account_id bigint not null references accounts(id),
+    debit numeric(20, 2) default 0 not null,
+    credit numeric(20, 2) default 0 not null,
+    CHECK (debit >= 0 AND credit >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS journal_lines_entry_idx ON journal_lines (journal_entry_id);
+CREATE INDEX IF NOT EXISTS journal_lines_account_idx ON journal_lines (account_id);
+
+INSERT INTO accounts (organization_id, code, name, account_type)
+SELECT id, '1000', 'Cash on Hand', 'ASSET' FROM organizations ON CONFLICT DO NOTHING;
+
+INSERT INTO accounts (organization_id, code, name, account_type)
+SELECT id, '1010', 'Bank', 'ASSET' FROM organizations ON CONFLICT DO NOTHING;
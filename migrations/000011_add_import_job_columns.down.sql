@@ -0,0 +1,10 @@
+ALTER TABLE imports DROP COLUMN IF EXISTS status;
+ALTER TABLE imports DROP COLUMN IF EXISTS rows_read;
+ALTER TABLE imports DROP COLUMN IF EXISTS rows_inserted;
+ALTER TABLE imports DROP COLUMN IF EXISTS rows_skipped;
+ALTER TABLE imports DROP COLUMN IF EXISTS errors;
+ALTER TABLE imports DROP COLUMN IF EXISTS started_at;
+ALTER TABLE imports DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE imports DROP COLUMN IF EXISTS created_by;
+DELETE FROM imports WHERE hash IS NULL;
+ALTER TABLE imports ALTER COLUMN hash SET NOT NULL;
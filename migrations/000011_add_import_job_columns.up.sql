@@ -0,0 +1,10 @@
+ALTER TABLE imports ALTER COLUMN hash DROP NOT NULL;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS status varchar(50) default 'SUCCEEDED' not null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS rows_read integer default 0 not null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS rows_inserted integer default 0 not null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS rows_skipped integer default 0 not null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS errors jsonb default '[]'::jsonb not null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS started_at timestamp with time zone null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS finished_at timestamp with time zone null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS created_by varchar(1000) null;
+ALTER TABLE imports ALTER COLUMN status SET DEFAULT 'QUEUED';
@@ -0,0 +1 @@
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS preview_token text unique null;
@@ -0,0 +1,2 @@
+ALTER TABLE imports DROP COLUMN IF EXISTS profile_id;
+DROP TABLE IF EXISTS import_profiles;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS import_profiles (
+    id bigserial primary key,
+    organization_id bigint not null,
+    name text not null,
+    header_row integer default 1 not null,
+    name_column varchar(3) not null,
+    receipt_column varchar(3) not null,
+    total_column varchar(3) not null,
+    category_start_column varchar(3) not null,
+    category_end_column varchar(3) null,
+    date_source varchar(50) not null,
+    date_column varchar(3) null,
+    fixed_date date null,
+    date_formats text[] not null,
+    skip_sheets text[] default '{}' not null,
+    created_at timestamp with time zone default now() not null,
+    modified_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null,
+    modified_by varchar(1000) null,
+    UNIQUE (organization_id, name)
+);
+
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS profile_id bigint null REFERENCES import_profiles(id);
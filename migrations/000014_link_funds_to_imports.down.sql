@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS closed_periods;
+
+ALTER TABLE imports DROP COLUMN IF EXISTS rows_rolled_back;
+ALTER TABLE imports DROP COLUMN IF EXISTS rolled_back_by;
+ALTER TABLE imports DROP COLUMN IF EXISTS rolled_back_at;
+
+DROP INDEX IF EXISTS funds_import_idx;
+
+ALTER TABLE funds DROP COLUMN IF EXISTS import_id;
@@ -0,0 +1,16 @@
+ALTER TABLE funds ADD COLUMN IF NOT EXISTS import_id bigint null REFERENCES imports(id);
+
+CREATE INDEX IF NOT EXISTS funds_import_idx ON funds (import_id);
+
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS rolled_back_at timestamp with time zone null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS rolled_back_by varchar(1000) null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS rows_rolled_back integer default 0 not null;
+
+CREATE TABLE IF NOT EXISTS closed_periods (
+    id bigserial primary key,
+    organization_id bigint not null,
+    period_end date not null,
+    created_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null,
+    UNIQUE (organization_id, period_end)
+);
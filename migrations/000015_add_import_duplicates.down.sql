@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS funds_contribution_date_idx;
+DROP INDEX IF EXISTS funds_receipt_no_idx;
+
+ALTER TABLE imports DROP COLUMN IF EXISTS duplicates;
+ALTER TABLE imports DROP COLUMN IF EXISTS rows_updated;
+ALTER TABLE imports DROP COLUMN IF EXISTS duplicate_action;
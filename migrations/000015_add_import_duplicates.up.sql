@@ -0,0 +1,6 @@
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS duplicate_action varchar(50) default 'SKIP' not null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS rows_updated integer default 0 not null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS duplicates jsonb default '[]'::jsonb not null;
+
+CREATE INDEX IF NOT EXISTS funds_receipt_no_idx ON funds (organization_id, receipt_no);
+CREATE INDEX IF NOT EXISTS funds_contribution_date_idx ON funds (organization_id, contribution_date);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id bigserial primary key,
+    kind varchar(50) not null,
+    organization_id bigint null,
+    payload jsonb default '{}'::jsonb not null,
+    status varchar(50) default 'QUEUED' not null,
+    attempts integer default 0 not null,
+    max_attempts integer default 5 not null,
+    run_at timestamp with time zone default now() not null,
+    locked_by varchar(100) null,
+    locked_until timestamp with time zone null,
+    last_error text null,
+    result jsonb null,
+    file bytea null,
+    file_name varchar(1000) null,
+    content_type varchar(255) null,
+    started_at timestamp with time zone null,
+    finished_at timestamp with time zone null,
+    created_at timestamp with time zone default now() not null,
+    modified_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null
+);
+
+CREATE INDEX IF NOT EXISTS jobs_pending_idx ON jobs (run_at, id) WHERE status IN ('QUEUED', 'RUNNING');
+CREATE INDEX IF NOT EXISTS jobs_organization_idx ON jobs (organization_id, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE imports DROP COLUMN IF EXISTS file_size;
+ALTER TABLE imports DROP COLUMN IF EXISTS file_hash;
+ALTER TABLE imports DROP COLUMN IF EXISTS file_path;
@@ -0,0 +1,3 @@
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS file_path varchar(4096) null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS file_hash varchar(255) null;
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS file_size bigint null;
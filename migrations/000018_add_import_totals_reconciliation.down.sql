@@ -0,0 +1,4 @@
+ALTER TABLE imports DROP COLUMN IF EXISTS discrepancies;
+
+ALTER TABLE import_profiles DROP COLUMN IF EXISTS totals_action;
+ALTER TABLE import_profiles DROP COLUMN IF EXISTS totals_label;
@@ -0,0 +1,4 @@
+ALTER TABLE import_profiles ADD COLUMN IF NOT EXISTS totals_label text default 'TOTAL' not null;
+ALTER TABLE import_profiles ADD COLUMN IF NOT EXISTS totals_action varchar(50) default 'WARN' not null;
+
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS discrepancies jsonb default '[]'::jsonb not null;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS import_versions_preview_token_idx;
+DROP TABLE IF EXISTS import_versions;
+ALTER TABLE imports DROP COLUMN IF EXISTS version;
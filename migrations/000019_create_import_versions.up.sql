@@ -0,0 +1,25 @@
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS version int not null default 1;
+
+CREATE TABLE IF NOT EXISTS import_versions(
+    id serial primary key,
+    import_id int not null references imports(id) ON DELETE CASCADE,
+    version int null,
+    filename varchar(1000) not null,
+    file_path varchar(4096) null,
+    file_hash varchar(255) null,
+    file_size bigint null,
+    profile_id int null,
+    status varchar(20) not null,
+    preview_token varchar(255) null,
+    diff_digest varchar(255) null,
+    rows_added int not null default 0,
+    rows_removed int not null default 0,
+    rows_changed int not null default 0,
+    created_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null,
+    applied_at timestamp with time zone null,
+    applied_by varchar(1000) null,
+    UNIQUE (import_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS import_versions_preview_token_idx ON import_versions (preview_token);
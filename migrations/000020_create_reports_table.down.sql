@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS reports_expires_at_idx;
+DROP INDEX IF EXISTS reports_organization_id_idx;
+DROP TABLE IF EXISTS reports;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS reports(
+    id serial primary key,
+    organization_id int not null,
+    type varchar(50) not null,
+    format varchar(20) not null,
+    parameters jsonb not null default '{}',
+    status varchar(20) not null,
+    job_id int null,
+    storage_key varchar(1000) null,
+    file_name varchar(1000) null,
+    content_type varchar(255) null,
+    size bigint null,
+    last_error text null,
+    expires_at timestamp with time zone null,
+    completed_at timestamp with time zone null,
+    created_at timestamp with time zone default now() not null,
+    modified_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null
+);
+
+CREATE INDEX IF NOT EXISTS reports_organization_id_idx ON reports (organization_id, created_at);
+CREATE INDEX IF NOT EXISTS reports_expires_at_idx ON reports (expires_at) WHERE status = 'READY';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS report_deliveries_subscription_id_idx;
+DROP TABLE IF EXISTS report_deliveries;
+DROP INDEX IF EXISTS report_subscriptions_next_run_at_idx;
+DROP INDEX IF EXISTS report_subscriptions_organization_id_idx;
+DROP TABLE IF EXISTS report_subscriptions;
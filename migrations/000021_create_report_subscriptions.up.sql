@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS report_subscriptions(
+    id serial primary key,
+    organization_id int not null,
+    name varchar(255) not null,
+    report_type varchar(50) not null,
+    period varchar(50) not null,
+    format varchar(20) not null,
+    recipients text[] not null,
+    cron varchar(255) not null,
+    charts text[] default '{}' not null,
+    active boolean default true not null,
+    next_run_at timestamp with time zone null,
+    last_run_at timestamp with time zone null,
+    created_at timestamp with time zone default now() not null,
+    modified_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null,
+    modified_by varchar(1000) null
+);
+
+CREATE INDEX IF NOT EXISTS report_subscriptions_organization_id_idx ON report_subscriptions (organization_id);
+CREATE INDEX IF NOT EXISTS report_subscriptions_next_run_at_idx ON report_subscriptions (next_run_at) WHERE active;
+
+CREATE TABLE IF NOT EXISTS report_deliveries(
+    id serial primary key,
+    subscription_id int not null references report_subscriptions(id) on delete cascade,
+    organization_id int not null,
+    scheduled_for timestamp with time zone not null,
+    period_from date not null,
+    period_to date not null,
+    status varchar(20) not null,
+    attempts int default 0 not null,
+    job_id int null,
+    recipients text[] not null,
+    file_name varchar(1000) null,
+    last_error text null,
+    sent_at timestamp with time zone null,
+    created_at timestamp with time zone default now() not null,
+    modified_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null,
+    UNIQUE (subscription_id, scheduled_for)
+);
+
+CREATE INDEX IF NOT EXISTS report_deliveries_subscription_id_idx ON report_deliveries (subscription_id, created_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS account_mappings (
+    id bigserial primary key,
+    organization_id bigint not null,
+    account_type varchar(50) not null,
+    category text not null,
+    external_code varchar(50) not null,
+    external_name text not null default '',
+    created_at timestamp with time zone default now() not null,
+    modified_at timestamp with time zone default now() not null,
+    created_by varchar(1000) null,
+    modified_by varchar(1000) null,
+    UNIQUE (organization_id, account_type, category)
+);
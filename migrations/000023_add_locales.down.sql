@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
+ALTER TABLE organizations DROP COLUMN IF EXISTS locale;
@@ -0,0 +1,2 @@
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS locale varchar(10) default 'en' not null;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale varchar(10) null;
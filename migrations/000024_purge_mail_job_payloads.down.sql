@@ -0,0 +1 @@
+-- the purged mail payloads cannot be restored
@@ -0,0 +1 @@
+UPDATE jobs SET payload = '{}'::jsonb WHERE kind = 'MAIL' AND status IN ('SUCCEEDED', 'DEAD');
@@ -0,0 +1,2 @@
+-- contributions saved before payment methods were recorded were received in cash
+ALTER TABLE funds ADD COLUMN IF NOT EXISTS payment_method varchar(50) default 'CASH' not null;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS organizations_seed_accounts ON organizations;
+DROP FUNCTION IF EXISTS seed_organization_accounts();
@@ -0,0 +1,23 @@
+-- every organization gets cash on hand and bank when it is created, contributions and
+-- expenditures cannot be posted without them
+CREATE OR REPLACE FUNCTION seed_organization_accounts() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO accounts (organization_id, code, name, account_type)
+    VALUES (NEW.id, '1000', 'Cash on Hand', 'ASSET'), (NEW.id, '1010', 'Bank', 'ASSET')
+    ON CONFLICT DO NOTHING;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS organizations_seed_accounts ON organizations;
+
+CREATE TRIGGER organizations_seed_accounts AFTER INSERT ON organizations
+    FOR EACH ROW EXECUTE FUNCTION seed_organization_accounts();
+
+-- organizations created since the ledger tables were added
+INSERT INTO accounts (organization_id, code, name, account_type)
+SELECT id, '1000', 'Cash on Hand', 'ASSET' FROM organizations ON CONFLICT DO NOTHING;
+
+INSERT INTO accounts (organization_id, code, name, account_type)
+SELECT id, '1010', 'Bank', 'ASSET' FROM organizations ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_organization_id_account_type_category_key;
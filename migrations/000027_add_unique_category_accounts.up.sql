@@ -0,0 +1,13 @@
+-- accounts opened twice for the same category by concurrent postings are merged into
+-- the first one before the constraint is added
+WITH duplicates AS (
+    SELECT id, min(id) OVER (PARTITION BY organization_id, account_type, category) AS keep
+    FROM accounts WHERE category IS NOT NULL
+)
+UPDATE journal_lines l SET account_id = d.keep FROM duplicates d WHERE l.account_id = d.id AND d.id <> d.keep;
+
+DELETE FROM accounts a USING accounts b
+WHERE a.organization_id = b.organization_id AND a.account_type = b.account_type AND a.category = b.category AND a.id > b.id;
+
+ALTER TABLE accounts ADD CONSTRAINT accounts_organization_id_account_type_category_key
+    UNIQUE (organization_id, account_type, category);
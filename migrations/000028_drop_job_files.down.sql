@@ -0,0 +1,3 @@
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS file bytea null,
+    ADD COLUMN IF NOT EXISTS file_name varchar(1000) null,
+    ADD COLUMN IF NOT EXISTS content_type varchar(255) null;
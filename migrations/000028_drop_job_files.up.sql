@@ -0,0 +1 @@
+ALTER TABLE jobs DROP COLUMN IF EXISTS file, DROP COLUMN IF EXISTS file_name, DROP COLUMN IF EXISTS content_type;
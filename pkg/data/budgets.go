@@ -0,0 +1,27 @@
+package data
+
+import (
+	"math"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/validator"
+)
+
+func ValidateBudget(v *validator.Validator, budget *models.Budget) {
+	v.Check(budget.Category != "", "category", "must be provided")
+	v.Check(budget.FiscalYear >= 2000 && budget.FiscalYear <= 2100, "fiscalYear", "must be a valid year")
+	v.Check(budget.Amount >= 0, "amount", "must not be negative")
+	v.Check(budget.AlertThreshold >= 0 && budget.AlertThreshold <= 100, "alertThreshold", "must be between 0 and 100")
+
+	if len(budget.MonthlyPhasing) > 0 {
+		v.Check(len(budget.MonthlyPhasing) == 12, "monthlyPhasing", "must have an amount for each of the 12 months")
+
+		var sum float64
+		for _, amount := range budget.MonthlyPhasing {
+			sum += amount
+		}
+
+		// compared in whole cents, as the sum of the months picks up rounding errors
+		v.Check(math.Round(sum*100) == math.Round(budget.Amount*100), "monthlyPhasing", "must add up to the budget amount")
+	}
+}
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/validator"
+)
+
+// even spreads an amount evenly over the 12 months of a year
+func even(amount float64) []float64 {
+	phasing := make([]float64, 12)
+
+	for i := range phasing {
+		phasing[i] = amount / 12
+	}
+
+	return phasing
+}
+
+func TestValidateBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  models.Budget
+		wantErr map[string]string
+	}{
+		{
+			name:   "without phasing",
+			budget: models.Budget{Category: "TITHE", FiscalYear: 2024, Amount: 12000, AlertThreshold: 80},
+		},
+		{
+			name:   "phased evenly",
+			budget: models.Budget{Category: "TITHE", FiscalYear: 2024, Amount: 12000, MonthlyPhasing: even(12000)},
+		},
+		{
+			name: "phasing that only adds up once rounded to the cent",
+			budget: models.Budget{Category: "TITHE", FiscalYear: 2024, Amount: 1.2,
+				MonthlyPhasing: []float64{0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1}},
+		},
+		{
+			name:    "phasing short of the amount by a cent",
+			budget:  models.Budget{Category: "TITHE", FiscalYear: 2024, Amount: 12000.01, MonthlyPhasing: even(12000)},
+			wantErr: map[string]string{"monthlyPhasing": "must add up to the budget amount"},
+		},
+		{
+			name: "phasing for fewer months",
+			budget: models.Budget{Category: "TITHE", FiscalYear: 2024, Amount: 3000,
+				MonthlyPhasing: []float64{1000, 1000, 1000}},
+			wantErr: map[string]string{"monthlyPhasing": "must have an amount for each of the 12 months"},
+		},
+		{
+			name: "phasing for more months",
+			budget: models.Budget{Category: "TITHE", FiscalYear: 2024, Amount: 1300,
+				MonthlyPhasing: append(even(1200), 100)},
+			wantErr: map[string]string{"monthlyPhasing": "must have an amount for each of the 12 months"},
+		},
+		{
+			name:   "invalid fields",
+			budget: models.Budget{FiscalYear: 1999, Amount: -1, AlertThreshold: 101},
+			wantErr: map[string]string{
+				"category":       "must be provided",
+				"fiscalYear":     "must be a valid year",
+				"amount":         "must not be negative",
+				"alertThreshold": "must be between 0 and 100",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+
+			ValidateBudget(v, &tt.budget)
+
+			want := tt.wantErr
+
+			if want == nil {
+				want = map[string]string{}
+			}
+
+			if !reflect.DeepEqual(v.Errors, want) {
+				t.Fatalf("ValidateBudget() errors = %v, want %v", v.Errors, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package data
+
+import (
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/validator"
+)
+
+var PaymentMethods = []string{"CASH", "CHEQUE", "BANK TRANSFER", "MPESA"}
+
+func ValidateExpenditure(v *validator.Validator, expenditure *models.Expenditure) {
+	v.Check(expenditure.Payee != "", "payee", "must be provided")
+	v.Check(expenditure.Amount > 0, "amount", "must be greater than zero")
+	v.Check(expenditure.Category != "", "category", "must be provided")
+	v.Check(validator.In(expenditure.PaymentMethod, PaymentMethods...), "paymentMethod", "must be one of CASH, CHEQUE, BANK TRANSFER or MPESA")
+
+	_, err := time.Parse("2006-01-02", expenditure.Date)
+	v.Check(err == nil, "date", "must be a valid date in the format YYYY-MM-DD")
+}
@@ -0,0 +1,43 @@
+package data
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/validator"
+)
+
+var ColumnRX = regexp.MustCompile(`^[A-Za-z]{1,3}$`)
+
+var DateSources = []string{imports.DateFromSheetName, imports.DateFromColumn, imports.DateFixed}
+
+var TotalsActions = []string{imports.TotalsWarn, imports.TotalsFail, imports.TotalsIgnore}
+
+func ValidateImportProfile(v *validator.Validator, profile *imports.Profile) {
+	v.Check(profile.Name != "", "name", "must be provided")
+	v.Check(profile.HeaderRow >= 1, "headerRow", "must be 1 or greater")
+
+	v.Check(validator.Matches(profile.NameColumn, ColumnRX), "nameColumn", "must be a column letter")
+	v.Check(validator.Matches(profile.ReceiptColumn, ColumnRX), "receiptColumn", "must be a column letter")
+	v.Check(validator.Matches(profile.TotalColumn, ColumnRX), "totalColumn", "must be a column letter")
+	v.Check(validator.Matches(profile.CategoryStart, ColumnRX), "categoryStartColumn", "must be a column letter")
+	v.Check(profile.CategoryEnd == "" || validator.Matches(profile.CategoryEnd, ColumnRX), "categoryEndColumn", "must be a column letter")
+
+	v.Check(validator.In(profile.DateSource, DateSources...), "dateSource", "must be one of SHEET_NAME, COLUMN or FIXED")
+
+	switch profile.DateSource {
+	case imports.DateFromColumn:
+		v.Check(validator.Matches(profile.DateColumn, ColumnRX), "dateColumn", "must be a column letter when dates are read from a column")
+	case imports.DateFixed:
+		_, err := time.Parse("2006-01-02", profile.FixedDate)
+		v.Check(err == nil, "fixedDate", "must be a valid date in the format YYYY-MM-DD")
+	}
+
+	v.Check(profile.TotalsAction == "" || validator.In(profile.TotalsAction, TotalsActions...), "totalsAction",
+		"must be one of WARN, FAIL or IGNORE")
+
+	if profile.DateSource != imports.DateFixed {
+		v.Check(len(profile.DateFormats) > 0, "dateFormats", "must have at least one date format")
+	}
+}
@@ -0,0 +1,17 @@
+package data
+
+const (
+	ImportPreview    = "PREVIEW"
+	ImportQueued     = "QUEUED"
+	ImportRunning    = "RUNNING"
+	ImportSucceeded  = "SUCCEEDED"
+	ImportFailed     = "FAILED"
+	ImportRolledBack = "ROLLED_BACK"
+)
+
+// statuses of a file uploaded to replace the file of an earlier import
+const (
+	VersionPending    = "PENDING"
+	VersionCurrent    = "CURRENT"
+	VersionSuperseded = "SUPERSEDED"
+)
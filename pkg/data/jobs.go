@@ -0,0 +1,15 @@
+package data
+
+const (
+	JobQueued    = "QUEUED"
+	JobRunning   = "RUNNING"
+	JobSucceeded = "SUCCEEDED"
+	JobDead      = "DEAD"
+)
+
+const (
+	JobImport   = "IMPORT"
+	JobReport   = "REPORT"
+	JobMail     = "MAIL"
+	JobDelivery = "DELIVERY"
+)
@@ -0,0 +1,73 @@
+package data
+
+import (
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/validator"
+)
+
+const (
+	AccountTypeAsset     = "ASSET"
+	AccountTypeLiability = "LIABILITY"
+	AccountTypeFund      = "FUND"
+	AccountTypeIncome    = "INCOME"
+	AccountTypeExpense   = "EXPENSE"
+)
+
+var AccountTypes = []string{AccountTypeAsset, AccountTypeLiability, AccountTypeFund, AccountTypeIncome, AccountTypeExpense}
+
+const (
+	SourceContribution = "CONTRIBUTION"
+	SourceExpenditure  = "EXPENDITURE"
+	SourceTransfer     = "TRANSFER"
+	SourceReversal     = "REVERSAL"
+	SourceManual       = "MANUAL"
+)
+
+// well known asset accounts seeded for every organization
+const (
+	AccountCodeCash = "1000"
+	AccountCodeBank = "1010"
+)
+
+// UnallocatedCategory is the income category credited with the difference when a
+// contribution total does not match its break down
+const UnallocatedCategory = "UNALLOCATED"
+
+// the cash and bank accounts are mapped to an accounting package under these
+// categories of the asset type
+const (
+	MappingCash = "CASH"
+	MappingBank = "BANK"
+)
+
+var MappingTypes = []string{AccountTypeAsset, AccountTypeIncome, AccountTypeExpense}
+
+func ValidateAccount(v *validator.Validator, account *models.Account) {
+	v.Check(account.Code != "", "code", "must be provided")
+	v.Check(account.Name != "", "name", "must be provided")
+	v.Check(validator.In(account.Type, AccountTypes...), "type", "must be one of ASSET, LIABILITY, FUND, INCOME or EXPENSE")
+}
+
+func ValidateTransfer(v *validator.Validator, transfer *models.Transfer) {
+	v.Check(transfer.FromAccountId > 0, "fromAccountId", "must be provided")
+	v.Check(transfer.ToAccountId > 0, "toAccountId", "must be provided")
+	v.Check(transfer.FromAccountId != transfer.ToAccountId, "toAccountId", "must be different from the source account")
+	v.Check(transfer.Amount > 0, "amount", "must be greater than zero")
+
+	_, err := time.Parse("2006-01-02", transfer.Date)
+	v.Check(err == nil, "date", "must be a valid date in the format YYYY-MM-DD")
+}
+
+func ValidateAccountMapping(v *validator.Validator, mapping *models.AccountMapping) {
+	v.Check(validator.In(mapping.AccountType, MappingTypes...), "accountType", "must be one of ASSET, INCOME or EXPENSE")
+	v.Check(mapping.Category != "", "category", "must be provided")
+
+	if mapping.AccountType == AccountTypeAsset {
+		v.Check(validator.In(mapping.Category, MappingCash, MappingBank), "category", "must be CASH or BANK for an asset account")
+	}
+
+	v.Check(mapping.Code != "", "code", "must be provided")
+	v.Check(len(mapping.Code) <= 50, "code", "must not be more than 50 characters")
+}
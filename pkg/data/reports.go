@@ -0,0 +1,118 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/validator"
+)
+
+// statuses of a report requested for download
+const (
+	ReportQueued  = "QUEUED"
+	ReportRunning = "RUNNING"
+	ReportReady   = "READY"
+	ReportFailed  = "FAILED"
+	ReportExpired = "EXPIRED"
+)
+
+const (
+	ReportContributions    = "contributions"
+	ReportExpenditures     = "expenditures"
+	ReportCashStatement    = "cash-statement"
+	ReportFundBalances     = "fund-balances"
+	ReportBudgetVsActual   = "budget-vs-actual"
+	ReportTrialBalance     = "trial-balance"
+	ReportIncomeStatement  = "income-statement"
+	ReportFundBalanceSheet = "fund-balance-sheet"
+	ReportVariance         = "variance"
+	ReportAnnual           = "annual"
+	ReportJournal          = "journal"
+)
+
+var ReportTypes = []string{ReportContributions, ReportExpenditures, ReportCashStatement, ReportFundBalances,
+	ReportBudgetVsActual, ReportTrialBalance, ReportIncomeStatement, ReportFundBalanceSheet, ReportVariance, ReportAnnual,
+	ReportJournal}
+
+// VarianceCategories are compared with the month before in the variance report
+var VarianceCategories = []string{"LCB", "COMB. OFFERING", "BUILDING CHURCH FUNDS"}
+
+// charts that can be asked for in report options; none leaves every chart out
+const (
+	ChartPie     = "pie"
+	ChartColumns = "columns"
+	ChartTrend   = "trend"
+	ChartNone    = "none"
+)
+
+var ChartTypes = []string{ChartPie, ChartColumns, ChartTrend, ChartNone}
+
+// SelectCharts turns chart names into the charts to draw. Every chart is drawn when
+// no names are given.
+func SelectCharts(names []string) models.Charts {
+	if len(names) == 0 {
+		return models.Charts{Pie: true, Columns: true, Trend: true}
+	}
+
+	return models.Charts{
+		Pie:     validator.In(ChartPie, names...),
+		Columns: validator.In(ChartColumns, names...),
+		Trend:   validator.In(ChartTrend, names...),
+	}
+}
+
+func ValidateCharts(v *validator.Validator, names []string) {
+	for _, name := range names {
+		v.Check(validator.In(name, ChartTypes...), "charts", "must be a list of "+strings.Join(ChartTypes, ", "))
+	}
+}
+
+// ValidateReport checks a report request. Formats are the extensions of the
+// registered exporters.
+func ValidateReport(v *validator.Validator, report *models.Report, formats []string) {
+	v.Check(validator.In(report.Type, ReportTypes...), "type", "must be one of "+strings.Join(ReportTypes, ", "))
+	v.Check(validator.In(report.Format, formats...), "format", "must be one of "+strings.Join(formats, ", "))
+
+	params := report.Parameters
+
+	ValidateCharts(v, params.Charts)
+
+	if params.Locale != "" {
+		ValidateLocale(v, params.Locale)
+	}
+
+	from, fromErr := time.Parse("2006-01-02", params.From)
+	to, toErr := time.Parse("2006-01-02", params.To)
+
+	if params.From != "" {
+		v.Check(fromErr == nil, "from", "must be a valid date in the format YYYY-MM-DD")
+	}
+
+	if params.To != "" {
+		v.Check(toErr == nil, "to", "must be a valid date in the format YYYY-MM-DD")
+	}
+
+	if fromErr == nil && toErr == nil {
+		v.Check(!to.Before(from), "to", "must not be before from date")
+	}
+
+	switch report.Type {
+	case ReportContributions, ReportExpenditures:
+		v.Check(params.Terms != "" || params.From != "", "from", "must be provided when there are no search terms")
+	case ReportCashStatement, ReportFundBalances, ReportIncomeStatement, ReportFundBalanceSheet, ReportJournal:
+		v.Check(params.From != "", "from", "must be provided")
+	case ReportBudgetVsActual:
+		v.Check(params.Year == 0 || (params.Year >= 2000 && params.Year <= 2100), "year", "must be a valid year")
+		v.Check(params.Month >= 0 && params.Month <= 12, "month", "must be between 1 and 12")
+	case ReportAnnual:
+		ValidateAnnualYear(v, params.Year)
+	}
+}
+
+// ValidateAnnualYear checks the year of an annual report, which can be the current
+// year but not one that has not started. Zero stands for the current year.
+func ValidateAnnualYear(v *validator.Validator, year int) {
+	v.Check(year == 0 || (year >= 2000 && year <= time.Now().Year()), "year",
+		"must be a year from 2000 to the current year")
+}
@@ -0,0 +1,72 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/pkg/schedule"
+	"github.com/VaudKK/CAS/pkg/validator"
+)
+
+// periods a scheduled report covers, counted back from when it falls due
+const (
+	PeriodLastMonth     = "last-month"
+	PeriodLastSabbath   = "last-sabbath"
+	PeriodQuarterToDate = "quarter-to-date"
+)
+
+var PeriodRules = []string{PeriodLastMonth, PeriodLastSabbath, PeriodQuarterToDate}
+
+// statuses of a scheduled report sent to its recipients
+const (
+	DeliveryPending = "PENDING"
+	DeliverySent    = "SENT"
+	DeliveryFailed  = "FAILED"
+)
+
+const maxRecipients = 50
+
+// SubscriptionPeriod returns the first and last days of the period a report due at
+// the given time covers. The last sabbath is the most recent Saturday, which is the
+// same day when the report falls due on a Saturday.
+func SubscriptionPeriod(rule string, at time.Time) (time.Time, time.Time) {
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch rule {
+	case PeriodLastSabbath:
+		sabbath := day.AddDate(0, 0, -int((day.Weekday()-time.Saturday+7)%7))
+		return sabbath, sabbath
+	case PeriodQuarterToDate:
+		quarter := time.Date(day.Year(), ((day.Month()-1)/3)*3+1, 1, 0, 0, 0, 0, time.UTC)
+		return quarter, day
+	default:
+		firstOfMonth := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return firstOfMonth.AddDate(0, -1, 0), firstOfMonth.AddDate(0, 0, -1)
+	}
+}
+
+// ValidateSubscription checks a report subscription. Formats are the extensions of
+// the registered exporters.
+func ValidateSubscription(v *validator.Validator, subscription *models.ReportSubscription, formats []string) {
+	v.Check(strings.TrimSpace(subscription.Name) != "", "name", "must be provided")
+	v.Check(len(subscription.Name) <= 255, "name", "must not be more than 255 characters")
+	v.Check(validator.In(subscription.ReportType, ReportTypes...), "reportType",
+		"must be one of "+strings.Join(ReportTypes, ", "))
+	v.Check(validator.In(subscription.Period, PeriodRules...), "period", "must be one of "+strings.Join(PeriodRules, ", "))
+	v.Check(validator.In(subscription.Format, formats...), "format", "must be one of "+strings.Join(formats, ", "))
+
+	v.Check(len(subscription.Recipients) > 0, "recipients", "must have at least one email address")
+	v.Check(len(subscription.Recipients) <= maxRecipients, "recipients", "must not have more than 50 email addresses")
+	v.Check(validator.Unique(subscription.Recipients), "recipients", "must not contain duplicate email addresses")
+
+	for _, recipient := range subscription.Recipients {
+		v.Check(validator.Matches(recipient, validator.EmailRX), "recipients", "must be valid email addresses")
+	}
+
+	if err := schedule.Validate(subscription.Cron); err != nil {
+		v.AddError("cron", err.Error())
+	}
+
+	ValidateCharts(v, subscription.Charts)
+}
@@ -2,7 +2,9 @@ package data
 
 import (
 	"errors"
+	"strings"
 
+	"github.com/VaudKK/CAS/pkg/i18n"
 	"github.com/VaudKK/CAS/pkg/validator"
 )
 
@@ -26,6 +28,10 @@ func ValidateUserName(v *validator.Validator, username string) {
 	v.Check(validator.Matches(username, validator.UserNameRX), "username", "must be words and spaces only with a max length of 50")
 }
 
+func ValidateLocale(v *validator.Validator, locale string) {
+	v.Check(validator.In(locale, i18n.Locales...), "locale", "must be one of "+strings.Join(i18n.Locales, ", "))
+}
+
 func ValidateResetToken(v *validator.Validator, token string) {
 	v.Check(token != "", "token", "must be provided")
 	v.Check(len(token) >= 30, "token", "must be a valid token")
@@ -0,0 +1,186 @@
+package exports
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JournalCsvExporter writes a document's journal as a generic double entry CSV, a row
+// per line with the date and reference of its entry
+type JournalCsvExporter struct {
+	Accounts AccountMappings
+}
+
+func (e *JournalCsvExporter) MediaType() string { return "application/vnd.cas.journal+csv" }
+
+func (e *JournalCsvExporter) Extension() string { return "journal.csv" }
+
+func (e *JournalCsvExporter) Export(w io.Writer, doc *Document) error {
+	if doc.Journal == nil {
+		return ErrNoJournal
+	}
+
+	account, err := accountResolver(e.Accounts, doc.OrganizationId)
+
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+
+	err = writer.Write([]string{"Date", "Reference", "Narration", "Account Code", "Account Name", "Debit", "Credit"})
+
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range doc.Journal {
+		for _, line := range entry.Lines {
+			external := account(line)
+
+			err = writer.Write([]string{entry.Date, entry.Reference, entry.Narration, external.code, external.name,
+				journalAmount(line.Debit), journalAmount(line.Credit)})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// IifExporter writes a document's journal as QuickBooks general journal
+// transactions. QuickBooks matches accounts by name, so the mapped name is used.
+type IifExporter struct {
+	Accounts AccountMappings
+}
+
+func (e *IifExporter) MediaType() string { return "application/x-iif" }
+
+func (e *IifExporter) Extension() string { return "iif" }
+
+func (e *IifExporter) Export(w io.Writer, doc *Document) error {
+	if doc.Journal == nil {
+		return ErrNoJournal
+	}
+
+	account, err := accountResolver(e.Accounts, doc.OrganizationId)
+
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(w)
+
+	row := func(fields ...string) {
+		for i, field := range fields {
+			fields[i] = iifField(field)
+		}
+
+		writer.WriteString(strings.Join(fields, "\t"))
+		writer.WriteString("\r\n")
+	}
+
+	row("!TRNS", "TRNSID", "TRNSTYPE", "DATE", "ACCNT", "AMOUNT", "DOCNUM", "MEMO")
+	row("!SPL", "SPLID", "TRNSTYPE", "DATE", "ACCNT", "AMOUNT", "DOCNUM", "MEMO")
+	row("!ENDTRNS")
+
+	for _, entry := range doc.Journal {
+		date, err := time.Parse("2006-01-02", entry.Date)
+
+		if err != nil {
+			return err
+		}
+
+		// the first line of a transaction is its TRNS line, the rest are splits.
+		// Debits are positive amounts and credits negative.
+		for i, line := range entry.Lines {
+			kind := "SPL"
+
+			if i == 0 {
+				kind = "TRNS"
+			}
+
+			row(kind, "", "GENERAL JOURNAL", date.Format("01/02/2006"), account(line).name,
+				journalAmount(line.Debit-line.Credit), entry.Reference, entry.Narration)
+		}
+
+		row("ENDTRNS")
+	}
+
+	return writer.Flush()
+}
+
+// xeroTaxRate is the tax rate of every journal line, as the funds of a church are not
+// subject to tax
+const xeroTaxRate = "Tax Exempt"
+
+// XeroExporter writes a document's journal in the layout of the Xero manual journal
+// import. Xero puts the lines with the same narration and date into one journal.
+type XeroExporter struct {
+	Accounts AccountMappings
+}
+
+func (e *XeroExporter) MediaType() string { return "application/vnd.xero.journal+csv" }
+
+func (e *XeroExporter) Extension() string { return "xero.csv" }
+
+func (e *XeroExporter) Export(w io.Writer, doc *Document) error {
+	if doc.Journal == nil {
+		return ErrNoJournal
+	}
+
+	account, err := accountResolver(e.Accounts, doc.OrganizationId)
+
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+
+	err = writer.Write([]string{"*Narration", "*Date", "Description", "*AccountCode", "*TaxRate", "*Amount",
+		"TrackingName1", "TrackingOption1", "TrackingName2", "TrackingOption2"})
+
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range doc.Journal {
+		date, err := time.Parse("2006-01-02", entry.Date)
+
+		if err != nil {
+			return err
+		}
+
+		for _, line := range entry.Lines {
+			external := account(line)
+
+			err = writer.Write([]string{entry.Narration, date.Format("02/01/2006"), line.Category, external.code,
+				xeroTaxRate, journalAmount(line.Debit - line.Credit), "", "", "", ""})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func journalAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// iifField keeps a value on its line and in its column, as IIF has no quoting
+func iifField(value string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ", `"`, "'").Replace(value)
+}
@@ -0,0 +1,433 @@
+package exports
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	excel "github.com/VaudKK/CAS/pkg/exports/excel"
+	pdf "github.com/VaudKK/CAS/pkg/exports/pdf"
+	"github.com/VaudKK/CAS/pkg/models"
+)
+
+// Contributions lists contributions with a column per category
+func Contributions(contributions []*models.Fund, categories []string, startDate, endDate time.Time, charts models.Charts) *Document {
+	table := &Table{
+		Title:   "Contributions",
+		Columns: []Column{{"date", "Date"}, {"receiptNo", "Receipt No"}, {"contributor", "Name"}},
+	}
+
+	table.Subtitle, table.SubtitleArgs = rangeLabel(startDate, endDate)
+
+	for _, category := range categories {
+		table.Columns = append(table.Columns, Column{category, category})
+	}
+
+	table.Columns = append(table.Columns, Column{"total", "Total"})
+
+	for _, contribution := range contributions {
+		row := []any{contribution.Date, contribution.ReceiptNo, contribution.Contributor}
+
+		for _, category := range categories {
+			row = append(row, contribution.BreakDown[category])
+		}
+
+		table.Rows = append(table.Rows, append(row, contribution.Total))
+	}
+
+	return &Document{
+		Name:  "contributions",
+		Table: table,
+		Excel: func(x *excel.ExcelExport) ([]byte, error) {
+			return x.GenerateExcelFile(contributions, categories, charts)
+		},
+		Pdf: func(p *pdf.PdfExport) ([]byte, error) {
+			return p.GeneratePdfFile(contributions, categories, startDate, endDate, charts)
+		},
+		Journal: contributionEntries(contributions, categories),
+	}
+}
+
+// ContributionRows is the contributions workbook written while the rows are read
+// from the cursor, which is closed once the workbook is written. It has no table as
+// the other formats need the rows in memory; they use Contributions.
+func ContributionRows(contributions models.FundCursor, categories []string, charts models.Charts) *Document {
+	return &Document{
+		Name: "contributions",
+		ExcelStream: func(x *excel.ExcelExport, w io.Writer) error {
+			defer contributions.Close()
+
+			return x.WriteContributions(w, contributions, categories, charts)
+		},
+	}
+}
+
+// CashStatement lists the receipts of the treasurer's cash statement per sabbath.
+// The laid out formats carry the balances, payments and disbursements as well.
+func CashStatement(statement *models.CashStatement, charts models.Charts) *Document {
+	table := &Table{
+		Title:   "Cash Statement",
+		Columns: []Column{{"date", "Date"}},
+	}
+
+	table.Subtitle, table.SubtitleArgs = rangeLabel(statement.From, statement.To)
+
+	for _, category := range statement.Categories {
+		table.Columns = append(table.Columns, Column{category, category})
+	}
+
+	table.Columns = append(table.Columns, Column{"total", "Total"})
+
+	for _, receipt := range statement.Receipts {
+		row := []any{receipt.Date}
+
+		for _, category := range statement.Categories {
+			row = append(row, receipt.Amounts[category])
+		}
+
+		table.Rows = append(table.Rows, append(row, receipt.Total))
+	}
+
+	return &Document{
+		Name:  "cash-statement",
+		Table: table,
+		ExcelStream: func(x *excel.ExcelExport, w io.Writer) error {
+			return x.WriteExcelSummary(w, statement, charts)
+		},
+		Pdf: func(p *pdf.PdfExport) ([]byte, error) {
+			return p.GeneratePdfSummary(statement)
+		},
+	}
+}
+
+// AnnualReport lists the receipts of each category per month of the year. The laid
+// out formats carry the whole annual report: the key figures, the payments, the
+// comparison with the year before and the cash statement of each month.
+func AnnualReport(report *models.AnnualReport) *Document {
+	table := &Table{
+		Title:     "Annual Report %d",
+		TitleArgs: []any{report.Year},
+		Subtitle:  "Receipts by month",
+		Columns:   []Column{{"category", "Category"}},
+	}
+
+	for _, statement := range report.Months {
+		month := statement.From.Month().String()
+		table.Columns = append(table.Columns, Column{strings.ToLower(month), month})
+	}
+
+	table.Columns = append(table.Columns, Column{"total", "Total"})
+
+	for _, category := range report.Categories {
+		row := []any{category}
+		var total float64
+
+		for _, statement := range report.Months {
+			row = append(row, statement.TotalReceipts[category])
+			total += statement.TotalReceipts[category]
+		}
+
+		table.Rows = append(table.Rows, append(row, total))
+	}
+
+	return &Document{
+		Name:  fmt.Sprintf("annual-report-%d", report.Year),
+		Table: table,
+		ExcelStream: func(x *excel.ExcelExport, w io.Writer) error {
+			return x.WriteAnnualReport(w, report)
+		},
+		Pdf: func(p *pdf.PdfExport) ([]byte, error) {
+			return p.GeneratePdfAnnualReport(report)
+		},
+	}
+}
+
+func Expenditures(expenditures []*models.Expenditure, startDate, endDate time.Time) *Document {
+	table := &Table{
+		Title: "Expenditures",
+		Columns: []Column{{"date", "Date"}, {"voucherNo", "Voucher No"}, {"payee", "Payee"}, {"category", "Category"},
+			{"paymentMethod", "Payment Method"}, {"description", "Description"}, {"amount", "Amount"}},
+	}
+
+	table.Subtitle, table.SubtitleArgs = rangeLabel(startDate, endDate)
+
+	for _, expenditure := range expenditures {
+		table.Rows = append(table.Rows, []any{expenditure.Date, expenditure.VoucherNo, expenditure.Payee, expenditure.Category,
+			expenditure.PaymentMethod, expenditure.Description, expenditure.Amount})
+	}
+
+	return &Document{
+		Name:  "expenditures",
+		Table: table,
+		Excel: func(x *excel.ExcelExport) ([]byte, error) {
+			return x.GenerateExpenditureFile(expenditures)
+		},
+		Pdf: func(p *pdf.PdfExport) ([]byte, error) {
+			return p.GenerateExpenditurePdf(expenditures, startDate, endDate)
+		},
+		Journal: expenditureEntries(expenditures),
+	}
+}
+
+func FundBalances(balances []*models.FundBalance, startDate, endDate time.Time) *Document {
+	table := &Table{
+		Title:   "Fund Balances",
+		Columns: []Column{{"category", "Fund"}, {"income", "Income"}, {"expenditure", "Expenditure"}, {"balance", "Balance"}},
+	}
+
+	table.Subtitle, table.SubtitleArgs = rangeLabel(startDate, endDate)
+
+	for _, balance := range balances {
+		table.Rows = append(table.Rows, []any{balance.Category, balance.Income, balance.Expenditure, balance.Balance})
+	}
+
+	return &Document{Name: "fund-balances", Table: table}
+}
+
+func Budgets(budgets []*models.Budget, fiscalYear int) *Document {
+	table := &Table{
+		Title:        "Budgets",
+		Subtitle:     "Fiscal year %d",
+		SubtitleArgs: []any{fiscalYear},
+		Columns:      []Column{{"category", "Category"}, {"amount", "Amount"}, {"alertThreshold", "Alert Threshold"}},
+	}
+
+	for month := time.January; month <= time.December; month++ {
+		table.Columns = append(table.Columns, Column{strings.ToLower(month.String()[:3]), month.String()[:3]})
+	}
+
+	for _, budget := range budgets {
+		row := []any{budget.Category, budget.Amount, budget.AlertThreshold}
+
+		for month := range 12 {
+			var phased any
+
+			if month < len(budget.MonthlyPhasing) {
+				phased = budget.MonthlyPhasing[month]
+			}
+
+			row = append(row, phased)
+		}
+
+		table.Rows = append(table.Rows, row)
+	}
+
+	return &Document{Name: "budgets", Table: table}
+}
+
+func BudgetVsActual(report []*models.BudgetVsActual, fiscalYear, month int) *Document {
+	table := &Table{
+		Title:        "Budget vs Actual",
+		Subtitle:     "Fiscal year %d to end of %s",
+		SubtitleArgs: []any{fiscalYear, time.Month(month)},
+		Columns: []Column{{"category", "Category"}, {"annualBudget", "Annual Budget"}, {"target", "Target"}, {"actual", "Actual"},
+			{"variance", "Variance"}, {"percentage", "Percentage"}, {"alert", "Alert"}},
+	}
+
+	for _, line := range report {
+		table.Rows = append(table.Rows, []any{line.Category, line.AnnualBudget, line.Target, line.Actual, line.Variance,
+			line.Percentage, line.Alert})
+	}
+
+	return &Document{
+		Name:  "budget-vs-actual",
+		Table: table,
+		Excel: func(x *excel.ExcelExport) ([]byte, error) {
+			return x.GenerateBudgetVsActual(report, fiscalYear, month)
+		},
+		Pdf: func(p *pdf.PdfExport) ([]byte, error) {
+			return p.GenerateBudgetVsActual(report, fiscalYear, month)
+		},
+	}
+}
+
+func MonthlyStats(stats []*models.MonthlyStats, year, month int) *Document {
+	table := &Table{
+		Title:        "Monthly Statistics",
+		Subtitle:     "%s %d",
+		SubtitleArgs: []any{time.Month(month), year},
+		Columns:      []Column{{"name", "Name"}, {"value", "Value"}},
+	}
+
+	for _, stat := range stats {
+		table.Rows = append(table.Rows, []any{stat.Name, stat.Value})
+	}
+
+	return &Document{Name: "monthly-statistics", Table: table}
+}
+
+func Variance(variance []*models.Variance) *Document {
+	table := &Table{
+		Title:   "Monthly Variance",
+		Columns: []Column{{"category", "Category"}, {"currentValue", "Current Value"}, {"percentage", "Percentage"}, {"direction", "Direction"}},
+	}
+
+	for _, v := range variance {
+		table.Rows = append(table.Rows, []any{v.Category, v.CurrentValue, v.Percentage, v.Direction})
+	}
+
+	return &Document{Name: "variance", Table: table}
+}
+
+func Accounts(accounts []*models.Account) *Document {
+	table := &Table{
+		Title:   "Chart of Accounts",
+		Columns: []Column{{"code", "Code"}, {"name", "Name"}, {"type", "Type"}, {"category", "Category"}},
+	}
+
+	for _, account := range accounts {
+		table.Rows = append(table.Rows, []any{account.Code, account.Name, account.Type, account.Category})
+	}
+
+	return &Document{Name: "accounts", Table: table}
+}
+
+// JournalEntries lists the lines of journal entries, one row per line
+func JournalEntries(entries []*models.JournalEntry, startDate, endDate time.Time) *Document {
+	table := &Table{
+		Title: "Journal",
+		Columns: []Column{{"entryId", "Entry"}, {"date", "Date"}, {"description", "Description"}, {"sourceType", "Source"},
+			{"accountCode", "Account Code"}, {"accountName", "Account"}, {"debit", "Debit"}, {"credit", "Credit"}},
+	}
+
+	table.Subtitle, table.SubtitleArgs = rangeLabel(startDate, endDate)
+
+	for _, entry := range entries {
+		for _, line := range entry.Lines {
+			table.Rows = append(table.Rows, []any{entry.ID, entry.Date, entry.Description, entry.SourceType, line.AccountCode,
+				line.AccountName, line.Debit, line.Credit})
+		}
+	}
+
+	return &Document{Name: "journal", Table: table}
+}
+
+func TrialBalance(report *models.TrialBalance) *Document {
+	table := &Table{
+		Title:        "Trial Balance",
+		Subtitle:     "As at %s",
+		SubtitleArgs: []any{report.Period.To},
+		Columns: []Column{{"code", "Code"}, {"name", "Account"}, {"type", "Type"}, {"debit", "Debit"}, {"credit", "Credit"},
+			{"priorDebit", "Prior Debit"}, {"priorCredit", "Prior Credit"}},
+	}
+
+	for _, line := range report.Lines {
+		table.Rows = append(table.Rows, []any{line.Code, line.Name, line.Type, line.Debit, line.Credit, line.PriorDebit,
+			line.PriorCredit})
+	}
+
+	table.Rows = append(table.Rows, []any{"", Label("Total"), "", report.TotalDebit, report.TotalCredit, report.PriorTotalDebit,
+		report.PriorTotalCredit})
+
+	return &Document{
+		Name:  "trial-balance",
+		Table: table,
+		Excel: func(x *excel.ExcelExport) ([]byte, error) {
+			return x.GenerateTrialBalance(report)
+		},
+		Pdf: func(p *pdf.PdfExport) ([]byte, error) {
+			return p.GenerateTrialBalance(report)
+		},
+	}
+}
+
+// IncomeStatement lists income and expenditure lines under a section column, with
+// the totals and the surplus as rows of their own
+func IncomeStatement(report *models.IncomeStatement) *Document {
+	table := &Table{
+		Title: "Income and Expenditure",
+		Columns: []Column{{"section", "Section"}, {"code", "Code"}, {"name", "Name"}, {"amount", "Amount"},
+			{"priorAmount", "Prior Amount"}},
+	}
+
+	table.Subtitle, table.SubtitleArgs = rangeLabel(report.Period.From, report.Period.To)
+
+	for _, line := range report.Income {
+		table.Rows = append(table.Rows, []any{Label("Income"), line.Code, line.Name, line.Amount, line.PriorAmount})
+	}
+
+	table.Rows = append(table.Rows, []any{Label("Income"), "", Label("Total income"), report.TotalIncome,
+		report.PriorTotalIncome})
+
+	for _, line := range report.Expenditure {
+		table.Rows = append(table.Rows, []any{Label("Expenditure"), line.Code, line.Name, line.Amount, line.PriorAmount})
+	}
+
+	table.Rows = append(table.Rows, []any{Label("Expenditure"), "", Label("Total expenditure"), report.TotalExpenditure,
+		report.PriorTotalExpenditure})
+	table.Rows = append(table.Rows, []any{Label("Surplus"), "", Label("Surplus (deficit)"), report.Surplus,
+		report.PriorSurplus})
+
+	return &Document{
+		Name:  "income-and-expenditure",
+		Table: table,
+		Excel: func(x *excel.ExcelExport) ([]byte, error) {
+			return x.GenerateIncomeStatement(report)
+		},
+		Pdf: func(p *pdf.PdfExport) ([]byte, error) {
+			return p.GenerateIncomeStatement(report)
+		},
+	}
+}
+
+func FundBalanceSheet(report *models.FundBalanceSheet) *Document {
+	table := &Table{
+		Title: "Fund Balance Sheet",
+		Columns: []Column{{"fund", "Fund"}, {"opening", "Opening"}, {"receipts", "Receipts"}, {"payments", "Payments"},
+			{"transfers", "Transfers"}, {"closing", "Closing"}, {"priorClosing", "Prior Closing"}},
+	}
+
+	table.Subtitle, table.SubtitleArgs = rangeLabel(report.Period.From, report.Period.To)
+
+	row := func(line *models.FundBalanceLine) []any {
+		return []any{line.Fund, line.Opening, line.Receipts, line.Payments, line.Transfers, line.Closing, line.PriorClosing}
+	}
+
+	for _, line := range report.Lines {
+		table.Rows = append(table.Rows, row(line))
+	}
+
+	table.Rows = append(table.Rows, row(&report.Totals))
+
+	return &Document{
+		Name:  "fund-balance-sheet",
+		Table: table,
+		Excel: func(x *excel.ExcelExport) ([]byte, error) {
+			return x.GenerateFundBalanceSheet(report)
+		},
+		Pdf: func(p *pdf.PdfExport) ([]byte, error) {
+			return p.GenerateFundBalanceSheet(report)
+		},
+	}
+}
+
+func Imports(jobs []*models.ImportJob) *Document {
+	table := &Table{
+		Title: "Imports",
+		Columns: []Column{{"id", "ID"}, {"fileName", "File"}, {"status", "Status"}, {"version", "Version"},
+			{"rowsRead", "Rows Read"}, {"rowsInserted", "Rows Inserted"}, {"rowsUpdated", "Rows Updated"},
+			{"rowsSkipped", "Rows Skipped"}, {"createdAt", "Created At"}, {"finishedAt", "Finished At"}},
+	}
+
+	for _, job := range jobs {
+		table.Rows = append(table.Rows, []any{job.ID, job.FileName, job.Status, job.Version, job.RowsRead, job.RowsInserted,
+			job.RowsUpdated, job.RowsSkipped, job.Audit.CreatedAt, job.FinishedAt})
+	}
+
+	return &Document{Name: "imports", Table: table}
+}
+
+// rangeLabel is the subtitle of a document covering a range of dates and the dates
+// that fill it in
+func rangeLabel(startDate, endDate time.Time) (string, []any) {
+	switch {
+	case startDate.IsZero() && endDate.IsZero():
+		return "", nil
+	case endDate.IsZero():
+		return "From %s", []any{startDate}
+	case startDate.IsZero():
+		return "To %s", []any{endDate}
+	}
+	return "For the period %s to %s", []any{startDate, endDate}
+}
@@ -0,0 +1,281 @@
+package exports
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// WriteAnnualReport writes the annual report workbook straight to w: the headline
+// figures, the year's receipts and payments per category and month, the comparison
+// with the year before and the cash statement of each month on a sheet of its own
+func (exExport *ExcelExport) WriteAnnualReport(w io.Writer, report *models.AnnualReport) error {
+	p := exExport.text()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	// the default sheet takes the headline figures rather than being left empty
+	const first = "KPIs"
+
+	if err := f.SetSheetName(f.GetSheetName(0), first); err != nil {
+		return err
+	}
+
+	type annualSheet struct {
+		name  string
+		write func(*sheetWriter) error
+	}
+
+	sheets := []annualSheet{
+		{first, func(s *sheetWriter) error { return writeKPIs(s, p, report) }},
+		{p.T("Year Summary"), func(s *sheetWriter) error { return writeYearSummary(s, p, report) }},
+		{p.T("Year on Year"), func(s *sheetWriter) error { return writeYearOnYear(s, p, report) }},
+	}
+
+	for _, statement := range report.Months {
+		sheets = append(sheets, annualSheet{p.Month(statement.From.Month()), func(s *sheetWriter) error {
+			return writeCashStatement(s, p, statement)
+		}})
+	}
+
+	for _, sheet := range sheets {
+		if sheet.name != first {
+			if _, err := f.NewSheet(sheet.name); err != nil {
+				return err
+			}
+		}
+
+		s, err := newSheetWriter(f, sheet.name)
+
+		if err != nil {
+			return err
+		}
+
+		if err = sheet.write(s); err != nil {
+			return err
+		}
+
+		if err = s.sw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return f.Write(w)
+}
+
+// annualTitle heads every sheet of the annual report other than the monthly
+// statements, which keep their own
+func annualTitle(s *sheetWriter, p *i18n.Printer, columns int, report *models.AnnualReport, heading string) error {
+	err := s.title(max(columns, 6), "KITENGELA CENTRAL SDA CHURCH",
+		caps(p, "Annual Report %d", report.Year), strings.ToUpper(heading))
+
+	if err != nil {
+		return err
+	}
+
+	s.skip(1)
+
+	return nil
+}
+
+func writeKPIs(s *sheetWriter, p *i18n.Printer, report *models.AnnualReport) error {
+	if err := s.sw.SetColWidth(1, 1, 42); err != nil {
+		return err
+	}
+
+	if err := s.sw.SetColWidth(2, 3, 20); err != nil {
+		return err
+	}
+
+	heading := p.T("Key figures for %s", monthsLabel(p, report.Year, len(report.Months)))
+
+	if err := annualTitle(s, p, 3, report, heading); err != nil {
+		return err
+	}
+
+	rows := make([][]any, 0, len(report.KPIs))
+
+	for _, kpi := range report.KPIs {
+		var value any = kpi.Value
+
+		// counts are whole numbers rather than amounts
+		if kpi.Unit == "" {
+			value = int(kpi.Value)
+		}
+
+		name := kpi.Name
+
+		if kpi.Label != "" {
+			name = p.T(kpi.Label, kpi.Args...)
+		}
+
+		rows = append(rows, []any{name, value, kpi.Unit})
+	}
+
+	return s.table([]string{caps(p, "Measure"), caps(p, "Value"), caps(p, "Unit")}, rows, nil)
+}
+
+// writeYearSummary lays out the receipts and then the payments of each category with
+// a column per month and the year's total
+func writeYearSummary(s *sheetWriter, p *i18n.Printer, report *models.AnnualReport) error {
+	months := len(report.Months)
+
+	if err := s.sw.SetColWidth(1, months+2, 17); err != nil {
+		return err
+	}
+
+	if err := annualTitle(s, p, months+2, report, p.T("Year summary")); err != nil {
+		return err
+	}
+
+	headers := []string{caps(p, "Category")}
+
+	for _, statement := range report.Months {
+		headers = append(headers, strings.ToUpper(p.Month(statement.From.Month())))
+	}
+
+	headers = append(headers, caps(p, "Total"))
+
+	sections := []struct {
+		title  string
+		amount func(*models.CashStatement) map[string]float64
+	}{
+		{caps(p, "Receipts"), func(c *models.CashStatement) map[string]float64 { return c.TotalReceipts }},
+		{caps(p, "Payments"), func(c *models.CashStatement) map[string]float64 { return c.Payments }},
+	}
+
+	for i, section := range sections {
+		if i > 0 {
+			s.skip(2)
+		}
+
+		if err := s.line([]any{excelize.Cell{StyleID: s.total, Value: section.title}}); err != nil {
+			return err
+		}
+
+		monthTotals := make([]float64, months)
+		rows := make([][]any, 0, len(report.Categories))
+
+		for _, category := range report.Categories {
+			row := []any{category}
+			var total float64
+
+			for m, statement := range report.Months {
+				amount := section.amount(statement)[category]
+				row = append(row, amount)
+				monthTotals[m] += amount
+				total += amount
+			}
+
+			rows = append(rows, append(row, total))
+		}
+
+		totals := []any{caps(p, "Total")}
+		var total float64
+
+		for _, amount := range monthTotals {
+			totals = append(totals, amount)
+			total += amount
+		}
+
+		if err := s.table(headers, rows, append(totals, total)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeYearOnYear compares the year with the same months of the year before, per
+// category and per month
+func writeYearOnYear(s *sheetWriter, p *i18n.Printer, report *models.AnnualReport) error {
+	current, previous := report.Current, report.Previous
+
+	if err := s.sw.SetColWidth(1, 5, 20); err != nil {
+		return err
+	}
+
+	heading := p.T("%d compared with %d, %s", current.Year, previous.Year,
+		strings.ToLower(monthsLabel(p, report.Year, current.Through)))
+
+	if err := annualTitle(s, p, 5, report, heading); err != nil {
+		return err
+	}
+
+	headers := func(label string) []string {
+		return []string{label, fmt.Sprint(previous.Year), fmt.Sprint(current.Year), caps(p, "Change"),
+			caps(p, "Change %")}
+	}
+
+	sections := []struct {
+		title             string
+		current, previous map[string]float64
+	}{
+		{caps(p, "Receipts"), current.Receipts, previous.Receipts},
+		{caps(p, "Payments"), current.Payments, previous.Payments},
+	}
+
+	for _, section := range sections {
+		if err := s.line([]any{excelize.Cell{StyleID: s.total, Value: section.title}}); err != nil {
+			return err
+		}
+
+		rows := make([][]any, 0, len(report.Categories))
+		var before, after float64
+
+		for _, category := range report.Categories {
+			rows = append(rows, comparison(category, section.previous[category], section.current[category]))
+			before += section.previous[category]
+			after += section.current[category]
+		}
+
+		if err := s.table(headers(caps(p, "Category")), rows, comparison(caps(p, "Total"), before, after)); err != nil {
+			return err
+		}
+
+		s.skip(2)
+	}
+
+	if err := s.line([]any{excelize.Cell{StyleID: s.total, Value: caps(p, "Receipts by month")}}); err != nil {
+		return err
+	}
+
+	rows := make([][]any, 0, current.Through)
+	var before, after float64
+
+	for m := 0; m < current.Through; m++ {
+		rows = append(rows, comparison(strings.ToUpper(p.Month(time.Month(m+1))), previous.MonthlyReceipts[m],
+			current.MonthlyReceipts[m]))
+		before += previous.MonthlyReceipts[m]
+		after += current.MonthlyReceipts[m]
+	}
+
+	return s.table(headers(caps(p, "Month")), rows, comparison(caps(p, "Total"), before, after))
+}
+
+// comparison is a row of the year on year sheet. The change in percent is left
+// blank when there is nothing to compare with.
+func comparison(label string, before, after float64) []any {
+	var percent any = ""
+
+	if before != 0 {
+		percent = (after - before) / before * 100
+	}
+
+	return []any{label, before, after, after - before, percent}
+}
+
+// monthsLabel names the months an annual report covers
+func monthsLabel(p *i18n.Printer, year, months int) string {
+	if months >= 12 {
+		return p.T("the year %d", year)
+	}
+
+	return p.T("January to %s %d", time.Month(months), year)
+}
@@ -0,0 +1,115 @@
+package exports
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/xuri/excelize/v2"
+)
+
+func (exExport *ExcelExport) GenerateBudgetVsActual(data []*models.BudgetVsActual, fiscalYear, month int) ([]byte, error) {
+	const sheet = "BudgetVsActual"
+
+	p := exExport.text()
+
+	f := excelize.NewFile()
+
+	index, err := f.NewSheet(sheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	headerStyle, err := newHeaderStyle(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	boarderStyle, err := newBorderStyle(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	amountStyle, err := newAmountStyle(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	totalStyle, err := newTotalStyle(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	headers := []string{caps(p, "Category"), caps(p, "Annual budget"), caps(p, "Target to date"), caps(p, "Actual"),
+		caps(p, "Variance"), caps(p, "Variance %"), caps(p, "Status")}
+
+	f.SetColWidth(sheet, "A", "G", 19)
+
+	err = writeTitle(f, sheet, len(headers),
+		caps(p, "Budget vs Actual"),
+		"KITENGELA CENTRAL SDA CHURCH",
+		caps(p, "Fiscal year %d to end of %s", fiscalYear, time.Month(month)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 4)
+		f.SetCellStyle(sheet, cell, cell, headerStyle)
+		f.SetCellValue(sheet, cell, header)
+	}
+
+	for i, row := range data {
+		r := i + 5
+
+		status := caps(p, "On track")
+		if row.Alert {
+			status = caps(p, "Below target")
+		}
+
+		values := []any{row.Category, row.AnnualBudget, row.Target, row.Actual, row.Variance,
+			p.Percent(float64(row.Percentage)), status}
+
+		for j, value := range values {
+			cell, _ := excelize.CoordinatesToCellName(j+1, r)
+			f.SetCellValue(sheet, cell, value)
+
+			if j >= 1 && j <= 4 {
+				f.SetCellStyle(sheet, cell, cell, amountStyle)
+			} else {
+				f.SetCellStyle(sheet, cell, cell, boarderStyle)
+			}
+		}
+	}
+
+	totalsRow := len(data) + 5
+
+	text, _ := excelize.CoordinatesToCellName(1, totalsRow)
+	f.SetCellValue(sheet, text, p.T("Total"))
+	f.SetCellStyle(sheet, text, text, totalStyle)
+
+	for i := 2; i <= 5; i++ {
+		cellStart, _ := excelize.CoordinatesToCellName(i, 5)
+		cellEnd, _ := excelize.CoordinatesToCellName(i, totalsRow-1)
+		cell, _ := excelize.CoordinatesToCellName(i, totalsRow)
+
+		f.SetCellFormula(sheet, cell, fmt.Sprintf("SUM(%s:%s)", cellStart, cellEnd))
+		f.SetCellStyle(sheet, cell, cell, totalStyle)
+	}
+
+	f.SetActiveSheet(index)
+
+	buff, err := f.WriteToBuffer()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
@@ -0,0 +1,247 @@
+package exports
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/xuri/excelize/v2"
+)
+
+const chartSheet = "Charts"
+
+// chartData collects the totals the charts are drawn from, per category, per sabbath
+// and per month. It is small whatever the number of contributions, so it can be
+// gathered while the rows are streamed.
+type chartData struct {
+	categories []string
+	totals     map[string]float64
+	bySabbath  map[string]map[string]float64
+	byMonth    map[string]map[string]float64
+}
+
+func newChartData(categories []string) *chartData {
+	return &chartData{
+		categories: categories,
+		totals:     make(map[string]float64),
+		bySabbath:  make(map[string]map[string]float64),
+		byMonth:    make(map[string]map[string]float64),
+	}
+}
+
+// add counts the amounts of a date given as YYYY-MM-DD. Categories that are not
+// charted are left out.
+func (d *chartData) add(date string, amounts map[string]float64) {
+	if len(date) < 7 {
+		return
+	}
+
+	month := date[:7]
+
+	if d.bySabbath[date] == nil {
+		d.bySabbath[date] = make(map[string]float64)
+	}
+
+	if d.byMonth[month] == nil {
+		d.byMonth[month] = make(map[string]float64)
+	}
+
+	for _, category := range d.categories {
+		if amount, ok := amounts[category]; ok {
+			d.totals[category] += amount
+			d.bySabbath[date][category] += amount
+			d.byMonth[month][category] += amount
+		}
+	}
+}
+
+// addCharts adds a sheet with the selected charts and the tables they are drawn from
+func addCharts(f *excelize.File, p *i18n.Printer, charts models.Charts, data *chartData) error {
+	if !charts.Any() || len(data.categories) == 0 {
+		return nil
+	}
+
+	if _, err := f.NewSheet(chartSheet); err != nil {
+		return err
+	}
+
+	if err := f.SetColWidth(chartSheet, "A", excelizeColumn(len(data.categories)+1), 14); err != nil {
+		return err
+	}
+
+	row := 1
+	anchor := 2
+	chartColumn := excelizeColumn(len(data.categories) + 3)
+
+	if charts.Pie {
+		rows := make([][]any, 0, len(data.categories))
+
+		for _, category := range data.categories {
+			rows = append(rows, []any{category, data.totals[category]})
+		}
+
+		first, last, err := writeChartTable(f, row, []string{caps(p, "Category"), caps(p, "Total")}, rows)
+
+		if err != nil {
+			return err
+		}
+
+		err = f.AddChart(chartSheet, fmt.Sprintf("%s%d", chartColumn, anchor), &excelize.Chart{
+			Type:   excelize.Pie,
+			Title:  []excelize.RichTextRun{{Text: p.T("Share of contributions by category")}},
+			Legend: excelize.ChartLegend{Position: "right"},
+			Series: []excelize.ChartSeries{{
+				Name:       p.T("Total"),
+				Categories: chartRange(1, first, last),
+				Values:     chartRange(2, first, last),
+			}},
+			PlotArea:  excelize.ChartPlotArea{ShowPercent: true},
+			Dimension: excelize.ChartDimension{Width: 640, Height: 360},
+		})
+
+		if err != nil {
+			return err
+		}
+
+		row = last + 2
+		anchor += 20
+	}
+
+	series := func(label string, keys []string, amounts map[string]map[string]float64) error {
+		rows := make([][]any, 0, len(keys))
+
+		for _, key := range keys {
+			values := []any{key}
+
+			for _, category := range data.categories {
+				values = append(values, amounts[key][category])
+			}
+
+			rows = append(rows, values)
+		}
+
+		_, _, err := writeChartTable(f, row, append([]string{label}, data.categories...), rows)
+
+		return err
+	}
+
+	chartSeries := func(first, last int) []excelize.ChartSeries {
+		all := make([]excelize.ChartSeries, 0, len(data.categories))
+
+		for i := range data.categories {
+			all = append(all, excelize.ChartSeries{
+				Name:       fmt.Sprintf("%s!$%s$%d", chartSheet, excelizeColumn(i+2), first-1),
+				Categories: chartRange(1, first, last),
+				Values:     chartRange(i+2, first, last),
+			})
+		}
+
+		return all
+	}
+
+	if charts.Columns && len(data.bySabbath) > 0 {
+		keys := slices.Sorted(maps.Keys(data.bySabbath))
+
+		if err := series(caps(p, "Sabbath"), keys, data.bySabbath); err != nil {
+			return err
+		}
+
+		first, last := row+1, row+len(keys)
+
+		err := f.AddChart(chartSheet, fmt.Sprintf("%s%d", chartColumn, anchor), &excelize.Chart{
+			Type:      excelize.ColStacked,
+			Title:     []excelize.RichTextRun{{Text: p.T("Contributions per sabbath")}},
+			Legend:    excelize.ChartLegend{Position: "bottom"},
+			Series:    chartSeries(first, last),
+			Dimension: excelize.ChartDimension{Width: 960, Height: 400},
+		})
+
+		if err != nil {
+			return err
+		}
+
+		row = last + 2
+		anchor += 22
+	}
+
+	if charts.Trend && len(data.byMonth) > 0 {
+		keys := slices.Sorted(maps.Keys(data.byMonth))
+
+		if err := series(caps(p, "Month"), keys, data.byMonth); err != nil {
+			return err
+		}
+
+		first, last := row+1, row+len(keys)
+
+		err := f.AddChart(chartSheet, fmt.Sprintf("%s%d", chartColumn, anchor), &excelize.Chart{
+			Type:      excelize.Line,
+			Title:     []excelize.RichTextRun{{Text: p.T("Monthly trend by category")}},
+			Legend:    excelize.ChartLegend{Position: "bottom"},
+			Series:    chartSeries(first, last),
+			Dimension: excelize.ChartDimension{Width: 960, Height: 400},
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeChartTable writes a header row and the rows at row and returns the first and
+// last data rows
+func writeChartTable(f *excelize.File, row int, headers []string, rows [][]any) (int, int, error) {
+	headerStyle, err := newHeaderStyle(f)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	amountStyle, err := newAmountStyle(f)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start, _ := excelize.CoordinatesToCellName(1, row)
+	end, _ := excelize.CoordinatesToCellName(len(headers), row)
+
+	if err = f.SetSheetRow(chartSheet, start, &headers); err != nil {
+		return 0, 0, err
+	}
+
+	f.SetCellStyle(chartSheet, start, end, headerStyle)
+
+	for i, values := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, row+1+i)
+
+		if err = f.SetSheetRow(chartSheet, cell, &values); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if len(rows) > 0 {
+		start, _ = excelize.CoordinatesToCellName(2, row+1)
+		end, _ = excelize.CoordinatesToCellName(len(headers), row+len(rows))
+		f.SetCellStyle(chartSheet, start, end, amountStyle)
+	}
+
+	return row + 1, row + len(rows), nil
+}
+
+// chartRange is an absolute reference to rows first to last of a column on the
+// charts sheet
+func chartRange(column, first, last int) string {
+	name := excelizeColumn(column)
+
+	return fmt.Sprintf("%s!$%s$%d:$%s$%d", chartSheet, name, first, name, last)
+}
+
+func excelizeColumn(column int) string {
+	name, _ := excelize.ColumnNumberToName(column)
+
+	return name
+}
@@ -1,294 +1,319 @@
 package exports
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"strings"
 
+	"github.com/VaudKK/CAS/pkg/i18n"
 	"github.com/VaudKK/CAS/pkg/models"
 	"github.com/xuri/excelize/v2"
 )
 
 type ExcelExport struct {
+	printer *i18n.Printer
 }
 
-func (exExport *ExcelExport) GenerateExcelFile(data []*models.Fund, categories []string) ([]byte, error) {
-	f := excelize.NewFile()
-
-	// Create a new sheet
-	index, err := f.NewSheet("Contributions")
+// ForLocale returns the export written in the language of the locale
+func (exExport *ExcelExport) ForLocale(locale string) *ExcelExport {
+	return &ExcelExport{printer: i18n.For(locale)}
+}
 
-	if err != nil {
-		return nil, err
+// text is the printer of the export's locale, that of the default locale when none
+// was chosen
+func (exExport *ExcelExport) text() *i18n.Printer {
+	if exExport.printer == nil {
+		return i18n.For(i18n.DefaultLocale)
 	}
 
-	headerStyle, err := f.NewStyle(&excelize.Style{
-		Font:      &excelize.Font{Bold: true, Size: 10, Family: "Arial"},
-		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#D3D3D3"}, Pattern: 1},
-		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		},
-	})
+	return exExport.printer
+}
 
-	if err != nil {
+// caps translates a heading, which workbooks write in capitals
+func caps(p *i18n.Printer, key string, args ...any) string {
+	return strings.ToUpper(p.T(key, args...))
+}
+
+// GenerateExcelFile writes the contributions workbook for contributions already in
+// memory
+func (exExport *ExcelExport) GenerateExcelFile(data []*models.Fund, categories []string, charts models.Charts) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := exExport.WriteContributions(&buf, &fundSlice{funds: data}, categories, charts); err != nil {
 		return nil, err
 	}
 
-	boarderStyle, err := f.NewStyle(&excelize.Style{
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		}})
+	return buf.Bytes(), nil
+}
+
+// WriteContributions writes a row per contribution read from the cursor, with a
+// column per category and totals, straight to w. The selected charts go on a sheet
+// of their own. The cursor is read to the end but not closed.
+func (exExport *ExcelExport) WriteContributions(w io.Writer, contributions models.FundCursor, categories []string, charts models.Charts) error {
+	const sheet = "Contributions"
+
+	p := exExport.text()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	// Create a new sheet
+	index, err := f.NewSheet(sheet)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	f.SetColWidth("Contributions", "A", "AZ", 21)
+	// the active sheet is set before streaming, setting it afterwards would parse the
+	// streamed sheet back into memory
+	f.SetActiveSheet(index)
 
-	categoryIndex := make(map[string]int)
+	s, err := newSheetWriter(f, sheet)
 
-	// Set the headers
-	headers := []string{"NAME", "RECEIPT NO", "TOTAL", "DATE"}
+	if err != nil {
+		return err
+	}
+
+	headers := []string{caps(p, "Name"), caps(p, "Receipt No"), caps(p, "Total"), caps(p, "Date")}
+	headers = append(headers, categories...)
 
-	for i, category := range categories {
-		headers = append(headers, category)
-		categoryIndex[category] = 4 + i
+	if err = s.sw.SetColWidth(1, max(len(headers), 52), 21); err != nil {
+		return err
 	}
 
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-		f.SetCellStyle("Contributions", cell, cell, headerStyle)
-		f.SetColWidth("Contributions", cell, cell, 17)
-		f.SetCellValue("Contributions", cell, header)
+	if err = s.line(s.styled(s.header, toAny(headers))); err != nil {
+		return err
 	}
 
-	// Set the data
-	for i, contribution := range data {
-		cellContributor, _ := excelize.CoordinatesToCellName(1, i+2)
-		cellReceiptNo, _ := excelize.CoordinatesToCellName(2, i+2)
-		cellTotal, _ := excelize.CoordinatesToCellName(3, i+2)
-		cellDate, _ := excelize.CoordinatesToCellName(4, i+2)
-
-		f.SetCellValue("Contributions", cellDate, strings.Split(contribution.Date, "T")[0])
-		f.SetCellValue("Contributions", cellContributor, contribution.Contributor)
-		f.SetCellValue("Contributions", cellTotal, contribution.Total)
-		f.SetCellValue("Contributions", cellReceiptNo, contribution.ReceiptNo)
-
-		f.SetCellStyle("Contributions", cellContributor, cellDate, boarderStyle)
-		f.SetCellStyle("Contributions", cellTotal, cellTotal, boarderStyle)
-		f.SetCellStyle("Contributions", cellReceiptNo, cellReceiptNo, boarderStyle)
-		f.SetCellStyle("Contributions", cellDate, cellDate, boarderStyle)
-
-		// set border on all data cells
-		for k := 3; k < len(headers); k++ {
-			cellCategory, _ := excelize.CoordinatesToCellName(k+1, i+2)
-			f.SetCellStyle("Contributions", cellCategory, cellCategory, boarderStyle)
+	// every data cell is bordered, categories without an amount included
+	cells := make([]any, len(headers))
+	totals := newChartData(categories)
+
+	for contributions.Next() {
+		contribution := contributions.Fund()
+		date := strings.Split(contribution.Date, "T")[0]
+
+		if charts.Any() {
+			totals.add(date, contribution.BreakDown)
 		}
 
-		for category, amount := range contribution.BreakDown {
-			if j, ok := categoryIndex[category]; ok {
-				cellCategory, _ := excelize.CoordinatesToCellName(j+1, i+2)
-				f.SetCellValue("Contributions", cellCategory, amount)
+		cells[0] = excelize.Cell{StyleID: s.border, Value: contribution.Contributor}
+		cells[1] = excelize.Cell{StyleID: s.border, Value: contribution.ReceiptNo}
+		cells[2] = excelize.Cell{StyleID: s.border, Value: contribution.Total}
+		cells[3] = excelize.Cell{StyleID: s.date, Value: cellDate(date)}
+
+		for i, category := range categories {
+			cell := excelize.Cell{StyleID: s.border}
+
+			if amount, ok := contribution.BreakDown[category]; ok {
+				cell.Value = amount
 			}
-		}
-	}
 
-	// summation
-	row := len(data) + 1
+			cells[4+i] = cell
+		}
 
-	totalStyle, err := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 11, Family: "Arial"},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"#D3D3D3"}, Pattern: 1},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		}})
+		if err = s.line(cells); err != nil {
+			return err
+		}
+	}
 
-	if err != nil {
-		return nil, err
+	if err = contributions.Err(); err != nil {
+		return err
 	}
 
-	cellContributor, _ := excelize.CoordinatesToCellName(1, row+2)
-	cellReceiptNo, _ := excelize.CoordinatesToCellName(2, row+2)
-	cellTotal, _ := excelize.CoordinatesToCellName(3, row+2)
-	cellDate, _ := excelize.CoordinatesToCellName(4, row+2)
+	// summation, a row below the last contribution
+	last := s.row - 1
+	s.skip(1)
 
-	f.SetCellStyle("Contributions", cellContributor, cellDate, totalStyle)
-	f.SetCellStyle("Contributions", cellTotal, cellTotal, totalStyle)
-	f.SetCellStyle("Contributions", cellReceiptNo, cellReceiptNo, totalStyle)
-	f.SetCellStyle("Contributions", cellDate, cellDate, totalStyle)
+	for i := range cells {
+		cells[i] = excelize.Cell{StyleID: s.total}
 
-	cellTotalStart, _ := excelize.CoordinatesToCellName(3, 2)
-	cellTotalEnd, _ := excelize.CoordinatesToCellName(3, row)
+		if i == 2 || i >= 4 {
+			start, _ := excelize.CoordinatesToCellName(i+1, 2)
+			end, _ := excelize.CoordinatesToCellName(i+1, last)
+			cells[i] = excelize.Cell{StyleID: s.total, Formula: fmt.Sprintf("SUM(%s:%s)", start, end)}
+		}
+	}
 
-	f.SetCellFormula("Contributions", cellTotal, fmt.Sprintf("SUM(%s:%s)", cellTotalStart, cellTotalEnd))
+	if err = s.line(cells); err != nil {
+		return err
+	}
 
-	for i := 3; i < len(categoryIndex)+3; i++ {
-		cellStart, _ := excelize.CoordinatesToCellName(i+2, 2)
-		cellEnd, _ := excelize.CoordinatesToCellName(i+2, row)
+	if err = s.sw.Flush(); err != nil {
+		return err
+	}
 
-		cellCategory, _ := excelize.CoordinatesToCellName(i+2, row+2)
-		f.SetCellFormula("Contributions", cellCategory, fmt.Sprintf("SUM(%s:%s)", cellStart, cellEnd))
-		f.SetCellStyle("Contributions", cellCategory, cellCategory, totalStyle)
+	if err = addCharts(f, p, charts, totals); err != nil {
+		return err
 	}
 
-	// Set the active sheet
-	f.SetActiveSheet(index)
+	return f.Write(w)
+}
 
-	buff, err := f.WriteToBuffer()
+// GenerateExcelSummary writes the treasurer's cash statement: balances brought
+// forward, receipts per sabbath, payments and transfers per fund, the balances
+// carried forward, the list of disbursements and a signature block
+func (exExport *ExcelExport) GenerateExcelSummary(statement *models.CashStatement, charts models.Charts) ([]byte, error) {
+	var buf bytes.Buffer
 
-	if err != nil {
+	if err := exExport.WriteExcelSummary(&buf, statement, charts); err != nil {
 		return nil, err
 	}
 
-	return buff.Bytes(), nil
+	return buf.Bytes(), nil
 }
 
-func (exExport *ExcelExport) GenerateExcelSummary(data map[string][]models.MonthlySummations,
-	categories []string) ([]byte, error) {
+// WriteExcelSummary writes the cash statement workbook straight to w, with the
+// selected charts of the receipts on a sheet of their own
+func (exExport *ExcelExport) WriteExcelSummary(w io.Writer, statement *models.CashStatement, charts models.Charts) error {
+	const sheet = "ContributionsSummary"
 
 	f := excelize.NewFile()
+	defer f.Close()
 
-	index, err := f.NewSheet("ContributionsSummary")
+	index, err := f.NewSheet(sheet)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	headerStyle, err := f.NewStyle(&excelize.Style{
-		Font:      &excelize.Font{Bold: true, Size: 10, Family: "Arial"},
-		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#D3D3D3"}, Pattern: 1},
-		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		},
-	})
+	// the active sheet is set before streaming, setting it afterwards would parse the
+	// streamed sheet back into memory
+	f.SetActiveSheet(index)
+
+	s, err := newSheetWriter(f, sheet)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	boarderStyle, err := f.NewStyle(&excelize.Style{
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		}})
+	p := exExport.text()
 
-	if err != nil {
-		return nil, err
+	if err = writeCashStatement(s, p, statement); err != nil {
+		return err
 	}
 
-	f.SetColWidth("ContributionsSummary", "A", "AZ", 17)
+	if err = s.sw.Flush(); err != nil {
+		return err
+	}
 
-	f.SetCellValue("ContributionsSummary", "A1", "CHURCH TREASURER'S CASH STATEMENT")
-	f.SetCellValue("ContributionsSummary", "A2", "KITENGELA CENTRAL SDA CHURCH")
-	f.SetCellValue("ContributionsSummary", "A3", "CONSOLIDATED REPORT")
+	receipts := newChartData(statement.Categories)
 
-	f.SetCellStyle("ContributionsSummary", "A1", "J1", headerStyle)
-	f.SetCellStyle("ContributionsSummary", "A2", "J2", headerStyle)
-	f.SetCellStyle("ContributionsSummary", "A3", "J3", headerStyle)
+	for _, receipt := range statement.Receipts {
+		receipts.add(receipt.Date, receipt.Amounts)
+	}
 
-	if err := f.MergeCell("ContributionsSummary", "A1", "J1"); err != nil {
-		return nil, err
+	if err = addCharts(f, p, charts, receipts); err != nil {
+		return err
 	}
 
-	if err := f.MergeCell("ContributionsSummary", "A2", "J2"); err != nil {
-		return nil, err
+	return f.Write(w)
+}
+
+// writeCashStatement lays out a cash statement on a sheet: the title, the receipts
+// per sabbath between the balances brought and carried forward, the disbursements and
+// the signature block
+func writeCashStatement(s *sheetWriter, p *i18n.Printer, statement *models.CashStatement) error {
+	if err := s.sw.SetColWidth(1, 52, 17); err != nil {
+		return err
 	}
 
-	if err := f.MergeCell("ContributionsSummary", "A3", "J3"); err != nil {
-		return nil, err
+	columns := len(statement.Categories) + 2
+
+	err := s.title(max(columns, 6),
+		caps(p, "Church Treasurer's Cash Statement"),
+		"KITENGELA CENTRAL SDA CHURCH",
+		caps(p, "For the period %s to %s", statement.From, statement.To))
+
+	if err != nil {
+		return err
 	}
 
-	categoryIndex := make(map[string]int)
+	s.skip(1)
 
-	// Set the headers
-	headers := []string{"SABBATH"}
+	headers := append([]string{caps(p, "Sabbath")}, statement.Categories...)
+	headers = append(headers, caps(p, "Total"))
 
-	for i, category := range categories {
-		headers = append(headers, category)
-		categoryIndex[category] = i
+	line := func(label any, values map[string]float64) []any {
+		row := []any{label}
+		var total float64
+
+		for _, category := range statement.Categories {
+			row = append(row, values[category])
+			total += values[category]
+		}
+
+		return append(row, total)
 	}
 
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 4)
-		f.SetCellStyle("ContributionsSummary", cell, cell, headerStyle)
-		f.SetCellValue("ContributionsSummary", cell, header)
+	rows := [][]any{line(caps(p, "Balance B/F"), statement.Opening)}
+
+	for _, receipt := range statement.Receipts {
+		rows = append(rows, line(cellDate(receipt.Date), receipt.Amounts))
 	}
 
-	// Set the data
-	i := 1
-	for key, value := range data {
-		cellCategory, _ := excelize.CoordinatesToCellName(1, i+4)
-		f.SetCellValue("ContributionsSummary", cellCategory, strings.Split(key, "T")[0])
-
-		for _, row := range value {
-			if j, ok := categoryIndex[row.Category]; ok {
-				j += 1
-				cellCategory, _ := excelize.CoordinatesToCellName(j+1, i+4)
-				f.SetCellValue("ContributionsSummary", cellCategory, row.Total)
-			}
-		}
+	rows = append(rows, line(caps(p, "Total receipts"), statement.TotalReceipts))
 
-		// set border on all data cells
-		for k := 0; k < len(headers); k++ {
-			cellCategory, _ := excelize.CoordinatesToCellName(k+1, i+4)
-			f.SetCellStyle("ContributionsSummary", cellCategory, cellCategory, boarderStyle)
-		}
-		i += 1
+	payments := make(map[string]float64)
+	for category, amount := range statement.Payments {
+		payments[category] = -amount
 	}
 
-	// summation
-	totalsRow := len(data) + 6
+	rows = append(rows, line(caps(p, "Less payments"), payments))
+	rows = append(rows, line(caps(p, "Transfers"), statement.Transfers))
 
-	totalStyle, err := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 11, Family: "Arial"},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"#D3D3D3"}, Pattern: 1},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		}})
+	// highlight the brought forward and total receipts rows
+	err = s.table(headers, rows, line(caps(p, "Balance C/F"), statement.Closing), 0, 1+len(statement.Receipts))
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	text, _ := excelize.CoordinatesToCellName(1, totalsRow)
-	f.SetCellStyle("ContributionsSummary", text, text, totalStyle)
-	f.SetCellValue("ContributionsSummary", text, "Total")
-
-	for i := 1; i < len(categoryIndex)+1; i++ {
-		cellStart, _ := excelize.CoordinatesToCellName(i+1, 5)
-		cellEnd, _ := excelize.CoordinatesToCellName(i+1, totalsRow-2)
+	s.skip(2)
 
-		cellCategory, _ := excelize.CoordinatesToCellName(i+1, totalsRow)
-		f.SetCellFormula("ContributionsSummary", cellCategory, fmt.Sprintf("SUM(%s:%s)", cellStart, cellEnd))
-		f.SetCellStyle("ContributionsSummary", cellCategory, cellCategory, totalStyle)
+	if err = s.line([]any{excelize.Cell{StyleID: s.total, Value: caps(p, "Disbursements")}}); err != nil {
+		return err
 	}
 
-	f.SetActiveSheet(index)
+	disbursements := make([][]any, 0, len(statement.Disbursements))
+	var totalPaid float64
+
+	for _, expenditure := range statement.Disbursements {
+		disbursements = append(disbursements, []any{cellDate(expenditure.Date), expenditure.VoucherNo,
+			expenditure.Payee, expenditure.Category, expenditure.PaymentMethod, expenditure.Amount})
+		totalPaid += expenditure.Amount
+	}
 
-	buff, err := f.WriteToBuffer()
+	err = s.table([]string{caps(p, "Date"), caps(p, "Voucher No"), caps(p, "Payee"), caps(p, "Category"),
+		caps(p, "Payment Method"), caps(p, "Amount (%s)", p.CurrencyCode())},
+		disbursements, []any{p.T("Total"), "", "", "", "", totalPaid})
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return buff.Bytes(), nil
+	s.skip(2)
+
+	return writeSignatureBlock(s, p)
+}
+
+// writeSignatureBlock adds the lines signed by the treasurer and the board before
+// the statement is presented
+func writeSignatureBlock(s *sheetWriter, p *i18n.Printer) error {
+	signatories := []string{caps(p, "Prepared by (Treasurer)"), caps(p, "Checked by (Auditor)"),
+		caps(p, "Approved by (Board Chair)")}
+
+	for _, signatory := range signatories {
+		blank := excelize.Cell{StyleID: s.border}
+
+		if err := s.line([]any{signatory, caps(p, "Name:"), blank, caps(p, "Signature:"), blank, caps(p, "Date:"),
+			blank}); err != nil {
+			return err
+		}
+
+		s.skip(1)
+	}
 
+	return nil
 }
@@ -0,0 +1,244 @@
+package exports
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/xuri/excelize/v2"
+)
+
+var benchmarkRows = []int{1000, 10000, 50000}
+
+// BenchmarkGenerateExcelFile writes the contributions workbook the way it was written
+// before the stream writer, cell by cell on a workbook held in memory, as the baseline
+// of BenchmarkWriteContributions
+func BenchmarkGenerateExcelFile(b *testing.B) {
+	categories := generateCategories(12)
+
+	for _, rows := range benchmarkRows {
+		b.Run("rows="+strconv.Itoa(rows), func(b *testing.B) {
+			funds := generateFunds(rows, categories)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := generateLegacy(funds, categories); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkWriteContributions streams the same contributions as
+// BenchmarkGenerateExcelFile through the StreamWriter
+func BenchmarkWriteContributions(b *testing.B) {
+	categories := generateCategories(12)
+
+	for _, rows := range benchmarkRows {
+		b.Run("rows="+strconv.Itoa(rows), func(b *testing.B) {
+			funds := generateFunds(rows, categories)
+			x := &ExcelExport{}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := x.WriteContributions(io.Discard, &fundSlice{funds: funds}, categories, models.Charts{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func generateCategories(count int) []string {
+	categories := make([]string, count)
+
+	for i := range categories {
+		categories[i] = fmt.Sprintf("CATEGORY %d", i+1)
+	}
+
+	return categories
+}
+
+func generateFunds(rows int, categories []string) []*models.Fund {
+	funds := make([]*models.Fund, 0, rows)
+
+	for i := 0; i < rows; i++ {
+		funds = append(funds, generateFund(i, categories))
+	}
+
+	return funds
+}
+
+// generateFund makes up a contribution to three of the categories
+func generateFund(i int, categories []string) *models.Fund {
+	breakDown := make(map[string]float64, 3)
+	var total float64
+
+	for j := 0; j < 3 && j < len(categories); j++ {
+		amount := float64((i*7+j*13)%5000 + 50)
+		breakDown[categories[(i+j)%len(categories)]] = amount
+		total += amount
+	}
+
+	return &models.Fund{
+		ID:          i + 1,
+		ReceiptNo:   fmt.Sprintf("R%07d", i+1),
+		Contributor: fmt.Sprintf("CONTRIBUTOR %d", i%2000),
+		Date:        time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 7*(i%52)).Format(time.RFC3339),
+		Total:       total,
+		BreakDown:   breakDown,
+	}
+}
+
+// generateLegacy is the contributions workbook as it was written before the stream
+// writer: every value and style set cell by cell on a workbook held in memory. It is
+// kept only as the baseline of the benchmarks.
+func generateLegacy(data []*models.Fund, categories []string) ([]byte, error) {
+	f := excelize.NewFile()
+
+	// Create a new sheet
+	index, err := f.NewSheet("Contributions")
+
+	if err != nil {
+		return nil, err
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 10, Family: "Arial"},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#D3D3D3"}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
+		Border: []excelize.Border{
+			{Type: "left", Color: "#000000", Style: 1},
+			{Type: "top", Color: "#000000", Style: 1},
+			{Type: "right", Color: "#000000", Style: 1},
+			{Type: "bottom", Color: "#000000", Style: 1},
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	boarderStyle, err := f.NewStyle(&excelize.Style{
+		Border: []excelize.Border{
+			{Type: "left", Color: "#000000", Style: 1},
+			{Type: "top", Color: "#000000", Style: 1},
+			{Type: "right", Color: "#000000", Style: 1},
+			{Type: "bottom", Color: "#000000", Style: 1},
+		}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.SetColWidth("Contributions", "A", "AZ", 21)
+
+	categoryIndex := make(map[string]int)
+
+	// Set the headers
+	headers := []string{"NAME", "RECEIPT NO", "TOTAL", "DATE"}
+
+	for i, category := range categories {
+		headers = append(headers, category)
+		categoryIndex[category] = 4 + i
+	}
+
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellStyle("Contributions", cell, cell, headerStyle)
+		f.SetColWidth("Contributions", cell, cell, 17)
+		f.SetCellValue("Contributions", cell, header)
+	}
+
+	// Set the data
+	for i, contribution := range data {
+		cellContributor, _ := excelize.CoordinatesToCellName(1, i+2)
+		cellReceiptNo, _ := excelize.CoordinatesToCellName(2, i+2)
+		cellTotal, _ := excelize.CoordinatesToCellName(3, i+2)
+		cellDate, _ := excelize.CoordinatesToCellName(4, i+2)
+
+		f.SetCellValue("Contributions", cellDate, strings.Split(contribution.Date, "T")[0])
+		f.SetCellValue("Contributions", cellContributor, contribution.Contributor)
+		f.SetCellValue("Contributions", cellTotal, contribution.Total)
+		f.SetCellValue("Contributions", cellReceiptNo, contribution.ReceiptNo)
+
+		f.SetCellStyle("Contributions", cellContributor, cellDate, boarderStyle)
+		f.SetCellStyle("Contributions", cellTotal, cellTotal, boarderStyle)
+		f.SetCellStyle("Contributions", cellReceiptNo, cellReceiptNo, boarderStyle)
+		f.SetCellStyle("Contributions", cellDate, cellDate, boarderStyle)
+
+		// set border on all data cells
+		for k := 3; k < len(headers); k++ {
+			cellCategory, _ := excelize.CoordinatesToCellName(k+1, i+2)
+			f.SetCellStyle("Contributions", cellCategory, cellCategory, boarderStyle)
+		}
+
+		for category, amount := range contribution.BreakDown {
+			if j, ok := categoryIndex[category]; ok {
+				cellCategory, _ := excelize.CoordinatesToCellName(j+1, i+2)
+				f.SetCellValue("Contributions", cellCategory, amount)
+			}
+		}
+	}
+
+	// summation
+	row := len(data) + 1
+
+	totalStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Size: 11, Family: "Arial"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#D3D3D3"}, Pattern: 1},
+		Border: []excelize.Border{
+			{Type: "left", Color: "#000000", Style: 1},
+			{Type: "top", Color: "#000000", Style: 1},
+			{Type: "right", Color: "#000000", Style: 1},
+			{Type: "bottom", Color: "#000000", Style: 1},
+		}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	cellContributor, _ := excelize.CoordinatesToCellName(1, row+2)
+	cellReceiptNo, _ := excelize.CoordinatesToCellName(2, row+2)
+	cellTotal, _ := excelize.CoordinatesToCellName(3, row+2)
+	cellDate, _ := excelize.CoordinatesToCellName(4, row+2)
+
+	f.SetCellStyle("Contributions", cellContributor, cellDate, totalStyle)
+	f.SetCellStyle("Contributions", cellTotal, cellTotal, totalStyle)
+	f.SetCellStyle("Contributions", cellReceiptNo, cellReceiptNo, totalStyle)
+	f.SetCellStyle("Contributions", cellDate, cellDate, totalStyle)
+
+	cellTotalStart, _ := excelize.CoordinatesToCellName(3, 2)
+	cellTotalEnd, _ := excelize.CoordinatesToCellName(3, row)
+
+	f.SetCellFormula("Contributions", cellTotal, fmt.Sprintf("SUM(%s:%s)", cellTotalStart, cellTotalEnd))
+
+	for i := 3; i < len(categoryIndex)+3; i++ {
+		cellStart, _ := excelize.CoordinatesToCellName(i+2, 2)
+		cellEnd, _ := excelize.CoordinatesToCellName(i+2, row)
+
+		cellCategory, _ := excelize.CoordinatesToCellName(i+2, row+2)
+		f.SetCellFormula("Contributions", cellCategory, fmt.Sprintf("SUM(%s:%s)", cellStart, cellEnd))
+		f.SetCellStyle("Contributions", cellCategory, cellCategory, totalStyle)
+	}
+
+	// Set the active sheet
+	f.SetActiveSheet(index)
+
+	buff, err := f.WriteToBuffer()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
@@ -0,0 +1,108 @@
+package exports
+
+import (
+	"fmt"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/xuri/excelize/v2"
+)
+
+func (exExport *ExcelExport) GenerateExpenditureFile(data []*models.Expenditure) ([]byte, error) {
+	const sheet = "Expenditures"
+
+	p := exExport.text()
+
+	f := excelize.NewFile()
+
+	index, err := f.NewSheet(sheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	headerStyle, err := newHeaderStyle(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	boarderStyle, err := newBorderStyle(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	amountStyle, err := newAmountStyle(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	totalStyle, err := newTotalStyle(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dateStyle, err := newDateStyle(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	headers := []string{caps(p, "Date"), caps(p, "Voucher No"), caps(p, "Payee"), caps(p, "Category"),
+		caps(p, "Payment Method"), caps(p, "Description"), caps(p, "Approved"), caps(p, "Amount (%s)", p.CurrencyCode())}
+
+	f.SetColWidth(sheet, "A", "H", 19)
+
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellStyle(sheet, cell, cell, headerStyle)
+		f.SetCellValue(sheet, cell, header)
+	}
+
+	for i, expenditure := range data {
+		approved := caps(p, "No")
+		if expenditure.ApprovedAt != nil {
+			approved = caps(p, "Yes")
+		}
+
+		values := []any{cellDate(expenditure.Date), expenditure.VoucherNo, expenditure.Payee,
+			expenditure.Category, expenditure.PaymentMethod, expenditure.Description, approved, expenditure.Amount}
+
+		for j, value := range values {
+			cell, _ := excelize.CoordinatesToCellName(j+1, i+2)
+			f.SetCellValue(sheet, cell, value)
+			f.SetCellStyle(sheet, cell, cell, boarderStyle)
+		}
+
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		f.SetCellStyle(sheet, cell, cell, dateStyle)
+
+		cell, _ = excelize.CoordinatesToCellName(len(headers), i+2)
+		f.SetCellStyle(sheet, cell, cell, amountStyle)
+	}
+
+	totalsRow := len(data) + 3
+
+	text, _ := excelize.CoordinatesToCellName(1, totalsRow)
+	f.SetCellValue(sheet, text, p.T("Total"))
+	f.SetCellStyle(sheet, text, text, totalStyle)
+
+	cellStart, _ := excelize.CoordinatesToCellName(len(headers), 2)
+	cellEnd, _ := excelize.CoordinatesToCellName(len(headers), totalsRow-1)
+	cellTotal, _ := excelize.CoordinatesToCellName(len(headers), totalsRow)
+
+	f.SetCellFormula(sheet, cellTotal, fmt.Sprintf("SUM(%s:%s)", cellStart, cellEnd))
+	f.SetCellStyle(sheet, cellTotal, cellTotal, totalStyle)
+
+	f.SetActiveSheet(index)
+
+	buff, err := f.WriteToBuffer()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
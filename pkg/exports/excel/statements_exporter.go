@@ -0,0 +1,162 @@
+package exports
+
+import (
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/xuri/excelize/v2"
+)
+
+func (exExport *ExcelExport) GenerateTrialBalance(report *models.TrialBalance) ([]byte, error) {
+	const sheet = "TrialBalance"
+
+	p := exExport.text()
+
+	comparative := !report.Period.PriorTo.IsZero()
+
+	headers := []string{caps(p, "Code"), caps(p, "Account"), caps(p, "Type"), caps(p, "Debit"), caps(p, "Credit")}
+	if comparative {
+		headers = append(headers, caps(p, "Prior Debit"), caps(p, "Prior Credit"))
+	}
+
+	rows := make([][]any, 0, len(report.Lines))
+
+	for _, line := range report.Lines {
+		row := []any{line.Code, line.Name, line.Type, line.Debit, line.Credit}
+		if comparative {
+			row = append(row, line.PriorDebit, line.PriorCredit)
+		}
+		rows = append(rows, row)
+	}
+
+	totals := []any{"", p.T("Total"), "", report.TotalDebit, report.TotalCredit}
+	if comparative {
+		totals = append(totals, report.PriorTotalDebit, report.PriorTotalCredit)
+	}
+
+	subtitle := caps(p, "As at %s", report.Period.To)
+	if comparative {
+		subtitle = caps(p, "As at %s, comparative as at %s", report.Period.To, report.Period.PriorTo)
+	}
+
+	return writeStatement(sheet, headers, rows, totals, caps(p, "Trial Balance"), subtitle)
+}
+
+func (exExport *ExcelExport) GenerateIncomeStatement(report *models.IncomeStatement) ([]byte, error) {
+	const sheet = "IncomeAndExpenditure"
+
+	p := exExport.text()
+
+	comparative := !report.Period.PriorTo.IsZero()
+
+	headers := []string{caps(p, "Code"), caps(p, "Account"), caps(p, "Amount (%s)", p.CurrencyCode())}
+	if comparative {
+		headers = append(headers, caps(p, "Prior Period"))
+	}
+
+	line := func(values ...any) []any {
+		if comparative {
+			return values
+		}
+		return values[:3]
+	}
+
+	rows := [][]any{line("", caps(p, "Income"), "", "")}
+
+	for _, item := range report.Income {
+		rows = append(rows, line(item.Code, item.Name, item.Amount, item.PriorAmount))
+	}
+
+	rows = append(rows, line("", p.T("Total income"), report.TotalIncome, report.PriorTotalIncome))
+	rows = append(rows, line("", caps(p, "Expenditure"), "", ""))
+
+	for _, item := range report.Expenditure {
+		rows = append(rows, line(item.Code, item.Name, item.Amount, item.PriorAmount))
+	}
+
+	rows = append(rows, line("", p.T("Total expenditure"), report.TotalExpenditure, report.PriorTotalExpenditure))
+
+	return writeStatement(sheet, headers, rows, line("", p.T("Surplus / (deficit)"), report.Surplus, report.PriorSurplus),
+		caps(p, "Statement of Income and Expenditure"),
+		periodLabel(p, report.Period))
+}
+
+func (exExport *ExcelExport) GenerateFundBalanceSheet(report *models.FundBalanceSheet) ([]byte, error) {
+	const sheet = "FundBalances"
+
+	p := exExport.text()
+
+	comparative := !report.Period.PriorTo.IsZero()
+
+	headers := []string{caps(p, "Fund"), caps(p, "Opening balance"), caps(p, "Receipts"), caps(p, "Payments"),
+		caps(p, "Transfers"), caps(p, "Closing balance")}
+	if comparative {
+		headers = append(headers, caps(p, "Prior Closing"))
+	}
+
+	line := func(item *models.FundBalanceLine) []any {
+		values := []any{item.Fund, item.Opening, item.Receipts, item.Payments, item.Transfers, item.Closing}
+		if comparative {
+			values = append(values, item.PriorClosing)
+		}
+		return values
+	}
+
+	rows := make([][]any, 0, len(report.Lines))
+
+	for _, item := range report.Lines {
+		rows = append(rows, line(item))
+	}
+
+	return writeStatement(sheet, headers, rows, line(&report.Totals), caps(p, "Fund Balance Sheet"),
+		periodLabel(p, report.Period))
+}
+
+func writeStatement(sheet string, headers []string, rows [][]any, totals []any, title, subtitle string) ([]byte, error) {
+	f := excelize.NewFile()
+
+	index, err := f.NewSheet(sheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.SetColWidth(sheet, "A", "A", 12)
+	f.SetColWidth(sheet, "B", "B", 32)
+	f.SetColWidth(sheet, "C", "H", 18)
+
+	err = writeTitle(f, sheet, len(headers), title, "KITENGELA CENTRAL SDA CHURCH", subtitle)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = writeTable(f, sheet, 5, headers, rows, totals)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.SetActiveSheet(index)
+
+	buff, err := f.WriteToBuffer()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+func periodLabel(p *i18n.Printer, period models.StatementPeriod) string {
+	if !period.PriorTo.IsZero() {
+		return caps(p, "For the period %s to %s, comparative %s to %s", period.From, period.To, period.PriorFrom,
+			period.PriorTo)
+	}
+
+	return caps(p, "For the period %s to %s", period.From, period.To)
+}
+
+// GenerateTable writes a plain table for documents that have no laid out workbook
+func (exExport *ExcelExport) GenerateTable(sheet, title, subtitle string, headers []string, rows [][]any) ([]byte, error) {
+	return writeStatement(sheet, headers, rows, nil, title, subtitle)
+}
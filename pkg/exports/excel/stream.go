@@ -0,0 +1,196 @@
+package exports
+
+import (
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetWriter writes a sheet from top to bottom through excelize's StreamWriter,
+// which keeps only a bounded buffer of rows in memory and spills the rest to a
+// temporary file. Rows cannot be revisited, so every cell gets its style as it is
+// written.
+type sheetWriter struct {
+	sw     *excelize.StreamWriter
+	row    int
+	header int
+	border int
+	amount int
+	date   int
+	total  int
+}
+
+func newSheetWriter(f *excelize.File, sheet string) (*sheetWriter, error) {
+	sw, err := f.NewStreamWriter(sheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sheetWriter{sw: sw, row: 1}
+
+	if s.header, err = newHeaderStyle(f); err != nil {
+		return nil, err
+	}
+
+	if s.border, err = newBorderStyle(f); err != nil {
+		return nil, err
+	}
+
+	if s.amount, err = newAmountStyle(f); err != nil {
+		return nil, err
+	}
+
+	if s.date, err = newDateStyle(f); err != nil {
+		return nil, err
+	}
+
+	if s.total, err = newTotalStyle(f); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// line writes the cells as the next row
+func (s *sheetWriter) line(cells []any) error {
+	cell, _ := excelize.CoordinatesToCellName(1, s.row)
+	s.row++
+
+	return s.sw.SetRow(cell, cells)
+}
+
+// skip leaves rows empty
+func (s *sheetWriter) skip(rows int) {
+	s.row += rows
+}
+
+// title writes the report title lines merged across the given number of columns
+func (s *sheetWriter) title(columns int, lines ...string) error {
+	for _, text := range lines {
+		cells := make([]any, columns)
+
+		for i := range cells {
+			cells[i] = excelize.Cell{StyleID: s.header}
+		}
+
+		cells[0] = excelize.Cell{StyleID: s.header, Value: text}
+
+		start, _ := excelize.CoordinatesToCellName(1, s.row)
+		end, _ := excelize.CoordinatesToCellName(columns, s.row)
+
+		if err := s.line(cells); err != nil {
+			return err
+		}
+
+		if err := s.sw.MergeCell(start, end); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// table writes a header row, the data rows and an optional totals row. Numeric cells
+// are formatted as amounts and the rows at the highlight indexes in the total style.
+func (s *sheetWriter) table(headers []string, rows [][]any, totals []any, highlight ...int) error {
+	if err := s.line(s.styled(s.header, toAny(headers))); err != nil {
+		return err
+	}
+
+	highlighted := make(map[int]bool, len(highlight))
+
+	for _, i := range highlight {
+		highlighted[i] = true
+	}
+
+	for i, values := range rows {
+		var cells []any
+
+		if highlighted[i] {
+			cells = s.styled(s.total, values)
+		} else {
+			cells = s.values(values)
+		}
+
+		if err := s.line(cells); err != nil {
+			return err
+		}
+	}
+
+	if totals != nil {
+		return s.line(s.styled(s.total, totals))
+	}
+
+	return nil
+}
+
+// values styles a data row, amounts with the amount style, dates with the date style
+// and the rest bordered
+func (s *sheetWriter) values(values []any) []any {
+	cells := make([]any, len(values))
+
+	for i, value := range values {
+		style := s.border
+
+		switch value.(type) {
+		case float64:
+			style = s.amount
+		case time.Time:
+			style = s.date
+		}
+
+		cells[i] = excelize.Cell{StyleID: style, Value: value}
+	}
+
+	return cells
+}
+
+func (s *sheetWriter) styled(style int, values []any) []any {
+	cells := make([]any, len(values))
+
+	for i, value := range values {
+		cells[i] = excelize.Cell{StyleID: style, Value: value}
+	}
+
+	return cells
+}
+
+func toAny(values []string) []any {
+	cells := make([]any, len(values))
+
+	for i, value := range values {
+		cells[i] = value
+	}
+
+	return cells
+}
+
+// fundSlice is a cursor over contributions already in memory
+type fundSlice struct {
+	funds []*models.Fund
+	next  int
+}
+
+func (c *fundSlice) Next() bool {
+	if c.next >= len(c.funds) {
+		return false
+	}
+
+	c.next++
+
+	return true
+}
+
+func (c *fundSlice) Fund() *models.Fund {
+	return c.funds[c.next-1]
+}
+
+func (c *fundSlice) Err() error {
+	return nil
+}
+
+func (c *fundSlice) Close() error {
+	return nil
+}
@@ -0,0 +1,152 @@
+package exports
+
+import (
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var cellBorders = []excelize.Border{
+	{Type: "left", Color: "#000000", Style: 1},
+	{Type: "top", Color: "#000000", Style: 1},
+	{Type: "right", Color: "#000000", Style: 1},
+	{Type: "bottom", Color: "#000000", Style: 1},
+}
+
+func newHeaderStyle(f *excelize.File) (int, error) {
+	return f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 10, Family: "Arial"},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#D3D3D3"}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
+		Border:    cellBorders,
+	})
+}
+
+func newBorderStyle(f *excelize.File) (int, error) {
+	return f.NewStyle(&excelize.Style{Border: cellBorders})
+}
+
+func newAmountStyle(f *excelize.File) (int, error) {
+	format := "#,##0.00"
+	return f.NewStyle(&excelize.Style{Border: cellBorders, CustomNumFmt: &format})
+}
+
+// newDateStyle writes dates day first, the way they are written in Kenya
+func newDateStyle(f *excelize.File) (int, error) {
+	format := "dd/mm/yyyy"
+	return f.NewStyle(&excelize.Style{Border: cellBorders, CustomNumFmt: &format})
+}
+
+func newTotalStyle(f *excelize.File) (int, error) {
+	format := "#,##0.00"
+	return f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: 11, Family: "Arial"},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{"#D3D3D3"}, Pattern: 1},
+		Border:       cellBorders,
+		CustomNumFmt: &format,
+	})
+}
+
+// writeTitle writes the report title lines merged across the given number of columns
+func writeTitle(f *excelize.File, sheet string, columns int, lines ...string) error {
+	style, err := newHeaderStyle(f)
+
+	if err != nil {
+		return err
+	}
+
+	for i, line := range lines {
+		start, _ := excelize.CoordinatesToCellName(1, i+1)
+		end, _ := excelize.CoordinatesToCellName(columns, i+1)
+
+		f.SetCellValue(sheet, start, line)
+		f.SetCellStyle(sheet, start, end, style)
+
+		if err := f.MergeCell(sheet, start, end); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeTable writes a header row, the data rows and an optional totals row starting
+// at row and returns the first free row after the table. Numeric cells are formatted
+// as amounts and times as dates.
+func writeTable(f *excelize.File, sheet string, row int, headers []string, rows [][]any, totals []any) (int, error) {
+	headerStyle, err := newHeaderStyle(f)
+
+	if err != nil {
+		return 0, err
+	}
+
+	boarderStyle, err := newBorderStyle(f)
+
+	if err != nil {
+		return 0, err
+	}
+
+	amountStyle, err := newAmountStyle(f)
+
+	if err != nil {
+		return 0, err
+	}
+
+	totalStyle, err := newTotalStyle(f)
+
+	if err != nil {
+		return 0, err
+	}
+
+	dateStyle, err := newDateStyle(f)
+
+	if err != nil {
+		return 0, err
+	}
+
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, row)
+		f.SetCellValue(sheet, cell, header)
+		f.SetCellStyle(sheet, cell, cell, headerStyle)
+	}
+
+	row++
+
+	for _, values := range rows {
+		for i, value := range values {
+			cell, _ := excelize.CoordinatesToCellName(i+1, row)
+			f.SetCellValue(sheet, cell, value)
+
+			if _, ok := value.(float64); ok {
+				f.SetCellStyle(sheet, cell, cell, amountStyle)
+			} else if _, ok := value.(time.Time); ok {
+				f.SetCellStyle(sheet, cell, cell, dateStyle)
+			} else {
+				f.SetCellStyle(sheet, cell, cell, boarderStyle)
+			}
+		}
+		row++
+	}
+
+	if totals != nil {
+		for i, value := range totals {
+			cell, _ := excelize.CoordinatesToCellName(i+1, row)
+			f.SetCellValue(sheet, cell, value)
+			f.SetCellStyle(sheet, cell, cell, totalStyle)
+		}
+		row++
+	}
+
+	return row, nil
+}
+
+// cellDate is a date given as YYYY-MM-DD, with or without a time, as a date cell.
+// Text that is not a date is written as it is.
+func cellDate(date string) any {
+	if t, err := time.Parse("2006-01-02", strings.Split(date, "T")[0]); err == nil {
+		return t
+	}
+
+	return date
+}
@@ -0,0 +1,214 @@
+package exports
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+
+	excel "github.com/VaudKK/CAS/pkg/exports/excel"
+	pdf "github.com/VaudKK/CAS/pkg/exports/pdf"
+	"github.com/VaudKK/CAS/pkg/i18n"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+var ErrUnsupportedDocument = errors.New("unsupported document, it has no table to export in this format")
+
+// Exporter renders documents in one format
+type Exporter interface {
+	MediaType() string
+	Extension() string
+	Export(w io.Writer, doc *Document) error
+}
+
+// Column is a column of a document's table. Key names the field in record based
+// formats such as JSON Lines, Title heads the column everywhere else.
+type Column struct {
+	Key   string
+	Title string
+}
+
+// Table is the tabular form every document can be exported in. The titles are in
+// English, with TitleArgs and SubtitleArgs filling in their verbs, and are written in
+// the language of the document by the formats meant to be read.
+type Table struct {
+	Title        string
+	TitleArgs    []any
+	Subtitle     string
+	SubtitleArgs []any
+	Columns      []Column
+	Rows         [][]any
+}
+
+// Label is a value of a row that is text of the report rather than data, such as the
+// name of a total, and is translated along with the titles
+type Label string
+
+func (t *Table) title(p *i18n.Printer) string {
+	return p.T(t.Title, t.TitleArgs...)
+}
+
+func (t *Table) subtitle(p *i18n.Printer) string {
+	return p.T(t.Subtitle, t.SubtitleArgs...)
+}
+
+func (t *Table) headers(p *i18n.Printer) []string {
+	headers := make([]string, 0, len(t.Columns))
+
+	for _, column := range t.Columns {
+		headers = append(headers, p.T(column.Title))
+	}
+
+	return headers
+}
+
+// Document is a list or report that can be exported. Excel and Pdf render the laid
+// out report where one exists; other formats, and documents without one, use the
+// table. ExcelStream, when set, writes the workbook straight to the output instead of
+// Excel; a document with no table can only be exported as a workbook. Laid out
+// reports take the theme of OrganizationId where it has one. Documents with a
+// Journal can also be exported to accounting packages. Workbooks and pdfs are
+// written in the language of Locale, English when it is empty.
+type Document struct {
+	Name           string
+	OrganizationId int
+	Locale         string
+	Table          *Table
+	Excel          func(*excel.ExcelExport) ([]byte, error)
+	ExcelStream    func(*excel.ExcelExport, io.Writer) error
+	Pdf            func(*pdf.PdfExport) ([]byte, error)
+	Journal        []*JournalEntry
+}
+
+// Registry holds the exporters by media type
+type Registry struct {
+	exporters map[string]Exporter
+}
+
+func NewRegistry(exporters ...Exporter) *Registry {
+	r := &Registry{exporters: make(map[string]Exporter)}
+
+	for _, exporter := range exporters {
+		r.Register(exporter)
+	}
+
+	return r
+}
+
+func (r *Registry) Register(exporter Exporter) {
+	r.exporters[exporter.MediaType()] = exporter
+}
+
+// Lookup finds an exporter by media type or file extension
+func (r *Registry) Lookup(format string) (Exporter, bool) {
+	format = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), "."))
+
+	if exporter, ok := r.exporters[format]; ok {
+		return exporter, true
+	}
+
+	for _, exporter := range r.exporters {
+		if exporter.Extension() == format {
+			return exporter, true
+		}
+	}
+
+	return nil, false
+}
+
+// Formats lists the extensions of the registered exporters
+func (r *Registry) Formats() []string {
+	formats := make([]string, 0, len(r.exporters))
+
+	for _, exporter := range r.exporters {
+		formats = append(formats, exporter.Extension())
+	}
+
+	slices.Sort(formats)
+
+	return formats
+}
+
+// Negotiate picks the exporter for a request. A format, given as an extension or a
+// media type, wins over the Accept header. A nil exporter means the response is the
+// usual json, which is also what an Accept header naming no registered format gets.
+func (r *Registry) Negotiate(format, accept string) (Exporter, error) {
+	if format != "" {
+		if format == "json" || format == "application/json" {
+			return nil, nil
+		}
+
+		exporter, ok := r.Lookup(format)
+
+		if !ok {
+			return nil, ErrUnknownFormat
+		}
+
+		return exporter, nil
+	}
+
+	for _, mediaType := range acceptedTypes(accept) {
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+			return nil, nil
+		}
+
+		if exporter, ok := r.exporters[mediaType]; ok {
+			return exporter, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// acceptedTypes lists the media types of an Accept header from the most to the
+// least preferred, leaving out those with a quality of zero
+func acceptedTypes(accept string) []string {
+	type accepted struct {
+		mediaType string
+		quality   float64
+	}
+
+	types := make([]accepted, 0)
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		if quality > 0 {
+			types = append(types, accepted{mediaType: mediaType, quality: quality})
+		}
+	}
+
+	slices.SortStableFunc(types, func(a, b accepted) int {
+		switch {
+		case a.quality > b.quality:
+			return -1
+		case a.quality < b.quality:
+			return 1
+		}
+		return 0
+	})
+
+	mediaTypes := make([]string, 0, len(types))
+
+	for _, t := range types {
+		mediaTypes = append(mediaTypes, t.mediaType)
+	}
+
+	return mediaTypes
+}
@@ -0,0 +1,87 @@
+package exports
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	excel "github.com/VaudKK/CAS/pkg/exports/excel"
+	pdf "github.com/VaudKK/CAS/pkg/exports/pdf"
+)
+
+func TestNegotiate(t *testing.T) {
+	registry := NewRegistry(&CsvExporter{}, &JsonLinesExporter{}, &ExcelExporter{}, &PdfExporter{})
+
+	tests := []struct {
+		name    string
+		format  string
+		accept  string
+		want    string
+		wantErr error
+	}{
+		{name: "no format or accept header", want: "json"},
+		{name: "format by extension", format: "csv", want: "text/csv"},
+		{name: "format by media type", format: "application/pdf", want: "application/pdf"},
+		{name: "format with a dot and in capitals", format: ".XLSX", want: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{name: "json format", format: "json", want: "json"},
+		{name: "unknown format", format: "docx", wantErr: ErrUnknownFormat},
+		{name: "format wins over accept", format: "csv", accept: "application/pdf", want: "text/csv"},
+		{name: "single accepted type", accept: "application/x-ndjson", want: "application/x-ndjson"},
+		{name: "highest quality wins", accept: "text/csv;q=0.5, application/pdf;q=0.9", want: "application/pdf"},
+		{name: "missing quality is one", accept: "text/csv;q=0.8, application/pdf", want: "application/pdf"},
+		{name: "equal quality keeps header order", accept: "text/csv;q=0.5, application/pdf;q=0.5", want: "text/csv"},
+		{name: "zero quality is refused", accept: "application/pdf;q=0, text/csv;q=0.1", want: "text/csv"},
+		{name: "json preferred over a format", accept: "text/csv;q=0.4, application/json", want: "json"},
+		{name: "wildcard is json", accept: "*/*", want: "json"},
+		{name: "unregistered types fall back to json", accept: "image/png, text/html;q=0.9", want: "json"},
+		{name: "unregistered type skipped for a registered one", accept: "image/png, text/csv;q=0.2", want: "text/csv"},
+		{name: "malformed quality is skipped", accept: "application/pdf;q=high, text/csv;q=0.1", want: "text/csv"},
+		{name: "malformed media type is skipped", accept: "pdf, text/csv", want: "text/csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter, err := registry.Negotiate(tt.format, tt.accept)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Negotiate(%q, %q) error = %v, want %v", tt.format, tt.accept, err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			got := "json"
+
+			if exporter != nil {
+				got = exporter.MediaType()
+			}
+
+			if got != tt.want {
+				t.Fatalf("Negotiate(%q, %q) = %s, want %s", tt.format, tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportWithoutTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter Exporter
+	}{
+		{name: "csv", exporter: &CsvExporter{}},
+		{name: "json lines", exporter: &JsonLinesExporter{}},
+		{name: "excel", exporter: &ExcelExporter{Excel: &excel.ExcelExport{}}},
+		{name: "pdf", exporter: &PdfExporter{Pdf: &pdf.PdfExport{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			if err := tt.exporter.Export(&buf, &Document{Name: "report"}); !errors.Is(err, ErrUnsupportedDocument) {
+				t.Fatalf("Export error = %v, want %v", err, ErrUnsupportedDocument)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package exports
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	excel "github.com/VaudKK/CAS/pkg/exports/excel"
+	pdf "github.com/VaudKK/CAS/pkg/exports/pdf"
+	"github.com/VaudKK/CAS/pkg/i18n"
+)
+
+// CsvExporter writes the document's table with a header row of column titles
+type CsvExporter struct{}
+
+func (e *CsvExporter) MediaType() string { return "text/csv" }
+
+func (e *CsvExporter) Extension() string { return "csv" }
+
+func (e *CsvExporter) Export(w io.Writer, doc *Document) error {
+	if doc.Table == nil {
+		return ErrUnsupportedDocument
+	}
+
+	writer := csv.NewWriter(w)
+
+	headers := make([]string, 0, len(doc.Table.Columns))
+
+	for _, column := range doc.Table.Columns {
+		headers = append(headers, column.Title)
+	}
+
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, row := range doc.Table.Rows {
+		if err := writer.Write(formatRow(row)); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// JsonLinesExporter writes one json object per row, with the fields in column order
+type JsonLinesExporter struct{}
+
+func (e *JsonLinesExporter) MediaType() string { return "application/x-ndjson" }
+
+func (e *JsonLinesExporter) Extension() string { return "jsonl" }
+
+func (e *JsonLinesExporter) Export(w io.Writer, doc *Document) error {
+	if doc.Table == nil {
+		return ErrUnsupportedDocument
+	}
+
+	writer := bufio.NewWriter(w)
+
+	for _, row := range doc.Table.Rows {
+		writer.WriteByte('{')
+
+		for i, column := range doc.Table.Columns {
+			if i > 0 {
+				writer.WriteByte(',')
+			}
+
+			key, err := json.Marshal(column.Key)
+
+			if err != nil {
+				return err
+			}
+
+			var value any
+
+			if i < len(row) {
+				value = row[i]
+			}
+
+			if t, ok := value.(time.Time); ok {
+				value = formatTime(t)
+			}
+
+			valueJs, err := json.Marshal(value)
+
+			if err != nil {
+				return err
+			}
+
+			writer.Write(key)
+			writer.WriteByte(':')
+			writer.Write(valueJs)
+		}
+
+		writer.WriteString("}\n")
+	}
+
+	return writer.Flush()
+}
+
+// ExcelExporter writes workbooks with the excel exports
+type ExcelExporter struct {
+	Excel *excel.ExcelExport
+}
+
+func (e *ExcelExporter) MediaType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func (e *ExcelExporter) Extension() string { return "xlsx" }
+
+func (e *ExcelExporter) Export(w io.Writer, doc *Document) error {
+	export := e.Excel.ForLocale(doc.Locale)
+
+	if doc.ExcelStream != nil {
+		return doc.ExcelStream(export, w)
+	}
+
+	var file []byte
+	var err error
+
+	if doc.Excel != nil {
+		file, err = doc.Excel(export)
+	} else if doc.Table == nil {
+		return ErrUnsupportedDocument
+	} else {
+		p := i18n.For(doc.Locale)
+
+		rows := make([][]any, 0, len(doc.Table.Rows))
+
+		for _, row := range doc.Table.Rows {
+			values := make([]any, 0, len(row))
+
+			for _, value := range row {
+				switch v := value.(type) {
+				case Label:
+					value = p.T(string(v))
+				case time.Time:
+					// dates are left to the date style of the workbook
+					if v.IsZero() || v.Hour() != 0 || v.Minute() != 0 || v.Second() != 0 {
+						value = formatTime(v)
+					}
+				}
+				values = append(values, value)
+			}
+
+			rows = append(rows, values)
+		}
+
+		file, err = export.GenerateTable(doc.Name, doc.Table.title(p), doc.Table.subtitle(p), doc.Table.headers(p), rows)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(file)
+
+	return err
+}
+
+// PdfExporter writes documents with the pdf exports
+type PdfExporter struct {
+	Pdf *pdf.PdfExport
+}
+
+func (e *PdfExporter) MediaType() string { return "application/pdf" }
+
+func (e *PdfExporter) Extension() string { return "pdf" }
+
+func (e *PdfExporter) Export(w io.Writer, doc *Document) error {
+	var file []byte
+	var err error
+
+	export := e.Pdf.ForOrganization(doc.OrganizationId).ForLocale(doc.Locale)
+
+	if doc.Pdf != nil {
+		file, err = doc.Pdf(export)
+	} else if doc.Table == nil {
+		return ErrUnsupportedDocument
+	} else {
+		p := i18n.For(doc.Locale)
+
+		rows := make([][]string, 0, len(doc.Table.Rows))
+
+		for _, row := range doc.Table.Rows {
+			rows = append(rows, printRow(p, row))
+		}
+
+		file, err = export.GenerateTable(doc.Table.title(p), doc.Table.subtitle(p), doc.Table.headers(p), rows)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(file)
+
+	return err
+}
+
+// formatRow converts the values of a row to text, amounts with two decimals and
+// dates without a time
+func formatRow(row []any) []string {
+	cells := make([]string, 0, len(row))
+
+	for _, value := range row {
+		switch v := value.(type) {
+		case nil:
+			cells = append(cells, "")
+		case string:
+			cells = append(cells, v)
+		case float64:
+			cells = append(cells, strconv.FormatFloat(v, 'f', 2, 64))
+		case float32:
+			cells = append(cells, strconv.FormatFloat(float64(v), 'f', 2, 32))
+		case time.Time:
+			cells = append(cells, formatTime(v))
+		case *time.Time:
+			if v == nil {
+				cells = append(cells, "")
+			} else {
+				cells = append(cells, formatTime(*v))
+			}
+		default:
+			cells = append(cells, fmt.Sprint(v))
+		}
+	}
+
+	return cells
+}
+
+// printRow converts the values of a row to text the way the locale writes them, for
+// documents that are read rather than imported
+func printRow(p *i18n.Printer, row []any) []string {
+	cells := make([]string, 0, len(row))
+
+	for _, value := range row {
+		switch v := value.(type) {
+		case Label:
+			cells = append(cells, p.T(string(v)))
+		case float64:
+			cells = append(cells, p.Number(v))
+		case float32:
+			cells = append(cells, p.Number(float64(v)))
+		case time.Time:
+			cells = append(cells, printTime(p, v))
+		case *time.Time:
+			if v == nil {
+				cells = append(cells, "")
+			} else {
+				cells = append(cells, printTime(p, *v))
+			}
+		default:
+			cells = append(cells, formatRow([]any{v})...)
+		}
+	}
+
+	return cells
+}
+
+func printTime(p *i18n.Printer, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return p.ShortDate(t)
+	}
+
+	return p.ShortDate(t) + " " + t.Format("15:04")
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+
+	return t.Format(time.RFC3339)
+}
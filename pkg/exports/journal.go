@@ -0,0 +1,209 @@
+package exports
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/models"
+)
+
+var ErrNoJournal = errors.New("the document cannot be exported as a journal")
+
+// JournalEntry is a balanced entry of a journal export. Its lines name the CAS side
+// of the entry, an account type and a category, which the accounting package
+// exporters map to the organization's accounts in the package.
+type JournalEntry struct {
+	Date      string        `json:"date"`
+	Reference string        `json:"reference"`
+	Narration string        `json:"narration"`
+	Lines     []JournalLine `json:"lines"`
+}
+
+type JournalLine struct {
+	AccountType string  `json:"accountType"`
+	Category    string  `json:"category"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+}
+
+// AccountMappings loads the accounts of the accounting package an organization's
+// categories are exported to
+type AccountMappings func(organizationId int) ([]*models.AccountMapping, error)
+
+// Journal lists the contributions and the expenditures of a period as double entry,
+// in date order
+func Journal(contributions []*models.Fund, expenditures []*models.Expenditure, categories []string,
+	startDate, endDate time.Time) *Document {
+	entries := append(contributionEntries(contributions, categories), expenditureEntries(expenditures)...)
+
+	slices.SortStableFunc(entries, func(a, b *JournalEntry) int {
+		return strings.Compare(a.Date, b.Date)
+	})
+
+	table := &Table{
+		Title: "Journal",
+		Columns: []Column{{"date", "Date"}, {"reference", "Reference"}, {"narration", "Narration"},
+			{"accountType", "Account Type"}, {"category", "Category"}, {"debit", "Debit"}, {"credit", "Credit"}},
+	}
+
+	table.Subtitle, table.SubtitleArgs = rangeLabel(startDate, endDate)
+
+	for _, entry := range entries {
+		for _, line := range entry.Lines {
+			table.Rows = append(table.Rows, []any{entry.Date, entry.Reference, entry.Narration, line.AccountType,
+				line.Category, line.Debit, line.Credit})
+		}
+	}
+
+	return &Document{Name: "journal", Table: table, Journal: entries}
+}
+
+// contributionEntries debit cash, or bank for contributions not received in cash,
+// with the total of each contribution and credit the income of every category in
+// its break down, the way the ledger posts them
+func contributionEntries(contributions []*models.Fund, categories []string) []*JournalEntry {
+	entries := make([]*JournalEntry, 0, len(contributions))
+
+	for _, contribution := range contributions {
+		receivedIn := data.MappingBank
+
+		if contribution.PaymentMethod == "" || contribution.PaymentMethod == "CASH" {
+			receivedIn = data.MappingCash
+		}
+
+		lines := []JournalLine{{AccountType: data.AccountTypeAsset, Category: receivedIn, Debit: contribution.Total}}
+
+		var allocated float64
+
+		for _, category := range breakDownOrder(categories, contribution.BreakDown) {
+			if contribution.BreakDown[category] == 0 {
+				continue
+			}
+
+			lines = append(lines, incomeLine(category, contribution.BreakDown[category]))
+			allocated += contribution.BreakDown[category]
+		}
+
+		if difference := contribution.Total - allocated; math.Round(difference*100) != 0 {
+			lines = append(lines, incomeLine(data.UnallocatedCategory, difference))
+		}
+
+		entries = append(entries, &JournalEntry{
+			Date:      strings.Split(contribution.Date, "T")[0],
+			Reference: contribution.ReceiptNo,
+			Narration: fmt.Sprintf("Contribution receipt %s from %s", contribution.ReceiptNo, strings.ToUpper(contribution.Contributor)),
+			Lines:     lines,
+		})
+	}
+
+	return entries
+}
+
+// expenditureEntries debit the expense of the category charged and credit cash or
+// bank depending on the payment method
+func expenditureEntries(expenditures []*models.Expenditure) []*JournalEntry {
+	entries := make([]*JournalEntry, 0, len(expenditures))
+
+	for _, expenditure := range expenditures {
+		paidFrom := data.MappingBank
+
+		if expenditure.PaymentMethod == "CASH" {
+			paidFrom = data.MappingCash
+		}
+
+		entries = append(entries, &JournalEntry{
+			Date:      strings.Split(expenditure.Date, "T")[0],
+			Reference: expenditure.VoucherNo,
+			Narration: fmt.Sprintf("Payment voucher %s to %s", expenditure.VoucherNo, strings.ToUpper(expenditure.Payee)),
+			Lines: []JournalLine{
+				{AccountType: data.AccountTypeExpense, Category: expenditure.Category, Debit: expenditure.Amount},
+				{AccountType: data.AccountTypeAsset, Category: paidFrom, Credit: expenditure.Amount},
+			},
+		})
+	}
+
+	return entries
+}
+
+// incomeLine credits a category, or debits it for a negative amount such as a refund
+func incomeLine(category string, amount float64) JournalLine {
+	if amount < 0 {
+		return JournalLine{AccountType: data.AccountTypeIncome, Category: category, Debit: -amount}
+	}
+
+	return JournalLine{AccountType: data.AccountTypeIncome, Category: category, Credit: amount}
+}
+
+// breakDownOrder lists the categories of a break down in their usual order, then any
+// others in alphabetical order
+func breakDownOrder(categories []string, breakDown map[string]float64) []string {
+	ordered := make([]string, 0, len(breakDown))
+
+	for _, category := range categories {
+		if _, ok := breakDown[category]; ok && !slices.Contains(ordered, category) {
+			ordered = append(ordered, category)
+		}
+	}
+
+	for _, category := range slices.Sorted(maps.Keys(breakDown)) {
+		if !slices.Contains(ordered, category) {
+			ordered = append(ordered, category)
+		}
+	}
+
+	return ordered
+}
+
+// externalAccount is an account of an accounting package
+type externalAccount struct {
+	code string
+	name string
+}
+
+// accountResolver finds the account of the accounting package a journal line is
+// exported to. Cash and bank default to the CAS accounts and a category without a
+// mapping goes under its own name.
+func accountResolver(load AccountMappings, organizationId int) (func(JournalLine) externalAccount, error) {
+	mapped := make(map[[2]string]externalAccount)
+
+	if load != nil {
+		mappings, err := load(organizationId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mapping := range mappings {
+			account := externalAccount{code: mapping.Code, name: mapping.Name}
+
+			if account.name == "" {
+				account.name = account.code
+			}
+
+			mapped[[2]string{mapping.AccountType, mapping.Category}] = account
+		}
+	}
+
+	defaults := map[string]externalAccount{
+		data.MappingCash: {code: data.AccountCodeCash, name: "Cash on Hand"},
+		data.MappingBank: {code: data.AccountCodeBank, name: "Bank"},
+	}
+
+	return func(line JournalLine) externalAccount {
+		if account, ok := mapped[[2]string{line.AccountType, line.Category}]; ok {
+			return account
+		}
+
+		if account, ok := defaults[line.Category]; ok && line.AccountType == data.AccountTypeAsset {
+			return account
+		}
+
+		return externalAccount{code: line.Category, name: line.Category}
+	}, nil
+}
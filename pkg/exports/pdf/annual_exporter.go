@@ -0,0 +1,331 @@
+package exports
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/signintech/gopdf"
+)
+
+// monthsPerTable is how many month columns fit across a portrait page next to the
+// category and the total
+const monthsPerTable = 6
+
+// GeneratePdfAnnualReport writes the annual report as one document: a cover page
+// with the table of contents, the key figures, the year's receipts and payments per
+// month, the comparison with the year before and the cash statement of each month.
+func (pdfExport *PdfExport) GeneratePdfAnnualReport(report *models.AnnualReport) ([]byte, error) {
+	pdf := &gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
+
+	err := pdfExport.loadFonts(pdf)
+	if err != nil {
+		return nil, err
+	}
+
+	p := pdfExport.text()
+
+	// the cover is filled in last, once the pages the sections start on are known
+	pdf.AddPage()
+
+	type section struct {
+		title string
+		page  int
+	}
+
+	var contents []section
+
+	start := func(title string) {
+		contents = append(contents, section{title: title, page: pdf.GetNumberOfPages() + 1})
+	}
+
+	period := monthsLabel(p, report.Year, len(report.Months))
+
+	start(p.T("Key figures"))
+
+	if _, err = drawTable(pdf, kpiTable(p, report, period)); err != nil {
+		return nil, err
+	}
+
+	start(p.T("Receipts and payments by month"))
+
+	for first := 0; first < len(report.Months); first += monthsPerTable {
+		last := min(first+monthsPerTable, len(report.Months))
+
+		if _, err = drawTable(pdf, yearSummaryTable(p, report, period, first, last)); err != nil {
+			return nil, err
+		}
+	}
+
+	start(p.T("%d compared with %d", report.Current.Year, report.Previous.Year))
+
+	for _, t := range yearOnYearTables(p, report) {
+		if _, err = drawTable(pdf, t); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, statement := range report.Months {
+		start(p.T("Cash statement for %s %d", statement.From.Month(), statement.From.Year()))
+
+		if err = drawCashStatement(pdf, p, statement); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = pdf.SetPage(1); err != nil {
+		return nil, err
+	}
+
+	if err = pdf.SetFont("Roboto-Bold", "", 24); err != nil {
+		return nil, err
+	}
+	pdf.SetX(40)
+	pdf.SetY(60)
+	pdf.Cell(nil, p.T("Annual Report %d", report.Year))
+
+	if err = pdf.SetFont("Roboto", "", 13); err != nil {
+		return nil, err
+	}
+	pdf.SetX(40)
+	pdf.SetY(100)
+	pdf.Cell(nil, "Kitengela Central SDA Church")
+	pdf.SetX(40)
+	pdf.SetY(120)
+	pdf.Cell(nil, p.T("Covering %s", period))
+
+	if err = pdf.SetFont("Roboto-Bold", "", 16); err != nil {
+		return nil, err
+	}
+	pdf.SetX(40)
+	pdf.SetY(180)
+	pdf.Cell(nil, p.T("Contents"))
+
+	if err = pdf.SetFont("Roboto", "", 11); err != nil {
+		return nil, err
+	}
+
+	y := 210.0
+
+	for _, entry := range contents {
+		page := fmt.Sprint(entry.page)
+		width, err := pdf.MeasureTextWidth(page)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pdf.SetX(40)
+		pdf.SetY(y)
+		pdf.Cell(nil, entry.title)
+
+		pdf.SetX(555 - width)
+		pdf.SetY(y)
+		pdf.Cell(nil, page)
+
+		y += 20
+	}
+
+	return writePdf(pdf, p)
+}
+
+func kpiTable(p *i18n.Printer, report *models.AnnualReport, period string) *table {
+	t := &table{
+		title:    p.T("Annual Report %d: Key Figures", report.Year),
+		subtitle: "Kitengela Central SDA Church. " + p.T("Covering %s", period),
+		headers:  []string{p.T("Measure"), p.T("Value")},
+		widths:   []float64{315, 200},
+		bold:     map[int]bool{},
+	}
+
+	for _, kpi := range report.KPIs {
+		var value string
+
+		switch kpi.Unit {
+		case "":
+			value = fmt.Sprintf("%d", int(kpi.Value))
+		case "%":
+			value = p.Percent(kpi.Value)
+		default:
+			value = p.Amount(kpi.Value)
+		}
+
+		name := kpi.Name
+
+		if kpi.Label != "" {
+			name = p.T(kpi.Label, kpi.Args...)
+		}
+
+		t.rows = append(t.rows, []string{name, value})
+	}
+
+	return t
+}
+
+// yearSummaryTable lays out the receipts and payments of each category for the
+// months from first up to last. The year's total follows the last month of the year.
+func yearSummaryTable(p *i18n.Printer, report *models.AnnualReport, period string, first, last int) *table {
+	months := report.Months[first:last]
+	withTotal := last == len(report.Months)
+
+	t := &table{
+		title:    p.T("Annual Report %d: Receipts and Payments", report.Year),
+		subtitle: "Kitengela Central SDA Church. " + p.T("Covering %s", period),
+		headers:  []string{p.T("Category")},
+		widths:   []float64{95},
+		bold:     map[int]bool{},
+		groups:   map[int]bool{},
+	}
+
+	for _, statement := range months {
+		t.headers = append(t.headers, p.Month(statement.From.Month()))
+		t.widths = append(t.widths, 60)
+	}
+
+	if withTotal {
+		t.headers = append(t.headers, p.T("Year"))
+		t.widths = append(t.widths, 60)
+	}
+
+	sections := []struct {
+		title  string
+		amount func(*models.CashStatement) map[string]float64
+	}{
+		{p.T("Receipts"), func(c *models.CashStatement) map[string]float64 { return c.TotalReceipts }},
+		{p.T("Payments"), func(c *models.CashStatement) map[string]float64 { return c.Payments }},
+	}
+
+	for _, section := range sections {
+		t.groups[len(t.rows)] = true
+		t.rows = append(t.rows, []string{section.title})
+
+		totals := make([]float64, len(months))
+		var yearTotal float64
+
+		for _, category := range report.Categories {
+			row := []string{category}
+
+			for m, statement := range months {
+				row = append(row, p.Number(section.amount(statement)[category]))
+				totals[m] += section.amount(statement)[category]
+			}
+
+			if withTotal {
+				var year float64
+
+				for _, statement := range report.Months {
+					year += section.amount(statement)[category]
+				}
+
+				row = append(row, p.Number(year))
+				yearTotal += year
+			}
+
+			t.rows = append(t.rows, row)
+		}
+
+		row := []string{p.T("Total")}
+
+		for _, total := range totals {
+			row = append(row, p.Number(total))
+		}
+
+		if withTotal {
+			row = append(row, p.Number(yearTotal))
+		}
+
+		t.bold[len(t.rows)] = true
+		t.rows = append(t.rows, row)
+	}
+
+	return t
+}
+
+// yearOnYearTables compare the year with the same months of the year before, per
+// category and then per month
+func yearOnYearTables(p *i18n.Printer, report *models.AnnualReport) []*table {
+	current, previous := report.Current, report.Previous
+
+	title := p.T("Annual Report %d: %d compared with %d", report.Year, current.Year, previous.Year)
+	subtitle := "Kitengela Central SDA Church. " + p.T("Covering %s", monthsLabel(p, report.Year, current.Through))
+	widths := []float64{135, 95, 95, 95, 95}
+
+	byCategory := &table{
+		title:    title,
+		subtitle: subtitle,
+		headers:  []string{p.T("Category"), fmt.Sprint(previous.Year), fmt.Sprint(current.Year), p.T("Change"), p.T("Change %")},
+		widths:   widths,
+		bold:     map[int]bool{},
+		groups:   map[int]bool{},
+	}
+
+	sections := []struct {
+		title             string
+		current, previous map[string]float64
+	}{
+		{p.T("Receipts"), current.Receipts, previous.Receipts},
+		{p.T("Payments"), current.Payments, previous.Payments},
+	}
+
+	for _, section := range sections {
+		byCategory.groups[len(byCategory.rows)] = true
+		byCategory.rows = append(byCategory.rows, []string{section.title})
+
+		var before, after float64
+
+		for _, category := range report.Categories {
+			byCategory.rows = append(byCategory.rows, comparison(p, category, section.previous[category],
+				section.current[category]))
+			before += section.previous[category]
+			after += section.current[category]
+		}
+
+		byCategory.bold[len(byCategory.rows)] = true
+		byCategory.rows = append(byCategory.rows, comparison(p, p.T("Total"), before, after))
+	}
+
+	byMonth := &table{
+		title:    title,
+		subtitle: subtitle,
+		headers:  []string{p.T("Month"), fmt.Sprint(previous.Year), fmt.Sprint(current.Year), p.T("Change"), p.T("Change %")},
+		widths:   widths,
+		bold:     map[int]bool{},
+	}
+
+	var before, after float64
+
+	for m := 0; m < current.Through; m++ {
+		byMonth.rows = append(byMonth.rows, comparison(p, p.Month(time.Month(m+1)), previous.MonthlyReceipts[m],
+			current.MonthlyReceipts[m]))
+		before += previous.MonthlyReceipts[m]
+		after += current.MonthlyReceipts[m]
+	}
+
+	byMonth.bold[len(byMonth.rows)] = true
+	byMonth.rows = append(byMonth.rows, comparison(p, p.T("Total receipts"), before, after))
+
+	return []*table{byCategory, byMonth}
+}
+
+// comparison is a row of a year on year table. The change in percent is left blank
+// when there is nothing to compare with.
+func comparison(p *i18n.Printer, label string, before, after float64) []string {
+	change := ""
+
+	if before != 0 {
+		change = p.Percent((after - before) / before * 100)
+	}
+
+	return []string{label, p.Number(before), p.Number(after), p.Number(after - before), change}
+}
+
+// monthsLabel names the months an annual report covers
+func monthsLabel(p *i18n.Printer, year, months int) string {
+	if months >= 12 {
+		return p.T("the year %d", year)
+	}
+
+	return p.T("January to %s %d", time.Month(months), year)
+}
@@ -0,0 +1,88 @@
+package exports
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/signintech/gopdf"
+)
+
+func (pdfExport *PdfExport) GenerateBudgetVsActual(data []*models.BudgetVsActual, fiscalYear, month int) ([]byte, error) {
+	pdf := &gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
+
+	err := pdfExport.loadFonts(pdf)
+	if err != nil {
+		return nil, err
+	}
+
+	p := pdfExport.text()
+
+	colWidth := 82.0
+	headers := []string{"N", p.T("Category"), p.T("Budget"), p.T("Target"), p.T("Actual"), p.T("Variance"), p.T("Variance %")}
+
+	var budget, target, actual float64
+	y := pageHeight
+
+	for i, row := range data {
+		if y+rowHeight > pageHeight-bottomMargin-footerHeight {
+			pdf.AddPage()
+
+			err = pdf.SetFont("Roboto-Bold", "", 20)
+			if err != nil {
+				return nil, err
+			}
+			pdf.SetX(40)
+			pdf.SetY(20)
+			pdf.Cell(nil, p.T("Budget vs Actual %d", fiscalYear))
+
+			err = pdf.SetFont("Roboto", "", 12)
+			if err != nil {
+				return nil, err
+			}
+			pdf.SetX(40)
+			pdf.SetY(50)
+			pdf.Cell(nil, p.T("Year to date, end of %s", time.Month(month)))
+
+			y = topMargin
+			drawRow(pdf, headers, 40, y, colWidth, rowHeight, true, false)
+			y += rowHeight
+		}
+
+		cells := []string{fmt.Sprintf("%d", i+1), row.Category, p.Number(row.AnnualBudget), p.Number(row.Target),
+			p.Number(row.Actual), p.Number(row.Variance), p.Percent(float64(row.Percentage))}
+
+		if row.Alert {
+			pdf.SetTextColor(200, 0, 0)
+		}
+		drawRow(pdf, cells, 40, y, colWidth, rowHeight, false, false)
+		pdf.SetTextColor(0, 0, 0)
+		y += rowHeight
+
+		budget += row.AnnualBudget
+		target += row.Target
+		actual += row.Actual
+	}
+
+	if len(data) == 0 {
+		pdf.AddPage()
+		y = topMargin
+	}
+
+	percentage := 0.0
+	if target != 0 {
+		percentage = (actual - target) / target * 100
+	}
+
+	drawRow(pdf, []string{"", p.T("Total"), p.Number(budget), p.Number(target), p.Number(actual),
+		p.Number(actual - target), p.Percent(percentage)},
+		40, y, colWidth, rowHeight, true, false)
+
+	pdf.SetX(40)
+	pdf.SetY(y + rowHeight + 20)
+	pdf.SetFont("Roboto", "", 10)
+	pdf.Cell(nil, p.T("Categories in red are tracking below their alert threshold"))
+
+	return writePdf(pdf, p)
+}
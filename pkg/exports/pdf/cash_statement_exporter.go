@@ -0,0 +1,138 @@
+package exports
+
+import (
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/signintech/gopdf"
+)
+
+// GeneratePdfSummary is the PDF twin of the Excel cash statement. The fund summary
+// and the signature block come first, followed by the receipts per sabbath and the
+// list of disbursements.
+func (pdfExport *PdfExport) GeneratePdfSummary(statement *models.CashStatement) ([]byte, error) {
+	pdf := &gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
+
+	err := pdfExport.loadFonts(pdf)
+	if err != nil {
+		return nil, err
+	}
+
+	p := pdfExport.text()
+
+	if err = drawCashStatement(pdf, p, statement); err != nil {
+		return nil, err
+	}
+
+	return writePdf(pdf, p)
+}
+
+// drawCashStatement lays out a cash statement from a new page on
+func drawCashStatement(pdf *gopdf.GoPdf, p *i18n.Printer, statement *models.CashStatement) error {
+	period := p.T("For the period %s to %s", statement.From, statement.To)
+
+	summary := &table{
+		title:    p.T("Church Treasurer's Cash Statement"),
+		subtitle: "Kitengela Central SDA Church. " + period,
+		headers: []string{p.T("Fund"), p.T("Balance B/F"), p.T("Receipts"), p.T("Payments"), p.T("Transfers"),
+			p.T("Balance C/F")},
+		widths: []float64{115, 80, 80, 80, 80, 80},
+		bold:   map[int]bool{len(statement.Categories): true},
+	}
+
+	var opening, receipts, payments, transfers, closing float64
+
+	for _, category := range statement.Categories {
+		summary.rows = append(summary.rows, []string{category, p.Number(statement.Opening[category]),
+			p.Number(statement.TotalReceipts[category]), p.Number(statement.Payments[category]),
+			p.Number(statement.Transfers[category]), p.Number(statement.Closing[category])})
+
+		opening += statement.Opening[category]
+		receipts += statement.TotalReceipts[category]
+		payments += statement.Payments[category]
+		transfers += statement.Transfers[category]
+		closing += statement.Closing[category]
+	}
+
+	summary.rows = append(summary.rows, []string{p.T("Total"), p.Number(opening), p.Number(receipts), p.Number(payments),
+		p.Number(transfers), p.Number(closing)})
+
+	y, err := drawTable(pdf, summary)
+
+	if err != nil {
+		return err
+	}
+
+	drawSignatureBlock(pdf, p, y+30)
+
+	sabbaths := &table{
+		title:    p.T("Receipts by Sabbath"),
+		subtitle: period,
+		headers:  []string{p.T("Sabbath"), p.T("Category"), p.T("Amount (%s)", p.CurrencyCode())},
+		widths:   []float64{120, 250, 145},
+		bold:     map[int]bool{},
+	}
+
+	for _, receipt := range statement.Receipts {
+		for _, category := range statement.Categories {
+			if value, ok := receipt.Amounts[category]; ok {
+				sabbaths.rows = append(sabbaths.rows, []string{shortDate(p, receipt.Date), category, p.Number(value)})
+			}
+		}
+
+		sabbaths.bold[len(sabbaths.rows)] = true
+		sabbaths.rows = append(sabbaths.rows, []string{shortDate(p, receipt.Date), p.T("Sabbath total"),
+			p.Number(receipt.Total)})
+	}
+
+	if _, err = drawTable(pdf, sabbaths); err != nil {
+		return err
+	}
+
+	disbursements := &table{
+		title:    p.T("Disbursements"),
+		subtitle: period,
+		headers:  []string{p.T("Date"), p.T("Voucher"), p.T("Payee"), p.T("Category"), p.T("Amount (%s)", p.CurrencyCode())},
+		widths:   []float64{75, 90, 140, 110, 100},
+		bold:     map[int]bool{len(statement.Disbursements): true},
+	}
+
+	var paid float64
+
+	for _, expenditure := range statement.Disbursements {
+		disbursements.rows = append(disbursements.rows, []string{shortDate(p, expenditure.Date),
+			expenditure.VoucherNo, expenditure.Payee, expenditure.Category, p.Number(expenditure.Amount)})
+		paid += expenditure.Amount
+	}
+
+	disbursements.rows = append(disbursements.rows, []string{"", "", "", p.T("Total"), p.Number(paid)})
+
+	_, err = drawTable(pdf, disbursements)
+
+	return err
+}
+
+// drawSignatureBlock draws the treasurer, auditor and board chair sign off lines,
+// moving to a new page when they do not fit below the summary
+func drawSignatureBlock(pdf *gopdf.GoPdf, p *i18n.Printer, y float64) {
+	signatories := []string{p.T("Prepared by (Treasurer)"), p.T("Checked by (Auditor)"), p.T("Approved by (Board Chair)")}
+
+	if y+float64(len(signatories))*45 > pageHeight-bottomMargin-footerHeight {
+		pdf.AddPage()
+		y = topMargin
+	}
+
+	pdf.SetFont("Roboto", "", 10)
+
+	for _, signatory := range signatories {
+		pdf.SetX(40)
+		pdf.SetY(y)
+		pdf.Cell(nil, signatory)
+
+		pdf.SetX(40)
+		pdf.SetY(y + 20)
+		pdf.Cell(nil, p.T("Name: ______________________   Signature: ________________   Date: ____________"))
+
+		y += 45
+	}
+}
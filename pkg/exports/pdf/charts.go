@@ -0,0 +1,201 @@
+package exports
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/signintech/gopdf"
+)
+
+// chartColors are used for the categories in turn, the same colour for a category in
+// every chart on a page
+var chartColors = [][3]uint8{
+	{31, 119, 180}, {255, 127, 14}, {44, 160, 44}, {214, 39, 40}, {148, 103, 189},
+	{140, 86, 75}, {227, 119, 194}, {127, 127, 127}, {188, 189, 34}, {23, 190, 207},
+}
+
+const (
+	chartHeight     = 150.0
+	legendRowHeight = 12.0
+)
+
+func setChartColor(pdf *gopdf.GoPdf, i int) {
+	c := chartColors[i%len(chartColors)]
+	pdf.SetFillColor(c[0], c[1], c[2])
+}
+
+// drawPieChart draws the share of each value as a slice of a circle. Values that
+// are not positive are left out.
+func drawPieChart(pdf *gopdf.GoPdf, cx, cy, radius float64, values []float64) {
+	var total float64
+
+	for _, value := range values {
+		if value > 0 {
+			total += value
+		}
+	}
+
+	if total == 0 {
+		return
+	}
+
+	start := -math.Pi / 2
+
+	for i, value := range values {
+		if value <= 0 {
+			continue
+		}
+
+		sweep := value / total * 2 * math.Pi
+		points := []gopdf.Point{{X: cx, Y: cy}}
+
+		// arcs are drawn as polygons with a point at least every two degrees
+		steps := int(math.Ceil(sweep/(math.Pi/90))) + 1
+
+		for step := 0; step <= steps; step++ {
+			angle := start + sweep*float64(step)/float64(steps)
+			points = append(points, gopdf.Point{X: cx + radius*math.Cos(angle), Y: cy + radius*math.Sin(angle)})
+		}
+
+		setChartColor(pdf, i)
+		pdf.Polygon(points, "F")
+
+		start += sweep
+	}
+
+	pdf.SetFillColor(0, 0, 0)
+}
+
+// drawColumnChart draws a column per label with the values of each series stacked
+// on one another. Labels are thinned out along the axis when they would overlap.
+func drawColumnChart(pdf *gopdf.GoPdf, p *i18n.Printer, x, y, width, height float64, labels []string, series [][]float64) {
+	var highest float64
+
+	for i := range labels {
+		var stacked float64
+
+		for _, values := range series {
+			if values[i] > 0 {
+				stacked += values[i]
+			}
+		}
+
+		highest = max(highest, stacked)
+	}
+
+	pdf.SetStrokeColor(0, 0, 0)
+	pdf.SetLineWidth(0.5)
+	pdf.Line(x, y+height, x+width, y+height)
+	pdf.Line(x, y, x, y+height)
+
+	if highest == 0 || len(labels) == 0 {
+		return
+	}
+
+	pdf.SetFont("Roboto", "", 7)
+
+	pdf.SetX(x + 2)
+	pdf.SetY(y - 10)
+	pdf.Cell(nil, p.Number(highest))
+
+	slot := width / float64(len(labels))
+	barWidth := slot * 0.7
+	every := int(math.Ceil(35 / slot))
+
+	for i, label := range labels {
+		barX := x + slot*float64(i) + (slot-barWidth)/2
+		top := y + height
+
+		for s, values := range series {
+			if values[i] <= 0 {
+				continue
+			}
+
+			barHeight := values[i] / highest * height
+			top -= barHeight
+
+			setChartColor(pdf, s)
+			pdf.RectFromUpperLeftWithStyle(barX, top, barWidth, barHeight, "F")
+		}
+
+		if i%every == 0 {
+			pdf.SetX(x + slot*float64(i))
+			pdf.SetY(y + height + 4)
+			pdf.Cell(nil, label)
+		}
+	}
+
+	pdf.SetFillColor(0, 0, 0)
+}
+
+// drawLegend lists the categories in two columns next to their colour and returns the
+// position below the legend
+func drawLegend(pdf *gopdf.GoPdf, p *i18n.Printer, x, y float64, names []string, values []float64) float64 {
+	var total float64
+
+	for _, value := range values {
+		if value > 0 {
+			total += value
+		}
+	}
+
+	pdf.SetFont("Roboto", "", 8)
+
+	for i, name := range names {
+		column := float64(i % 2)
+		rowY := y + float64(i/2)*legendRowHeight
+
+		setChartColor(pdf, i)
+		pdf.RectFromUpperLeftWithStyle(x+column*255, rowY, 8, 8, "F")
+		pdf.SetFillColor(0, 0, 0)
+
+		text := name
+
+		if total > 0 && values[i] > 0 {
+			text = fmt.Sprintf("%s  %s (%.1f%%)", name, p.Number(values[i]), values[i]/total*100)
+		}
+
+		pdf.SetX(x + column*255 + 12)
+		pdf.SetY(rowY)
+		pdf.Cell(nil, text)
+	}
+
+	return y + float64((len(names)+1)/2)*legendRowHeight
+}
+
+// chartBlockHeight is the room the charts and legend of a summary take up
+func chartBlockHeight(categories int) float64 {
+	return chartHeight + 40 + float64((categories+1)/2)*legendRowHeight
+}
+
+// sabbathSeries arranges amounts per date and category as the labels and stacked
+// series of a column chart, dates in order
+func sabbathSeries(byDate map[string]map[string]float64, categories []string) ([]string, [][]float64) {
+	dates := slices.Sorted(maps.Keys(byDate))
+
+	series := make([][]float64, len(categories))
+
+	for c, category := range categories {
+		series[c] = make([]float64, len(dates))
+
+		for d, date := range dates {
+			series[c][d] = byDate[date][category]
+		}
+	}
+
+	labels := make([]string, len(dates))
+
+	for i, date := range dates {
+		labels[i] = date
+
+		// day and month are enough under the axis
+		if len(date) == 10 {
+			labels[i] = date[8:] + "/" + date[5:7]
+		}
+	}
+
+	return labels, series
+}
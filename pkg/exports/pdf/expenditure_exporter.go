@@ -0,0 +1,89 @@
+package exports
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/signintech/gopdf"
+)
+
+func (pdfExport *PdfExport) GenerateExpenditurePdf(data []*models.Expenditure, startDate, endDate time.Time) ([]byte, error) {
+	pdf := &gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
+
+	err := pdfExport.loadFonts(pdf)
+	if err != nil {
+		return nil, err
+	}
+
+	p := pdfExport.text()
+
+	colWidth := 98.0
+	headers := []string{"N", p.T("Date"), p.T("Voucher"), p.T("Payee"), p.T("Category"), p.T("Amount (%s)", p.CurrencyCode())}
+
+	period := p.T("Expenditures")
+	if !startDate.IsZero() && !endDate.IsZero() {
+		period = p.T("Expenditures from: %s to: %s", startDate, endDate)
+	} else if !startDate.IsZero() {
+		period = p.T("Expenditures for: %s", startDate)
+	}
+
+	var total float64
+	y := pageHeight
+
+	for i, row := range data {
+		if y+rowHeight*2 > pageHeight-bottomMargin-footerHeight {
+			pdf.AddPage()
+
+			err = pdf.SetFont("Roboto-Bold", "", 20)
+			if err != nil {
+				return nil, err
+			}
+			pdf.SetX(40)
+			pdf.SetY(20)
+			pdf.Cell(nil, p.T("KCSDA Expenditure Report"))
+
+			err = pdf.SetFont("Roboto", "", 12)
+			if err != nil {
+				return nil, err
+			}
+			pdf.SetX(40)
+			pdf.SetY(50)
+			pdf.Cell(nil, period)
+
+			y = topMargin
+			drawRow(pdf, headers, 40, y, colWidth, rowHeight, true, false)
+			y += rowHeight
+		}
+
+		cells := []string{fmt.Sprintf("%d", i+1), shortDate(p, row.Date), row.VoucherNo, row.Payee,
+			row.Category, p.Number(row.Amount)}
+
+		drawRow(pdf, cells, 40, y, colWidth, rowHeight, false, false)
+		y += rowHeight
+
+		approval := p.T("Pending approval")
+		if row.ApprovedAt != nil {
+			approval = p.T("Approved on %s", *row.ApprovedAt)
+		}
+
+		drawRow(pdf, []string{fmt.Sprintf("%s | %s | %s", row.PaymentMethod, approval, row.Description)},
+			40, y, 500, rowHeight, false, true)
+		y += rowHeight
+
+		total += row.Amount
+	}
+
+	if len(data) == 0 {
+		pdf.AddPage()
+		y = topMargin
+	}
+
+	pdf.SetX(40)
+	pdf.SetY(y + 20)
+	pdf.SetFont("Roboto-Bold", "", 12)
+	pdf.Cell(nil, p.T("Total expenditure: %s", p.Amount(total)))
+
+	return writePdf(pdf, p)
+}
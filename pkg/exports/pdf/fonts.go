@@ -0,0 +1,118 @@
+package exports
+
+import (
+	"embed"
+	"fmt"
+	"sync"
+
+	"github.com/VaudKK/CAS/pkg/theme"
+	"github.com/VaudKK/CAS/utils"
+	"github.com/signintech/gopdf"
+	"github.com/signintech/gopdf/fontmaker/core"
+)
+
+//go:embed fonts/roboto/Roboto-Regular.ttf fonts/roboto/Roboto-Bold.ttf
+var fontFS embed.FS
+
+// Fonts are the font files reports are set in. They are read and checked once and
+// every document takes them from memory.
+type Fonts struct {
+	Regular []byte
+	Bold    []byte
+}
+
+// defaultFonts are the fonts built into the binary, for exports made without
+// NewPdfExport
+var defaultFonts = sync.OnceValues(func() (*Fonts, error) {
+	return LoadFonts("", 0)
+})
+
+// LoadFonts reads the fonts of an organization's theme, using the built in fonts for
+// any the theme leaves out, and checks that they parse
+func LoadFonts(themes theme.Dir, organizationId int) (*Fonts, error) {
+	regular, err := themes.ReadFile(organizationId, "fonts/Roboto-Regular.ttf", fontFS, "fonts/roboto/Roboto-Regular.ttf")
+
+	if err != nil {
+		return nil, err
+	}
+
+	bold, err := themes.ReadFile(organizationId, "fonts/Roboto-Bold.ttf", fontFS, "fonts/roboto/Roboto-Bold.ttf")
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, data := range [][]byte{regular, bold} {
+		var parser core.TTFParser
+
+		if err = parser.ParseFontData(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Fonts{Regular: regular, Bold: bold}, nil
+}
+
+// NewPdfExport loads the default fonts and those of every organization with a theme
+// up front, so that a broken theme stops the server from starting rather than
+// failing its reports
+func NewPdfExport(logger *utils.CLogger, themes theme.Dir) (*PdfExport, error) {
+	fonts, err := LoadFonts(themes, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	organizations, err := themes.Organizations()
+
+	if err != nil {
+		return nil, err
+	}
+
+	pdfExport := &PdfExport{Logger: logger, fonts: fonts, themes: make(map[int]*Fonts)}
+
+	for _, organizationId := range organizations {
+		if pdfExport.themes[organizationId], err = LoadFonts(themes, organizationId); err != nil {
+			return nil, fmt.Errorf("theme of organization %d: %w", organizationId, err)
+		}
+	}
+
+	return pdfExport, nil
+}
+
+// ForOrganization returns the export set in the fonts of the organization's theme
+func (pdfExport *PdfExport) ForOrganization(organizationId int) *PdfExport {
+	fonts, ok := pdfExport.themes[organizationId]
+
+	if !ok {
+		return pdfExport
+	}
+
+	themed := *pdfExport
+	themed.fonts = fonts
+
+	return &themed
+}
+
+// loadFonts adds the fonts of the export to a document. The files are read and
+// checked once per theme when the export is made; gopdf keeps the parsed tables on
+// the document's own font objects and has no way to share them, so each document
+// still parses the bytes held in memory, about a quarter of a millisecond for both.
+func (pdfExport *PdfExport) loadFonts(pdf *gopdf.GoPdf) error {
+	fonts := pdfExport.fonts
+
+	if fonts == nil {
+		var err error
+
+		if fonts, err = defaultFonts(); err != nil {
+			return err
+		}
+	}
+
+	err := pdf.AddTTFFontData("Roboto", fonts.Regular)
+	if err != nil {
+		return err
+	}
+
+	return pdf.AddTTFFontData("Roboto-Bold", fonts.Bold)
+}
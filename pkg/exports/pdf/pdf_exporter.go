@@ -1,11 +1,13 @@
 package exports
 
 import (
-	"bytes"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/VaudKK/CAS/pkg/i18n"
 	"github.com/VaudKK/CAS/pkg/models"
 	"github.com/VaudKK/CAS/utils"
 	"github.com/signintech/gopdf"
@@ -13,6 +15,28 @@ import (
 
 type PdfExport struct {
 	Logger *utils.CLogger
+
+	fonts   *Fonts
+	themes  map[int]*Fonts
+	printer *i18n.Printer
+}
+
+// ForLocale returns the export written in the language of the locale
+func (pdfExport *PdfExport) ForLocale(locale string) *PdfExport {
+	localized := *pdfExport
+	localized.printer = i18n.For(locale)
+
+	return &localized
+}
+
+// text is the printer of the export's locale, that of the default locale when none
+// was chosen
+func (pdfExport *PdfExport) text() *i18n.Printer {
+	if pdfExport.printer == nil {
+		return i18n.For(i18n.DefaultLocale)
+	}
+
+	return pdfExport.printer
 }
 
 const (
@@ -20,40 +44,21 @@ const (
 	topMargin    = 80.0
 	bottomMargin = 60.0
 	rowHeight    = 25.0
-	colCount     = 5
-	tableWidth   = 500.0
 	footerHeight = 30.0
 )
 
-func (pdfExport *PdfExport) GeneratePdfFile(data []*models.Fund, categories []string, startDate, endDate time.Time) ([]byte, error) {
+// GeneratePdfFile writes a summary page with the total of each category and the
+// selected charts, followed by the contributions of each sabbath with its subtotal
+func (pdfExport *PdfExport) GeneratePdfFile(data []*models.Fund, categories []string, startDate, endDate time.Time, charts models.Charts) ([]byte, error) {
 	pdf := &gopdf.GoPdf{}
 	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
 
-	// Load font
-	err := pdf.AddTTFFont("Roboto", "./pkg/exports/pdf/fonts/roboto/Roboto-Regular.ttf")
+	err := pdfExport.loadFonts(pdf)
 	if err != nil {
 		return nil, err
 	}
 
-	err = pdf.AddTTFFont("Roboto-Bold", "./pkg/exports/pdf/fonts/roboto/Roboto-Bold.ttf")
-	if err != nil {
-		return nil, err
-	}
-
-	// Calculate how many rows per page
-	availableHeight := pageHeight - topMargin - bottomMargin - rowHeight - footerHeight
-	rowsPerPage := int(availableHeight / (rowHeight * 2))
-
-	colWidth := 120.0
-	totalRows := len(data)
-	pages := ((totalRows * 2) + rowsPerPage - 1) / rowsPerPage
-
-	rowIndex := 0
-
-	// prevents having a zero paged document
-	if pages == 1 {
-		pages = 2
-	}
+	p := pdfExport.text()
 
 	//summary page
 	pdf.AddPage()
@@ -65,7 +70,7 @@ func (pdfExport *PdfExport) GeneratePdfFile(data []*models.Fund, categories []st
 	}
 	pdf.SetX(40)
 	pdf.SetY(20)
-	pdf.Cell(nil, "KCSDA Contributions Report")
+	pdf.Cell(nil, p.T("KCSDA Contributions Report"))
 
 	err = pdf.SetFont("Roboto", "", 15)
 	if err != nil {
@@ -75,97 +80,171 @@ func (pdfExport *PdfExport) GeneratePdfFile(data []*models.Fund, categories []st
 	pdf.SetX(40)
 	pdf.SetY(170)
 
+	period := p.T("Consolidated summary")
+
 	if !startDate.IsZero() && !endDate.IsZero() {
-		pdf.Cell(nil, fmt.Sprintf("Consolidated summary from: %s to: %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02")))
+		period = p.T("Consolidated summary from: %s to: %s", startDate, endDate)
 	} else if !startDate.IsZero() && endDate.IsZero() {
-		pdf.Cell(nil, fmt.Sprintf("Consolidated summary for: %s", startDate.Format("2006-01-02")))
-	} else {
-		pdf.Cell(nil, "Consolidated summary")
+		period = p.T("Consolidated summary for: %s", startDate)
 	}
 
-	y := 200.0
+	pdf.Cell(nil, period)
 
 	summation := make(map[string]float64)
+	bySabbath := make(map[string]map[string]float64)
+	contributions := make(map[string][]*models.Fund)
 
 	for _, row := range data {
+		date := strings.Split(row.Date, "T")[0]
+
+		if bySabbath[date] == nil {
+			bySabbath[date] = make(map[string]float64)
+		}
+
 		for key, value := range row.BreakDown {
-			if val, ok := summation[key]; ok {
-				summation[key] = val + value
-			} else {
-				summation[key] = value
-			}
+			summation[key] += value
+			bySabbath[date][key] += value
 		}
+
+		contributions[date] = append(contributions[date], row)
 	}
 
-	headers := []string{"N", "Fund Category", "Amount"}
-	drawRow(pdf, headers, 40, y, 200, rowHeight, true, false)
-	y += rowHeight
+	summed := orderCategories(categories, summation)
 
-	k := 0
-	for key, value := range summation {
-		row := []string{fmt.Sprintf("%d", k+1), key, fmt.Sprintf("%.2f", value)}
-		drawRow(pdf, row, 40, y, 200, rowHeight, true, false)
-		y += rowHeight
-		k += 1
+	summary := &table{
+		title:    p.T("Consolidated summary"),
+		subtitle: period,
+		headers:  []string{"N", p.T("Fund Category"), p.T("Amount (%s)", p.CurrencyCode())},
+		widths:   []float64{30, 285, 200},
+		bold:     map[int]bool{len(summed): true},
 	}
 
-	pdf.SetX(40)
-	pdf.SetY(y + 50)
-	pdf.Cell(nil, "Turn over page for detailed contributions")
+	totals := make([]float64, len(summed))
+	var total float64
 
-	for page := 0; page < pages/2; page++ {
-		pdf.AddPage()
+	for k, key := range summed {
+		totals[k] = summation[key]
+		total += summation[key]
 
-		y := topMargin
+		summary.rows = append(summary.rows, []string{fmt.Sprintf("%d", k+1), key, p.Number(summation[key])})
+	}
 
-		err = pdf.SetFont("Roboto-Bold", "", 20)
-		if err != nil {
-			return nil, err
-		}
-		pdf.SetX(40)
-		pdf.SetY(20)
-		pdf.Cell(nil, "Details of Contributions")
+	summary.rows = append(summary.rows, []string{"", p.T("Total"), p.Amount(total)})
 
-		// Draw header row
-		headers := []string{"N", "Name", "Receipt", "Total", "Date"}
-		drawRow(pdf, headers, 40, y, colWidth, rowHeight, true, false)
-		y += rowHeight
+	y, err := drawTableAt(pdf, summary, 200)
 
-		// Draw data rows for this page
-		for i := 0; i < rowsPerPage && rowIndex < totalRows; i++ {
-			rowData := data[rowIndex]
+	if err != nil {
+		return nil, err
+	}
 
-			breakDown := ""
+	if (charts.Pie || charts.Columns) && len(summed) > 0 {
+		y += 30
 
-			for category, amount := range rowData.BreakDown {
-				breakDown += fmt.Sprintf("%s: %.2f  ", category, amount)
+		// the charts move to a page of their own when the summary leaves no room
+		if y+chartBlockHeight(len(summed)) > pageHeight-bottomMargin-footerHeight {
+			pdf.AddPage()
+
+			if err = pdf.SetFont("Roboto-Bold", "", 20); err != nil {
+				return nil, err
 			}
+			pdf.SetX(40)
+			pdf.SetY(20)
+			pdf.Cell(nil, p.T("Consolidated summary"))
+
+			y = topMargin
+		}
 
-			row := []string{fmt.Sprintf("%d", (rowIndex + 1)), rowData.Contributor, rowData.ReceiptNo,
-				fmt.Sprintf("%.2f", rowData.Total), strings.Split(rowData.Date, "T")[0]}
+		x := 40.0
 
-			drawRow(pdf, row, 40, y, colWidth, rowHeight, false, false)
-			y += rowHeight
+		if charts.Pie {
+			drawPieChart(pdf, x+chartHeight/2, y+chartHeight/2, chartHeight/2, totals)
+			x += chartHeight + 30
+		}
 
-			drawRow(pdf, []string{breakDown}, 40, y, 500, rowHeight, false, true)
-			y += rowHeight
-			rowIndex++
+		if charts.Columns {
+			labels, series := sabbathSeries(bySabbath, summed)
+			drawColumnChart(pdf, p, x, y+10, 555-x, chartHeight-10, labels, series)
 		}
 
-		// Draw footer
-		pdf.SetX(40)
-		pdf.SetY(pageHeight - bottomMargin + 10)
-		pdf.Cell(nil, fmt.Sprintf("Page %d of %d %50v", page+1, pages/2, "Generated on "+time.Now().Format("2006-01-02 15:04:05")))
+		drawLegend(pdf, p, 40, y+chartHeight+25, summed, totals)
 	}
 
-	// Write to buffer
-	var buf bytes.Buffer
-	_, err = pdf.WriteTo(&buf)
-	if err != nil {
-		panic(err)
+	details := &table{
+		title:    p.T("Details of Contributions"),
+		subtitle: period,
+		headers:  []string{"N", p.T("Name"), p.T("Receipt"), p.T("Breakdown"), p.T("Total")},
+		widths:   []float64{30, 130, 70, 205, 80},
+		bold:     map[int]bool{},
+		groups:   map[int]bool{},
+	}
+
+	n := 0
+
+	for _, date := range slices.Sorted(maps.Keys(contributions)) {
+		details.groups[len(details.rows)] = true
+		details.rows = append(details.rows, []string{p.T("Sabbath %s", sabbathDate(date))})
+
+		var subtotal float64
+
+		for _, row := range contributions[date] {
+			n++
+			subtotal += row.Total
+
+			details.rows = append(details.rows, []string{fmt.Sprintf("%d", n), row.Contributor, row.ReceiptNo,
+				breakDown(p, orderCategories(categories, row.BreakDown), row.BreakDown), p.Number(row.Total)})
+		}
+
+		details.bold[len(details.rows)] = true
+		details.rows = append(details.rows, []string{"", p.T("Sabbath total"), "",
+			breakDown(p, orderCategories(categories, bySabbath[date]), bySabbath[date]), p.Number(subtotal)})
+	}
+
+	if _, err = drawTable(pdf, details); err != nil {
+		return nil, err
+	}
+
+	return writePdf(pdf, p)
+}
+
+// sabbathDate is the date of a sabbath, YYYY-MM-DD, to be written in full
+func sabbathDate(date string) any {
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		return t
+	}
+
+	return date
+}
+
+// orderCategories lists the categories that have an amount in their usual order,
+// then any others in alphabetical order, so that the same category always comes in
+// the same place
+func orderCategories(categories []string, amounts map[string]float64) []string {
+	ordered := make([]string, 0, len(amounts))
+
+	for _, category := range categories {
+		if _, ok := amounts[category]; ok && !slices.Contains(ordered, category) {
+			ordered = append(ordered, category)
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(amounts)) {
+		if !slices.Contains(ordered, key) {
+			ordered = append(ordered, key)
+		}
+	}
+
+	return ordered
+}
+
+// breakDown lists the amount of each category on one line, for the table to wrap
+func breakDown(p *i18n.Printer, categories []string, amounts map[string]float64) string {
+	parts := make([]string, len(categories))
+
+	for i, category := range categories {
+		parts[i] = fmt.Sprintf("%s: %s", category, p.Number(amounts[category]))
 	}
 
-	return buf.Bytes(), nil
+	return strings.Join(parts, ", ")
 }
 
 func drawRow(pdf *gopdf.GoPdf, cells []string, x, y, colWidth, rowHeight float64, isHeader bool, isSingleCellRow bool) {
@@ -0,0 +1,167 @@
+package exports
+
+import (
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/signintech/gopdf"
+)
+
+func (pdfExport *PdfExport) GenerateTrialBalance(report *models.TrialBalance) ([]byte, error) {
+	comparative := !report.Period.PriorTo.IsZero()
+	p := pdfExport.text()
+
+	t := &table{
+		title:    p.T("Trial Balance"),
+		subtitle: p.T("As at %s", report.Period.To),
+		headers:  []string{p.T("Code"), p.T("Account"), p.T("Debit"), p.T("Credit")},
+		widths:   []float64{50, 175, 145, 145},
+		bold:     map[int]bool{len(report.Lines): true},
+	}
+
+	if comparative {
+		t.subtitle = p.T("As at %s, comparative as at %s", report.Period.To, report.Period.PriorTo)
+		t.headers = append(t.headers, p.T("Prior Debit"), p.T("Prior Credit"))
+		t.widths = []float64{45, 130, 85, 85, 85, 85}
+	}
+
+	for _, line := range report.Lines {
+		row := []string{line.Code, line.Name, p.Number(line.Debit), p.Number(line.Credit)}
+		if comparative {
+			row = append(row, p.Number(line.PriorDebit), p.Number(line.PriorCredit))
+		}
+		t.rows = append(t.rows, row)
+	}
+
+	totals := []string{"", p.T("Total"), p.Number(report.TotalDebit), p.Number(report.TotalCredit)}
+	if comparative {
+		totals = append(totals, p.Number(report.PriorTotalDebit), p.Number(report.PriorTotalCredit))
+	}
+	t.rows = append(t.rows, totals)
+
+	return pdfExport.renderTable(t)
+}
+
+func (pdfExport *PdfExport) GenerateIncomeStatement(report *models.IncomeStatement) ([]byte, error) {
+	comparative := !report.Period.PriorTo.IsZero()
+	p := pdfExport.text()
+
+	t := &table{
+		title:    p.T("Statement of Income and Expenditure"),
+		subtitle: periodLabel(p, report.Period),
+		headers:  []string{p.T("Code"), p.T("Account"), p.T("Amount (%s)", p.CurrencyCode())},
+		widths:   []float64{60, 285, 170},
+		bold:     map[int]bool{},
+	}
+
+	if comparative {
+		t.headers = append(t.headers, p.T("Prior Period"))
+		t.widths = []float64{50, 225, 120, 120}
+	}
+
+	add := func(bold bool, values ...string) {
+		if bold {
+			t.bold[len(t.rows)] = true
+		}
+		if !comparative {
+			values = values[:3]
+		}
+		t.rows = append(t.rows, values)
+	}
+
+	add(true, "", p.T("Income"), "", "")
+
+	for _, line := range report.Income {
+		add(false, line.Code, line.Name, p.Number(line.Amount), p.Number(line.PriorAmount))
+	}
+
+	add(true, "", p.T("Total income"), p.Number(report.TotalIncome), p.Number(report.PriorTotalIncome))
+	add(true, "", p.T("Expenditure"), "", "")
+
+	for _, line := range report.Expenditure {
+		add(false, line.Code, line.Name, p.Number(line.Amount), p.Number(line.PriorAmount))
+	}
+
+	add(true, "", p.T("Total expenditure"), p.Number(report.TotalExpenditure), p.Number(report.PriorTotalExpenditure))
+	add(true, "", p.T("Surplus / (deficit)"), p.Number(report.Surplus), p.Number(report.PriorSurplus))
+
+	return pdfExport.renderTable(t)
+}
+
+func (pdfExport *PdfExport) GenerateFundBalanceSheet(report *models.FundBalanceSheet) ([]byte, error) {
+	comparative := !report.Period.PriorTo.IsZero()
+	p := pdfExport.text()
+
+	t := &table{
+		title:    p.T("Fund Balance Sheet"),
+		subtitle: periodLabel(p, report.Period),
+		headers:  []string{p.T("Fund"), p.T("Opening"), p.T("Receipts"), p.T("Payments"), p.T("Transfers"), p.T("Closing")},
+		widths:   []float64{115, 80, 80, 80, 80, 80},
+		bold:     map[int]bool{len(report.Lines): true},
+	}
+
+	if comparative {
+		t.headers = append(t.headers, p.T("Prior Closing"))
+		t.widths = []float64{100, 70, 70, 70, 65, 70, 70}
+	}
+
+	row := func(line *models.FundBalanceLine) []string {
+		values := []string{line.Fund, p.Number(line.Opening), p.Number(line.Receipts), p.Number(line.Payments),
+			p.Number(line.Transfers), p.Number(line.Closing)}
+		if comparative {
+			values = append(values, p.Number(line.PriorClosing))
+		}
+		return values
+	}
+
+	for _, line := range report.Lines {
+		t.rows = append(t.rows, row(line))
+	}
+
+	totals := row(&report.Totals)
+	totals[0] = p.T("Total")
+	t.rows = append(t.rows, totals)
+
+	return pdfExport.renderTable(t)
+}
+
+func (pdfExport *PdfExport) renderTable(t *table) ([]byte, error) {
+	pdf := &gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
+
+	err := pdfExport.loadFonts(pdf)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = drawTable(pdf, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return writePdf(pdf, pdfExport.text())
+}
+
+func periodLabel(p *i18n.Printer, period models.StatementPeriod) string {
+	if !period.PriorTo.IsZero() {
+		return p.T("For the period %s to %s, comparative %s to %s", period.From, period.To, period.PriorFrom, period.PriorTo)
+	}
+
+	return p.T("For the period %s to %s", period.From, period.To)
+}
+
+// GenerateTable draws a plain table, with the columns sharing the width of the page,
+// for documents that have no laid out report
+func (pdfExport *PdfExport) GenerateTable(title, subtitle string, headers []string, rows [][]string) ([]byte, error) {
+	t := &table{
+		title:    title,
+		subtitle: subtitle,
+		headers:  headers,
+		rows:     rows,
+	}
+
+	for range headers {
+		t.widths = append(t.widths, 515/float64(len(headers)))
+	}
+
+	return pdfExport.renderTable(t)
+}
@@ -0,0 +1,279 @@
+package exports
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/signintech/gopdf"
+)
+
+const (
+	cellPadding = 5.0
+	lineHeight  = 12.0
+
+	// minWrapWidth is the narrowest text width that is wrapped. Narrower cells keep
+	// their text on one line rather than splitting it a letter at a time.
+	minWrapWidth = 20.0
+)
+
+// table is a simple report table with per column widths. Rows listed in bold are
+// drawn with the header font, e.g. section headings and totals.
+type table struct {
+	title    string
+	subtitle string
+	headers  []string
+	widths   []float64
+	rows     [][]string
+	bold     map[int]bool
+
+	// groups are the rows that open a group, e.g. the contributions of a sabbath.
+	// Their first cell spans the width of the table and they are kept on the same
+	// page as the row after them.
+	groups map[int]bool
+}
+
+// tableRow is a row wrapped to the widths of its columns, with the height it needs
+type tableRow struct {
+	lines  [][]string
+	widths []float64
+	height float64
+	bold   bool
+}
+
+// drawTable renders the table from a new page, over as many pages as needed. It
+// returns the position below the last row.
+func drawTable(pdf *gopdf.GoPdf, t *table) (float64, error) {
+	return drawTableAt(pdf, t, pageHeight)
+}
+
+// drawTableAt renders the table below y on the current page, moving on to a new page
+// whenever the next row does not fit. New pages repeat the title and the header row.
+// It returns the position below the last row.
+func drawTableAt(pdf *gopdf.GoPdf, t *table, y float64) (float64, error) {
+	var tableWidth float64
+
+	for _, width := range t.widths {
+		tableWidth += width
+	}
+
+	header, err := measureRow(pdf, t.headers, t.widths, true)
+
+	if err != nil {
+		return y, err
+	}
+
+	rows := make([]*tableRow, len(t.rows))
+
+	for i, cells := range t.rows {
+		widths := t.widths
+
+		if t.groups[i] {
+			widths = []float64{tableWidth}
+			cells = cells[:min(len(cells), 1)]
+		}
+
+		if rows[i], err = measureRow(pdf, cells, widths, t.bold[i] || t.groups[i]); err != nil {
+			return y, err
+		}
+	}
+
+	newPage := func() error {
+		pdf.AddPage()
+
+		if err := pdf.SetFont("Roboto-Bold", "", 18); err != nil {
+			return err
+		}
+		pdf.SetX(40)
+		pdf.SetY(20)
+		pdf.Cell(nil, t.title)
+
+		if err := pdf.SetFont("Roboto", "", 11); err != nil {
+			return err
+		}
+		pdf.SetX(40)
+		pdf.SetY(48)
+		pdf.Cell(nil, t.subtitle)
+
+		y = topMargin
+
+		return nil
+	}
+
+	// the header goes where the table starts only if a row fits below it
+	needed := header.height
+
+	if len(rows) > 0 {
+		needed += keepTogether(rows, t.groups, 0)
+	}
+
+	if y+needed > pageHeight-bottomMargin-footerHeight {
+		if err = newPage(); err != nil {
+			return y, err
+		}
+	}
+
+	if err = drawCells(pdf, header, 40, y); err != nil {
+		return y, err
+	}
+	y += header.height
+
+	for i, row := range rows {
+		if y+keepTogether(rows, t.groups, i) > pageHeight-bottomMargin-footerHeight {
+			if err = newPage(); err != nil {
+				return y, err
+			}
+
+			if err = drawCells(pdf, header, 40, y); err != nil {
+				return y, err
+			}
+			y += header.height
+		}
+
+		if err = drawCells(pdf, row, 40, y); err != nil {
+			return y, err
+		}
+		y += row.height
+	}
+
+	return y, nil
+}
+
+// keepTogether is the height that has to fit on the page before row i is drawn: the
+// row itself, and the row after it when row i opens a group
+func keepTogether(rows []*tableRow, groups map[int]bool, i int) float64 {
+	height := rows[i].height
+
+	if groups[i] && i+1 < len(rows) {
+		height += rows[i+1].height
+	}
+
+	return height
+}
+
+// measureRow wraps each cell to the width of its column. Cells beyond the last
+// column are left out.
+func measureRow(pdf *gopdf.GoPdf, cells []string, widths []float64, bold bool) (*tableRow, error) {
+	if err := setCellFont(pdf, bold); err != nil {
+		return nil, err
+	}
+
+	cells = cells[:min(len(cells), len(widths))]
+	row := &tableRow{lines: make([][]string, len(cells)), widths: widths, height: rowHeight, bold: bold}
+
+	// a row never grows taller than a page can hold below the header
+	var available float64 = pageHeight - topMargin - bottomMargin - footerHeight - 2*rowHeight
+	maxLines := int(available/lineHeight) + 1
+
+	for i, cell := range cells {
+		lines, err := wrapText(pdf, cell, widths[i]-2*cellPadding)
+
+		if err != nil {
+			return nil, err
+		}
+
+		row.lines[i] = lines[:min(len(lines), maxLines)]
+		row.height = max(row.height, rowHeight+float64(len(row.lines[i])-1)*lineHeight)
+	}
+
+	return row, nil
+}
+
+// wrapText breaks text into lines no wider than width, between words where it can
+func wrapText(pdf *gopdf.GoPdf, text string, width float64) ([]string, error) {
+	if text == "" {
+		return []string{""}, nil
+	}
+
+	textWidth, err := pdf.MeasureTextWidth(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if width < minWrapWidth || (textWidth <= width && !strings.Contains(text, "\n")) {
+		return []string{text}, nil
+	}
+
+	return pdf.SplitTextWithWordWrap(text, width)
+}
+
+func drawCells(pdf *gopdf.GoPdf, row *tableRow, x, y float64) error {
+	if err := setCellFont(pdf, row.bold); err != nil {
+		return err
+	}
+
+	for i, lines := range row.lines {
+		pdf.RectFromUpperLeftWithStyle(x, y, row.widths[i], row.height, "D")
+
+		for l, line := range lines {
+			pdf.SetX(x + cellPadding)
+			pdf.SetY(y + 7 + float64(l)*lineHeight)
+			pdf.Cell(nil, line)
+		}
+
+		x += row.widths[i]
+	}
+
+	return nil
+}
+
+func setCellFont(pdf *gopdf.GoPdf, bold bool) error {
+	if bold {
+		return pdf.SetFont("Roboto-Bold", "", 10)
+	}
+
+	return pdf.SetFont("Roboto", "", 10)
+}
+
+// writePdf numbers the pages of a finished document and writes it out. The footers
+// go on last, once the number of pages is known.
+func writePdf(pdf *gopdf.GoPdf, p *i18n.Printer) ([]byte, error) {
+	pages := pdf.GetNumberOfPages()
+	generated := p.T("Generated on %s", p.DateTime(time.Now()))
+
+	for page := 1; page <= pages; page++ {
+		if err := pdf.SetPage(page); err != nil {
+			return nil, err
+		}
+
+		if err := pdf.SetFont("Roboto", "", 10); err != nil {
+			return nil, err
+		}
+
+		pdf.SetX(40)
+		pdf.SetY(pageHeight - bottomMargin + 10)
+		pdf.Cell(nil, p.T("Page %d of %d", page, pages))
+
+		width, err := pdf.MeasureTextWidth(generated)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pdf.SetX(555 - width)
+		pdf.SetY(pageHeight - bottomMargin + 10)
+		pdf.Cell(nil, generated)
+	}
+
+	var buf bytes.Buffer
+	_, err := pdf.WriteTo(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// shortDate writes a date, YYYY-MM-DD with or without a time, the way the locale
+// writes dates in a table. Text that is not a date is kept as it is.
+func shortDate(p *i18n.Printer, date string) string {
+	t, err := time.Parse("2006-01-02", strings.Split(date, "T")[0])
+
+	if err != nil {
+		return date
+	}
+
+	return p.ShortDate(t)
+}
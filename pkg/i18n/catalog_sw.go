@@ -0,0 +1,317 @@
+package i18n
+
+// swahili translates the reports, emails and API responses into Swahili
+var swahili = map[string]string{
+	// months
+	"January":   "Januari",
+	"February":  "Februari",
+	"March":     "Machi",
+	"April":     "Aprili",
+	"May":       "Mei",
+	"June":      "Juni",
+	"July":      "Julai",
+	"August":    "Agosti",
+	"September": "Septemba",
+	"October":   "Oktoba",
+	"November":  "Novemba",
+	"December":  "Desemba",
+	"Jan":       "Jan",
+	"Feb":       "Feb",
+	"Mar":       "Mac",
+	"Apr":       "Apr",
+	"Jun":       "Jun",
+	"Jul":       "Jul",
+	"Aug":       "Ago",
+	"Sep":       "Sep",
+	"Oct":       "Okt",
+	"Nov":       "Nov",
+	"Dec":       "Des",
+
+	// report titles and headings
+	"KCSDA Contributions Report":              "Ripoti ya Michango ya KCSDA",
+	"KCSDA Expenditure Report":                "Ripoti ya Matumizi ya KCSDA",
+	"Consolidated summary":                    "Muhtasari wa jumla",
+	"Consolidated summary from: %s to: %s":    "Muhtasari wa jumla kuanzia: %s hadi: %s",
+	"Consolidated summary for: %s":            "Muhtasari wa jumla wa: %s",
+	"Details of Contributions":                "Maelezo ya Michango",
+	"Receipts by Sabbath":                     "Mapokezi kwa Sabato",
+	"Receipts by month":                       "Mapokezi kwa mwezi",
+	"Disbursements":                           "Malipo Yaliyotolewa",
+	"Church Treasurer's Cash Statement":       "Taarifa ya Fedha ya Mweka Hazina wa Kanisa",
+	"Statement of Income and Expenditure":     "Taarifa ya Mapato na Matumizi",
+	"Fund Balance Sheet":                      "Mizania ya Mifuko",
+	"Trial Balance":                           "Mizani ya Majaribio",
+	"Budget vs Actual":                        "Bajeti dhidi ya Halisi",
+	"Budget vs Actual %d":                     "Bajeti dhidi ya Halisi %d",
+	"Fiscal year %d to end of %s":             "Mwaka wa fedha %d hadi mwisho wa %s",
+	"Year to date, end of %s":                 "Mwaka hadi sasa, mwisho wa %s",
+	"Annual Report %d":                        "Ripoti ya Mwaka %d",
+	"Annual Report %d: Key Figures":           "Ripoti ya Mwaka %d: Takwimu Muhimu",
+	"Annual Report %d: Receipts and Payments": "Ripoti ya Mwaka %d: Mapokezi na Malipo",
+	"Annual Report %d: %d compared with %d":   "Ripoti ya Mwaka %d: %d ikilinganishwa na %d",
+	"Covering %s":                             "Inayohusu %s",
+	"Contents":                                "Yaliyomo",
+	"Key figures":                             "Takwimu muhimu",
+	"Key figures for %s":                      "Takwimu muhimu za %s",
+	"Receipts and payments by month":          "Mapokezi na malipo kwa mwezi",
+	"%d compared with %d":                     "%d ikilinganishwa na %d",
+	"%d compared with %d, %s":                 "%d ikilinganishwa na %d, %s",
+	"Cash statement for %s %d":                "Taarifa ya fedha ya %s %d",
+	"Year Summary":                            "Muhtasari wa Mwaka",
+	"Year summary":                            "Muhtasari wa mwaka",
+	"Year on Year":                            "Mwaka kwa Mwaka",
+	"the year %d":                             "mwaka %d",
+	"January to %s %d":                        "Januari hadi %s %d",
+	"Expenditures":                            "Matumizi",
+	"Expenditures from: %s to: %s":            "Matumizi kuanzia: %s hadi: %s",
+	"Expenditures for: %s":                    "Matumizi ya: %s",
+	"Share of contributions by category":      "Mgawanyo wa michango kwa aina",
+	"Contributions per sabbath":               "Michango kwa kila Sabato",
+	"Monthly trend by category":               "Mwenendo wa kila mwezi kwa aina",
+	"Contributions":                           "Michango",
+	"Cash Statement":                          "Taarifa ya Fedha",
+	"Fund Balances":                           "Salio za Mifuko",
+	"Budgets":                                 "Bajeti",
+	"Fiscal year %d":                          "Mwaka wa fedha %d",
+	"Monthly Statistics":                      "Takwimu za Mwezi",
+	"Monthly Variance":                        "Tofauti ya Mwezi",
+	"Chart of Accounts":                       "Orodha ya Akaunti",
+	"Journal":                                 "Jarida",
+	"Income and Expenditure":                  "Mapato na Matumizi",
+	"Imports":                                 "Uingizaji",
+
+	// periods
+	"For the period %s to %s":                       "Kwa kipindi cha %s hadi %s",
+	"For the period %s to %s, comparative %s to %s": "Kwa kipindi cha %s hadi %s, ikilinganishwa na %s hadi %s",
+	"As at %s":                       "Kufikia %s",
+	"As at %s, comparative as at %s": "Kufikia %s, ikilinganishwa na %s",
+	"From %s":                        "Kuanzia %s",
+	"To %s":                          "Hadi %s",
+	"%s to %s":                       "%s hadi %s",
+
+	// columns and rows
+	"Fund Category":         "Aina ya Mfuko",
+	"Amount (%s)":           "Kiasi (%s)",
+	"Amount":                "Kiasi",
+	"Total":                 "Jumla",
+	"Name":                  "Jina",
+	"Receipt":               "Risiti",
+	"Receipt No":            "Nambari ya Risiti",
+	"Breakdown":             "Mchanganuo",
+	"Sabbath":               "Sabato",
+	"Sabbath %s":            "Sabato %s",
+	"Sabbath total":         "Jumla ya Sabato",
+	"Category":              "Aina",
+	"Budget":                "Bajeti",
+	"Annual budget":         "Bajeti ya mwaka",
+	"Target":                "Lengo",
+	"Target to date":        "Lengo hadi sasa",
+	"Actual":                "Halisi",
+	"Variance":              "Tofauti",
+	"Variance %":            "Tofauti %",
+	"Status":                "Hali",
+	"On track":              "Inaendelea vyema",
+	"Below target":          "Chini ya lengo",
+	"Date":                  "Tarehe",
+	"Voucher":               "Hati ya Malipo",
+	"Voucher No":            "Nambari ya Hati ya Malipo",
+	"Payee":                 "Mlipwaji",
+	"Payment Method":        "Njia ya Malipo",
+	"Description":           "Maelezo",
+	"Approved":              "Imeidhinishwa",
+	"Yes":                   "Ndiyo",
+	"No":                    "Hapana",
+	"Fund":                  "Mfuko",
+	"Balance B/F":           "Salio Lililoletwa",
+	"Balance C/F":           "Salio Linalopelekwa",
+	"Receipts":              "Mapokezi",
+	"Payments":              "Malipo",
+	"Less payments":         "Toa malipo",
+	"Transfers":             "Uhamisho",
+	"Code":                  "Msimbo",
+	"Account":               "Akaunti",
+	"Type":                  "Aina ya Akaunti",
+	"Debit":                 "Debiti",
+	"Credit":                "Krediti",
+	"Prior Debit":           "Debiti ya Awali",
+	"Prior Credit":          "Krediti ya Awali",
+	"Prior Period":          "Kipindi cha Awali",
+	"Opening":               "Salio la Mwanzo",
+	"Opening balance":       "Salio la mwanzo",
+	"Closing":               "Salio la Mwisho",
+	"Closing balance":       "Salio la mwisho",
+	"Prior Closing":         "Salio la Mwisho la Awali",
+	"Measure":               "Kipimo",
+	"Value":                 "Thamani",
+	"Unit":                  "Kizio",
+	"Year":                  "Mwaka",
+	"Change":                "Mabadiliko",
+	"Change %":              "Mabadiliko %",
+	"Month":                 "Mwezi",
+	"Income":                "Mapato",
+	"Expenditure":           "Matumizi",
+	"Total income":          "Jumla ya mapato",
+	"Total expenditure":     "Jumla ya matumizi",
+	"Total expenditure: %s": "Jumla ya matumizi: %s",
+	"Surplus / (deficit)":   "Ziada / (upungufu)",
+	"Total receipts":        "Jumla ya mapokezi",
+	"Balance":               "Salio",
+	"Annual Budget":         "Bajeti ya Mwaka",
+	"Alert Threshold":       "Kiwango cha Tahadhari",
+	"Alert":                 "Tahadhari",
+	"Percentage":            "Asilimia",
+	"Current Value":         "Thamani ya Sasa",
+	"Direction":             "Mwelekeo",
+	"Entry":                 "Ingizo",
+	"Source":                "Chanzo",
+	"Reference":             "Marejeo",
+	"Narration":             "Maelezo",
+	"Account Code":          "Msimbo wa Akaunti",
+	"Account Type":          "Aina ya Akaunti",
+	"Section":               "Sehemu",
+	"Prior Amount":          "Kiasi cha Awali",
+	"Surplus":               "Ziada",
+	"Surplus (deficit)":     "Ziada (upungufu)",
+	"File":                  "Faili",
+	"Version":               "Toleo",
+	"Rows Read":             "Safu Zilizosomwa",
+	"Rows Inserted":         "Safu Zilizoongezwa",
+	"Rows Updated":          "Safu Zilizosasishwa",
+	"Rows Skipped":          "Safu Zilizorukwa",
+	"Created At":            "Iliundwa",
+	"Finished At":           "Ilikamilika",
+
+	// key figures of the annual report
+	"Total payments":                 "Jumla ya malipo",
+	"Surplus for the year":           "Ziada ya mwaka",
+	"Change in receipts on %d":       "Mabadiliko ya mapokezi ukilinganisha na %d",
+	"Balance brought forward":        "Salio lililoletwa",
+	"Balance carried forward":        "Salio linalopelekwa mbele",
+	"Contributions received":         "Michango iliyopokelewa",
+	"Contributors":                   "Wachangiaji",
+	"Average receipts per sabbath":   "Wastani wa mapokezi kwa kila Sabato",
+	"Highest month (%s)":             "Mwezi wa juu zaidi (%s)",
+	"Share of the largest fund (%s)": "Sehemu ya mfuko mkubwa zaidi (%s)",
+
+	// notes, signatures and footers
+	"Categories in red are tracking below their alert threshold": "Aina zilizo kwa rangi nyekundu ziko chini ya kiwango chao cha tahadhari",
+	"Pending approval":          "Inasubiri idhini",
+	"Approved on %s":            "Iliidhinishwa tarehe %s",
+	"Prepared by (Treasurer)":   "Imetayarishwa na (Mweka Hazina)",
+	"Checked by (Auditor)":      "Imekaguliwa na (Mkaguzi)",
+	"Approved by (Board Chair)": "Imeidhinishwa na (Mwenyekiti wa Bodi)",
+	"Name: ______________________   Signature: ________________   Date: ____________": "Jina: ______________________   Sahihi: ________________   Tarehe: ____________",
+	"Name:":           "Jina:",
+	"Signature:":      "Sahihi:",
+	"Date:":           "Tarehe:",
+	"Generated on %s": "Imetolewa tarehe %s",
+	"Page %d of %d":   "Ukurasa %d kati ya %d",
+
+	// API responses
+	"path parameter must be a positive INTEGER":    "kigezo cha njia lazima kiwe NAMBA KAMILI chanya",
+	"record not found":                             "rekodi haikupatikana",
+	"report not found":                             "ripoti haikupatikana",
+	"report subscription not found":                "usajili wa ripoti haukupatikana",
+	"report delivery not found":                    "uwasilishaji wa ripoti haukupatikana",
+	"report is not available for download":         "ripoti haipatikani kwa kupakuliwa",
+	"import not found":                             "uingizaji haukupatikana",
+	"import profile not found":                     "wasifu wa uingizaji haukupatikana",
+	"job not found":                                "kazi haikupatikana",
+	"file not found":                               "faili halikupatikana",
+	"file not found or job not finished":           "faili halikupatikana au kazi haijakamilika",
+	"preview not found or expired":                 "hakikisho halikupatikana au limeisha muda",
+	"invalid or missing authentication token":      "tokeni ya uthibitishaji si sahihi au haipo",
+	"unauthorized":                                 "hujaruhusiwa",
+	"Unauthorized":                                 "Hujaruhusiwa",
+	"unverified user":                              "mtumiaji hajathibitishwa",
+	"user already exists":                          "mtumiaji tayari yupo",
+	"link has expired":                             "kiungo kimeisha muda",
+	"invalid signature":                            "sahihi si sahihi",
+	"body must not be empty":                       "mwili wa ombi haupaswi kuwa tupu",
+	"body contains badly-formed JSON":              "mwili wa ombi una JSON isiyo sahihi",
+	"email parameter missing":                      "kigezo cha barua pepe hakipo",
+	"missing sessionId and/or otp parameter":       "sessionId na/au otp haipo",
+	"missing from request param":                   "kigezo cha from hakipo",
+	"to date must not be before from date":         "tarehe ya mwisho haipaswi kuwa kabla ya tarehe ya mwanzo",
+	"date must be in the format YYYY-MM-DD":        "tarehe lazima iwe katika muundo YYYY-MM-DD",
+	"from must be a date in the format YYYY-MM-DD": "from lazima iwe tarehe katika muundo YYYY-MM-DD",
+	"total and break down items dont tally":        "jumla na mchanganuo havilingani",
+	"duplicate contributions found":                "michango iliyorudiwa imepatikana",
+	"the period has been closed":                   "kipindi kimefungwa",
+	"unknown account":                              "akaunti haijulikani",
+	"unknown export format":                        "muundo wa kuhamisha haujulikani",
+	"reset link expired":                           "kiungo cha kuweka upya kimeisha muda",
+	"invalid reset token":                          "tokeni ya kuweka upya si sahihi",
+	"the server encountered a problem and could not process your request": "seva imekumbwa na tatizo na haikuweza kushughulikia ombi lako",
+
+	// confirmations
+	"budget saved":                           "bajeti imehifadhiwa",
+	"successfully added expenditure":         "matumizi yameongezwa",
+	"successfully added contribution":        "mchango umeongezwa",
+	"updated successfully":                   "imesasishwa",
+	"expenditure approved":                   "matumizi yameidhinishwa",
+	"document uploaded successfully":         "hati imepakiwa",
+	"report queued":                          "ripoti imepangwa kutayarishwa",
+	"file queued for import":                 "faili limepangwa kuingizwa",
+	"import rolled back":                     "uingizaji umerudishwa nyuma",
+	"import replaced":                        "uingizaji umebadilishwa",
+	"job queued":                             "kazi imepangwa",
+	"account created":                        "akaunti imeundwa",
+	"entry reversed":                         "ingizo limegeuzwa",
+	"transfer posted":                        "uhamisho umerekodiwa",
+	"ledger backfilled":                      "leja imejazwa",
+	"period closed":                          "kipindi kimefungwa",
+	"account mapping saved":                  "uhusiano wa akaunti umehifadhiwa",
+	"account mapping deleted":                "uhusiano wa akaunti umefutwa",
+	"successfully saved import profile":      "wasifu wa uingizaji umehifadhiwa",
+	"successfully saved report subscription": "usajili wa ripoti umehifadhiwa",
+	"locale updated":                         "lugha imesasishwa",
+	"password changed successfully":          "nenosiri limebadilishwa",
+	"reset link sent to email":               "kiungo cha kuweka upya kimetumwa kwa barua pepe",
+	"invalid otp":                            "otp si sahihi",
+	"success":                                "imefanikiwa",
+
+	// validation errors
+	"must be provided": "lazima itolewe",
+	"must be provided when there are no search terms":           "lazima itolewe wakati hakuna maneno ya kutafuta",
+	"must be a valid date in the format YYYY-MM-DD":             "lazima iwe tarehe sahihi katika muundo YYYY-MM-DD",
+	"must be a column letter":                                   "lazima iwe herufi ya safu",
+	"must be a column letter when dates are read from a column": "lazima iwe herufi ya safu wakati tarehe zinasomwa kutoka safu",
+	"must be greater than zero":                                 "lazima iwe zaidi ya sifuri",
+	"must be a valid year":                                      "lazima uwe mwaka sahihi",
+	"must be a year from 2000 to the current year":              "lazima uwe mwaka kuanzia 2000 hadi mwaka huu",
+	"must not be negative":                                      "haipaswi kuwa hasi",
+	"must not be before from date":                              "haipaswi kuwa kabla ya tarehe ya mwanzo",
+	"must not be more than 50 characters":                       "haipaswi kuzidi herufi 50",
+	"must not be more than 255 characters":                      "haipaswi kuzidi herufi 255",
+	"must not be more than 72 bytes long":                       "haipaswi kuzidi baiti 72",
+	"must be at least 8 bytes long":                             "lazima iwe na angalau baiti 8",
+	"must be a valid email address":                             "lazima iwe anwani sahihi ya barua pepe",
+	"must be valid email addresses":                             "lazima ziwe anwani sahihi za barua pepe",
+	"must have at least one email address":                      "lazima iwe na angalau anwani moja ya barua pepe",
+	"must not have more than 50 email addresses":                "haipaswi kuwa na zaidi ya anwani 50 za barua pepe",
+	"must not contain duplicate email addresses":                "haipaswi kuwa na anwani za barua pepe zilizorudiwa",
+	"must be a valid token":                                     "lazima iwe tokeni sahihi",
+	"must be words and spaces only with a max length of 50":     "lazima iwe maneno na nafasi tu, zisizozidi herufi 50",
+	"must be between 1 and 12":                                  "lazima iwe kati ya 1 na 12",
+	"must be between 0 and 100":                                 "lazima iwe kati ya 0 na 100",
+	"must be 1 or greater":                                      "lazima iwe 1 au zaidi",
+	"must add up to the budget amount":                          "lazima ijumlishe kiasi cha bajeti",
+	"must have an amount for each of the 12 months":             "lazima iwe na kiasi kwa kila mwezi kati ya miezi 12",
+	"must have at least one date format":                        "lazima iwe na angalau muundo mmoja wa tarehe",
+	"must be different from the source account":                 "lazima iwe tofauti na akaunti ya chanzo",
+	"must be CASH or BANK for an asset account":                 "lazima iwe CASH au BANK kwa akaunti ya mali",
+}
+
+// swahiliPrefixes translate the start of messages that end in a list or a value of
+// their own
+var swahiliPrefixes = [][2]string{
+	{"must be one of ", "lazima iwe mojawapo ya "},
+	{"must be a list of ", "lazima iwe orodha ya "},
+	{"format must be one of ", "muundo lazima uwe mojawapo ya "},
+	{"missing file or field name ", "faili au jina la sehemu halipo "},
+	{"file must not be larger than ", "faili halipaswi kuzidi "},
+	{"body contains incorrect JSON type for field ", "mwili wa ombi una aina ya JSON isiyo sahihi kwa sehemu "},
+	{"body contains badly-formed JSON (at character ", "mwili wa ombi una JSON isiyo sahihi (kwenye herufi "},
+	{"body contains unknown key ", "mwili wa ombi una ufunguo usiojulikana "},
+}
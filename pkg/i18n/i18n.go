@@ -0,0 +1,210 @@
+// Package i18n writes the text of the reports, emails and API responses in the
+// language of the organization or user they are for. The English text is the key of
+// every message; a language without a translation of a message shows it in English.
+package i18n
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+const (
+	English = "en"
+	Swahili = "sw"
+)
+
+// DefaultLocale is the locale of organizations that have not chosen one
+const DefaultLocale = English
+
+// Locales lists the locales there is a catalog for
+var Locales = []string{English, Swahili}
+
+// Currency is the currency of every amount
+var Currency = currency.MustParseISO("KES")
+
+// catalogs hold the translations of each language other than English
+var catalogs = map[string]map[string]string{
+	Swahili: swahili,
+}
+
+// prefixes translate messages that end in a list or a value, such as validation
+// errors naming the values allowed. The longest prefix that matches is used.
+var prefixes = map[string][][2]string{
+	Swahili: swahiliPrefixes,
+}
+
+var tags = map[string]language.Tag{
+	English: language.English,
+	Swahili: language.Swahili,
+}
+
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Swahili})
+
+// Printer writes messages, numbers, amounts and dates in one locale
+type Printer struct {
+	locale  string
+	numbers *message.Printer
+}
+
+var printers = func() map[string]*Printer {
+	printers := make(map[string]*Printer, len(Locales))
+
+	for _, locale := range Locales {
+		printers[locale] = &Printer{locale: locale, numbers: message.NewPrinter(tags[locale])}
+	}
+
+	return printers
+}()
+
+// For returns the printer of a locale, or of the default locale when there is no
+// catalog for it
+func For(locale string) *Printer {
+	if normalized, ok := Normalize(locale); ok {
+		return printers[normalized]
+	}
+
+	return printers[DefaultLocale]
+}
+
+// Normalize reduces a locale such as sw-KE to the locale of its catalog, and reports
+// whether there is one
+func Normalize(locale string) (string, bool) {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+
+	if base, _, found := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-"); found {
+		locale = base
+	}
+
+	return locale, slices.Contains(Locales, locale)
+}
+
+// Match picks the locale of an Accept-Language header, the default locale when it
+// names none there is a catalog for
+func Match(acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return DefaultLocale
+	}
+
+	preferred, _, err := language.ParseAcceptLanguage(acceptLanguage)
+
+	if err != nil || len(preferred) == 0 {
+		return DefaultLocale
+	}
+
+	_, index, confidence := matcher.Match(preferred...)
+
+	if confidence == language.No {
+		return DefaultLocale
+	}
+
+	return Locales[index]
+}
+
+func (p *Printer) Locale() string {
+	return p.locale
+}
+
+// T translates a message and formats its arguments the way Sprintf does, with dates,
+// months and amounts written the way the locale writes them
+func (p *Printer) T(key string, args ...any) string {
+	text := key
+
+	if translated, ok := catalogs[p.locale][key]; ok {
+		text = translated
+	}
+
+	if len(args) == 0 {
+		return text
+	}
+
+	values := make([]any, len(args))
+
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			values[i] = p.Date(v)
+		case time.Month:
+			values[i] = p.Month(v)
+		case float64:
+			values[i] = p.Number(v)
+		default:
+			values[i] = arg
+		}
+	}
+
+	return fmt.Sprintf(text, values...)
+}
+
+// Message translates a message that may end in a value of its own, such as an error.
+// Messages that are not in the catalog are returned as they are.
+func (p *Printer) Message(text string) string {
+	if translated, ok := catalogs[p.locale][text]; ok {
+		return translated
+	}
+
+	var match [2]string
+
+	for _, prefix := range prefixes[p.locale] {
+		if strings.HasPrefix(text, prefix[0]) && len(prefix[0]) > len(match[0]) {
+			match = prefix
+		}
+	}
+
+	if match[0] == "" {
+		return text
+	}
+
+	return match[1] + strings.TrimPrefix(text, match[0])
+}
+
+// Number writes a value with two decimals and the thousands grouped, e.g. 1,234.50
+func (p *Printer) Number(value float64) string {
+	return p.numbers.Sprint(number.Decimal(value, number.MinFractionDigits(2), number.MaxFractionDigits(2)))
+}
+
+// Percent writes a percentage with one decimal, e.g. 12.5%
+func (p *Printer) Percent(value float64) string {
+	return p.numbers.Sprint(number.Decimal(value, number.MinFractionDigits(1), number.MaxFractionDigits(1))) + "%"
+}
+
+// Amount writes a value in the currency, e.g. KES 1,234.50
+func (p *Printer) Amount(value float64) string {
+	return p.numbers.Sprint(currency.Symbol(Currency.Amount(value)))
+}
+
+// CurrencyCode is the code amounts are labelled with, e.g. in column headings
+func (p *Printer) CurrencyCode() string {
+	return Currency.String()
+}
+
+// Date writes a date in full, e.g. 4 January 2025
+func (p *Printer) Date(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), p.Month(t.Month()), t.Year())
+}
+
+// ShortDate writes a date day first, e.g. 04/01/2025, as both languages do in Kenya
+func (p *Printer) ShortDate(t time.Time) string {
+	return t.Format("02/01/2006")
+}
+
+// DateTime writes a date in full with the time of day
+func (p *Printer) DateTime(t time.Time) string {
+	return p.Date(t) + " " + t.Format("15:04")
+}
+
+// Month is the name of a month, e.g. January
+func (p *Printer) Month(month time.Month) string {
+	return p.T(month.String())
+}
+
+// MonthAbbr is the short name of a month, e.g. Jan
+func (p *Printer) MonthAbbr(month time.Month) string {
+	return p.T(month.String()[:3])
+}
@@ -0,0 +1,125 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		locale string
+		value  float64
+		want   string
+	}{
+		{locale: English, value: 0, want: "KES 0.00"},
+		{locale: English, value: 999.5, want: "KES 999.50"},
+		{locale: English, value: 1234.5, want: "KES 1,234.50"},
+		{locale: English, value: 1234567.891, want: "KES 1,234,567.89"},
+		{locale: English, value: -1500, want: "KES -1,500.00"},
+		{locale: Swahili, value: 1234.5, want: "Ksh 1,234.50"},
+		{locale: Swahili, value: 1234567.891, want: "Ksh 1,234,567.89"},
+		{locale: "sw-KE", value: 1000, want: "Ksh 1,000.00"},
+		{locale: "fr", value: 1000, want: "KES 1,000.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+"/"+tt.want, func(t *testing.T) {
+			if got := For(tt.locale).Amount(tt.value); got != tt.want {
+				t.Fatalf("Amount(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		locale string
+		value  float64
+		want   string
+	}{
+		{locale: English, value: 0, want: "0.00"},
+		{locale: English, value: 12.345, want: "12.35"},
+		{locale: English, value: 1234.5, want: "1,234.50"},
+		{locale: English, value: 12345678, want: "12,345,678.00"},
+		{locale: Swahili, value: 1234.5, want: "1,234.50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+"/"+tt.want, func(t *testing.T) {
+			if got := For(tt.locale).Number(tt.value); got != tt.want {
+				t.Fatalf("Number(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+		wantOk bool
+	}{
+		{locale: "en", want: English, wantOk: true},
+		{locale: "sw-KE", want: Swahili, wantOk: true},
+		{locale: " SW_ke ", want: Swahili, wantOk: true},
+		{locale: "fr-FR", want: "fr"},
+		{locale: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			got, ok := Normalize(tt.locale)
+
+			if got != tt.want || ok != tt.wantOk {
+				t.Fatalf("Normalize(%q) = %q, %v, want %q, %v", tt.locale, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{acceptLanguage: "", want: English},
+		{acceptLanguage: "sw", want: Swahili},
+		{acceptLanguage: "sw-KE,sw;q=0.9,en;q=0.8", want: Swahili},
+		{acceptLanguage: "en;q=0.5, sw;q=0.9", want: Swahili},
+		{acceptLanguage: "fr-FR", want: English},
+		{acceptLanguage: "not a language;;", want: English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			if got := Match(tt.acceptLanguage); got != tt.want {
+				t.Fatalf("Match(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	date := time.Date(2025, time.January, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale string
+		key    string
+		args   []any
+		want   string
+	}{
+		{locale: English, key: "January", want: "January"},
+		{locale: Swahili, key: "January", want: "Januari"},
+		{locale: Swahili, key: "January to %s %d", args: []any{time.March, 2025}, want: "Januari hadi Machi 2025"},
+		{locale: English, key: "Total %s", args: []any{1234.5}, want: "Total 1,234.50"},
+		{locale: Swahili, key: "not in the catalog %s", args: []any{date}, want: "not in the catalog 4 Januari 2025"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+"/"+tt.key, func(t *testing.T) {
+			if got := For(tt.locale).T(tt.key, tt.args...); got != tt.want {
+				t.Fatalf("T(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package csv
+
+import (
+	"bytes"
+	encsv "encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/VaudKK/CAS/pkg/imports"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// sniffSize is how much of a file is read to detect its encoding and delimiter
+const sniffSize = 64 << 10
+
+// delimiters are the separators tried when detecting the delimiter of a file
+var delimiters = []rune{',', ';', '\t', '|'}
+
+// CsvImport reads contributions from a delimited text file. The file is read as a
+// single sheet named SheetName.
+type CsvImport struct {
+	// Profile describes the layout of the file, the default layout is used when it is nil
+	Profile *imports.Profile
+	// SheetName stands in for the sheet name. It is the file name, which
+	// importers.Detect requires to be a date when dates come from sheet names.
+	SheetName string
+
+	progress *imports.Progress
+}
+
+func (csvImport *CsvImport) Import(file io.ReaderAt, size int64, emit func(imports.ImportModel) error) ([]string, error) {
+	parser, err := imports.NewParser(csvImport.Profile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	csvImport.progress = &parser.Progress
+
+	if !parser.BeginSheet(csvImport.SheetName) {
+		return parser.Categories(), nil
+	}
+
+	text, err := decode(file, size)
+
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(text, head)
+
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	sample := head[:n]
+
+	// a line cut off by the sample would have fewer delimiters than the others
+	if i := bytes.LastIndexByte(sample, '\n'); n == sniffSize && i > 0 {
+		sample = sample[:i]
+	}
+
+	reader := encsv.NewReader(io.MultiReader(bytes.NewReader(head[:n]), text))
+	reader.Comma = detectDelimiter(string(sample))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	for number := 1; ; number++ {
+		row, err := reader.Read()
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+
+		if model := parser.Row(number, row); model != nil {
+			if err = emit(*model); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return parser.Categories(), nil
+}
+
+func (csvImport *CsvImport) Progress() *imports.Progress {
+	if csvImport.progress == nil {
+		return &imports.Progress{}
+	}
+	return csvImport.progress
+}
+
+// decode returns a reader converting the file to UTF-8. UTF-16 files are recognised by
+// their byte order mark and files whose first bytes are not valid UTF-8 are read as
+// Windows-1252, the encoding Excel uses when saving CSV on Windows.
+func decode(file io.ReaderAt, size int64) (io.Reader, error) {
+	content := io.NewSectionReader(file, 0, size)
+
+	head := make([]byte, sniffSize)
+	n, err := file.ReadAt(head, 0)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return io.NewSectionReader(file, 3, size-3), nil
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}), bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return transform.NewReader(content, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()), nil
+	case validUTF8(head, int64(n) < size):
+		return content, nil
+	default:
+		return transform.NewReader(content, charmap.Windows1252.NewDecoder()), nil
+	}
+}
+
+// validUTF8 checks the start of a file, allowing for a rune cut off at the end of
+// the sample
+func validUTF8(head []byte, truncated bool) bool {
+	for i := 0; truncated && i < utf8.UTFMax-1 && len(head) > 0; i++ {
+		if r, _ := utf8.DecodeLastRune(head); r != utf8.RuneError {
+			break
+		}
+		head = head[:len(head)-1]
+	}
+
+	return utf8.Valid(head)
+}
+
+// detectDelimiter picks the separator found most often on every one of the first
+// lines of the file. Commas are assumed when no separator is found on every line.
+func detectDelimiter(text string) rune {
+	lines := make([]string, 0)
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+
+		if len(lines) == 10 {
+			break
+		}
+	}
+
+	best, bestCount := ',', 0
+
+	for _, delimiter := range delimiters {
+		count := -1
+
+		for _, line := range lines {
+			n := strings.Count(line, string(delimiter))
+
+			if count == -1 || n < count {
+				count = n
+			}
+		}
+
+		if count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,132 @@
+package csv
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/VaudKK/CAS/pkg/imports"
+)
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want rune
+	}{
+		{name: "commas", text: "NAME,RECEIPT,TOTAL\nJane,R1,100\n", want: ','},
+		{name: "semicolons", text: "NAME;RECEIPT;TOTAL\nJane;R1;1,5\n", want: ';'},
+		{name: "tabs", text: "NAME\tRECEIPT\tTOTAL\nJane\tR1\t100\n", want: '\t'},
+		{name: "pipes", text: "NAME|RECEIPT|TOTAL\nJane|R1|100\n", want: '|'},
+		{name: "commas in amounts of a semicolon file", text: "NAME;TOTAL\nJane;1,500,000\nPeter;2,000\n", want: ';'},
+		{name: "delimiter missing from a line", text: "NAME;RECEIPT\nJane,R1,100\n", want: ','},
+		{name: "blank lines are ignored", text: "NAME;TOTAL\n\n  \nJane;100\n", want: ';'},
+		{name: "no delimiter", text: "NAME\nJane\n", want: ','},
+		{name: "empty", text: "", want: ','},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectDelimiter(tt.text); got != tt.want {
+				t.Fatalf("detectDelimiter(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{name: "utf-8", content: []byte("Wanjirũ,100"), want: "Wanjirũ,100"},
+		{name: "utf-8 with a byte order mark", content: []byte("\xEF\xBB\xBFWanjirũ,100"), want: "Wanjirũ,100"},
+		{name: "utf-16 little endian", content: []byte("\xFF\xFEA\x00,\x001\x00"), want: "A,1"},
+		{name: "utf-16 big endian", content: []byte("\xFE\xFF\x00A\x00,\x001"), want: "A,1"},
+		{name: "windows-1252", content: []byte("Ren\xE9e,\x80100"), want: "Renée,€100"},
+		{name: "empty", content: []byte{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := decode(bytes.NewReader(tt.content), int64(len(tt.content)))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := io.ReadAll(reader)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Fatalf("decode(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidUTF8(t *testing.T) {
+	euro := []byte("€")
+
+	tests := []struct {
+		name      string
+		head      []byte
+		truncated bool
+		want      bool
+	}{
+		{name: "ascii", head: []byte("abc"), want: true},
+		{name: "whole rune", head: append([]byte("a"), euro...), want: true},
+		{name: "rune cut off by the sample", head: append([]byte("a"), euro[:2]...), truncated: true, want: true},
+		{name: "rune cut off at the end of the file", head: append([]byte("a"), euro[:2]...)},
+		{name: "windows-1252", head: []byte("Ren\xE9e"), truncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validUTF8(tt.head, tt.truncated); got != tt.want {
+				t.Fatalf("validUTF8(%q, %v) = %v, want %v", tt.head, tt.truncated, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	content := "\xEF\xBB\xBFNAME;RECEIPT;TOTAL;TITHE;OFFERING\n" +
+		" Jane Wanjiru ;R1;1 500;1 000;500\n" +
+		"\"Otieno; Peter\";R2;200;;200\n" +
+		"TOTAL;;1700;1000;700\n"
+
+	importer := &CsvImport{SheetName: "3rd Mar 2024"}
+
+	rows, categories, err := imports.ReadAll(importer, strings.NewReader(content), int64(len(content)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"TITHE", "OFFERING"}; !reflect.DeepEqual(categories, want) {
+		t.Fatalf("categories = %v, want %v", categories, want)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("read %d rows, want 2", len(rows))
+	}
+
+	if rows[0].Name != "Jane Wanjiru" || rows[0].Total != 1500 || rows[0].Date.Format("2006-01-02") != "2024-03-03" {
+		t.Fatalf("first row = %+v", rows[0])
+	}
+
+	if rows[1].Name != "Otieno; Peter" || !reflect.DeepEqual(rows[1].BreakDown, map[string]float64{"OFFERING": 200}) {
+		t.Fatalf("second row = %+v", rows[1])
+	}
+
+	if progress := importer.Progress(); len(progress.Errors) != 0 || len(progress.Discrepancies) != 0 {
+		t.Fatalf("errors = %v, discrepancies = %v", progress.Errors, progress.Discrepancies)
+	}
+}
@@ -0,0 +1,174 @@
+package imports
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DiffRow is a contribution on either side of a comparison between two versions of
+// an import. ContributionId is set for contributions that have been saved, Sheet and
+// Row for rows read from the replacement file.
+type DiffRow struct {
+	ContributionId int                `json:"contributionId,omitempty"`
+	ReceiptNo      string             `json:"receiptNo"`
+	Contributor    string             `json:"contributor"`
+	Date           string             `json:"date"`
+	Total          float64            `json:"total"`
+	BreakDown      map[string]float64 `json:"breakDown"`
+	Sheet          string             `json:"sheet,omitempty"`
+	Row            int                `json:"row,omitempty"`
+}
+
+// Change is a contribution whose details differ between two versions
+type Change struct {
+	ReceiptNo string   `json:"receiptNo"`
+	Fields    []string `json:"fields"`
+	Before    DiffRow  `json:"before"`
+	After     DiffRow  `json:"after"`
+}
+
+// Diff lists what applying a replacement file would add, remove and change
+type Diff struct {
+	Added     []DiffRow `json:"added"`
+	Removed   []DiffRow `json:"removed"`
+	Changed   []Change  `json:"changed"`
+	Unchanged int       `json:"unchanged"`
+}
+
+// Replacement previews the delta a corrected file would apply to an earlier import.
+// The token confirms it; the delta is checked again when it is applied.
+type Replacement struct {
+	ImportId        int           `json:"importId"`
+	Token           string        `json:"previewToken"`
+	FileName        string        `json:"fileName"`
+	AlreadyImported bool          `json:"alreadyImported"`
+	RowsRead        int           `json:"rowsRead"`
+	NewCategories   []string      `json:"newCategories"`
+	Discrepancies   []Discrepancy `json:"discrepancies"`
+	Errors          []RowError    `json:"errors"`
+	Diff
+}
+
+// Compare matches the saved contributions of an import with the rows of its
+// replacement by receipt number. Saved contributions always have one, generated when
+// the file left it blank, so rows without a receipt number are matched on
+// contributor, date and total instead.
+func Compare(saved, replacement []DiffRow) *Diff {
+	diff := &Diff{Added: []DiffRow{}, Removed: []DiffRow{}, Changed: []Change{}}
+
+	byReceipt := make(map[string][]int)
+	byDetails := make(map[string][]int)
+	matched := make([]bool, len(saved))
+
+	for i, row := range saved {
+		byReceipt[row.ReceiptNo] = append(byReceipt[row.ReceiptNo], i)
+		byDetails[detailsKey(row)] = append(byDetails[detailsKey(row)], i)
+	}
+
+	next := func(candidates []int) int {
+		for _, i := range candidates {
+			if !matched[i] {
+				matched[i] = true
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, row := range replacement {
+		var match int
+
+		if row.ReceiptNo != "" {
+			match = next(byReceipt[row.ReceiptNo])
+		} else {
+			match = next(byDetails[detailsKey(row)])
+		}
+
+		if match < 0 {
+			diff.Added = append(diff.Added, row)
+			continue
+		}
+
+		before := saved[match]
+
+		fields := changedFields(before, row)
+
+		if len(fields) == 0 {
+			diff.Unchanged++
+			continue
+		}
+
+		row.ContributionId = before.ContributionId
+		diff.Changed = append(diff.Changed, Change{ReceiptNo: before.ReceiptNo, Fields: fields, Before: before, After: row})
+	}
+
+	for i, row := range saved {
+		if !matched[i] {
+			diff.Removed = append(diff.Removed, row)
+		}
+	}
+
+	return diff
+}
+
+// NewDiffRow converts a row read from a file
+func NewDiffRow(row ImportModel) DiffRow {
+	return DiffRow{
+		ReceiptNo:   row.ReceiptNo,
+		Contributor: row.Name,
+		Date:        row.Date.Format("2006-01-02"),
+		Total:       row.Total,
+		BreakDown:   row.BreakDown,
+		Sheet:       row.Sheet,
+		Row:         row.Row,
+	}
+}
+
+// Empty reports whether applying the diff would change nothing
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func detailsKey(row DiffRow) string {
+	return fmt.Sprintf("%s|%s|%d", normalizeName(row.Contributor), row.Date, cents(row.Total))
+}
+
+func changedFields(before, after DiffRow) []string {
+	fields := make([]string, 0)
+
+	if normalizeName(before.Contributor) != normalizeName(after.Contributor) {
+		fields = append(fields, "contributor")
+	}
+
+	if before.Date != after.Date {
+		fields = append(fields, "date")
+	}
+
+	if cents(before.Total) != cents(after.Total) {
+		fields = append(fields, "total")
+	}
+
+	categories := slices.Sorted(maps.Keys(before.BreakDown))
+
+	for category := range after.BreakDown {
+		if _, ok := before.BreakDown[category]; !ok {
+			categories = append(categories, category)
+		}
+	}
+
+	for _, category := range categories {
+		if cents(before.BreakDown[category]) != cents(after.BreakDown[category]) {
+			fields = append(fields, "breakDown")
+			break
+		}
+	}
+
+	return fields
+}
+
+// normalizeName compares names the way they are saved, in upper case
+func normalizeName(name string) string {
+	return strings.ToUpper(strings.TrimSpace(name))
+}
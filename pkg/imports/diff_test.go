@@ -0,0 +1,156 @@
+package imports
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	jane := DiffRow{ContributionId: 1, ReceiptNo: "R1", Contributor: "JANE WANJIRU", Date: "2024-03-03", Total: 1500,
+		BreakDown: map[string]float64{"TITHE": 1000, "OFFERING": 500}}
+	peter := DiffRow{ContributionId: 2, ReceiptNo: "GEN-2", Contributor: "PETER OTIENO", Date: "2024-03-03", Total: 200,
+		BreakDown: map[string]float64{"OFFERING": 200}}
+
+	// with reads a saved contribution back from a replacement file, changed as given
+	with := func(row DiffRow, change func(row *DiffRow)) DiffRow {
+		row.ContributionId = 0
+		row.BreakDown = maps.Clone(row.BreakDown)
+		change(&row)
+		return row
+	}
+
+	unchanged := func(row *DiffRow) {}
+
+	tests := []struct {
+		name          string
+		saved         []DiffRow
+		replacement   []DiffRow
+		wantAdded     int
+		wantRemoved   int
+		wantChanged   [][]string
+		wantUnchanged int
+	}{
+		{
+			name:          "same rows",
+			saved:         []DiffRow{jane, peter},
+			replacement:   []DiffRow{with(jane, unchanged), with(peter, unchanged)},
+			wantUnchanged: 2,
+		},
+		{
+			name:          "names compared the way they are saved",
+			saved:         []DiffRow{jane},
+			replacement:   []DiffRow{with(jane, func(row *DiffRow) { row.Contributor = " Jane Wanjiru " })},
+			wantUnchanged: 1,
+		},
+		{
+			name:          "amounts compared in whole cents",
+			saved:         []DiffRow{jane},
+			replacement:   []DiffRow{with(jane, func(row *DiffRow) { row.Total = 1500.001; row.BreakDown["TITHE"] = 1000.004 })},
+			wantUnchanged: 1,
+		},
+		{
+			name:        "changed total",
+			saved:       []DiffRow{jane},
+			replacement: []DiffRow{with(jane, func(row *DiffRow) { row.Total = 1600 })},
+			wantChanged: [][]string{{"total"}},
+		},
+		{
+			name:  "changed details",
+			saved: []DiffRow{jane},
+			replacement: []DiffRow{with(jane, func(row *DiffRow) {
+				row.Contributor = "Jane Njeri"
+				row.Date = "2024-03-10"
+				row.BreakDown["BUILDING"] = 100
+			})},
+			wantChanged: [][]string{{"contributor", "date", "breakDown"}},
+		},
+		{
+			name:        "category left out of the replacement",
+			saved:       []DiffRow{jane},
+			replacement: []DiffRow{with(jane, func(row *DiffRow) { delete(row.BreakDown, "OFFERING") })},
+			wantChanged: [][]string{{"breakDown"}},
+		},
+		{
+			name:          "added and removed rows",
+			saved:         []DiffRow{jane, peter},
+			replacement:   []DiffRow{with(jane, unchanged), with(jane, func(row *DiffRow) { row.ReceiptNo = "R3" })},
+			wantAdded:     1,
+			wantRemoved:   1,
+			wantUnchanged: 1,
+		},
+		{
+			name:          "row without a receipt number matched on its details",
+			saved:         []DiffRow{peter},
+			replacement:   []DiffRow{with(peter, func(row *DiffRow) { row.ReceiptNo = ""; row.Contributor = "Peter Otieno" })},
+			wantUnchanged: 1,
+		},
+		{
+			name:        "row without a receipt number and other details",
+			saved:       []DiffRow{peter},
+			replacement: []DiffRow{with(peter, func(row *DiffRow) { row.ReceiptNo = ""; row.Total = 250 })},
+			wantAdded:   1,
+			wantRemoved: 1,
+		},
+		{
+			name:        "row with another receipt number is not matched on its details",
+			saved:       []DiffRow{peter},
+			replacement: []DiffRow{with(peter, func(row *DiffRow) { row.ReceiptNo = "R9" })},
+			wantAdded:   1,
+			wantRemoved: 1,
+		},
+		{
+			name:          "repeated receipt numbers matched once each",
+			saved:         []DiffRow{jane, jane},
+			replacement:   []DiffRow{with(jane, unchanged), with(jane, unchanged), with(jane, unchanged)},
+			wantAdded:     1,
+			wantUnchanged: 2,
+		},
+		{
+			name:        "empty replacement",
+			saved:       []DiffRow{jane, peter},
+			wantRemoved: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := Compare(tt.saved, tt.replacement)
+
+			if len(diff.Added) != tt.wantAdded || len(diff.Removed) != tt.wantRemoved || diff.Unchanged != tt.wantUnchanged {
+				t.Fatalf("Compare() added %d, removed %d, left %d unchanged, want %d, %d, %d", len(diff.Added),
+					len(diff.Removed), diff.Unchanged, tt.wantAdded, tt.wantRemoved, tt.wantUnchanged)
+			}
+
+			if len(diff.Changed) != len(tt.wantChanged) {
+				t.Fatalf("Compare() changed %d rows, want %d", len(diff.Changed), len(tt.wantChanged))
+			}
+
+			for i, change := range diff.Changed {
+				if !reflect.DeepEqual(change.Fields, tt.wantChanged[i]) {
+					t.Fatalf("Compare() changed %v, want %v", change.Fields, tt.wantChanged[i])
+				}
+
+				if change.After.ContributionId != change.Before.ContributionId || change.ReceiptNo != change.Before.ReceiptNo {
+					t.Fatalf("change %+v is not tied to the saved contribution", change)
+				}
+			}
+
+			if diff.Empty() != (tt.wantAdded == 0 && tt.wantRemoved == 0 && len(tt.wantChanged) == 0) {
+				t.Fatalf("Empty() = %v for %+v", diff.Empty(), diff)
+			}
+		})
+	}
+}
+
+func TestNewDiffRow(t *testing.T) {
+	row := ImportModel{Name: "Jane", ReceiptNo: "R1", Total: 100, BreakDown: map[string]float64{"TITHE": 100},
+		Date: date("2024-03-03"), Sheet: "3rd Mar 2024", Row: 4}
+
+	want := DiffRow{ReceiptNo: "R1", Contributor: "Jane", Date: "2024-03-03", Total: 100,
+		BreakDown: map[string]float64{"TITHE": 100}, Sheet: "3rd Mar 2024", Row: 4}
+
+	if got := NewDiffRow(row); !reflect.DeepEqual(got, want) {
+		t.Fatalf("NewDiffRow() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,31 @@
+package imports
+
+// What to do with a row that duplicates a saved contribution
+const (
+	DuplicateSkip   = "SKIP"
+	DuplicateUpdate = "UPDATE"
+	DuplicateFail   = "FAIL"
+)
+
+// How a duplicate was matched
+const (
+	MatchReceipt              = "RECEIPT"
+	MatchContributorDateTotal = "CONTRIBUTOR_DATE_TOTAL"
+)
+
+var DuplicateActions = []string{DuplicateSkip, DuplicateUpdate, DuplicateFail}
+
+// Duplicate is a row that matches a saved contribution, or an earlier row of the same
+// file when InFile is set, by receipt number or by contributor, date and total
+type Duplicate struct {
+	Sheet       string  `json:"sheet,omitempty"`
+	Row         int     `json:"row,omitempty"`
+	ReceiptNo   string  `json:"receiptNo"`
+	Contributor string  `json:"contributor"`
+	Date        string  `json:"date"`
+	Total       float64 `json:"total"`
+	MatchedId   int     `json:"matchedId,omitempty"`
+	InFile      bool    `json:"inFile,omitempty"`
+	MatchedOn   string  `json:"matchedOn"`
+	Action      string  `json:"action,omitempty"`
+}
@@ -1,153 +1,103 @@
 package excel
 
 import (
-	"bytes"
-	"crypto/sha256"
-	"fmt"
-	"strconv"
-	"strings"
-	"time"
+	"io"
 
 	"github.com/VaudKK/CAS/pkg/imports"
 	"github.com/xuri/excelize/v2"
 )
 
+// unzipXMLSizeLimit is the largest worksheet excelize unzips into memory, larger
+// worksheets are extracted to temporary files and read from there
+const unzipXMLSizeLimit = 4 << 20
+
+// ExcelImport reads contributions from an xlsx workbook
 type ExcelImport struct {
+	// Profile describes the layout of the workbook, the default layout is used when it is nil
+	Profile *imports.Profile
+
+	progress *imports.Progress
 }
 
-func (exImport *ExcelImport) ProcessExcelFile(data []byte) ([]imports.ImportModel, []string, error) {
+func (exImport *ExcelImport) Import(file io.ReaderAt, size int64, emit func(imports.ImportModel) error) ([]string, error) {
+	return exImport.ProcessExcelFile(file, size, emit)
+}
 
-	uniqueCategories := make(map[string]bool, 0)
+func (exImport *ExcelImport) Progress() *imports.Progress {
+	if exImport.progress == nil {
+		return &imports.Progress{}
+	}
+	return exImport.progress
+}
 
-	f, err := excelize.OpenReader(bytes.NewReader(data))
+// ProcessExcelFile reads the workbook one row at a time. A file spooled to disk is
+// opened by its path and worksheets larger than unzipXMLSizeLimit are extracted to
+// temporary files rather than memory.
+func (exImport *ExcelImport) ProcessExcelFile(file io.ReaderAt, size int64, emit func(imports.ImportModel) error) ([]string, error) {
+	parser, err := imports.NewParser(exImport.Profile)
 
 	if err != nil {
-		return []imports.ImportModel{}, nil, err
+		return nil, err
 	}
 
-	defer f.Close()
+	exImport.progress = &parser.Progress
 
-	excelData := make([]imports.ImportModel, 0)
+	f, err := open(file, size)
 
-	for _, sheet := range f.GetSheetList() {
-		// read the sheets with the data, the sheets with data are named as dates
-		layout := "_2 Jan 2006"
-
-		t, err := time.Parse(layout, exImport.cleanDate(sheet))
-		if err != nil {
-			break
-		}
-
-		rows, err := f.GetRows(sheet)
-
-		if err != nil {
-			return []imports.ImportModel{}, nil, err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		// read the categories from the first row
-		categories := make([]string, 0)
+	defer f.Close()
 
-		for i := 3; i < len(rows[0]); i++ {
-			if rows[0][i] == "" {
-				break
-			}
-			categories = append(categories, rows[0][i])
+	for _, sheet := range f.GetSheetList() {
+		if !parser.BeginSheet(sheet) {
+			continue
 		}
 
-		exImport.getUniqueCategories(categories, uniqueCategories)
-
-		for i := 1; i < len(rows); i++ {
-			if rows[i][0] == "" {
-				break
-			}
-
-			total, err := strconv.ParseFloat(exImport.cleanNumericField(rows[i][2]), 32)
-
-			if err != nil {
-				return []imports.ImportModel{}, nil, err
-			}
-
-			breakdown, err := exImport.readBreakDown(categories, rows[i])
-
-			if err != nil {
-				return []imports.ImportModel{}, nil, err
-			}
-
-			excelData = append(excelData, imports.ImportModel{
-				Name:      exImport.escapeSingleQuote(&rows[i][0]),
-				ReceiptNo: rows[i][1],
-				Total:     total,
-				Date:      t,
-				BreakDown: breakdown})
+		if err = exImport.processSheet(f, sheet, parser, emit); err != nil {
+			return nil, err
 		}
-
 	}
 
-	return excelData, exImport.convertMapToStringArray(uniqueCategories), nil
+	return parser.Categories(), nil
 }
 
-func (exImport *ExcelImport) cleanDate(date string) string {
-	ordinals := []string{"st", "nd", "rd", "th"}
-	for _, ordinal := range ordinals {
-		date = strings.ReplaceAll(strings.ToLower(date), ordinal, "")
+// open reads the workbook from disk when the file has a path, as a spooled upload
+// does, and from the reader otherwise
+func open(file io.ReaderAt, size int64) (*excelize.File, error) {
+	options := excelize.Options{UnzipXMLSizeLimit: unzipXMLSizeLimit}
+
+	if named, ok := file.(interface{ Name() string }); ok {
+		return excelize.OpenFile(named.Name(), options)
 	}
-	return date
-}
 
-func (exImport *ExcelImport) cleanNumericField(field string) string {
-	replacer := strings.NewReplacer(",", "", " ", "")
-	return replacer.Replace(field)
+	return excelize.OpenReader(io.NewSectionReader(file, 0, size), options)
 }
 
-func (exImport *ExcelImport) readBreakDown(categories []string, row []string) (map[string]float64, error) {
-	breakdown := make(map[string]float64)
-
-	for i := 0; i < len(categories); i++ {
-		// categories columns start from 3, so we need to add 3 to the index
-		if row[i+3] == "" {
-			continue
-		}
-
-		value, err := strconv.ParseFloat(exImport.cleanNumericField(row[i+3]), 64)
-
-		if err != nil {
-			continue
-		}
+func (exImport *ExcelImport) processSheet(f *excelize.File, sheet string, parser *imports.Parser,
+	emit func(imports.ImportModel) error) error {
+	rows, err := f.Rows(sheet)
 
-		breakdown[categories[i]] = value
+	if err != nil {
+		return err
 	}
 
-	return breakdown, nil
-}
+	defer rows.Close()
 
-func (exImport *ExcelImport) escapeSingleQuote(s *string) string {
-	if condition := strings.Contains(*s, "'"); condition {
-		*s = strings.ReplaceAll(*s, "'", "''")
-		return *s
-	}
-	return *s
-}
+	for number := 1; rows.Next(); number++ {
+		row, err := rows.Columns()
 
-func (exImport *ExcelImport) getUniqueCategories(categories []string, uniqueCategories map[string]bool) {
-	for _, category := range categories {
-		if _, ok := uniqueCategories[category]; !ok {
-			uniqueCategories[category] = true
+		if err != nil {
+			return err
 		}
-	}
-}
-
-func (exImport *ExcelImport) convertMapToStringArray(uniqueCategories map[string]bool) []string {
-	categories := make([]string, 0)
 
-	for category := range uniqueCategories {
-		categories = append(categories, category)
+		if model := parser.Row(number, row); model != nil {
+			if err = emit(*model); err != nil {
+				return err
+			}
+		}
 	}
 
-	return categories
-}
-
-func HashFile(data []byte) string {
-	hashObject := sha256.New()
-	hashObject.Write(data)
-	return fmt.Sprintf("%x", hashObject.Sum(nil))
+	return rows.Error()
 }
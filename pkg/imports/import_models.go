@@ -8,4 +8,32 @@ type ImportModel struct {
 	Total     float64
 	BreakDown map[string]float64
 	Date      time.Time
+	// Sheet and Row locate the contribution in the uploaded file
+	Sheet string
+	Row   int
+}
+
+// RowError describes a row that could not be read from an uploaded file. Row is 0 when
+// a whole sheet was skipped.
+type RowError struct {
+	Sheet   string `json:"sheet"`
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// Sheet is a sheet of contributions and the sabbath date it was read for
+type Sheet struct {
+	Name string
+	Date time.Time
+}
+
+// Discrepancy is a difference between a sheet's totals row and the sum of the rows
+// read from the sheet. Category is empty for the grand total.
+type Discrepancy struct {
+	Sheet      string  `json:"sheet"`
+	Row        int     `json:"row"`
+	Category   string  `json:"category,omitempty"`
+	Expected   float64 `json:"expected"`
+	Actual     float64 `json:"actual"`
+	Difference float64 `json:"difference"`
 }
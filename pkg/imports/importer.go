@@ -0,0 +1,48 @@
+package imports
+
+import "io"
+
+// Importer reads the contributions and categories from an uploaded file. Rows are
+// handed to emit as they are read so that large files are never held in memory.
+type Importer interface {
+	Import(file io.ReaderAt, size int64, emit func(ImportModel) error) ([]string, error)
+	// Progress reports the rows read and skipped by the last import
+	Progress() *Progress
+}
+
+// Progress counts the rows read from a file and records the rows and sheets that
+// were skipped
+type Progress struct {
+	// RowsRead counts the contribution rows found in the file, including the rows
+	// that were skipped because they could not be parsed
+	RowsRead int
+	// Errors lists the skipped rows and sheets and the reason they were skipped
+	Errors []RowError
+	// Sheets lists the sheets that were read and their sabbath dates
+	Sheets []Sheet
+	// Duplicates lists the rows matching contributions that were already saved
+	Duplicates []Duplicate
+	// Discrepancies lists the totals rows that do not match the rows above them
+	Discrepancies []Discrepancy
+}
+
+func (p *Progress) addError(sheet string, row int, message string) {
+	p.Errors = append(p.Errors, RowError{Sheet: sheet, Row: row, Message: message})
+}
+
+// ReadAll collects every row of a file. It is meant for previews, imports save the
+// rows as they are emitted.
+func ReadAll(importer Importer, file io.ReaderAt, size int64) ([]ImportModel, []string, error) {
+	rows := make([]ImportModel, 0)
+
+	categories, err := importer.Import(file, size, func(row ImportModel) error {
+		rows = append(rows, row)
+		return nil
+	})
+
+	if err != nil {
+		return []ImportModel{}, nil, err
+	}
+
+	return rows, categories, nil
+}
@@ -0,0 +1,157 @@
+package importers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/imports/csv"
+	"github.com/VaudKK/CAS/pkg/imports/excel"
+	"github.com/VaudKK/CAS/pkg/imports/ods"
+)
+
+// Supported upload formats
+const (
+	FormatXlsx = "xlsx"
+	FormatOds  = "ods"
+	FormatCsv  = "csv"
+)
+
+const odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported file type, expected an xlsx, ods or csv file")
+	ErrCsvDate           = errors.New("the name of a CSV file must be its date, or the file must be imported " +
+		"with a profile that reads dates from a column or uses a fixed date")
+)
+
+// Detect chooses the importer for a file from its content. The file name is only used
+// as the sheet name of CSV files: a CSV file read with a profile that takes dates from
+// sheet names, as the default profile does, must be named after its date, for
+// example "3 Mar 2024.csv". Any other CSV file needs a profile that reads dates from
+// a column or uses a fixed date.
+func Detect(file io.ReaderAt, size int64, fileName string, profile *imports.Profile) (imports.Importer, string, error) {
+	format, err := DetectFormat(file, size)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch format {
+	case FormatXlsx:
+		return &excel.ExcelImport{Profile: profile}, format, nil
+	case FormatOds:
+		return &ods.OdsImport{Profile: profile}, format, nil
+	default:
+		sheetName := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+
+		if profile == nil || profile.DateSource == imports.DateFromSheetName {
+			dates := profile
+
+			if dates == nil {
+				dates = imports.DefaultProfile()
+			}
+
+			if _, err := dates.ParseSheetDate(sheetName); err != nil {
+				return nil, "", ErrCsvDate
+			}
+		}
+
+		return &csv.CsvImport{Profile: profile, SheetName: sheetName}, format, nil
+	}
+}
+
+// ContentType returns the media type of a format
+func ContentType(format string) string {
+	switch format {
+	case FormatXlsx:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatOds:
+		return odsMimeType
+	case FormatCsv:
+		return "text/csv"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// DetectFormat sniffs the content of a file. Spreadsheets are zip archives told apart
+// by their contents, anything that reads as text is treated as CSV.
+func DetectFormat(file io.ReaderAt, size int64) (string, error) {
+	head := make([]byte, 512)
+	n, err := file.ReadAt(head, 0)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	head = head[:n]
+
+	if bytes.HasPrefix(head, []byte("PK\x03\x04")) {
+		return zipFormat(file, size)
+	}
+
+	if isText(head) {
+		return FormatCsv, nil
+	}
+
+	return "", ErrUnsupportedFormat
+}
+
+func zipFormat(file io.ReaderAt, size int64) (string, error) {
+	archive, err := zip.NewReader(file, size)
+
+	if err != nil {
+		return "", ErrUnsupportedFormat
+	}
+
+	for _, file := range archive.File {
+		switch file.Name {
+		case "xl/workbook.xml":
+			return FormatXlsx, nil
+		case "mimetype":
+			reader, err := file.Open()
+
+			if err != nil {
+				return "", ErrUnsupportedFormat
+			}
+
+			mimeType, err := io.ReadAll(io.LimitReader(reader, 100))
+			reader.Close()
+
+			if err == nil && strings.TrimSpace(string(mimeType)) == odsMimeType {
+				return FormatOds, nil
+			}
+		}
+	}
+
+	return "", ErrUnsupportedFormat
+}
+
+// isText accepts UTF-16 files with a byte order mark and files without binary
+// control characters in their first 512 bytes
+func isText(data []byte) bool {
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		return true
+	}
+
+	if strings.HasPrefix(http.DetectContentType(data), "text/") {
+		return true
+	}
+
+	// Windows-1252 text is not valid UTF-8 and is reported as binary by DetectContentType
+	sample := data[:min(len(data), 512)]
+
+	for _, b := range sample {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' {
+			return false
+		}
+	}
+
+	return len(sample) > 0
+}
@@ -0,0 +1,125 @@
+package importers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/VaudKK/CAS/pkg/imports"
+)
+
+// archive zips files named after the keys with the values as their content
+func archive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	for name, content := range files {
+		f, err := w.Create(name)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+		wantErr error
+	}{
+		{name: "xlsx", content: archive(t, map[string]string{"xl/workbook.xml": "<workbook/>"}), want: FormatXlsx},
+		{name: "ods", content: archive(t, map[string]string{"mimetype": odsMimeType, "content.xml": ""}), want: FormatOds},
+		{name: "other zip", content: archive(t, map[string]string{"word/document.xml": ""}), wantErr: ErrUnsupportedFormat},
+		{name: "csv", content: []byte("NAME,TOTAL\nJane,100\n"), want: FormatCsv},
+		{name: "windows-1252 csv", content: []byte("NAME,TOTAL\nRen\xE9e,100\n"), want: FormatCsv},
+		{name: "utf-16 csv", content: []byte("\xFF\xFEN\x00,\x00"), want: FormatCsv},
+		{name: "binary", content: []byte("\x00\x01\x02\x03PDF"), wantErr: ErrUnsupportedFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat(bytes.NewReader(tt.content), int64(len(tt.content)))
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DetectFormat() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("DetectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectCsvDate(t *testing.T) {
+	content := []byte("NAME,RECEIPT,TOTAL\nJane,R1,100\n")
+
+	tests := []struct {
+		name     string
+		fileName string
+		profile  *imports.Profile
+		wantErr  error
+	}{
+		{name: "named after its date", fileName: "3 Mar 2024.csv"},
+		{name: "named after its date with an ordinal", fileName: "uploads/23rd Mar 2024.CSV"},
+		{name: "not named after a date", fileName: "contributions.csv", wantErr: ErrCsvDate},
+		{
+			name:     "profile taking dates from the name",
+			fileName: "2024-03-03.csv",
+			profile:  &imports.Profile{HeaderRow: 1, DateSource: imports.DateFromSheetName, DateFormats: []string{"2006-01-02"}},
+		},
+		{
+			name:     "profile taking dates from the name in another format",
+			fileName: "3 Mar 2024.csv",
+			profile:  &imports.Profile{HeaderRow: 1, DateSource: imports.DateFromSheetName, DateFormats: []string{"2006-01-02"}},
+			wantErr:  ErrCsvDate,
+		},
+		{
+			name:     "profile reading dates from a column",
+			fileName: "contributions.csv",
+			profile:  &imports.Profile{HeaderRow: 1, DateSource: imports.DateFromColumn, DateColumn: "D"},
+		},
+		{
+			name:     "profile with a fixed date",
+			fileName: "contributions.csv",
+			profile:  &imports.Profile{HeaderRow: 1, DateSource: imports.DateFixed, FixedDate: "2024-03-03"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importer, format, err := Detect(bytes.NewReader(content), int64(len(content)), tt.fileName, tt.profile)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Detect(%q) error = %v, want %v", tt.fileName, err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && (importer == nil || format != FormatCsv) {
+				t.Fatalf("Detect(%q) = %T %q, want a csv importer", tt.fileName, importer, format)
+			}
+		})
+	}
+}
+
+func TestDetectSpreadsheetIgnoresName(t *testing.T) {
+	content := archive(t, map[string]string{"xl/workbook.xml": "<workbook/>"})
+
+	if _, format, err := Detect(bytes.NewReader(content), int64(len(content)), "contributions.xlsx", nil); err != nil || format != FormatXlsx {
+		t.Fatalf("Detect() = %q, %v, want %q", format, err, FormatXlsx)
+	}
+}
@@ -0,0 +1,197 @@
+package ods
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/VaudKK/CAS/pkg/imports"
+)
+
+// maxRepeat limits how many times a repeated row, cell or space is expanded. LibreOffice
+// pads sheets with a single row or cell repeated to the end of the sheet, and a
+// crafted file could otherwise ask for billions of them.
+const maxRepeat = 1000
+
+// OdsImport reads contributions from an OpenDocument spreadsheet
+type OdsImport struct {
+	// Profile describes the layout of the workbook, the default layout is used when it is nil
+	Profile *imports.Profile
+
+	progress *imports.Progress
+}
+
+func (odsImport *OdsImport) Import(file io.ReaderAt, size int64, emit func(imports.ImportModel) error) ([]string, error) {
+	parser, err := imports.NewParser(odsImport.Profile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	odsImport.progress = &parser.Progress
+
+	err = readTables(file, size, parser.BeginSheet, func(number int, row []string) error {
+		if model := parser.Row(number, row); model != nil {
+			return emit(*model)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parser.Categories(), nil
+}
+
+func (odsImport *OdsImport) Progress() *imports.Progress {
+	if odsImport.progress == nil {
+		return &imports.Progress{}
+	}
+	return odsImport.progress
+}
+
+// readTables reads the text of every cell in content.xml and hands each row to
+// emit as soon as it is decoded, numbered from 1 within its sheet. The rows of a
+// sheet are only read when begin, called with the sheet's name, returns true.
+func readTables(file io.ReaderAt, size int64, begin func(sheet string) bool, emit func(number int, row []string) error) error {
+	archive, err := zip.NewReader(file, size)
+
+	if err != nil {
+		return err
+	}
+
+	content, err := archive.Open("content.xml")
+
+	if err != nil {
+		return errors.New("invalid OpenDocument spreadsheet, content.xml is missing")
+	}
+
+	defer content.Close()
+
+	decoder := xml.NewDecoder(content)
+
+	var inTable, reading bool
+	var number int
+	var row []string
+	var rowRepeat, cellRepeat int
+	var text strings.Builder
+	var inCell, inParagraph bool
+	paragraphs := 0
+
+	for {
+		token, err := decoder.Token()
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "table":
+				inTable, number = true, 0
+				reading = begin(attr(t, "name"))
+			case "table-row":
+				row = make([]string, 0)
+				rowRepeat = count(attr(t, "number-rows-repeated"))
+			case "table-cell", "covered-table-cell":
+				inCell = true
+				paragraphs = 0
+				text.Reset()
+				cellRepeat = min(count(attr(t, "number-columns-repeated")), maxRepeat)
+			case "p":
+				if inCell {
+					if paragraphs > 0 {
+						text.WriteString("\n")
+					}
+					paragraphs++
+					inParagraph = true
+				}
+			case "s":
+				if inParagraph {
+					text.WriteString(strings.Repeat(" ", min(count(attr(t, "c")), maxRepeat)))
+				}
+			case "tab":
+				if inParagraph {
+					text.WriteString("\t")
+				}
+			}
+		case xml.CharData:
+			if inParagraph {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				inParagraph = false
+			case "table-cell", "covered-table-cell":
+				inCell = false
+				value := strings.TrimSpace(text.String())
+
+				for i := 0; i < cellRepeat; i++ {
+					row = append(row, value)
+				}
+			case "table-row":
+				if !inTable {
+					continue
+				}
+
+				// rows past the cap are not read but still counted, so that the rows
+				// after them keep their numbers
+				if reading {
+					row = trimTrailing(row)
+
+					for i := 1; i <= min(rowRepeat, maxRepeat); i++ {
+						if err = emit(number+i, row); err != nil {
+							return err
+						}
+					}
+				}
+
+				number += rowRepeat
+			case "table":
+				inTable, reading = false, false
+			}
+		}
+	}
+
+	return nil
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// count reads a repeat attribute, which is 1 when it is missing
+func count(value string) int {
+	n, err := strconv.Atoi(value)
+
+	if err != nil || n < 1 {
+		return 1
+	}
+
+	return n
+}
+
+func trimTrailing(row []string) []string {
+	end := len(row)
+
+	for end > 0 && row[end-1] == "" {
+		end--
+	}
+
+	return row[:end]
+}
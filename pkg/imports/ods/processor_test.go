@@ -0,0 +1,170 @@
+package ods
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// spreadsheet zips the tables into the content.xml of an OpenDocument spreadsheet
+func spreadsheet(t *testing.T, tables string) *bytes.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	content, err := archive.Create("content.xml")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = content.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet>` + tables + `</office:spreadsheet></office:body></office:document-content>`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.NewReader(buf.Bytes())
+}
+
+type readRow struct {
+	sheet  string
+	number int
+	row    []string
+}
+
+func TestReadTables(t *testing.T) {
+	tests := []struct {
+		name   string
+		tables string
+		want   []readRow
+	}{
+		{
+			name: "cells and paragraphs",
+			tables: `<table:table table:name="3rd March 2024">
+				<table:table-row><table:table-cell><text:p>NAME</text:p></table:table-cell><table:table-cell><text:p>TOTAL</text:p></table:table-cell></table:table-row>
+				<table:table-row><table:table-cell><text:p>Jane<text:s text:c="2"/>Wanjiru</text:p><text:p>Nairobi</text:p></table:table-cell><table:table-cell><text:p>1,500</text:p></table:table-cell></table:table-row>
+			</table:table>`,
+			want: []readRow{
+				{sheet: "3rd March 2024", number: 1, row: []string{"NAME", "TOTAL"}},
+				{sheet: "3rd March 2024", number: 2, row: []string{"Jane  Wanjiru\nNairobi", "1,500"}},
+			},
+		},
+		{
+			name: "repeated cells and rows",
+			tables: `<table:table table:name="Sheet1">
+				<table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="3"><text:p>50</text:p></table:table-cell></table:table-row>
+				<table:table-row><table:table-cell><text:p>END</text:p></table:table-cell><table:table-cell table:number-columns-repeated="1020"/></table:table-row>
+			</table:table>`,
+			want: []readRow{
+				{sheet: "Sheet1", number: 1, row: []string{"50", "50", "50"}},
+				{sheet: "Sheet1", number: 2, row: []string{"50", "50", "50"}},
+				{sheet: "Sheet1", number: 3, row: []string{"END"}},
+			},
+		},
+		{
+			name: "rows past the cap keep their numbers",
+			tables: `<table:table table:name="Sheet1">
+				<table:table-row table:number-rows-repeated="1000000"><table:table-cell/></table:table-row>
+				<table:table-row><table:table-cell><text:p>LAST</text:p></table:table-cell></table:table-row>
+			</table:table>`,
+			want: []readRow{{sheet: "Sheet1", number: 1000001, row: []string{"LAST"}}},
+		},
+		{
+			name: "skipped sheet",
+			tables: `<table:table table:name="Summary">
+				<table:table-row><table:table-cell><text:p>SKIPPED</text:p></table:table-cell></table:table-row>
+			</table:table>
+			<table:table table:name="Sheet2">
+				<table:table-row><table:table-cell><text:p>READ</text:p></table:table-cell></table:table-row>
+			</table:table>`,
+			want: []readRow{{sheet: "Sheet2", number: 1, row: []string{"READ"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := spreadsheet(t, tt.tables)
+
+			var got []readRow
+			var sheet string
+
+			begin := func(name string) bool {
+				sheet = name
+				return name != "Summary"
+			}
+
+			err := readTables(file, file.Size(), begin, func(number int, row []string) error {
+				// empty rows are padding, the parser only needs them to end a sheet
+				if len(row) > 0 {
+					got = append(got, readRow{sheet: sheet, number: number, row: row})
+				}
+				return nil
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("readTables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTablesCapsRepeats(t *testing.T) {
+	file := spreadsheet(t, `<table:table table:name="Sheet1">
+		<table:table-row table:number-rows-repeated="5000"><table:table-cell table:number-columns-repeated="5000"><text:p>1</text:p></table:table-cell></table:table-row>
+	</table:table>`)
+
+	rows := 0
+
+	err := readTables(file, file.Size(), func(string) bool { return true }, func(number int, row []string) error {
+		rows++
+
+		if len(row) != maxRepeat {
+			t.Fatalf("row %d has %d cells, want %d", number, len(row), maxRepeat)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows != maxRepeat {
+		t.Fatalf("read %d rows, want %d", rows, maxRepeat)
+	}
+}
+
+func TestReadTablesStopsOnEmitError(t *testing.T) {
+	errStop := errors.New("stop")
+
+	file := spreadsheet(t, `<table:table table:name="Sheet1">`+
+		strings.Repeat(`<table:table-row><table:table-cell><text:p>1</text:p></table:table-cell></table:table-row>`, 10)+
+		`</table:table>`)
+
+	rows := 0
+
+	err := readTables(file, file.Size(), func(string) bool { return true }, func(number int, row []string) error {
+		rows++
+		return errStop
+	})
+
+	if !errors.Is(err, errStop) || rows != 1 {
+		t.Fatalf("readTables() = %v after %d rows, want %v after the first row", err, rows, errStop)
+	}
+}
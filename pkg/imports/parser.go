@@ -0,0 +1,363 @@
+package imports
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Parser turns the rows of a spreadsheet into contributions following the layout of
+// a profile. Sheets are read one at a time by calling BeginSheet and then Row for
+// every row of the sheet in order.
+type Parser struct {
+	Progress
+
+	profile    *Profile
+	cols       columns
+	fixedDate  time.Time
+	categories map[string]bool
+	order      []string
+
+	sheet           string
+	sheetDate       time.Time
+	sheetCategories []string
+	ended           bool
+
+	// sums of the rows read from the sheet in cents, to reconcile with its totals row
+	sheetTotal  int64
+	sheetSums   map[string]int64
+	totalsFound bool
+}
+
+// columns holds the zero based indexes of the columns named in a profile
+type columns struct {
+	name, receipt, total, date int
+	categoryStart, categoryEnd int
+}
+
+// NewParser checks the columns of the profile. The default layout is used when
+// profile is nil.
+func NewParser(profile *Profile) (*Parser, error) {
+	if profile == nil {
+		profile = DefaultProfile()
+	}
+
+	cols, err := profileColumns(profile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &Parser{profile: profile, cols: cols, categories: make(map[string]bool)}
+
+	if profile.DateSource == DateFixed {
+		parser.fixedDate, err = time.Parse("2006-01-02", profile.FixedDate)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixed date %q", profile.FixedDate)
+		}
+	}
+
+	return parser, nil
+}
+
+// BeginSheet starts reading a sheet and reports whether its rows should be read.
+// Sheets that are skipped by the profile or, when dates come from sheet names, are
+// not named as a date are not read.
+func (p *Parser) BeginSheet(sheet string) bool {
+	p.sheet = sheet
+	p.sheetDate = p.fixedDate
+	p.sheetCategories = nil
+	p.ended = false
+	p.sheetTotal = 0
+	p.sheetSums = make(map[string]int64)
+	p.totalsFound = false
+
+	if p.profile.SkipsSheet(sheet) {
+		return false
+	}
+
+	if p.profile.DateSource == DateFromSheetName {
+		date, err := p.profile.ParseSheetDate(sheet)
+
+		if err != nil {
+			p.addError(sheet, 0, "sheet skipped, its name is not a date")
+			return false
+		}
+
+		p.sheetDate = date
+	}
+
+	p.Sheets = append(p.Sheets, Sheet{Name: sheet, Date: p.sheetDate})
+
+	return true
+}
+
+// Row reads the cells of a row, numbered from 1. It returns nil for the header, for
+// rows after the first blank name and for rows that could not be parsed. The totals
+// row, which may follow the blank name, is reconciled with the rows read before it.
+func (p *Parser) Row(number int, row []string) *ImportModel {
+	if p.totalsFound || number < p.profile.HeaderRow {
+		return nil
+	}
+
+	if number == p.profile.HeaderRow {
+		p.readCategories(row)
+		return nil
+	}
+
+	if p.isTotalsRow(row) {
+		p.totalsFound, p.ended = true, true
+
+		if p.profile.TotalsAction != TotalsIgnore {
+			p.reconcile(number, row)
+		}
+		return nil
+	}
+
+	if p.ended {
+		return nil
+	}
+
+	if cell(row, p.cols.name) == "" {
+		p.ended = true
+		return nil
+	}
+
+	p.RowsRead++
+
+	if cell(row, p.cols.total) == "" {
+		p.addError(p.sheet, number, "missing total")
+		return nil
+	}
+
+	total, err := strconv.ParseFloat(cleanNumericField(cell(row, p.cols.total)), 64)
+
+	if err != nil {
+		p.addError(p.sheet, number, fmt.Sprintf("invalid total %q", cell(row, p.cols.total)))
+		return nil
+	}
+
+	breakdown, err := p.readBreakDown(row)
+
+	if err != nil {
+		p.addError(p.sheet, number, err.Error())
+		return nil
+	}
+
+	date := p.sheetDate
+
+	if p.profile.DateSource == DateFromColumn {
+		date, err = p.parseDateCell(cell(row, p.cols.date))
+
+		if err != nil {
+			p.addError(p.sheet, number, err.Error())
+			return nil
+		}
+	}
+
+	name := row[p.cols.name]
+
+	p.sheetTotal += cents(total)
+
+	for category, amount := range breakdown {
+		p.sheetSums[category] += cents(amount)
+	}
+
+	return &ImportModel{
+		Name:      name,
+		ReceiptNo: cell(row, p.cols.receipt),
+		Total:     total,
+		Date:      date,
+		BreakDown: breakdown,
+		Sheet:     p.sheet,
+		Row:       number,
+	}
+}
+
+// Categories returns the categories found in the header rows of every sheet read
+func (p *Parser) Categories() []string {
+	categories := make([]string, len(p.order))
+	copy(categories, p.order)
+	return categories
+}
+
+// isTotalsRow reports whether the name or receipt cell of a row holds the totals label
+func (p *Parser) isTotalsRow(row []string) bool {
+	label := p.profile.TotalsLabel
+
+	if label == "" {
+		label = DefaultTotalsLabel
+	}
+
+	for _, index := range []int{p.cols.name, p.cols.receipt} {
+		value := strings.TrimSuffix(strings.TrimSpace(cell(row, index)), ":")
+
+		if value != "" && strings.EqualFold(value, label) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reconcile compares the grand total and every category total on the totals row with
+// the rows read from the sheet. Blank totals are not compared.
+func (p *Parser) reconcile(number int, row []string) {
+	compare := func(category, value string, actual int64) {
+		if value == "" {
+			return
+		}
+
+		expected, err := strconv.ParseFloat(cleanNumericField(value), 64)
+
+		if err != nil {
+			p.addError(p.sheet, number, fmt.Sprintf("invalid amount %q on the totals row", value))
+			return
+		}
+
+		if cents(expected) != actual {
+			p.Discrepancies = append(p.Discrepancies, Discrepancy{
+				Sheet:      p.sheet,
+				Row:        number,
+				Category:   category,
+				Expected:   float64(cents(expected)) / 100,
+				Actual:     float64(actual) / 100,
+				Difference: float64(actual-cents(expected)) / 100,
+			})
+		}
+	}
+
+	for i, category := range p.sheetCategories {
+		compare(category, cell(row, p.cols.categoryStart+i), p.sheetSums[category])
+	}
+
+	compare("", cell(row, p.cols.total), p.sheetTotal)
+}
+
+func (p *Parser) readCategories(header []string) {
+	p.sheetCategories = make([]string, 0)
+
+	for i := p.cols.categoryStart; i < len(header) && (p.cols.categoryEnd < 0 || i <= p.cols.categoryEnd); i++ {
+		if header[i] == "" {
+			break
+		}
+
+		p.sheetCategories = append(p.sheetCategories, header[i])
+
+		if !p.categories[header[i]] {
+			p.categories[header[i]] = true
+			p.order = append(p.order, header[i])
+		}
+	}
+}
+
+func (p *Parser) readBreakDown(row []string) (map[string]float64, error) {
+	breakdown := make(map[string]float64)
+
+	for i, category := range p.sheetCategories {
+		value := cell(row, p.cols.categoryStart+i)
+
+		if value == "" {
+			continue
+		}
+
+		amount, err := strconv.ParseFloat(cleanNumericField(value), 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount %q for %s", value, category)
+		}
+
+		breakdown[category] = amount
+	}
+
+	return breakdown, nil
+}
+
+// parseDateCell reads a date typed as text in one of the profile's formats or stored
+// as an excel serial number
+func (p *Parser) parseDateCell(value string) (time.Time, error) {
+	if serial, err := strconv.ParseFloat(value, 64); err == nil {
+		return excelize.ExcelDateToTime(serial, false)
+	}
+
+	if t, err := p.profile.ParseDate(value); err == nil {
+		return t, nil
+	}
+
+	return p.profile.ParseDate(cleanDate(value))
+}
+
+// profileColumns converts the column letters of a profile into indexes
+func profileColumns(profile *Profile) (columns, error) {
+	cols := columns{date: -1, categoryEnd: -1}
+
+	if profile.HeaderRow < 1 {
+		return cols, fmt.Errorf("invalid header row %d", profile.HeaderRow)
+	}
+
+	targets := []struct {
+		name   string
+		index  *int
+		column string
+	}{
+		{"name", &cols.name, profile.NameColumn},
+		{"receipt", &cols.receipt, profile.ReceiptColumn},
+		{"total", &cols.total, profile.TotalColumn},
+		{"category start", &cols.categoryStart, profile.CategoryStart},
+		{"category end", &cols.categoryEnd, profile.CategoryEnd},
+		{"date", &cols.date, profile.DateColumn},
+	}
+
+	for _, target := range targets {
+		if target.column == "" {
+			continue
+		}
+
+		number, err := excelize.ColumnNameToNumber(target.column)
+
+		if err != nil {
+			return cols, fmt.Errorf("invalid %s column %q", target.name, target.column)
+		}
+
+		*target.index = number - 1
+	}
+
+	if profile.DateSource == DateFromColumn && cols.date < 0 {
+		return cols, fmt.Errorf("a date column is required when dates are read from a column")
+	}
+
+	return cols, nil
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// cell returns the value at index or an empty string when the row is shorter
+func cell(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+// ordinal matches the suffix of a day such as 1st or 23rd
+var ordinal = regexp.MustCompile(`(?i)(\d)(st|nd|rd|th)\b`)
+
+// cleanDate drops the ordinal suffixes of days so that "3rd Mar 2024" reads as
+// "3 Mar 2024". Suffixes are only dropped after a digit, month names such as August
+// are left alone.
+func cleanDate(date string) string {
+	return ordinal.ReplaceAllString(date, "$1")
+}
+
+func cleanNumericField(field string) string {
+	replacer := strings.NewReplacer(",", "", " ", "")
+	return replacer.Replace(field)
+}
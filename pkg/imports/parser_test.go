@@ -0,0 +1,363 @@
+package imports
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func TestCleanDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "3rd Mar 2024", want: "3 Mar 2024"},
+		{value: "21ST MAR 2024", want: "21 MAR 2024"},
+		{value: "1st August 2024", want: "1 August 2024"},
+		{value: "August 22nd, 2024", want: "August 22, 2024"},
+		{value: "Thursday 4th Jul", want: "Thursday 4 Jul"},
+		{value: "2024-03-03", want: "2024-03-03"},
+		{value: "3rdMar 2024", want: "3rdMar 2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := cleanDate(tt.value); got != tt.want {
+				t.Fatalf("cleanDate(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeginSheet(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    *Profile
+		sheet      string
+		want       bool
+		wantDate   time.Time
+		wantErrors int
+	}{
+		{name: "date", sheet: "3 Mar 2024", want: true, wantDate: date("2024-03-03")},
+		{name: "date with an ordinal", sheet: "23rd Mar 2024", want: true, wantDate: date("2024-03-23")},
+		{name: "not a date", sheet: "Summary", wantErrors: 1},
+		{
+			name:    "skipped sheet",
+			profile: &Profile{HeaderRow: 1, DateSource: DateFromSheetName, DateFormats: []string{"_2 Jan 2006"}, SkipSheets: []string{" summary "}},
+			sheet:   "Summary",
+		},
+		{
+			name:     "month name with an ordinal's letters",
+			profile:  &Profile{HeaderRow: 1, DateSource: DateFromSheetName, DateFormats: []string{"2 January 2006"}},
+			sheet:    "1st August 2024",
+			want:     true,
+			wantDate: date("2024-08-01"),
+		},
+		{
+			name:     "raw name tried before the cleaned one",
+			profile:  &Profile{HeaderRow: 1, DateSource: DateFromSheetName, DateFormats: []string{"2 Jan 2006 (8th service)"}},
+			sheet:    "3 Mar 2024 (8th service)",
+			want:     true,
+			wantDate: date("2024-03-03"),
+		},
+		{
+			name:     "fixed date",
+			profile:  &Profile{HeaderRow: 1, DateSource: DateFixed, FixedDate: "2024-03-09"},
+			sheet:    "Summary",
+			want:     true,
+			wantDate: date("2024-03-09"),
+		},
+		{
+			name:    "date from a column",
+			profile: &Profile{HeaderRow: 1, DateSource: DateFromColumn, DateColumn: "E"},
+			sheet:   "Summary",
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParser(tt.profile)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := parser.BeginSheet(tt.sheet); got != tt.want {
+				t.Fatalf("BeginSheet(%q) = %v, want %v", tt.sheet, got, tt.want)
+			}
+
+			if !parser.sheetDate.Equal(tt.wantDate) {
+				t.Fatalf("sheet date = %s, want %s", parser.sheetDate, tt.wantDate)
+			}
+
+			if len(parser.Errors) != tt.wantErrors {
+				t.Fatalf("errors = %v, want %d", parser.Errors, tt.wantErrors)
+			}
+
+			if tt.want && (len(parser.Sheets) != 1 || parser.Sheets[0].Name != tt.sheet) {
+				t.Fatalf("sheets = %v, want %q", parser.Sheets, tt.sheet)
+			}
+		})
+	}
+}
+
+func TestParseDateCell(t *testing.T) {
+	parser, err := NewParser(&Profile{HeaderRow: 1, DateSource: DateFromColumn, DateColumn: "E",
+		DateFormats: []string{"2006-01-02", "_2 Jan 2006"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-03-03", want: date("2024-03-03")},
+		{value: "45354", want: date("2024-03-03")},
+		{value: "3rd Mar 2024", want: date("2024-03-03")},
+		{value: " 3 Mar 2024 ", want: date("2024-03-03")},
+		{value: "03/03/2024", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parser.parseDateCell(tt.value)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDateCell(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Fatalf("parseDateCell(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile *Profile
+		want    columns
+		wantErr bool
+	}{
+		{name: "default", want: columns{name: 0, receipt: 1, total: 2, date: -1, categoryStart: 3, categoryEnd: -1}},
+		{
+			name: "every column",
+			profile: &Profile{HeaderRow: 2, NameColumn: "B", ReceiptColumn: "A", TotalColumn: "AA", CategoryStart: "C",
+				CategoryEnd: "F", DateSource: DateFromColumn, DateColumn: "G"},
+			want: columns{name: 1, receipt: 0, total: 26, date: 6, categoryStart: 2, categoryEnd: 5},
+		},
+		{name: "no header row", profile: &Profile{NameColumn: "A"}, wantErr: true},
+		{name: "invalid column", profile: &Profile{HeaderRow: 1, NameColumn: "1A"}, wantErr: true},
+		{name: "date column missing", profile: &Profile{HeaderRow: 1, DateSource: DateFromColumn}, wantErr: true},
+		{name: "invalid fixed date", profile: &Profile{HeaderRow: 1, DateSource: DateFixed, FixedDate: "03/03/2024"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParser(tt.profile)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewParser() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && parser.cols != tt.want {
+				t.Fatalf("columns = %+v, want %+v", parser.cols, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipsSheet(t *testing.T) {
+	profile := &Profile{SkipSheets: []string{"Summary", " Totals "}}
+
+	tests := []struct {
+		sheet string
+		want  bool
+	}{
+		{sheet: "Summary", want: true},
+		{sheet: "SUMMARY ", want: true},
+		{sheet: "totals", want: true},
+		{sheet: "3 Mar 2024"},
+		{sheet: "Summary 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sheet, func(t *testing.T) {
+			if got := profile.SkipsSheet(tt.sheet); got != tt.want {
+				t.Fatalf("SkipsSheet(%q) = %v, want %v", tt.sheet, got, tt.want)
+			}
+		})
+	}
+}
+
+// readSheet runs the rows of one sheet named after its date through the default layout
+func readSheet(t *testing.T, profile *Profile, rows [][]string) (*Parser, []ImportModel) {
+	t.Helper()
+
+	parser, err := NewParser(profile)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !parser.BeginSheet("3 Mar 2024") {
+		t.Fatalf("sheet skipped: %v", parser.Errors)
+	}
+
+	models := make([]ImportModel, 0)
+
+	for i, row := range rows {
+		if model := parser.Row(i+1, row); model != nil {
+			models = append(models, *model)
+		}
+	}
+
+	return parser, models
+}
+
+func TestRow(t *testing.T) {
+	header := []string{"NAME", "RECEIPT", "TOTAL", "TITHE", "OFFERING"}
+
+	tests := []struct {
+		name       string
+		row        []string
+		wantTotal  float64
+		wantSplit  map[string]float64
+		wantErrors int
+	}{
+		{name: "amounts", row: []string{"Jane", "R1", "1,500", "1,000", "500"}, wantTotal: 1500,
+			wantSplit: map[string]float64{"TITHE": 1000, "OFFERING": 500}},
+		{name: "blank category", row: []string{"Jane", "R1", "1000", "1000"}, wantTotal: 1000,
+			wantSplit: map[string]float64{"TITHE": 1000}},
+		{name: "large total keeps its cents", row: []string{"Jane", "R1", "1 234 567.89", "1234567.89"}, wantTotal: 1234567.89,
+			wantSplit: map[string]float64{"TITHE": 1234567.89}},
+		{name: "missing total", row: []string{"Jane", "R1", "", "100"}, wantErrors: 1},
+		{name: "invalid total", row: []string{"Jane", "R1", "KES 100"}, wantErrors: 1},
+		{name: "invalid category amount", row: []string{"Jane", "R1", "100", "ten"}, wantErrors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, models := readSheet(t, nil, [][]string{header, tt.row})
+
+			if len(parser.Errors) != tt.wantErrors {
+				t.Fatalf("errors = %v, want %d", parser.Errors, tt.wantErrors)
+			}
+
+			if parser.RowsRead != 1 {
+				t.Fatalf("rows read = %d, want 1", parser.RowsRead)
+			}
+
+			if tt.wantErrors > 0 {
+				if len(models) != 0 {
+					t.Fatalf("read %v from a row that could not be parsed", models)
+				}
+				return
+			}
+
+			if len(models) != 1 {
+				t.Fatalf("read %d contributions, want 1", len(models))
+			}
+
+			got := models[0]
+
+			if got.Total != tt.wantTotal || !reflect.DeepEqual(got.BreakDown, tt.wantSplit) {
+				t.Fatalf("read %v %v, want %v %v", got.Total, got.BreakDown, tt.wantTotal, tt.wantSplit)
+			}
+
+			if got.Sheet != "3 Mar 2024" || got.Row != 2 || !got.Date.Equal(date("2024-03-03")) {
+				t.Fatalf("read from %s row %d on %s", got.Sheet, got.Row, got.Date)
+			}
+		})
+	}
+}
+
+func TestRowEndsAtBlankName(t *testing.T) {
+	parser, models := readSheet(t, nil, [][]string{
+		{"NAME", "RECEIPT", "TOTAL", "TITHE"},
+		{"Jane", "R1", "100", "100"},
+		{"", "", "", ""},
+		{"Peter", "R2", "200", "200"},
+	})
+
+	if len(models) != 1 || parser.RowsRead != 1 {
+		t.Fatalf("read %d contributions of %d rows, want the row before the blank name", len(models), parser.RowsRead)
+	}
+
+	if got := parser.Categories(); !reflect.DeepEqual(got, []string{"TITHE"}) {
+		t.Fatalf("categories = %v, want [TITHE]", got)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	header := []string{"NAME", "RECEIPT", "TOTAL", "TITHE", "OFFERING"}
+	rows := [][]string{
+		{"Jane", "R1", "1234567.89", "1234567.89"},
+		{"Peter", "R2", "300.10", "", "300.10"},
+	}
+
+	tests := []struct {
+		name    string
+		profile *Profile
+		totals  []string
+		want    []Discrepancy
+	}{
+		{name: "totals match", totals: []string{"TOTAL", "", "1,234,867.99", "1,234,567.89", "300.10"}},
+		{name: "label in the receipt column", totals: []string{"", "Total:", "1234867.99"}},
+		{name: "blank totals are not compared", totals: []string{"TOTAL", "", "", "", "300.10"}},
+		{
+			name:   "category and grand total differ",
+			totals: []string{"TOTAL", "", "1234868.99", "1234567.89", "301.10"},
+			want: []Discrepancy{
+				{Sheet: "3 Mar 2024", Row: 4, Category: "OFFERING", Expected: 301.1, Actual: 300.1, Difference: -1},
+				{Sheet: "3 Mar 2024", Row: 4, Expected: 1234868.99, Actual: 1234867.99, Difference: -1},
+			},
+		},
+		{
+			name: "custom label",
+			profile: &Profile{HeaderRow: 1, NameColumn: "A", ReceiptColumn: "B", TotalColumn: "C", CategoryStart: "D",
+				DateSource: DateFromSheetName, DateFormats: []string{"_2 Jan 2006"}, TotalsLabel: "Jumla"},
+			totals: []string{"JUMLA", "", "1234867.98"},
+			want:   []Discrepancy{{Sheet: "3 Mar 2024", Row: 4, Expected: 1234867.98, Actual: 1234867.99, Difference: 0.01}},
+		},
+		{
+			name: "ignored totals",
+			profile: &Profile{HeaderRow: 1, NameColumn: "A", ReceiptColumn: "B", TotalColumn: "C", CategoryStart: "D",
+				DateSource: DateFromSheetName, DateFormats: []string{"_2 Jan 2006"}, TotalsAction: TotalsIgnore},
+			totals: []string{"TOTAL", "", "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheet := append([][]string{header}, rows...)
+			sheet = append(sheet, tt.totals, []string{"Mary", "R3", "50"})
+
+			parser, models := readSheet(t, tt.profile, sheet)
+
+			if len(models) != 2 {
+				t.Fatalf("read %d contributions, want the 2 before the totals row", len(models))
+			}
+
+			if !reflect.DeepEqual(parser.Discrepancies, tt.want) {
+				t.Fatalf("discrepancies = %+v, want %+v", parser.Discrepancies, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package imports
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Preview reports what an import would save without saving anything
+type Preview struct {
+	ImportId          int                 `json:"importId"`
+	Token             string              `json:"previewToken"`
+	FileName          string              `json:"fileName"`
+	AlreadyImported   bool                `json:"alreadyImported"`
+	Dates             []string            `json:"dates"`
+	NewCategories     []string            `json:"newCategories"`
+	Sheets            []*SheetSummary     `json:"sheets"`
+	Rows              []*RowCheck         `json:"rows"`
+	DuplicateReceipts []*DuplicateReceipt `json:"duplicateReceipts"`
+	Duplicates        []Duplicate         `json:"duplicates"`
+	Discrepancies     []Discrepancy       `json:"discrepancies"`
+	Errors            []RowError          `json:"errors"`
+	RowsRead          int                 `json:"rowsRead"`
+	GrandTotal        float64             `json:"grandTotal"`
+}
+
+// SheetSummary totals the contributions read from a sheet
+type SheetSummary struct {
+	Sheet     string             `json:"sheet"`
+	Date      string             `json:"date,omitempty"`
+	Rows      int                `json:"rows"`
+	Total     float64            `json:"total"`
+	BreakDown map[string]float64 `json:"breakDown"`
+}
+
+// RowCheck compares the total entered on a row with the sum of its breakdown
+type RowCheck struct {
+	Sheet          string  `json:"sheet"`
+	Row            int     `json:"row"`
+	ReceiptNo      string  `json:"receiptNo"`
+	Contributor    string  `json:"contributor"`
+	Total          float64 `json:"total"`
+	BreakDownTotal float64 `json:"breakDownTotal"`
+	Balanced       bool    `json:"balanced"`
+}
+
+// DuplicateReceipt lists the rows sharing a receipt number. Existing is set when the
+// receipt number has already been saved.
+type DuplicateReceipt struct {
+	ReceiptNo string   `json:"receiptNo"`
+	Rows      []string `json:"rows"`
+	Existing  bool     `json:"existing"`
+}
+
+// NewPreview summarises the sheets and rows read from a file. Categories and receipts
+// already saved are filled in by the caller.
+func NewPreview(sheets []Sheet, rows []ImportModel, rowErrors []RowError, rowsRead int) *Preview {
+	preview := &Preview{
+		Dates:             []string{},
+		NewCategories:     []string{},
+		Sheets:            []*SheetSummary{},
+		Rows:              []*RowCheck{},
+		DuplicateReceipts: []*DuplicateReceipt{},
+		Duplicates:        []Duplicate{},
+		Discrepancies:     []Discrepancy{},
+		Errors:            rowErrors,
+		RowsRead:          rowsRead,
+	}
+
+	if preview.Errors == nil {
+		preview.Errors = []RowError{}
+	}
+
+	summaries := make(map[string]*SheetSummary)
+
+	dates := make(map[string]bool)
+
+	for _, sheet := range sheets {
+		summary := &SheetSummary{Sheet: sheet.Name, BreakDown: map[string]float64{}}
+
+		// sheets read with dates from a column have no date of their own
+		if !sheet.Date.IsZero() {
+			summary.Date = sheet.Date.Format("2006-01-02")
+			dates[summary.Date] = true
+		}
+
+		summaries[sheet.Name] = summary
+		preview.Sheets = append(preview.Sheets, summary)
+	}
+
+	receipts := make(map[string]*DuplicateReceipt)
+	receiptOrder := make([]string, 0)
+
+	for _, row := range rows {
+		var breakDownTotal float64
+
+		for _, value := range row.BreakDown {
+			breakDownTotal += value
+		}
+
+		preview.Rows = append(preview.Rows, &RowCheck{
+			Sheet:          row.Sheet,
+			Row:            row.Row,
+			ReceiptNo:      row.ReceiptNo,
+			Contributor:    row.Name,
+			Total:          row.Total,
+			BreakDownTotal: breakDownTotal,
+			Balanced:       math.Abs(row.Total-breakDownTotal) < 0.005,
+		})
+
+		if summary, ok := summaries[row.Sheet]; ok {
+			summary.Rows++
+			summary.Total += row.Total
+
+			for category, value := range row.BreakDown {
+				summary.BreakDown[category] += value
+			}
+		}
+
+		preview.GrandTotal += row.Total
+		dates[row.Date.Format("2006-01-02")] = true
+
+		if row.ReceiptNo == "" {
+			continue
+		}
+
+		receipt, ok := receipts[row.ReceiptNo]
+
+		if !ok {
+			receipt = &DuplicateReceipt{ReceiptNo: row.ReceiptNo}
+			receipts[row.ReceiptNo] = receipt
+			receiptOrder = append(receiptOrder, row.ReceiptNo)
+		}
+
+		receipt.Rows = append(receipt.Rows, fmt.Sprintf("%s!%d", row.Sheet, row.Row))
+	}
+
+	for date := range dates {
+		preview.Dates = append(preview.Dates, date)
+	}
+
+	slices.Sort(preview.Dates)
+
+	for _, receiptNo := range receiptOrder {
+		if len(receipts[receiptNo].Rows) > 1 {
+			preview.DuplicateReceipts = append(preview.DuplicateReceipts, receipts[receiptNo])
+		}
+	}
+
+	return preview
+}
+
+// AddExistingReceipts marks receipt numbers that have already been saved as duplicates
+func (preview *Preview) AddExistingReceipts(rows []ImportModel, existing []string) {
+	for _, receiptNo := range existing {
+		index := slices.IndexFunc(preview.DuplicateReceipts, func(d *DuplicateReceipt) bool {
+			return d.ReceiptNo == receiptNo
+		})
+
+		if index >= 0 {
+			preview.DuplicateReceipts[index].Existing = true
+			continue
+		}
+
+		duplicate := &DuplicateReceipt{ReceiptNo: receiptNo, Existing: true}
+
+		for _, row := range rows {
+			if row.ReceiptNo == receiptNo {
+				duplicate.Rows = append(duplicate.Rows, fmt.Sprintf("%s!%d", row.Sheet, row.Row))
+			}
+		}
+
+		preview.DuplicateReceipts = append(preview.DuplicateReceipts, duplicate)
+	}
+}
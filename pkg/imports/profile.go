@@ -0,0 +1,98 @@
+package imports
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Date sources of an import profile
+const (
+	DateFromSheetName = "SHEET_NAME"
+	DateFromColumn    = "COLUMN"
+	DateFixed         = "FIXED"
+)
+
+// Actions taken when the rows of a sheet do not add up to its totals row
+const (
+	TotalsWarn   = "WARN"
+	TotalsFail   = "FAIL"
+	TotalsIgnore = "IGNORE"
+)
+
+// DefaultTotalsLabel marks the totals row of a sheet when a profile does not name one
+const DefaultTotalsLabel = "TOTAL"
+
+// Profile describes the layout of an organization's workbooks. Columns are given as
+// spreadsheet letters and rows are numbered from 1.
+type Profile struct {
+	ID             int      `json:"id"`
+	OrganizationId int      `json:"organizationId"`
+	Name           string   `json:"name"`
+	HeaderRow      int      `json:"headerRow"`
+	NameColumn     string   `json:"nameColumn"`
+	ReceiptColumn  string   `json:"receiptColumn"`
+	TotalColumn    string   `json:"totalColumn"`
+	CategoryStart  string   `json:"categoryStartColumn"`
+	CategoryEnd    string   `json:"categoryEndColumn,omitempty"`
+	DateSource     string   `json:"dateSource"`
+	DateColumn     string   `json:"dateColumn,omitempty"`
+	FixedDate      string   `json:"fixedDate,omitempty"`
+	DateFormats    []string `json:"dateFormats"`
+	SkipSheets     []string `json:"skipSheets"`
+	// TotalsLabel is the text in the name or receipt column of a sheet's totals row
+	TotalsLabel string `json:"totalsLabel"`
+	// TotalsAction is WARN, FAIL or IGNORE
+	TotalsAction string `json:"totalsAction"`
+}
+
+// DefaultProfile is the layout of the original sabbath workbooks: one sheet per sabbath
+// named after its date, names in A, receipts in B, totals in C and categories from D.
+func DefaultProfile() *Profile {
+	return &Profile{
+		Name:          "Default",
+		HeaderRow:     1,
+		NameColumn:    "A",
+		ReceiptColumn: "B",
+		TotalColumn:   "C",
+		CategoryStart: "D",
+		DateSource:    DateFromSheetName,
+		DateFormats:   []string{"_2 Jan 2006"},
+		SkipSheets:    []string{},
+		TotalsLabel:   DefaultTotalsLabel,
+		TotalsAction:  TotalsWarn,
+	}
+}
+
+// SkipsSheet reports whether a sheet should not be read
+func (p *Profile) SkipsSheet(sheet string) bool {
+	return slices.ContainsFunc(p.SkipSheets, func(s string) bool {
+		return strings.EqualFold(strings.TrimSpace(s), strings.TrimSpace(sheet))
+	})
+}
+
+// ParseDate parses value with the first of the profile's date formats that matches
+func (p *Profile) ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range p.DateFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%q does not match any of the date formats %s", value, strings.Join(p.DateFormats, ", "))
+}
+
+// ParseSheetDate reads the date a sheet is named after, trying the name as it is
+// before the name without the ordinal suffixes of its day
+func (p *Profile) ParseSheetDate(sheet string) (time.Time, error) {
+	date, err := p.ParseDate(sheet)
+
+	if err != nil {
+		date, err = p.ParseDate(cleanDate(sheet))
+	}
+
+	return date, err
+}
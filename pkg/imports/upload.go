@@ -0,0 +1,63 @@
+package imports
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+)
+
+// File is an uploaded file opened for reading
+type File interface {
+	io.ReaderAt
+	io.ReadSeeker
+	io.Closer
+}
+
+// Upload is an uploaded file spooled to disk. The file is hashed while it is copied
+// so that it is only read once.
+type Upload struct {
+	Name string
+	Path string
+	Hash string
+	Size int64
+}
+
+// Spool copies an uploaded file to a new file in dir
+func Spool(dir, name string, r io.Reader) (*Upload, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(dir, "upload-*")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(file, hash), r)
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return &Upload{Name: name, Path: file.Name(), Hash: fmt.Sprintf("%x", hash.Sum(nil)), Size: size}, nil
+}
+
+func (u *Upload) Open() (File, error) {
+	return os.Open(u.Path)
+}
+
+// Remove deletes the spooled file once it is no longer needed
+func (u *Upload) Remove() error {
+	return os.Remove(u.Path)
+}
@@ -0,0 +1,305 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+)
+
+// Handler runs a job of one kind. The context is cancelled when the pool gives up
+// waiting for running jobs on shutdown or when the job's lock is lost.
+type Handler func(ctx context.Context, job *models.Job) (*Result, error)
+
+// BuryHook is told about a job of one kind that has been given up on, so that
+// whatever the job was working on can be failed as well
+type BuryHook func(job *models.Job, cause error)
+
+// Queue is the store the pool claims jobs from and records their outcome in
+type Queue interface {
+	Claim(worker string, visibility time.Duration) (*models.Job, error)
+	Extend(id int, worker string, visibility time.Duration) (bool, error)
+	Complete(id int, worker string, result json.RawMessage) error
+	Retry(id int, worker string, cause error, delay time.Duration) error
+	Bury(id int, worker string, cause error) error
+	Release(id int, worker string) error
+}
+
+// Result is what a successful job leaves behind. Data is kept as json.
+type Result struct {
+	Data any
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error that retrying will not fix. The job goes straight to the
+// dead letter state.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether an error was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+
+	return errors.As(err, &permanent)
+}
+
+// Pool runs queued jobs on a fixed number of workers
+type Pool struct {
+	Jobs         Queue
+	Workers      int
+	PollInterval time.Duration
+	Visibility   time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+	Logger       *utils.CLogger
+
+	handlers map[string]Handler
+	buried   map[string]BuryHook
+	stop     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewPool(jobs Queue, workers int, logger *utils.CLogger) *Pool {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Pool{
+		Jobs:         jobs,
+		Workers:      workers,
+		PollInterval: time.Second,
+		Visibility:   5 * time.Minute,
+		MinBackoff:   10 * time.Second,
+		MaxBackoff:   time.Hour,
+		Logger:       logger,
+		handlers:     make(map[string]Handler),
+		buried:       make(map[string]BuryHook),
+		stop:         make(chan struct{}),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Register sets the handler for a kind of job. Handlers must be registered before
+// the pool is started.
+func (p *Pool) Register(kind string, handler Handler) {
+	p.handlers[kind] = handler
+}
+
+// OnBury sets the hook run whenever a job of the kind is buried, whether the
+// handler gave up on it or the pool did because it had no handler or the job kept
+// outliving its lock. Hooks must be registered before the pool is started.
+func (p *Pool) OnBury(kind string, hook BuryHook) {
+	p.buried[kind] = hook
+}
+
+func (p *Pool) Start() {
+	hostname, _ := os.Hostname()
+
+	for i := 1; i <= p.Workers; i++ {
+		p.wg.Add(1)
+		go p.work(fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), i))
+	}
+
+	p.Logger.InfoLog.Printf("Started %d job workers", p.Workers)
+}
+
+// Shutdown stops claiming jobs and waits for the running ones to finish. When the
+// context ends first the running jobs are cancelled and handed back to the queue;
+// any that ignore the cancellation are picked up again once their lock expires.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	close(p.stop)
+
+	done := make(chan struct{})
+
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work(worker string) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		job, err := p.Jobs.Claim(worker, p.Visibility)
+
+		if err != nil {
+			p.Logger.ErrorLog.Printf("Error while claiming a job: %v", err)
+		}
+
+		if job == nil {
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(p.PollInterval):
+			}
+			continue
+		}
+
+		p.run(worker, job)
+	}
+}
+
+func (p *Pool) run(worker string, job *models.Job) {
+	handler, ok := p.handlers[job.Kind]
+
+	if !ok {
+		p.bury(worker, job, fmt.Errorf("no handler for %s jobs", job.Kind))
+		return
+	}
+
+	if job.Attempts > job.MaxAttempts {
+		p.bury(worker, job, fmt.Errorf("gave up after %d attempts, the last one did not finish within the visibility timeout",
+			job.MaxAttempts))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go p.heartbeat(worker, job.ID, cancel, done)
+
+	result, err := call(ctx, handler, job)
+	close(done)
+
+	if err != nil && p.ctx.Err() != nil {
+		if err := p.Jobs.Release(job.ID, worker); err != nil {
+			p.Logger.ErrorLog.Printf("Error while releasing job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	if err != nil {
+		if IsPermanent(err) || job.Attempts >= job.MaxAttempts {
+			p.bury(worker, job, err)
+			return
+		}
+
+		delay := p.backoff(job.Attempts)
+		p.Logger.ErrorLog.Printf("Job %d (%s) failed on attempt %d, retrying in %s: %v", job.ID, job.Kind, job.Attempts, delay, err)
+
+		if err := p.Jobs.Retry(job.ID, worker, err, delay); err != nil {
+			p.Logger.ErrorLog.Printf("Error while rescheduling job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	if result == nil {
+		result = &Result{}
+	}
+
+	var data json.RawMessage
+
+	if result.Data != nil {
+		data, err = json.Marshal(result.Data)
+
+		if err != nil {
+			p.bury(worker, job, err)
+			return
+		}
+	}
+
+	if err := p.Jobs.Complete(job.ID, worker, data); err != nil {
+		p.Logger.ErrorLog.Printf("Error while completing job %d: %v", job.ID, err)
+	}
+}
+
+// heartbeat extends the lock on a running job until it is done. The job is cancelled
+// if another worker has taken it over.
+func (p *Pool) heartbeat(worker string, id int, cancel context.CancelFunc, done chan struct{}) {
+	ticker := time.NewTicker(p.Visibility / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			held, err := p.Jobs.Extend(id, worker, p.Visibility)
+
+			if err != nil {
+				p.Logger.ErrorLog.Printf("Error while extending the lock on job %d: %v", id, err)
+				continue
+			}
+
+			if !held {
+				p.Logger.ErrorLog.Printf("Lost the lock on job %d", id)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (p *Pool) bury(worker string, job *models.Job, cause error) {
+	p.Logger.ErrorLog.Printf("Job %d (%s) is dead after %d attempts: %v", job.ID, job.Kind, job.Attempts, cause)
+
+	if err := p.Jobs.Bury(job.ID, worker, cause); err != nil {
+		p.Logger.ErrorLog.Printf("Error while burying job %d: %v", job.ID, err)
+	}
+
+	if hook, ok := p.buried[job.Kind]; ok {
+		hook(job, cause)
+	}
+}
+
+// backoff doubles the delay with every attempt up to MaxBackoff, with some jitter so
+// that jobs failing together do not retry together
+func (p *Pool) backoff(attempt int) time.Duration {
+	delay := p.MinBackoff
+
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	delay = min(delay, p.MaxBackoff)
+
+	return delay + rand.N(delay/5+1)
+}
+
+// call runs the handler, turning a panic into an error
+func call(ctx context.Context, handler Handler, job *models.Job) (result *Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unexpected error while running job %d: %v", job.ID, r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+)
+
+// fakeQueue records what the pool does with the job it hands out
+type fakeQueue struct {
+	mu       sync.Mutex
+	held     bool
+	outcome  string
+	delay    time.Duration
+	cause    error
+	extended int
+}
+
+func (q *fakeQueue) Claim(worker string, visibility time.Duration) (*models.Job, error) {
+	return nil, nil
+}
+
+func (q *fakeQueue) Extend(id int, worker string, visibility time.Duration) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.extended++
+
+	return q.held, nil
+}
+
+func (q *fakeQueue) Complete(id int, worker string, result json.RawMessage) error {
+	q.outcome = "complete"
+	return nil
+}
+
+func (q *fakeQueue) Retry(id int, worker string, cause error, delay time.Duration) error {
+	q.outcome, q.cause, q.delay = "retry", cause, delay
+	return nil
+}
+
+func (q *fakeQueue) Bury(id int, worker string, cause error) error {
+	q.outcome, q.cause = "bury", cause
+	return nil
+}
+
+func (q *fakeQueue) Release(id int, worker string) error {
+	q.outcome = "release"
+	return nil
+}
+
+func newTestPool(queue *fakeQueue) *Pool {
+	discard := log.New(io.Discard, "", 0)
+
+	return NewPool(queue, 1, &utils.CLogger{InfoLog: discard, ErrorLog: discard})
+}
+
+func TestBackoff(t *testing.T) {
+	p := newTestPool(&fakeQueue{})
+	p.MinBackoff = 10 * time.Second
+	p.MaxBackoff = time.Minute
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: 10 * time.Second},
+		{attempt: 1, want: 10 * time.Second},
+		{attempt: 2, want: 20 * time.Second},
+		{attempt: 3, want: 40 * time.Second},
+		{attempt: 4, want: time.Minute},
+		{attempt: 50, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			got := p.backoff(tt.attempt)
+
+			if got < tt.want || got > tt.want+tt.want/5 {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.want, tt.want+tt.want/5)
+			}
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name        string
+		kind        string
+		attempts    int
+		handler     Handler
+		want        string
+		wantRan     bool
+		wantBuryErr error
+	}{
+		{
+			name:     "success",
+			attempts: 1,
+			handler:  func(ctx context.Context, job *models.Job) (*Result, error) { return &Result{Data: "done"}, nil },
+			want:     "complete",
+			wantRan:  true,
+		},
+		{
+			name:     "error with attempts left",
+			attempts: 1,
+			handler:  func(ctx context.Context, job *models.Job) (*Result, error) { return nil, errFailed },
+			want:     "retry",
+			wantRan:  true,
+		},
+		{
+			name:     "panic with attempts left",
+			attempts: 1,
+			handler:  func(ctx context.Context, job *models.Job) (*Result, error) { panic("broken") },
+			want:     "retry",
+			wantRan:  true,
+		},
+		{
+			name:        "error on the last attempt",
+			attempts:    3,
+			handler:     func(ctx context.Context, job *models.Job) (*Result, error) { return nil, errFailed },
+			want:        "bury",
+			wantRan:     true,
+			wantBuryErr: errFailed,
+		},
+		{
+			name:        "permanent error",
+			attempts:    1,
+			handler:     func(ctx context.Context, job *models.Job) (*Result, error) { return nil, Permanent(errFailed) },
+			want:        "bury",
+			wantRan:     true,
+			wantBuryErr: errFailed,
+		},
+		{
+			name:     "attempts used up by visibility timeouts",
+			attempts: 4,
+			handler:  func(ctx context.Context, job *models.Job) (*Result, error) { return nil, nil },
+			want:     "bury",
+		},
+		{
+			name:     "no handler",
+			kind:     "UNKNOWN",
+			attempts: 1,
+			want:     "bury",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &fakeQueue{held: true}
+			p := newTestPool(queue)
+
+			ran := false
+			var buried error
+
+			if tt.handler != nil {
+				p.Register("TEST", func(ctx context.Context, job *models.Job) (*Result, error) {
+					ran = true
+					return tt.handler(ctx, job)
+				})
+			}
+
+			p.OnBury("TEST", func(job *models.Job, cause error) { buried = cause })
+			p.OnBury("UNKNOWN", func(job *models.Job, cause error) { buried = cause })
+
+			kind := tt.kind
+
+			if kind == "" {
+				kind = "TEST"
+			}
+
+			p.run("worker", &models.Job{ID: 1, Kind: kind, Attempts: tt.attempts, MaxAttempts: 3})
+
+			if queue.outcome != tt.want {
+				t.Fatalf("outcome = %q, want %q", queue.outcome, tt.want)
+			}
+
+			if ran != tt.wantRan {
+				t.Fatalf("handler ran = %v, want %v", ran, tt.wantRan)
+			}
+
+			if tt.want == "bury" && (buried == nil || buried != queue.cause) {
+				t.Fatalf("bury hook got %v, want %v", buried, queue.cause)
+			}
+
+			if tt.want != "bury" && buried != nil {
+				t.Fatalf("bury hook got %v for a job that was not buried", buried)
+			}
+
+			if tt.wantBuryErr != nil && !errors.Is(queue.cause, tt.wantBuryErr) {
+				t.Fatalf("buried with %v, want %v", queue.cause, tt.wantBuryErr)
+			}
+
+			if tt.want == "retry" && (queue.delay < p.MinBackoff || queue.delay > p.MinBackoff+p.MinBackoff/5) {
+				t.Fatalf("retried in %s, want the first backoff", queue.delay)
+			}
+		})
+	}
+}
+
+func TestRunLostLock(t *testing.T) {
+	queue := &fakeQueue{held: false}
+	p := newTestPool(queue)
+	p.Visibility = 30 * time.Millisecond
+
+	p.Register("TEST", func(ctx context.Context, job *models.Job) (*Result, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil, nil
+		}
+	})
+
+	p.run("worker", &models.Job{ID: 1, Kind: "TEST", Attempts: 1, MaxAttempts: 3})
+
+	if queue.extended == 0 {
+		t.Fatal("lock was never extended")
+	}
+
+	if queue.outcome != "retry" || !errors.Is(queue.cause, context.Canceled) {
+		t.Fatalf("outcome = %q with %v, want the job retried after it was cancelled", queue.outcome, queue.cause)
+	}
+}
+
+func TestRunOnShutdown(t *testing.T) {
+	queue := &fakeQueue{held: true}
+	p := newTestPool(queue)
+
+	p.Register("TEST", func(ctx context.Context, job *models.Job) (*Result, error) {
+		p.cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	p.run("worker", &models.Job{ID: 1, Kind: "TEST", Attempts: 3, MaxAttempts: 3})
+
+	if queue.outcome != "release" {
+		t.Fatalf("outcome = %q, want the job handed back to the queue", queue.outcome)
+	}
+}
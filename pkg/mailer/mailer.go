@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"embed"
 	"html/template"
+	"io/fs"
 	"time"
 
+	"github.com/VaudKK/CAS/pkg/i18n"
+	"github.com/VaudKK/CAS/pkg/theme"
 	"github.com/go-mail/mail/v2"
 )
 
@@ -15,23 +18,69 @@ var templateFS embed.FS // embedded file system
 // Define a Mailer struct which contains a mail.Dialer instance (used to connect to a
 // SMTP server) and the sender information for your emails (the name and address you
 // want the email to be from, such as "Alice Smith <alice@example.com>").
+// Organizations with a theme get their own templates and logo where they have them.
+// Templates translated into a language are kept in a directory named after its
+// locale, and write amounts, numbers and dates the way the language does with the
+// amount, number and date functions.
 type Mailer struct {
 	dialer *mail.Dialer
 	sender string
+	themes theme.Dir
 }
 
-func New(host string, port int, username, password, sender string) Mailer {
+// Message is an email waiting to be rendered from a template and sent. Zero for
+// OrganizationId sends it with the default templates and logo, and an empty Locale in
+// English.
+type Message struct {
+	OrganizationId int    `json:"organizationId,omitempty"`
+	Recipient      string `json:"recipient"`
+	Locale         string `json:"locale,omitempty"`
+	Template       string `json:"template"`
+	Data           any    `json:"data"`
+}
+
+// Attachment is a file sent along with an email
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+func New(host string, port int, username, password, sender string, themes theme.Dir) Mailer {
 	dialer := mail.NewDialer(host, port, username, password)
 	dialer.Timeout = 5 * time.Second
 
 	return Mailer{
 		dialer: dialer,
 		sender: sender,
+		themes: themes,
 	}
 }
 
-func (m *Mailer) Send(recipient, templateFile string, data interface{}) error {
-	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
+func (m *Mailer) Send(message Message) error {
+	return m.SendAttachments(message.OrganizationId, []string{message.Recipient}, message.Locale, message.Template,
+		message.Data)
+}
+
+// SendAttachments sends one email to all the recipients with the files attached,
+// written in the language of locale
+func (m *Mailer) SendAttachments(organizationId int, recipients []string, locale, templateFile string, data any,
+	attachments ...Attachment) error {
+	p := i18n.For(locale)
+
+	templates, path := m.template(organizationId, p.Locale(), templateFile)
+
+	tmpl, err := template.New("email").Funcs(template.FuncMap{
+		"amount": p.Amount,
+		"number": p.Number,
+		"date":   p.Date,
+	}).ParseFS(templates, path)
+
+	if err != nil {
+		return err
+	}
+
+	logo, err := m.themes.ReadFile(organizationId, "logo.png", templateFS, "templates/images/logo.png")
 
 	if err != nil {
 		return err
@@ -65,12 +114,17 @@ func (m *Mailer) Send(recipient, templateFile string, data interface{}) error {
 	// always be called *after* SetBody().
 	msg := mail.NewMessage()
 
-	msg.SetHeader("To", recipient)
+	msg.SetHeader("To", recipients...)
 	msg.SetHeader("From", m.sender)
 	msg.SetHeader("Subject", subject.String())
 	msg.SetBody("text/plain", plainBody.String())
 	msg.AddAlternative("text/html", htmlBody.String())
-	msg.Embed("./pkg/mailer/templates/images/logo.png")
+	msg.EmbedReader("logo.png", bytes.NewReader(logo))
+
+	for _, attachment := range attachments {
+		msg.AttachReader(attachment.FileName, bytes.NewReader(attachment.Data),
+			mail.SetHeader(map[string][]string{"Content-Type": {attachment.ContentType}}))
+	}
 
 	// Call the DialAndSend() method on the dialer, passing in the message to send. This
 	// opens a connection to the SMTP server, sends the message, then closes the
@@ -83,3 +137,30 @@ func (m *Mailer) Send(recipient, templateFile string, data interface{}) error {
 	return nil
 
 }
+
+// template finds the template of an email, preferring the organization's theme and,
+// within each, the translation into the locale. A theme's templates are read as they
+// are sent, so that they can be changed without a restart.
+func (m *Mailer) template(organizationId int, locale, templateFile string) (fs.FS, string) {
+	paths := []string{"templates/" + templateFile}
+
+	if locale != i18n.DefaultLocale {
+		paths = append([]string{"templates/" + locale + "/" + templateFile}, paths...)
+	}
+
+	if themed := m.themes.FS(organizationId); themed != nil {
+		for _, path := range paths {
+			if _, err := fs.Stat(themed, path); err == nil {
+				return themed, path
+			}
+		}
+	}
+
+	for _, path := range paths {
+		if _, err := fs.Stat(templateFS, path); err == nil {
+			return templateFS, path
+		}
+	}
+
+	return templateFS, paths[len(paths)-1]
+}
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/imports"
+)
 
 type Audit struct {
 	CreatedAt  time.Time `json:"createdAt"`
@@ -18,6 +23,9 @@ type User struct {
 	Verified       bool      `json:"verified"`
 	Active         bool      `json:"active"`
 	LastLogin      time.Time `json:"lastLogin"`
+	// Locale is the language the user reads reports and emails in: their own choice,
+	// otherwise that of their organization
+	Locale string `json:"locale"`
 	Audit
 }
 
@@ -29,9 +37,20 @@ type Fund struct {
 	OrganizationId int                `json:"organizationId"`
 	Date           string             `json:"date"`
 	Contributor    string             `json:"contributor"`
+	PaymentMethod  string             `json:"paymentMethod"`
+	ImportId       int                `json:"importId,omitempty"`
 	Audit
 }
 
+// FundCursor reads contributions one at a time, such as from an open query, so that
+// exports do not need every row in memory
+type FundCursor interface {
+	Next() bool
+	Fund() *Fund
+	Err() error
+	Close() error
+}
+
 type UpdateFund struct {
 	Contributor string             `json:"contributor"`
 	Date        string             `json:"date"`
@@ -60,12 +79,6 @@ type MonthlyStats struct {
 	Value float64 `json:"value"`
 }
 
-type MonthlySummations struct {
-	Category string
-	Date     string
-	Total    float64
-}
-
 type StatisticalVariance struct {
 	Category      string
 	Total         float64
@@ -79,3 +92,381 @@ type Variance struct {
 	Percentage   float32 `json:"percentage"`
 	Direction    int8    `json:"direction"`
 }
+
+type Budget struct {
+	ID             int       `json:"id"`
+	OrganizationId int       `json:"organizationId"`
+	Category       string    `json:"category"`
+	FiscalYear     int       `json:"fiscalYear"`
+	Amount         float64   `json:"amount"`
+	MonthlyPhasing []float64 `json:"monthlyPhasing,omitempty"`
+	AlertThreshold float64   `json:"alertThreshold"`
+	Audit
+}
+
+type BudgetVsActual struct {
+	Category     string  `json:"category"`
+	FiscalYear   int     `json:"fiscalYear"`
+	Month        int     `json:"month"`
+	AnnualBudget float64 `json:"annualBudget"`
+	Target       float64 `json:"target"`
+	Actual       float64 `json:"actual"`
+	Variance     float64 `json:"variance"`
+	Percentage   float32 `json:"percentage"`
+	Direction    int8    `json:"direction"`
+	Alert        bool    `json:"alert"`
+}
+
+type Expenditure struct {
+	ID             int        `json:"id"`
+	Payee          string     `json:"payee"`
+	Amount         float64    `json:"amount"`
+	Category       string     `json:"category"`
+	PaymentMethod  string     `json:"paymentMethod"`
+	VoucherNo      string     `json:"voucherNo"`
+	Description    string     `json:"description"`
+	OrganizationId int        `json:"organizationId"`
+	Date           string     `json:"date"`
+	DocumentName   string     `json:"documentName,omitempty"`
+	ApprovedBy     int        `json:"approvedBy,omitempty"`
+	ApprovedAt     *time.Time `json:"approvedAt,omitempty"`
+	Audit
+}
+
+type ExpenditureDocument struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+type FundBalance struct {
+	Category    string  `json:"category"`
+	Income      float64 `json:"income"`
+	Expenditure float64 `json:"expenditure"`
+	Balance     float64 `json:"balance"`
+}
+
+type Account struct {
+	ID             int    `json:"id"`
+	OrganizationId int    `json:"organizationId"`
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Category       string `json:"category,omitempty"`
+	Audit
+}
+
+type JournalEntry struct {
+	ID             int           `json:"id"`
+	OrganizationId int           `json:"organizationId"`
+	Date           string        `json:"date"`
+	Description    string        `json:"description"`
+	SourceType     string        `json:"sourceType"`
+	SourceId       int           `json:"sourceId,omitempty"`
+	ReversesId     int           `json:"reversesId,omitempty"`
+	Lines          []JournalLine `json:"lines"`
+	Audit
+}
+
+// AccountMapping names the account of an accounting package that the entries of a
+// CAS category, or of cash and bank, are exported to
+type AccountMapping struct {
+	ID             int    `json:"id"`
+	OrganizationId int    `json:"organizationId"`
+	AccountType    string `json:"accountType"`
+	Category       string `json:"category"`
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	Audit
+}
+
+type JournalLine struct {
+	AccountId   int     `json:"accountId"`
+	AccountCode string  `json:"accountCode"`
+	AccountName string  `json:"accountName"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+}
+
+type LedgerImbalance struct {
+	EntryId int     `json:"entryId"`
+	Debit   float64 `json:"debit"`
+	Credit  float64 `json:"credit"`
+}
+
+type Transfer struct {
+	FromAccountId int     `json:"fromAccountId"`
+	ToAccountId   int     `json:"toAccountId"`
+	Amount        float64 `json:"amount"`
+	Date          string  `json:"date"`
+	Description   string  `json:"description"`
+}
+
+type StatementPeriod struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	PriorFrom time.Time `json:"priorFrom,omitempty"`
+	PriorTo   time.Time `json:"priorTo,omitempty"`
+}
+
+type TrialBalanceLine struct {
+	Code        string  `json:"code"`
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+	PriorDebit  float64 `json:"priorDebit"`
+	PriorCredit float64 `json:"priorCredit"`
+}
+
+type TrialBalance struct {
+	Period           StatementPeriod     `json:"period"`
+	Lines            []*TrialBalanceLine `json:"lines"`
+	TotalDebit       float64             `json:"totalDebit"`
+	TotalCredit      float64             `json:"totalCredit"`
+	PriorTotalDebit  float64             `json:"priorTotalDebit"`
+	PriorTotalCredit float64             `json:"priorTotalCredit"`
+}
+
+type StatementLine struct {
+	Code        string  `json:"code"`
+	Name        string  `json:"name"`
+	Amount      float64 `json:"amount"`
+	PriorAmount float64 `json:"priorAmount"`
+}
+
+type IncomeStatement struct {
+	Period                StatementPeriod  `json:"period"`
+	Income                []*StatementLine `json:"income"`
+	Expenditure           []*StatementLine `json:"expenditure"`
+	TotalIncome           float64          `json:"totalIncome"`
+	TotalExpenditure      float64          `json:"totalExpenditure"`
+	Surplus               float64          `json:"surplus"`
+	PriorTotalIncome      float64          `json:"priorTotalIncome"`
+	PriorTotalExpenditure float64          `json:"priorTotalExpenditure"`
+	PriorSurplus          float64          `json:"priorSurplus"`
+}
+
+type FundBalanceLine struct {
+	Fund         string  `json:"fund"`
+	Opening      float64 `json:"opening"`
+	Receipts     float64 `json:"receipts"`
+	Payments     float64 `json:"payments"`
+	Transfers    float64 `json:"transfers"`
+	Closing      float64 `json:"closing"`
+	PriorClosing float64 `json:"priorClosing"`
+}
+
+type FundBalanceSheet struct {
+	Period StatementPeriod    `json:"period"`
+	Lines  []*FundBalanceLine `json:"lines"`
+	Totals FundBalanceLine    `json:"totals"`
+}
+
+type CashStatementRow struct {
+	Date    string             `json:"date"`
+	Amounts map[string]float64 `json:"amounts"`
+	Total   float64            `json:"total"`
+}
+
+type CashStatement struct {
+	From          time.Time           `json:"from"`
+	To            time.Time           `json:"to"`
+	Categories    []string            `json:"categories"`
+	Opening       map[string]float64  `json:"opening"`
+	Receipts      []*CashStatementRow `json:"receipts"`
+	TotalReceipts map[string]float64  `json:"totalReceipts"`
+	Payments      map[string]float64  `json:"payments"`
+	Transfers     map[string]float64  `json:"transfers"`
+	Closing       map[string]float64  `json:"closing"`
+	Disbursements []*Expenditure      `json:"disbursements"`
+}
+
+// AnnualReport is the yearly pack presented at the AGM: the cash statement of each
+// month, the year's totals against the year before and the headline figures
+type AnnualReport struct {
+	Year       int              `json:"year"`
+	Categories []string         `json:"categories"`
+	Months     []*CashStatement `json:"months"`
+	Current    *YearTotals      `json:"current"`
+	Previous   *YearTotals      `json:"previous"`
+	KPIs       []*KPI           `json:"kpis"`
+}
+
+// YearTotals are the receipts and payments of a year per category, and per month
+// with January first. Through is the number of months counted.
+type YearTotals struct {
+	Year            int                `json:"year"`
+	Through         int                `json:"through"`
+	Receipts        map[string]float64 `json:"receipts"`
+	Payments        map[string]float64 `json:"payments"`
+	MonthlyReceipts []float64          `json:"monthlyReceipts"`
+	MonthlyPayments []float64          `json:"monthlyPayments"`
+}
+
+// KPI is a headline figure of a report. Unit is KES for amounts, % for percentages
+// and empty for counts. Name is in English; reports in other languages write it from
+// the Label message and its Args.
+type KPI struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+	Label string  `json:"-"`
+	Args  []any   `json:"-"`
+}
+
+type ImportJob struct {
+	ID              int                   `json:"id"`
+	FileName        string                `json:"fileName"`
+	Hash            string                `json:"hash,omitempty"`
+	OrganizationId  int                   `json:"organizationId"`
+	ProfileId       int                   `json:"profileId,omitempty"`
+	DuplicateAction string                `json:"duplicateAction"`
+	Status          string                `json:"status"`
+	RowsRead        int                   `json:"rowsRead"`
+	RowsInserted    int                   `json:"rowsInserted"`
+	RowsUpdated     int                   `json:"rowsUpdated"`
+	RowsSkipped     int                   `json:"rowsSkipped"`
+	Errors          []imports.RowError    `json:"errors"`
+	Duplicates      []imports.Duplicate   `json:"duplicates"`
+	Discrepancies   []imports.Discrepancy `json:"discrepancies"`
+	StartedAt       *time.Time            `json:"startedAt,omitempty"`
+	FinishedAt      *time.Time            `json:"finishedAt,omitempty"`
+	RowsRolledBack  int                   `json:"rowsRolledBack,omitempty"`
+	RolledBackAt    *time.Time            `json:"rolledBackAt,omitempty"`
+	Version         int                   `json:"version"`
+	Audit
+}
+
+// ImportVersion is one of the files an import has been built from. The first version
+// is the file originally uploaded, later ones replaced it with the delta applied.
+type ImportVersion struct {
+	ID          int        `json:"id"`
+	ImportId    int        `json:"importId"`
+	Version     int        `json:"version,omitempty"`
+	FileName    string     `json:"fileName"`
+	FileHash    string     `json:"fileHash,omitempty"`
+	FileSize    int64      `json:"fileSize,omitempty"`
+	ProfileId   int        `json:"profileId,omitempty"`
+	Status      string     `json:"status"`
+	RowsAdded   int        `json:"rowsAdded"`
+	RowsRemoved int        `json:"rowsRemoved"`
+	RowsChanged int        `json:"rowsChanged"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CreatedBy   string     `json:"createdBy,omitempty"`
+	AppliedAt   *time.Time `json:"appliedAt,omitempty"`
+	AppliedBy   string     `json:"appliedBy,omitempty"`
+}
+
+// ClosedPeriod closes the books of an organization up to and including PeriodEnd
+type ClosedPeriod struct {
+	ID             int       `json:"id"`
+	OrganizationId int       `json:"organizationId"`
+	PeriodEnd      time.Time `json:"periodEnd"`
+	Audit
+}
+
+// Job is a unit of background work. The payload is kept out of responses as it can
+// carry tokens meant for a single recipient.
+type Job struct {
+	ID             int             `json:"id"`
+	Kind           string          `json:"kind"`
+	OrganizationId int             `json:"organizationId,omitempty"`
+	Payload        json.RawMessage `json:"-"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	MaxAttempts    int             `json:"maxAttempts"`
+	RunAt          time.Time       `json:"runAt"`
+	LastError      string          `json:"lastError,omitempty"`
+	Result         json.RawMessage `json:"result,omitempty"`
+	StartedAt      *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt     *time.Time      `json:"finishedAt,omitempty"`
+	Audit
+}
+
+// Report is a file generated in the background for download. The file is kept in
+// storage until ExpiresAt and is downloaded through a signed link.
+type Report struct {
+	ID             int              `json:"id"`
+	OrganizationId int              `json:"organizationId"`
+	Type           string           `json:"type"`
+	Format         string           `json:"format"`
+	Parameters     ReportParameters `json:"parameters"`
+	Status         string           `json:"status"`
+	JobId          int              `json:"jobId,omitempty"`
+	StorageKey     string           `json:"-"`
+	FileName       string           `json:"fileName,omitempty"`
+	ContentType    string           `json:"contentType,omitempty"`
+	Size           int64            `json:"size,omitempty"`
+	LastError      string           `json:"lastError,omitempty"`
+	ExpiresAt      *time.Time       `json:"expiresAt,omitempty"`
+	CompletedAt    *time.Time       `json:"completedAt,omitempty"`
+	DownloadURL    string           `json:"downloadUrl,omitempty"`
+	Audit
+}
+
+// ReportParameters select what goes into a report. Which of them apply depends on the
+// report type; dates are in the format YYYY-MM-DD. Locale is the language it is
+// written in.
+type ReportParameters struct {
+	From        string   `json:"from,omitempty"`
+	To          string   `json:"to,omitempty"`
+	Terms       string   `json:"terms,omitempty"`
+	Exact       bool     `json:"exact,omitempty"`
+	Year        int      `json:"year,omitempty"`
+	Month       int      `json:"month,omitempty"`
+	Comparative *bool    `json:"comparative,omitempty"`
+	Charts      []string `json:"charts,omitempty"`
+	Locale      string   `json:"locale,omitempty"`
+}
+
+// ReportSubscription sends a report to its recipients each time its cron expression
+// falls due. The report covers the period named by Period, counted back from then.
+type ReportSubscription struct {
+	ID             int        `json:"id"`
+	OrganizationId int        `json:"organizationId"`
+	Name           string     `json:"name"`
+	ReportType     string     `json:"reportType"`
+	Period         string     `json:"period"`
+	Format         string     `json:"format"`
+	Recipients     []string   `json:"recipients"`
+	Cron           string     `json:"cron"`
+	Charts         []string   `json:"charts,omitempty"`
+	Active         bool       `json:"active"`
+	NextRunAt      *time.Time `json:"nextRunAt,omitempty"`
+	LastRunAt      *time.Time `json:"lastRunAt,omitempty"`
+	Audit
+}
+
+// ReportDelivery records a report of a subscription being sent, one per time the
+// subscription fell due or was sent by hand
+type ReportDelivery struct {
+	ID             int        `json:"id"`
+	SubscriptionId int        `json:"subscriptionId"`
+	OrganizationId int        `json:"organizationId"`
+	ScheduledFor   time.Time  `json:"scheduledFor"`
+	PeriodFrom     string     `json:"periodFrom"`
+	PeriodTo       string     `json:"periodTo"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	JobId          int        `json:"jobId,omitempty"`
+	Recipients     []string   `json:"recipients"`
+	FileName       string     `json:"fileName,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	SentAt         *time.Time `json:"sentAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	CreatedBy      string     `json:"createdBy,omitempty"`
+}
+
+// Charts selects the charts added to contribution reports and cash statements: the
+// share of each category, stacked columns per sabbath and monthly trend lines
+type Charts struct {
+	Pie     bool
+	Columns bool
+	Trend   bool
+}
+
+// Any reports whether any chart is selected
+func (c Charts) Any() bool {
+	return c.Pie || c.Columns || c.Trend
+}
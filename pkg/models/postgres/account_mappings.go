@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/VaudKK/CAS/pkg/models"
+)
+
+// GetAccountMappings lists the accounting package accounts the organization's
+// categories are exported to
+func (m *LedgerModel) GetAccountMappings(organizationId int) ([]*models.AccountMapping, error) {
+	stmt := `SELECT id,organization_id,account_type,category,external_code,external_name,created_at,modified_at,
+				coalesce(created_by,''),coalesce(modified_by,'')
+				FROM account_mappings WHERE organization_id = $1 ORDER BY account_type, category;`
+
+	rows, err := m.DB.Query(stmt, organizationId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	mappings := []*models.AccountMapping{}
+
+	for rows.Next() {
+		mapping := &models.AccountMapping{}
+
+		err := rows.Scan(&mapping.ID, &mapping.OrganizationId, &mapping.AccountType, &mapping.Category, &mapping.Code,
+			&mapping.Name, &mapping.Audit.CreatedAt, &mapping.Audit.ModifiedAt, &mapping.Audit.CreatedBy,
+			&mapping.Audit.ModifiedBy)
+
+		if err != nil {
+			return nil, err
+		}
+
+		mappings = append(mappings, mapping)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return mappings, nil
+}
+
+// SaveAccountMapping maps a category to an account of the accounting package,
+// replacing the account it was mapped to before
+func (m *LedgerModel) SaveAccountMapping(currentUser *models.User, mapping *models.AccountMapping) (int, error) {
+	stmt := `INSERT INTO account_mappings(organization_id,account_type,category,external_code,external_name,created_by)
+				VALUES ($1,$2,$3,$4,$5,$6)
+				ON CONFLICT (organization_id, account_type, category) DO UPDATE
+				SET external_code = excluded.external_code, external_name = excluded.external_name,
+				modified_at = now(), modified_by = excluded.created_by
+				RETURNING id;`
+
+	var id int
+
+	err := m.DB.QueryRow(stmt, currentUser.OrganizationId, mapping.AccountType, mapping.Category, mapping.Code,
+		mapping.Name, fmt.Sprint(currentUser.ID)).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// DeleteAccountMapping removes a mapping, leaving the category to be exported under
+// its own name
+func (m *LedgerModel) DeleteAccountMapping(organizationId, id int) error {
+	result, err := m.DB.Exec(`DELETE FROM account_mappings WHERE organization_id = $1 AND id = $2;`,
+		organizationId, id)
+
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
@@ -0,0 +1,229 @@
+package postgres
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+)
+
+// GetAnnualReport puts together the annual report of a year: a cash statement per
+// month, the year's totals next to those of the year before and the headline
+// figures. The report of the current year runs to the current month, and the year
+// before is taken over the same months so that the two compare like for like.
+func (m *FundsModel) GetAnnualReport(organizationId, year int) (*models.AnnualReport, error) {
+	now := time.Now()
+
+	if year > now.Year() {
+		return nil, fmt.Errorf("the annual report of %d has not started yet", year)
+	}
+
+	months := 12
+
+	if year == now.Year() {
+		months = int(now.Month())
+	}
+
+	report := &models.AnnualReport{Year: year, Months: make([]*models.CashStatement, 0, months)}
+	categories := make(map[string]bool)
+
+	for month := 1; month <= months; month++ {
+		from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+
+		statement, err := m.GetCashStatement(from, from.AddDate(0, 1, -1), organizationId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, category := range statement.Categories {
+			categories[category] = true
+		}
+
+		report.Months = append(report.Months, statement)
+	}
+
+	var err error
+
+	if report.Current, err = m.yearTotals(organizationId, year, months); err != nil {
+		return nil, err
+	}
+
+	if report.Previous, err = m.yearTotals(organizationId, year-1, months); err != nil {
+		return nil, err
+	}
+
+	for _, totals := range []*models.YearTotals{report.Current, report.Previous} {
+		for category := range totals.Receipts {
+			categories[category] = true
+		}
+
+		for category := range totals.Payments {
+			categories[category] = true
+		}
+	}
+
+	report.Categories = make([]string, 0, len(categories))
+
+	for category := range categories {
+		report.Categories = append(report.Categories, category)
+	}
+
+	slices.Sort(report.Categories)
+
+	if report.KPIs, err = m.annualKPIs(organizationId, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// yearTotals sums the receipts and payments of the first months of a year per
+// category and per month
+func (m *FundsModel) yearTotals(organizationId, year, months int) (*models.YearTotals, error) {
+	totals := &models.YearTotals{
+		Year:            year,
+		Through:         months,
+		Receipts:        make(map[string]float64),
+		Payments:        make(map[string]float64),
+		MonthlyReceipts: make([]float64, 12),
+		MonthlyPayments: make([]float64, 12),
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, months, 0)
+
+	queries := []struct {
+		stmt    string
+		byName  map[string]float64
+		byMonth []float64
+	}{
+		{`SELECT extract(month from contribution_date)::int, key, sum(value::jsonb::text::numeric)
+				FROM funds, jsonb_each(funds.break_down)
+				WHERE organization_id = $1 AND contribution_date >= $2 AND contribution_date < $3
+				GROUP BY 1, 2;`, totals.Receipts, totals.MonthlyReceipts},
+		{`SELECT extract(month from expenditure_date)::int, category, sum(amount) FROM expenditures
+				WHERE organization_id = $1 AND expenditure_date >= $2 AND expenditure_date < $3
+				GROUP BY 1, 2;`, totals.Payments, totals.MonthlyPayments},
+	}
+
+	for _, query := range queries {
+		rows, err := m.DB.Query(query.stmt, organizationId, from, to)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var month int
+			var category string
+			var total float64
+
+			if err = rows.Scan(&month, &category, &total); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			query.byName[category] += total
+			query.byMonth[month-1] += total
+		}
+
+		err = rows.Err()
+		rows.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return totals, nil
+}
+
+// annualKPIs works out the headline figures of an annual report
+func (m *FundsModel) annualKPIs(organizationId int, report *models.AnnualReport) ([]*models.KPI, error) {
+	var contributions, contributors, sabbaths int
+
+	stmt := `SELECT count(*), count(DISTINCT lower(contributor)), count(DISTINCT contribution_date) FROM funds
+				WHERE organization_id = $1 AND contribution_date >= $2 AND contribution_date < $3;`
+
+	from := time.Date(report.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	err := m.DB.QueryRow(stmt, organizationId, from, from.AddDate(0, len(report.Months), 0)).Scan(&contributions, &contributors, &sabbaths)
+
+	if err != nil {
+		return nil, err
+	}
+
+	current, previous := report.Current, report.Previous
+	receipts, payments := sumValues(current.Receipts), sumValues(current.Payments)
+
+	kpis := []*models.KPI{
+		newKPI(receipts, "KES", "Total receipts"),
+		newKPI(payments, "KES", "Total payments"),
+		newKPI(receipts-payments, "KES", "Surplus for the year"),
+	}
+
+	if previousReceipts := sumValues(previous.Receipts); previousReceipts != 0 {
+		kpis = append(kpis, newKPI((receipts-previousReceipts)/previousReceipts*100, "%", "Change in receipts on %d",
+			previous.Year))
+	}
+
+	if len(report.Months) > 0 {
+		first, last := report.Months[0], report.Months[len(report.Months)-1]
+
+		kpis = append(kpis,
+			newKPI(sumValues(first.Opening), "KES", "Balance brought forward"),
+			newKPI(sumValues(last.Closing), "KES", "Balance carried forward"))
+	}
+
+	kpis = append(kpis,
+		newKPI(float64(contributions), "", "Contributions received"),
+		newKPI(float64(contributors), "", "Contributors"))
+
+	if sabbaths > 0 {
+		kpis = append(kpis, newKPI(receipts/float64(sabbaths), "KES", "Average receipts per sabbath"))
+	}
+
+	best := -1
+
+	for month, total := range current.MonthlyReceipts {
+		if total > 0 && (best < 0 || total > current.MonthlyReceipts[best]) {
+			best = month
+		}
+	}
+
+	if best >= 0 {
+		kpis = append(kpis, newKPI(current.MonthlyReceipts[best], "KES", "Highest month (%s)", time.Month(best+1)))
+	}
+
+	var largest string
+
+	for _, category := range report.Categories {
+		if current.Receipts[category] > current.Receipts[largest] {
+			largest = category
+		}
+	}
+
+	if largest != "" && receipts > 0 {
+		kpis = append(kpis, newKPI(current.Receipts[largest]/receipts*100, "%", "Share of the largest fund (%s)", largest))
+	}
+
+	return kpis, nil
+}
+
+// newKPI names a key figure from a message, in English and for the reports to
+// translate
+func newKPI(value float64, unit, label string, args ...any) *models.KPI {
+	return &models.KPI{Name: fmt.Sprintf(label, args...), Value: value, Unit: unit, Label: label, Args: args}
+}
+
+func sumValues(values map[string]float64) float64 {
+	var total float64
+
+	for _, value := range values {
+		total += value
+	}
+
+	return total
+}
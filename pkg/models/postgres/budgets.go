@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+)
+
+type BudgetModel struct {
+	DB     *sql.DB
+	Logger *utils.CLogger
+}
+
+// SaveBudget creates the budget for a category and fiscal year or replaces the
+// existing one
+func (m *BudgetModel) SaveBudget(currentUser *models.User, budget *models.Budget) (int, error) {
+	stmt := `INSERT INTO budgets(organization_id,category,fiscal_year,amount,monthly_phasing,alert_threshold,created_by)
+				VALUES ($1,$2,$3,$4,$5,$6,$7)
+				ON CONFLICT (organization_id,category,fiscal_year) DO UPDATE SET
+				amount = EXCLUDED.amount, monthly_phasing = EXCLUDED.monthly_phasing,
+				alert_threshold = EXCLUDED.alert_threshold, modified_at = now(), modified_by = EXCLUDED.created_by
+				RETURNING id;`
+
+	var phasing any
+
+	if len(budget.MonthlyPhasing) > 0 {
+		js, err := json.Marshal(budget.MonthlyPhasing)
+
+		if err != nil {
+			return 0, err
+		}
+
+		phasing = string(js)
+	}
+
+	var id int
+
+	err := m.DB.QueryRow(stmt, budget.OrganizationId, budget.Category, budget.FiscalYear, budget.Amount,
+		phasing, budget.AlertThreshold, currentUser.ID).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *BudgetModel) GetBudgets(organizationId, fiscalYear int) ([]*models.Budget, error) {
+	stmt := `SELECT id,organization_id,category,fiscal_year,amount,monthly_phasing,alert_threshold,created_at,modified_at
+				FROM budgets WHERE organization_id = $1 AND fiscal_year = $2 ORDER BY category;`
+
+	rows, err := m.DB.Query(stmt, organizationId, fiscalYear)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	budgets := []*models.Budget{}
+
+	for rows.Next() {
+		budget := &models.Budget{}
+		var phasing []byte
+
+		err := rows.Scan(&budget.ID, &budget.OrganizationId, &budget.Category, &budget.FiscalYear, &budget.Amount,
+			&phasing, &budget.AlertThreshold, &budget.Audit.CreatedAt, &budget.Audit.ModifiedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if phasing != nil {
+			err = json.Unmarshal(phasing, &budget.MonthlyPhasing)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		budgets = append(budgets, budget)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return budgets, nil
+}
+
+// GetBudgetVsActual compares the budget phased up to the end of month with the
+// contributions received in the fiscal year up to the same point
+func (m *BudgetModel) GetBudgetVsActual(organizationId, fiscalYear, month int) ([]*models.BudgetVsActual, error) {
+	budgets, err := m.GetBudgets(organizationId, fiscalYear)
+
+	if err != nil {
+		return nil, err
+	}
+
+	startDate := time.Date(fiscalYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(fiscalYear, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC)
+
+	stmt := `SELECT key as name, sum(value::jsonb::text::numeric) as value
+				FROM funds, jsonb_each(funds.break_down)
+				WHERE organization_id = $1 AND contribution_date BETWEEN $2 AND $3
+				GROUP BY key;`
+
+	rows, err := m.DB.Query(stmt, organizationId, startDate, endDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	actuals := make(map[string]float64)
+
+	for rows.Next() {
+		var category string
+		var total float64
+
+		err := rows.Scan(&category, &total)
+
+		if err != nil {
+			return nil, err
+		}
+
+		actuals[category] = total
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report := []*models.BudgetVsActual{}
+
+	for _, budget := range budgets {
+		target := phasedTarget(budget, month)
+		actual := actuals[budget.Category]
+		percentage, direction := calculateVariance(actual, target)
+
+		report = append(report, &models.BudgetVsActual{
+			Category:     budget.Category,
+			FiscalYear:   fiscalYear,
+			Month:        month,
+			AnnualBudget: budget.Amount,
+			Target:       target,
+			Actual:       actual,
+			Variance:     actual - target,
+			Percentage:   percentage,
+			Direction:    direction,
+			Alert:        target > 0 && (actual/target)*100 < budget.AlertThreshold,
+		})
+	}
+
+	return report, nil
+}
+
+// GetBudgetAlerts returns only the categories tracking below their alert threshold
+func (m *BudgetModel) GetBudgetAlerts(organizationId, fiscalYear, month int) ([]*models.BudgetVsActual, error) {
+	report, err := m.GetBudgetVsActual(organizationId, fiscalYear, month)
+
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := []*models.BudgetVsActual{}
+
+	for _, row := range report {
+		if row.Alert {
+			alerts = append(alerts, row)
+		}
+	}
+
+	return alerts, nil
+}
+
+// phasedTarget sums the monthly phasing up to and including month, spreading the
+// annual amount evenly when no phasing was given
+func phasedTarget(budget *models.Budget, month int) float64 {
+	if len(budget.MonthlyPhasing) != 12 {
+		return budget.Amount * float64(month) / 12
+	}
+
+	var target float64
+
+	for i := 0; i < month; i++ {
+		target += budget.MonthlyPhasing[i]
+	}
+
+	return target
+}
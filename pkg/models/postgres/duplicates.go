@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/lib/pq"
+)
+
+// FindDuplicates returns, by index, the contributions that match one already saved
+func (m *FundsModel) FindDuplicates(organizationId int, contributions []models.Fund) (map[int]*imports.Duplicate, error) {
+	return m.findDuplicates(context.Background(), m.DB, organizationId, 0, contributions)
+}
+
+// findDuplicates matches contributions against the organization's saved contributions
+// and against the earlier contributions of the same batch. A receipt number match is
+// preferred, contributor, date and total are checked when the receipt does not match
+// and one of the two contributions has no receipt number.
+// Matches saved by earlier batches of the same import count as rows of the same file.
+func (m *FundsModel) findDuplicates(ctx context.Context, q queryer, organizationId, importId int,
+	contributions []models.Fund) (map[int]*imports.Duplicate, error) {
+
+	receipts := make([]string, 0, len(contributions))
+	dates := make([]string, 0, len(contributions))
+
+	for _, contribution := range contributions {
+		if contribution.ReceiptNo != "" {
+			receipts = append(receipts, contribution.ReceiptNo)
+		}
+		dates = append(dates, contribution.Date)
+	}
+
+	stmt := `SELECT id, receipt_no, upper(contributor), contribution_date, total, coalesce(import_id,0) FROM funds
+				WHERE organization_id = $1 AND (receipt_no = ANY($2) OR contribution_date = ANY($3::date[]))
+				ORDER BY id;`
+
+	rows, err := q.QueryContext(ctx, stmt, organizationId, pq.Array(receipts), pq.Array(dates))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	saved := newSavedContributions()
+
+	for rows.Next() {
+		var id int
+		var receiptNo, contributor string
+		var date time.Time
+		var total float64
+		var matchedImport int
+
+		if err := rows.Scan(&id, &receiptNo, &contributor, &date, &total, &matchedImport); err != nil {
+			return nil, err
+		}
+
+		saved.add(id, receiptNo, contributor, date.Format("2006-01-02"), total, importId != 0 && matchedImport == importId)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return saved.match(contributions), nil
+}
+
+// savedContributions indexes the saved contributions a batch is matched against by
+// receipt number and by contributor, date and total. Contributions saved without a
+// receipt number, which were given a generated one, are also indexed on their own.
+type savedContributions struct {
+	byReceipt   map[string]int
+	byDetails   map[string]int
+	unreceipted map[string]int
+	sameImport  map[int]bool
+}
+
+func newSavedContributions() *savedContributions {
+	return &savedContributions{
+		byReceipt:   make(map[string]int),
+		byDetails:   make(map[string]int),
+		unreceipted: make(map[string]int),
+		sameImport:  make(map[int]bool),
+	}
+}
+
+// add indexes a saved contribution. Contributions are added in id order and the
+// first one added under a key is the one matched.
+func (s *savedContributions) add(id int, receiptNo, contributor, date string, total float64, sameImport bool) {
+	if sameImport {
+		s.sameImport[id] = true
+	}
+
+	if _, ok := s.byReceipt[receiptNo]; !ok {
+		s.byReceipt[receiptNo] = id
+	}
+
+	key := detailsKey(contributor, date, total)
+
+	if _, ok := s.byDetails[key]; !ok {
+		s.byDetails[key] = id
+	}
+
+	if _, ok := s.unreceipted[key]; !ok && generatedReceipt(receiptNo) {
+		s.unreceipted[key] = id
+	}
+}
+
+// match returns, by index, the contributions that duplicate a saved contribution or
+// an earlier contribution of the batch. Contributor, date and total only match when
+// one of the two has no receipt number; two different receipts are two contributions.
+func (s *savedContributions) match(contributions []models.Fund) map[int]*imports.Duplicate {
+	duplicates := make(map[int]*imports.Duplicate)
+	seenReceipts := make(map[string]bool)
+	seenDetails := make(map[string]bool)
+	seenUnreceipted := make(map[string]bool)
+
+	for i, contribution := range contributions {
+		key := detailsKey(contribution.Contributor, contribution.Date, contribution.Total)
+		hasReceipt := contribution.ReceiptNo != ""
+
+		byDetails, seen := s.byDetails, seenDetails
+
+		if hasReceipt {
+			byDetails, seen = s.unreceipted, seenUnreceipted
+		}
+
+		duplicate := &imports.Duplicate{
+			ReceiptNo:   contribution.ReceiptNo,
+			Contributor: strings.ToUpper(contribution.Contributor),
+			Date:        contribution.Date,
+			Total:       contribution.Total,
+		}
+
+		switch {
+		case hasReceipt && s.byReceipt[contribution.ReceiptNo] != 0:
+			duplicate.MatchedId, duplicate.MatchedOn = s.byReceipt[contribution.ReceiptNo], imports.MatchReceipt
+		case byDetails[key] != 0:
+			duplicate.MatchedId, duplicate.MatchedOn = byDetails[key], imports.MatchContributorDateTotal
+		case hasReceipt && seenReceipts[contribution.ReceiptNo]:
+			duplicate.InFile, duplicate.MatchedOn = true, imports.MatchReceipt
+		case seen[key]:
+			duplicate.InFile, duplicate.MatchedOn = true, imports.MatchContributorDateTotal
+		default:
+			duplicate = nil
+		}
+
+		if duplicate != nil && s.sameImport[duplicate.MatchedId] {
+			duplicate.MatchedId, duplicate.InFile = 0, true
+		}
+
+		if duplicate != nil {
+			duplicates[i] = duplicate
+		}
+
+		if hasReceipt {
+			seenReceipts[contribution.ReceiptNo] = true
+		} else {
+			seenUnreceipted[key] = true
+		}
+
+		seenDetails[key] = true
+	}
+
+	return duplicates
+}
+
+// generatedReceipt reports whether a saved receipt number was generated because the
+// contribution was saved without one
+func generatedReceipt(receiptNo string) bool {
+	return receiptNo == "" || strings.HasPrefix(receiptNo, generatedReceiptPrefix)
+}
+
+func detailsKey(contributor, date string, total float64) string {
+	return fmt.Sprintf("%s|%s|%d", strings.ToUpper(strings.TrimSpace(contributor)), date, toCents(total))
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/models"
+)
+
+func TestSavedContributionsMatch(t *testing.T) {
+	type saved struct {
+		id          int
+		receiptNo   string
+		contributor string
+		date        string
+		total       float64
+		sameImport  bool
+	}
+
+	existing := []saved{
+		{id: 10, receiptNo: "R001", contributor: "JANE WANJIRU", date: "2024-03-03", total: 1500},
+		{id: 11, receiptNo: "R002", contributor: "O'BRIEN OTIENO", date: "2024-03-03", total: 250.5},
+		{id: 12, receiptNo: "R002", contributor: "PETER KAMAU", date: "2024-03-10", total: 100},
+		{id: 13, receiptNo: "R003", contributor: "MARY ACHIENG", date: "2024-03-10", total: 300, sameImport: true},
+		{id: 14, receiptNo: "KCS-1709460000000000", contributor: "GRACE MUTHONI", date: "2024-03-03", total: 800},
+	}
+
+	tests := []struct {
+		name          string
+		contributions []models.Fund
+		want          map[int]*imports.Duplicate
+	}{
+		{
+			name:          "no match",
+			contributions: []models.Fund{{ReceiptNo: "R100", Contributor: "New Member", Date: "2024-03-03", Total: 1500}},
+			want:          map[int]*imports.Duplicate{},
+		},
+		{
+			name:          "receipt number",
+			contributions: []models.Fund{{ReceiptNo: "R001", Contributor: "Someone Else", Date: "2024-04-01", Total: 10}},
+			want: map[int]*imports.Duplicate{0: {ReceiptNo: "R001", Contributor: "SOMEONE ELSE", Date: "2024-04-01", Total: 10,
+				MatchedId: 10, MatchedOn: imports.MatchReceipt}},
+		},
+		{
+			name:          "earliest saved contribution with the receipt number",
+			contributions: []models.Fund{{ReceiptNo: "R002", Contributor: "Peter Kamau", Date: "2024-03-10", Total: 100}},
+			want: map[int]*imports.Duplicate{0: {ReceiptNo: "R002", Contributor: "PETER KAMAU", Date: "2024-03-10", Total: 100,
+				MatchedId: 11, MatchedOn: imports.MatchReceipt}},
+		},
+		{
+			name:          "receipt number wins over details",
+			contributions: []models.Fund{{ReceiptNo: "R001", Contributor: "Peter Kamau", Date: "2024-03-10", Total: 100}},
+			want: map[int]*imports.Duplicate{0: {ReceiptNo: "R001", Contributor: "PETER KAMAU", Date: "2024-03-10", Total: 100,
+				MatchedId: 10, MatchedOn: imports.MatchReceipt}},
+		},
+		{
+			name:          "contributor date and total without a receipt",
+			contributions: []models.Fund{{Contributor: " jane wanjiru ", Date: "2024-03-03", Total: 1500.001}},
+			want: map[int]*imports.Duplicate{0: {Contributor: " JANE WANJIRU ", Date: "2024-03-03", Total: 1500.001,
+				MatchedId: 10, MatchedOn: imports.MatchContributorDateTotal}},
+		},
+		{
+			name:          "contributor with a quote",
+			contributions: []models.Fund{{Contributor: "O'Brien Otieno", Date: "2024-03-03", Total: 250.5}},
+			want: map[int]*imports.Duplicate{0: {Contributor: "O'BRIEN OTIENO", Date: "2024-03-03", Total: 250.5,
+				MatchedId: 11, MatchedOn: imports.MatchContributorDateTotal}},
+		},
+		{
+			name:          "details with a different receipt number",
+			contributions: []models.Fund{{ReceiptNo: "R200", Contributor: "O'Brien Otieno", Date: "2024-03-03", Total: 250.5}},
+			want:          map[int]*imports.Duplicate{},
+		},
+		{
+			name:          "details of a contribution saved without a receipt number",
+			contributions: []models.Fund{{ReceiptNo: "R201", Contributor: "Grace Muthoni", Date: "2024-03-03", Total: 800}},
+			want: map[int]*imports.Duplicate{0: {ReceiptNo: "R201", Contributor: "GRACE MUTHONI", Date: "2024-03-03", Total: 800,
+				MatchedId: 14, MatchedOn: imports.MatchContributorDateTotal}},
+		},
+		{
+			name:          "total differs by a cent",
+			contributions: []models.Fund{{Contributor: "Jane Wanjiru", Date: "2024-03-03", Total: 1500.01}},
+			want:          map[int]*imports.Duplicate{},
+		},
+		{
+			name: "receipt repeated in the batch",
+			contributions: []models.Fund{
+				{ReceiptNo: "R300", Contributor: "Ann Njeri", Date: "2024-05-05", Total: 50},
+				{ReceiptNo: "R300", Contributor: "Ann Njeri", Date: "2024-05-12", Total: 70},
+			},
+			want: map[int]*imports.Duplicate{1: {ReceiptNo: "R300", Contributor: "ANN NJERI", Date: "2024-05-12", Total: 70,
+				InFile: true, MatchedOn: imports.MatchReceipt}},
+		},
+		{
+			name: "details repeated in the batch",
+			contributions: []models.Fund{
+				{Contributor: "Ann Njeri", Date: "2024-05-05", Total: 50},
+				{Contributor: "ANN NJERI", Date: "2024-05-05", Total: 50},
+			},
+			want: map[int]*imports.Duplicate{1: {Contributor: "ANN NJERI", Date: "2024-05-05", Total: 50,
+				InFile: true, MatchedOn: imports.MatchContributorDateTotal}},
+		},
+		{
+			name: "details repeated in the batch with different receipts",
+			contributions: []models.Fund{
+				{ReceiptNo: "R400", Contributor: "Ann Njeri", Date: "2024-05-05", Total: 50},
+				{ReceiptNo: "R401", Contributor: "Ann Njeri", Date: "2024-05-05", Total: 50},
+			},
+			want: map[int]*imports.Duplicate{},
+		},
+		{
+			name: "details repeated in the batch after a row without a receipt",
+			contributions: []models.Fund{
+				{Contributor: "Ann Njeri", Date: "2024-05-05", Total: 50},
+				{ReceiptNo: "R402", Contributor: "Ann Njeri", Date: "2024-05-05", Total: 50},
+			},
+			want: map[int]*imports.Duplicate{1: {ReceiptNo: "R402", Contributor: "ANN NJERI", Date: "2024-05-05", Total: 50,
+				InFile: true, MatchedOn: imports.MatchContributorDateTotal}},
+		},
+		{
+			name:          "saved by an earlier batch of the same import",
+			contributions: []models.Fund{{ReceiptNo: "R003", Contributor: "Mary Achieng", Date: "2024-03-10", Total: 300}},
+			want: map[int]*imports.Duplicate{0: {ReceiptNo: "R003", Contributor: "MARY ACHIENG", Date: "2024-03-10", Total: 300,
+				InFile: true, MatchedOn: imports.MatchReceipt}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := newSavedContributions()
+
+			for _, s := range existing {
+				index.add(s.id, s.receiptNo, s.contributor, s.date, s.total, s.sameImport)
+			}
+
+			got := index.match(tt.contributions)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("match() = %+v, want %+v", describe(got), describe(tt.want))
+			}
+		})
+	}
+}
+
+func describe(duplicates map[int]*imports.Duplicate) map[int]imports.Duplicate {
+	values := make(map[int]imports.Duplicate, len(duplicates))
+
+	for i, duplicate := range duplicates {
+		values[i] = *duplicate
+	}
+
+	return values
+}
@@ -0,0 +1,327 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+)
+
+type ExpenditureModel struct {
+	DB     *sql.DB
+	Ledger *LedgerModel
+	Logger *utils.CLogger
+}
+
+const expenditureColumns = `id,payee,amount,category,payment_method,voucher_no,coalesce(description,''),organization_id,
+	expenditure_date,coalesce(document_name,''),coalesce(approved_by,0),approved_at,created_at,modified_at`
+
+// SaveExpenditure records an expenditure and posts it to the ledger
+func (m *ExpenditureModel) SaveExpenditure(currentUser *models.User, expenditure *models.Expenditure) (int, error) {
+	stmt := `INSERT INTO expenditures(payee,amount,category,payment_method,voucher_no,description,organization_id,
+				expenditure_date,created_by) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING id;`
+
+	if expenditure.VoucherNo == "" {
+		expenditure.VoucherNo = "PV-" + fmt.Sprint(time.Now().UnixMicro())
+	}
+
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, stmt, strings.ToUpper(expenditure.Payee), expenditure.Amount, expenditure.Category,
+		expenditure.PaymentMethod, expenditure.VoucherNo, expenditure.Description, expenditure.OrganizationId,
+		expenditure.Date, currentUser.ID).Scan(&expenditure.ID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = m.Ledger.PostExpenditure(tx, ctx, currentUser, expenditure)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return expenditure.ID, tx.Commit()
+}
+
+// UpdateExpenditure changes an unapproved expenditure, reversing its previous ledger
+// posting and posting the new amounts
+func (m *ExpenditureModel) UpdateExpenditure(currentUser *models.User, id int, expenditure *models.Expenditure) (int, error) {
+	stmt := `UPDATE expenditures SET payee = $1, amount = $2, category = $3, payment_method = $4, description = $5,
+				expenditure_date = $6, modified_at = now(), modified_by = $7
+				WHERE id = $8 AND organization_id = $9 AND approved_by IS NULL RETURNING voucher_no;`
+
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, stmt, strings.ToUpper(expenditure.Payee), expenditure.Amount, expenditure.Category,
+		expenditure.PaymentMethod, expenditure.Description, expenditure.Date, currentUser.ID, id,
+		currentUser.OrganizationId).Scan(&expenditure.VoucherNo)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("expenditure not found or already approved")
+		}
+		return 0, err
+	}
+
+	expenditure.ID = id
+
+	err = m.Ledger.ReverseSource(tx, ctx, currentUser, data.SourceExpenditure, id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = m.Ledger.PostExpenditure(tx, ctx, currentUser, expenditure)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	m.Logger.InfoLog.Printf("Updated expenditure with ID %d", id)
+
+	return 1, nil
+}
+
+// ApproveExpenditure records the approver of an expenditure. The person who
+// captured the expenditure cannot approve it.
+func (m *ExpenditureModel) ApproveExpenditure(currentUser *models.User, id int) error {
+	stmt := `UPDATE expenditures SET approved_by = $1, approved_at = now(), modified_at = now(), modified_by = $1
+				WHERE id = $2 AND organization_id = $3 AND approved_by IS NULL AND coalesce(created_by,'') <> $4;`
+
+	result, err := m.DB.Exec(stmt, currentUser.ID, id, currentUser.OrganizationId, fmt.Sprint(currentUser.ID))
+
+	if err != nil {
+		return err
+	}
+
+	rowAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowAffected == 0 {
+		return errors.New("expenditure not found, already approved or captured by the approver")
+	}
+
+	return nil
+}
+
+func (m *ExpenditureModel) SaveDocument(currentUser *models.User, id int, document *models.ExpenditureDocument) error {
+	stmt := `UPDATE expenditures SET document = $1, document_name = $2, document_type = $3, modified_at = now(), modified_by = $4
+				WHERE id = $5 AND organization_id = $6;`
+
+	result, err := m.DB.Exec(stmt, document.Data, document.Name, document.ContentType, currentUser.ID, id, currentUser.OrganizationId)
+
+	if err != nil {
+		return err
+	}
+
+	rowAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowAffected == 0 {
+		return data.ErrorNoRecords
+	}
+
+	return nil
+}
+
+func (m *ExpenditureModel) GetDocument(organizationId, id int) (*models.ExpenditureDocument, error) {
+	stmt := `SELECT document, document_name, document_type FROM expenditures
+				WHERE id = $1 AND organization_id = $2 AND document IS NOT NULL;`
+
+	document := &models.ExpenditureDocument{}
+
+	err := m.DB.QueryRow(stmt, id, organizationId).Scan(&document.Data, &document.Name, &document.ContentType)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, data.ErrorNoRecords
+		}
+		return nil, err
+	}
+
+	return document, nil
+}
+
+func (m *ExpenditureModel) GetExpenditures(organizationId int, pageable utils.Pageable) ([]*models.Expenditure, utils.PageInfo, error) {
+	stmt := `SELECT count(*) OVER(), ` + expenditureColumns + ` FROM expenditures WHERE organization_id = $1
+				ORDER BY expenditure_date DESC, id DESC LIMIT $2 OFFSET $3;`
+
+	rows, err := m.DB.Query(stmt, organizationId, pageable.Size, pageable.OffSet)
+
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	defer rows.Close()
+
+	return mapSqlRowsToExpenditures(rows, pageable)
+}
+
+// SearchExpenditures filters expenditures by payee or voucher number and an optional
+// date range. An end date on its own is ignored, a start date on its own matches that day.
+func (m *ExpenditureModel) SearchExpenditures(organizationId int, searchString string, exact bool, startDate, endDate time.Time,
+	pageable utils.Pageable) ([]*models.Expenditure, utils.PageInfo, error) {
+
+	conditions := []string{"organization_id = $1"}
+	args := []any{organizationId}
+
+	if searchString != "" {
+		if exact {
+			args = append(args, "%"+searchString+"%")
+			conditions = append(conditions, fmt.Sprintf("(payee ILIKE $%d OR voucher_no ILIKE $%d)", len(args), len(args)))
+		} else {
+			terms := make([]string, 0)
+			for _, token := range strings.Fields(searchString) {
+				terms = append(terms, token+":*")
+			}
+
+			args = append(args, strings.Join(terms, " | "))
+			conditions = append(conditions, fmt.Sprintf("to_tsvector(payee || ' ' || voucher_no) @@ to_tsquery($%d)", len(args)))
+		}
+	}
+
+	if !startDate.IsZero() && !endDate.IsZero() {
+		args = append(args, startDate, endDate)
+		conditions = append(conditions, fmt.Sprintf("expenditure_date BETWEEN $%d AND $%d", len(args)-1, len(args)))
+	} else if !startDate.IsZero() {
+		args = append(args, startDate)
+		conditions = append(conditions, fmt.Sprintf("expenditure_date = $%d", len(args)))
+	}
+
+	args = append(args, pageable.Size, pageable.OffSet)
+
+	stmt := fmt.Sprintf(`SELECT count(*) OVER(), %s FROM expenditures WHERE %s
+				ORDER BY expenditure_date DESC, id DESC LIMIT $%d OFFSET $%d;`,
+		expenditureColumns, strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	rows, err := m.DB.Query(stmt, args...)
+
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	defer rows.Close()
+
+	return mapSqlRowsToExpenditures(rows, pageable)
+}
+
+// GetFundBalances returns income less expenditure per category for the period. Zero
+// dates leave the period open on that side.
+func (m *ExpenditureModel) GetFundBalances(organizationId int, startDate, endDate time.Time) ([]*models.FundBalance, error) {
+	if startDate.IsZero() {
+		startDate = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	if endDate.IsZero() {
+		endDate = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+	}
+
+	stmt := `WITH income AS (SELECT key as category, sum(value::jsonb::text::numeric) as total
+				FROM funds, jsonb_each(funds.break_down)
+				WHERE organization_id = $1 AND contribution_date BETWEEN $2 AND $3
+				GROUP BY key),
+				expense AS (SELECT category, sum(amount) as total
+				FROM expenditures
+				WHERE organization_id = $1 AND expenditure_date BETWEEN $2 AND $3
+				GROUP BY category)
+
+				SELECT coalesce(income.category, expense.category), coalesce(income.total, 0), coalesce(expense.total, 0)
+				FROM income FULL OUTER JOIN expense ON income.category = expense.category
+				ORDER BY 1;`
+
+	rows, err := m.DB.Query(stmt, organizationId, startDate, endDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	balances := []*models.FundBalance{}
+
+	for rows.Next() {
+		balance := &models.FundBalance{}
+
+		err := rows.Scan(&balance.Category, &balance.Income, &balance.Expenditure)
+
+		if err != nil {
+			return nil, err
+		}
+
+		balance.Balance = balance.Income - balance.Expenditure
+		balances = append(balances, balance)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+func mapSqlRowsToExpenditures(rows *sql.Rows, pageable utils.Pageable) ([]*models.Expenditure, utils.PageInfo, error) {
+	expenditures := []*models.Expenditure{}
+	totalRecords := 0
+
+	for rows.Next() {
+		row := &models.Expenditure{}
+
+		err := rows.Scan(&totalRecords, &row.ID, &row.Payee, &row.Amount, &row.Category, &row.PaymentMethod, &row.VoucherNo,
+			&row.Description, &row.OrganizationId, &row.Date, &row.DocumentName, &row.ApprovedBy, &row.ApprovedAt,
+			&row.Audit.CreatedAt, &row.Audit.ModifiedAt)
+
+		if err != nil {
+			return nil, utils.PageInfo{}, err
+		}
+
+		expenditures = append(expenditures, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	pageInfo := utils.PageInfo{
+		CurrentPage: pageable.Page,
+		Size:        pageable.Size,
+		TotalItems:  totalRecords,
+		FirstPage:   0,
+		LastPage:    int(math.Floor(float64(totalRecords) / float64(pageable.Size))),
+	}
+
+	return expenditures, pageInfo, nil
+}
@@ -4,91 +4,291 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"math"
-	"mime/multipart"
 	"strconv"
 	"strings"
 	"time"
 
 	"slices"
 
-	exporter "github.com/VaudKK/CAS/pkg/exports/excel"
-	pdf_exporter "github.com/VaudKK/CAS/pkg/exports/pdf"
-	"github.com/VaudKK/CAS/pkg/imports/excel"
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/imports/importers"
 	"github.com/VaudKK/CAS/pkg/models"
 	"github.com/VaudKK/CAS/utils"
+	"github.com/lib/pq"
 )
 
+var ErrDuplicateRows = errors.New("duplicate contributions found")
+
+var ErrTotalsMismatch = errors.New("sheet totals do not match the rows read")
+
+type invalidImportError struct {
+	err error
+}
+
+func (e *invalidImportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *invalidImportError) Unwrap() error {
+	return e.err
+}
+
+// IsInvalidImport reports whether ProcessFile failed because of the file itself, e.g.
+// it could not be parsed or its rows did not validate, so that running the import
+// again would fail the same way
+func IsInvalidImport(err error) bool {
+	var invalid *invalidImportError
+
+	return errors.As(err, &invalid)
+}
+
 type FundsModel struct {
-	DB            *sql.DB
-	ExcelExporter *exporter.ExcelExport
-	PdfExporter   *pdf_exporter.PdfExport
-	Logger        *utils.CLogger
+	DB      *sql.DB
+	Ledger  *LedgerModel
+	Imports *ImportJobModel
+	Logger  *utils.CLogger
 }
 
-func (m *FundsModel) ValidateFile(file multipart.File, fileName string) ([]byte, error) {
-	fileData, err := io.ReadAll(file)
+// importBatchSize bounds the rows checked for duplicates and inserted at a time, so
+// that large workbooks are saved without holding every row in memory
+const importBatchSize = 500
+
+// generatedReceiptPrefix starts the receipt numbers given to contributions saved
+// without one
+const generatedReceiptPrefix = "KCS-"
+
+// ValidateFile checks the format of a spooled upload, and that the rows of a CSV file
+// can be dated, and queues an import job for it.
+// The job is rejected when the same file has already been imported.
+func (m *FundsModel) ValidateFile(currentUser *models.User, upload *imports.Upload, job *models.ImportJob,
+	profile *imports.Profile) error {
+	file, err := upload.Open()
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	if _, _, err := importers.Detect(file, upload.Size, job.FileName, profile); err != nil {
+		return err
+	}
+
+	_, err = m.Imports.CreateImport(currentUser, job, upload)
 
 	if err != nil {
 		utils.GetLoggerInstance().ErrorLog.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// PreviewFile reads an upload without saving any contributions and reports the dates,
+// categories, totals, duplicates and unreadable rows it contains. The file is kept so
+// the import can be confirmed with the preview token.
+func (m *FundsModel) PreviewFile(currentUser *models.User, upload *imports.Upload, job *models.ImportJob,
+	profile *imports.Profile) (*imports.Preview, error) {
+	file, err := upload.Open()
+
+	if err != nil {
 		return nil, err
 	}
 
 	defer file.Close()
 
-	hash := utils.HashFile(fileData)
+	importer, _, err := importers.Detect(file, upload.Size, job.FileName, profile)
 
-	err = m.SaveFileHash(hash, fileName, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, categories, err := imports.ReadAll(importer, file, upload.Size)
 
 	if err != nil {
-		utils.GetLoggerInstance().ErrorLog.Println(err)
 		return nil, err
 	}
 
-	return fileData, nil
-}
+	progress := importer.Progress()
 
-func (m *FundsModel) ProcessExcelFile(currentUser *models.User, fileData []byte, fileName string) error {
-	ctx := context.Background()
+	preview := imports.NewPreview(progress.Sheets, rows, progress.Errors, progress.RowsRead)
+	preview.FileName = job.FileName
+	preview.Discrepancies = append(preview.Discrepancies, progress.Discrepancies...)
+	preview.NewCategories = makeCategoriesUnique(&categories, m.GetCategories())
+	slices.Sort(preview.NewCategories)
+
+	funds := toFunds(rows, currentUser.OrganizationId, 0)
+
+	duplicates, err := m.FindDuplicates(currentUser.OrganizationId, funds)
 
-	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		m.Logger.ErrorLog.Printf("Error while starting db transaction: %s", err.Error())
-		return err
+		return nil, err
+	}
+
+	existing := make([]string, 0)
+
+	for i := range funds {
+		duplicate, ok := duplicates[i]
+
+		if !ok {
+			continue
+		}
+
+		duplicate.Sheet, duplicate.Row, duplicate.Action = rows[i].Sheet, rows[i].Row, job.DuplicateAction
+		preview.Duplicates = append(preview.Duplicates, *duplicate)
+
+		if duplicate.MatchedOn == imports.MatchReceipt && !duplicate.InFile && !slices.Contains(existing, duplicate.ReceiptNo) {
+			existing = append(existing, duplicate.ReceiptNo)
+		}
 	}
 
+	preview.AddExistingReceipts(rows, existing)
+
+	preview.AlreadyImported, err = m.Imports.HashExists(upload.Hash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	preview.ImportId, preview.Token, err = m.Imports.CreatePreview(currentUser, job, upload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return preview, nil
+}
+
+// ProcessFile imports the rows of a queued upload and records the outcome on the
+// import job. A file that cannot be read or whose rows do not validate fails the job,
+// as does a panic, instead of the server; any other error, e.g. a lost connection,
+// leaves the job running so that it can be retried. Rows
+// matching saved contributions are skipped, update the saved contribution or fail
+// the import as chosen for the job. Rows are saved in batches as they are read, all
+// within one transaction. Totals rows that disagree with the sheet fail the import
+// when the profile's totals action is FAIL.
+func (m *FundsModel) ProcessFile(currentUser *models.User, job *models.ImportJob, profile *imports.Profile) (err error) {
+	var importer imports.Importer
+
 	defer func() {
 		if r := recover(); r != nil {
-			tx.Rollback()
-			m.Logger.ErrorLog.Fatalf("Error while processing excel data: %v", err)
+			err = &invalidImportError{err: fmt.Errorf("unexpected error while processing %s: %v", job.FileName, r)}
 		}
 
-		tx.Rollback()
+		if IsInvalidImport(err) {
+			m.Logger.ErrorLog.Printf("Import %d of %s failed: %v", job.ID, job.FileName, err)
+
+			progress := &imports.Progress{}
+
+			if importer != nil {
+				progress = importer.Progress()
+			}
+
+			if failErr := m.Imports.FailImport(job.ID, progress, err); failErr != nil {
+				m.Logger.ErrorLog.Printf("Error while recording failure of import %d: %v", job.ID, failErr)
+			}
+		}
 	}()
 
-	excelModel := excel.ExcelImport{}
+	if err = m.Imports.StartImport(job.ID); err != nil {
+		return err
+	}
+
+	_, file, size, err := m.Imports.OpenFile(currentUser.OrganizationId, job.ID)
 
-	data, categories, err := excelModel.ProcessExcelFile(fileData)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	ctx := context.Background()
 
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		m.Logger.ErrorLog.Printf("Error while processing excel data: %v", err)
+		m.Logger.ErrorLog.Printf("Error while starting db transaction: %s", err.Error())
 		return err
 	}
 
-	funds := make([]models.Fund, 0)
+	defer tx.Rollback()
 
-	for _, row := range data {
-		fund := models.Fund{
-			BreakDown:      row.BreakDown,
-			Total:          row.Total,
-			ReceiptNo:      row.ReceiptNo,
-			OrganizationId: 1,
-			Date:           row.Date.Format("2006-01-02"),
-			Contributor:    row.Name,
+	importer, format, err := importers.Detect(file, size, job.FileName, profile)
+
+	if err != nil {
+		return &invalidImportError{err: err}
+	}
+
+	batch := make([]imports.ImportModel, 0, importBatchSize)
+	inserted, updated := 0, 0
+
+	saveBatch := func() error {
+		funds, batchUpdated, err := m.resolveDuplicates(tx, ctx, currentUser, job, batch, importer.Progress())
+
+		if errors.Is(err, ErrDuplicateRows) {
+			return &invalidImportError{err: err}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		updated += batchUpdated
+
+		if len(funds) > 0 {
+			batchInserted, err := m.insert(tx, ctx, currentUser, funds)
+
+			if err != nil {
+				m.Logger.ErrorLog.Printf("Error while saving contributions: %v", err)
+				return err
+			}
+
+			inserted += batchInserted
+		}
+
+		batch = batch[:0]
+
+		return nil
+	}
+
+	// errors returned while saving a batch pass through the importer and are told
+	// apart from errors in the file itself
+	var saveErr error
+
+	categories, err := importer.Import(file, size, func(row imports.ImportModel) error {
+		batch = append(batch, row)
+
+		if len(batch) < importBatchSize {
+			return nil
+		}
+
+		saveErr = saveBatch()
+
+		return saveErr
+	})
+
+	if err != nil {
+		m.Logger.ErrorLog.Printf("Error while processing %s data: %v", format, err)
+
+		if saveErr != nil {
+			return saveErr
+		}
+
+		return &invalidImportError{err: err}
+	}
+
+	if len(batch) > 0 {
+		if err = saveBatch(); err != nil {
+			return err
 		}
-		funds = append(funds, fund)
+	}
+
+	if discrepancies := importer.Progress().Discrepancies; len(discrepancies) > 0 && profile != nil &&
+		profile.TotalsAction == imports.TotalsFail {
+		return &invalidImportError{err: fmt.Errorf("%w: %d totals differ, see discrepancies", ErrTotalsMismatch, len(discrepancies))}
 	}
 
 	insertedCategories, err := m.SaveCategories(tx, ctx, categories)
@@ -98,19 +298,87 @@ func (m *FundsModel) ProcessExcelFile(currentUser *models.User, fileData []byte,
 		return err
 	}
 
-	utils.GetLoggerInstance().InfoLog.Printf("Will insert %d categories", insertedCategories)
+	utils.GetLoggerInstance().InfoLog.Printf("Inserted %d categories and %d funds from import %d", insertedCategories, inserted, job.ID)
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	return m.Imports.CompleteImport(job.ID, importer.Progress(), inserted, updated)
+}
+
+// resolveDuplicates returns the contributions to insert. Duplicates are recorded on
+// the progress and, when the job updates duplicates, saved over the matched
+// contribution. Rows duplicating an earlier row of the same file are always skipped.
+func (m *FundsModel) resolveDuplicates(tx *sql.Tx, ctx context.Context, currentUser *models.User, job *models.ImportJob,
+	rows []imports.ImportModel, progress *imports.Progress) ([]models.Fund, int, error) {
+
+	funds := toFunds(rows, currentUser.OrganizationId, job.ID)
 
-	inserted, err := m.insert(tx, ctx, currentUser, funds)
+	duplicates, err := m.findDuplicates(ctx, tx, currentUser.OrganizationId, job.ID, funds)
 
 	if err != nil {
-		m.Logger.ErrorLog.Printf("Error while saving contributions: %v", err)
-		return err
+		return nil, 0, err
 	}
 
-	utils.GetLoggerInstance().InfoLog.Printf("Will insert %d funds", inserted)
+	toInsert := make([]models.Fund, 0, len(funds))
+	updated := 0
 
-	tx.Commit()
-	return nil
+	for i, fund := range funds {
+		duplicate, ok := duplicates[i]
+
+		if !ok {
+			toInsert = append(toInsert, fund)
+			continue
+		}
+
+		duplicate.Sheet, duplicate.Row, duplicate.Action = rows[i].Sheet, rows[i].Row, job.DuplicateAction
+
+		if job.DuplicateAction == imports.DuplicateUpdate {
+			if duplicate.InFile {
+				duplicate.Action = imports.DuplicateSkip
+			} else {
+				err = m.updateContribution(tx, ctx, currentUser, duplicate.MatchedId, &models.UpdateFund{
+					Contributor: fund.Contributor,
+					Date:        fund.Date,
+					Total:       fund.Total,
+					BreakDown:   fund.BreakDown,
+				})
+
+				if err != nil {
+					return nil, 0, err
+				}
+
+				updated++
+			}
+		}
+
+		progress.Duplicates = append(progress.Duplicates, *duplicate)
+	}
+
+	if job.DuplicateAction == imports.DuplicateFail && len(progress.Duplicates) > 0 {
+		return nil, 0, fmt.Errorf("%w: %d rows match saved contributions or earlier rows", ErrDuplicateRows, len(progress.Duplicates))
+	}
+
+	return toInsert, updated, nil
+}
+
+func toFunds(rows []imports.ImportModel, organizationId, importId int) []models.Fund {
+	funds := make([]models.Fund, 0, len(rows))
+
+	for _, row := range rows {
+		funds = append(funds, models.Fund{
+			BreakDown:      row.BreakDown,
+			Total:          row.Total,
+			ReceiptNo:      row.ReceiptNo,
+			OrganizationId: organizationId,
+			Date:           row.Date.Format("2006-01-02"),
+			Contributor:    row.Name,
+			ImportId:       importId,
+		})
+	}
+
+	return funds
 }
 
 func (m *FundsModel) SaveContributions(user *models.User, contributions []models.Fund) (int, error) {
@@ -118,72 +386,112 @@ func (m *FundsModel) SaveContributions(user *models.User, contributions []models
 	tx, err := m.DB.BeginTx(ctx, nil)
 
 	if err != nil {
-		m.Logger.ErrorLog.Fatalf("Error while starting transaction: %v", err)
+		m.Logger.ErrorLog.Printf("Error while starting transaction: %v", err)
 		return -1, err
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			m.Logger.ErrorLog.Fatalf("Error while saving contribution(s): %v", err)
-		}
-
-		tx.Rollback()
-	}()
+	defer tx.Rollback()
 
 	response, err := m.insert(tx, ctx, user, contributions)
-	tx.Commit()
 
-	return response, err
+	if err != nil {
+		return 0, err
+	}
+
+	return response, tx.Commit()
 }
 
+// insert saves the contributions and posts each one to the ledger within the same
+// transaction
 func (m *FundsModel) insert(tx *sql.Tx, ctx context.Context, currentUser *models.User, contributions []models.Fund) (int, error) {
 
-	stmt := `INSERT INTO funds(break_down,total,organization_id,contribution_date,contributor,receipt_no,created_by) VALUES`
-
-	for i, contribution := range contributions {
+	breakDowns := make([]string, len(contributions))
+	totals := make([]float64, len(contributions))
+	organizations := make([]int64, len(contributions))
+	dates := make([]string, len(contributions))
+	contributors := make([]string, len(contributions))
+	receipts := make([]string, len(contributions))
+	importIds := make([]int64, len(contributions))
+	methods := make([]string, len(contributions))
+
+	for i := range contributions {
+		contribution := &contributions[i]
 		breakDown, err := json.Marshal(contribution.BreakDown)
 
 		if err != nil {
-			utils.GetLoggerInstance().ErrorLog.Println(err)
-			continue
+			return 0, err
 		}
 
 		if contribution.ReceiptNo == "" {
-			contribution.ReceiptNo = "KCS-" + strconv.Itoa(int(time.Now().UnixMicro()))
+			contribution.ReceiptNo = generatedReceiptPrefix + strconv.Itoa(int(time.Now().UnixMicro()))
 		}
 
-		s := fmt.Sprintf("('%s',%.2f,%d,'%s','%s','%s',%s)", string(breakDown), contribution.Total, contribution.OrganizationId, contribution.Date,
-			strings.ToUpper(contribution.Contributor), contribution.ReceiptNo, strconv.Itoa(currentUser.ID))
+		breakDowns[i] = string(breakDown)
+		totals[i] = math.Round(contribution.Total*100) / 100
+		organizations[i] = int64(contribution.OrganizationId)
+		dates[i] = contribution.Date
+		contributors[i] = strings.ToUpper(contribution.Contributor)
+		receipts[i] = contribution.ReceiptNo
+		importIds[i] = int64(contribution.ImportId)
 
-		if i != len(contributions)-1 {
-			s += ","
+		if contribution.PaymentMethod == "" {
+			contribution.PaymentMethod = "CASH"
 		}
 
-		stmt += s
+		methods[i] = contribution.PaymentMethod
 	}
 
-	stmt += ";"
+	// the rows are passed as one array per column, so the statement does not grow
+	// with the batch and no value is ever part of the sql
+	stmt := `INSERT INTO funds(break_down,total,organization_id,contribution_date,contributor,receipt_no,created_by,import_id,
+					payment_method)
+				SELECT b, t, o, d, c, r, $7, nullif(i, 0), p
+				FROM unnest($1::jsonb[], $2::numeric[], $3::bigint[], $4::date[], $5::text[], $6::text[], $8::bigint[], $9::text[])
+					WITH ORDINALITY AS f(b, t, o, d, c, r, i, p, n)
+				ORDER BY n
+				RETURNING id;`
 
-	result, err := tx.ExecContext(ctx, stmt)
+	rows, err := tx.QueryContext(ctx, stmt, pq.Array(breakDowns), pq.Array(totals), pq.Array(organizations), pq.Array(dates),
+		pq.Array(contributors), pq.Array(receipts), strconv.Itoa(currentUser.ID), pq.Array(importIds), pq.Array(methods))
 
 	if err != nil {
 		return 0, err
 	}
 
-	rowAffected, err := result.RowsAffected()
+	inserted := 0
 
-	if err != nil {
+	for rows.Next() {
+		err = rows.Scan(&contributions[inserted].ID)
+
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		inserted++
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
 		return 0, err
 	}
 
-	return int(rowAffected), nil
+	for i := range contributions {
+		_, err = m.Ledger.PostContribution(tx, ctx, currentUser, &contributions[i])
+
+		if err != nil {
+			return 0, fmt.Errorf("posting receipt %s to the ledger: %w", contributions[i].ReceiptNo, err)
+		}
+	}
+
+	return inserted, nil
 
 }
 
 func (m *FundsModel) GetContributions(organizationId int, pageable utils.Pageable) ([]*models.Fund, utils.PageInfo, error) {
 	stmt := `SELECT count(*) OVER(), id,receipt_no,total,organization_id,contribution_date,
-	contributor,break_down,created_at,modified_at FROM funds WHERE organization_id = $1 ORDER BY contribution_date DESC, id DESC LIMIT $2 OFFSET $3;`
+	contributor,payment_method,break_down,created_at,modified_at FROM funds WHERE organization_id = $1 ORDER BY contribution_date DESC, id DESC LIMIT $2 OFFSET $3;`
 
 	rows, err := m.DB.Query(stmt, organizationId, pageable.Size, pageable.OffSet)
 
@@ -202,102 +510,183 @@ func (m *FundsModel) GetContributions(organizationId int, pageable utils.Pageabl
 	return contributions, pageInfo, nil
 }
 
-func (m *FundsModel) UpdateContribution(id int, updateFund *models.UpdateFund) (int, error) {
-	stmt := `UPDATE funds SET total = $1,contribution_date = $2,contributor = $3,break_down = $4 WHERE
-				id = $5;`
+// RollbackImport removes the contributions saved by an import. Their ledger entries
+// are reversed rather than deleted and the import is kept, marked as rolled back, with
+// its original file. The hash is released so the file can be uploaded again. An
+// import that updated existing contributions is refused, as their previous values
+// are not kept.
+func (m *FundsModel) RollbackImport(currentUser *models.User, importId int) (int, error) {
+	ctx := context.Background()
 
-	breakDown, err := json.Marshal(updateFund.BreakDown)
+	tx, err := m.DB.BeginTx(ctx, nil)
 
 	if err != nil {
 		return 0, err
 	}
 
-	result, err := m.DB.Exec(stmt, updateFund.Total, updateFund.Date, strings.ToUpper(updateFund.Contributor), string(breakDown), id)
+	defer tx.Rollback()
+
+	var status string
+	var updated int
+
+	err = tx.QueryRowContext(ctx, `SELECT status, rows_updated FROM imports WHERE id = $1 AND organization_id = $2 FOR UPDATE;`,
+		importId, currentUser.OrganizationId).Scan(&status, &updated)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, data.ErrorNoRecords
+		}
 		return 0, err
 	}
 
-	rowAffected, err := result.RowsAffected()
+	if status != data.ImportSucceeded {
+		return 0, ErrImportNotSucceeded
+	}
+
+	if updated > 0 {
+		return 0, ErrImportUpdatedRows
+	}
+
+	stmt := `SELECT id, contribution_date FROM funds WHERE import_id = $1 AND organization_id = $2;`
+
+	rows, err := tx.QueryContext(ctx, stmt, importId, currentUser.OrganizationId)
 
 	if err != nil {
 		return 0, err
 	}
 
-	m.Logger.InfoLog.Printf("Updated contribution with ID %d, rows affected: %d", id, rowAffected)
+	ids := make([]int, 0)
+	dates := make([]string, 0)
 
-	return int(rowAffected), nil
+	for rows.Next() {
+		var id int
+		var date time.Time
+
+		if err := rows.Scan(&id, &date); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		ids = append(ids, id)
+		dates = append(dates, date.Format("2006-01-02"))
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		closed, err := m.Ledger.IsClosed(ctx, tx, currentUser.OrganizationId, dates[i])
+
+		if err != nil {
+			return 0, err
+		}
+
+		if closed {
+			return 0, ErrPeriodClosed
+		}
+
+		if err = m.Ledger.ReverseSource(tx, ctx, currentUser, data.SourceContribution, id); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM funds WHERE import_id = $1 AND organization_id = $2;`, importId, currentUser.OrganizationId)
+
+	if err != nil {
+		return 0, err
+	}
+
+	stmt = `UPDATE imports SET status = $1, hash = NULL, rows_rolled_back = $2, rolled_back_at = now(), rolled_back_by = $3,
+				modified_at = now() WHERE id = $4;`
+
+	_, err = tx.ExecContext(ctx, stmt, data.ImportRolledBack, len(ids), currentUser.ID, importId)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	m.Logger.InfoLog.Printf("Rolled back import %d, removed %d contributions", importId, len(ids))
+
+	return len(ids), nil
 }
 
-func (m *FundsModel) FullTextSearch(searchString string, exact bool, startDate, endDate time.Time, pageable utils.Pageable) ([]*models.Fund, utils.PageInfo, error) {
+// UpdateContribution changes a contribution and replaces its ledger posting with a
+// reversal and a fresh entry
+func (m *FundsModel) UpdateContribution(currentUser *models.User, id int, updateFund *models.UpdateFund) (int, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
 
-	var query string
+	if err != nil {
+		return 0, err
+	}
 
-	match := "(to_tsvector(contributor || ' ' || receipt_no) @@ to_tsquery('%s'))"
+	defer tx.Rollback()
 
-	if exact {
-		match = fmt.Sprintf("(contributor ILIKE '%%%s%%' OR receipt_no ILIKE '%%%s%%')", searchString, searchString)
+	if err = m.updateContribution(tx, ctx, currentUser, id, updateFund); err != nil {
+		return 0, err
 	}
 
-	if startDate.IsZero() && endDate.IsZero() {
-		query = `SELECT count(*) OVER(), id,receipt_no,total,organization_id,contribution_date,
-						contributor,break_down,created_at,modified_at 
-				FROM funds
-				where organization_id = $1 AND ` + match + `
-				ORDER BY created_at DESC LIMIT $2 OFFSET $3;`
-	} else if !startDate.IsZero() && !endDate.IsZero() {
-		query = `SELECT count(*) OVER(), id,receipt_no,total,organization_id,contribution_date,
-						contributor,break_down,created_at,modified_at 
-				FROM funds
-				where organization_id = $1 AND ` + match + `
-				AND contribution_date BETWEEN $2 AND $3
-				ORDER BY created_at DESC LIMIT $4 OFFSET $5;`
-	} else if !startDate.IsZero() {
-		query = `SELECT count(*) OVER(), id,receipt_no,total,organization_id,contribution_date,
-						contributor,break_down,created_at,modified_at 
-				FROM funds
-				where organization_id = $1 AND ` + match + `
-				AND contribution_date = $2
-				ORDER BY created_at DESC LIMIT $3 OFFSET $4;`
+	if err = tx.Commit(); err != nil {
+		return 0, err
 	}
 
-	if !exact {
-		tokens := strings.Split(searchString, " ")
-		searchTerms := ""
+	m.Logger.InfoLog.Printf("Updated contribution with ID %d", id)
 
-		for i, token := range tokens {
-			if token != "" {
-				if i == len(tokens)-1 {
-					searchTerms += token + ":*"
-				} else {
-					searchTerms += token + ":* | "
-				}
-			}
-		}
+	return 1, nil
+}
+
+func (m *FundsModel) updateContribution(tx *sql.Tx, ctx context.Context, currentUser *models.User, id int,
+	updateFund *models.UpdateFund) error {
+	stmt := `UPDATE funds SET total = $1,contribution_date = $2,contributor = $3,break_down = $4,modified_at = now(),modified_by = $5 WHERE
+				id = $6 AND organization_id = $7 RETURNING receipt_no, organization_id, payment_method;`
 
-		query = fmt.Sprintf(query, searchTerms)
+	breakDown, err := json.Marshal(updateFund.BreakDown)
+
+	if err != nil {
+		return err
 	}
 
-	var rows *sql.Rows
-	var err error
+	fund := &models.Fund{
+		ID:          id,
+		Total:       updateFund.Total,
+		Date:        updateFund.Date,
+		Contributor: updateFund.Contributor,
+		BreakDown:   updateFund.BreakDown,
+	}
 
-	if startDate.IsZero() && endDate.IsZero() {
-		rows, err = m.DB.Query(query, 1, pageable.Size, pageable.OffSet)
-	} else if !startDate.IsZero() && !endDate.IsZero() {
-		rows, err = m.DB.Query(query, 1, startDate, endDate, pageable.Size, pageable.OffSet)
-	} else if !startDate.IsZero() {
-		rows, err = m.DB.Query(query, 1, startDate, pageable.Size, pageable.OffSet)
+	err = tx.QueryRowContext(ctx, stmt, updateFund.Total, updateFund.Date, strings.ToUpper(updateFund.Contributor), string(breakDown),
+		currentUser.ID, id, currentUser.OrganizationId).Scan(&fund.ReceiptNo, &fund.OrganizationId, &fund.PaymentMethod)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.ErrorNoRecords
+		}
+		return err
 	}
 
+	err = m.Ledger.ReverseSource(tx, ctx, currentUser, data.SourceContribution, id)
+
 	if err != nil {
-		return nil, utils.PageInfo{}, err
+		return err
 	}
 
-	defer rows.Close()
+	_, err = m.Ledger.PostContribution(tx, ctx, currentUser, fund)
 
-	contributions, pageInfo := mapSqlRowsToModel(rows, pageable)
+	return err
+}
 
-	if err = rows.Err(); err != nil {
+func (m *FundsModel) FullTextSearch(organizationId int, searchString string, exact bool, startDate, endDate time.Time, pageable utils.Pageable) ([]*models.Fund, utils.PageInfo, error) {
+	contributions, pageInfo, err := m.searchContributions(organizationId, searchString, exact, startDate, endDate, pageable)
+
+	if err != nil {
 		return nil, utils.PageInfo{}, err
 	}
 
@@ -306,31 +695,23 @@ func (m *FundsModel) FullTextSearch(searchString string, exact bool, startDate,
 	return contributions, pageInfo, nil
 }
 
-func (m *FundsModel) SearchByDateRange(startDate, endDate time.Time, pageable utils.Pageable) ([]*models.Fund, utils.PageInfo, error) {
-	query := ""
+func (m *FundsModel) SearchByDateRange(organizationId int, startDate, endDate time.Time, pageable utils.Pageable) ([]*models.Fund, utils.PageInfo, error) {
+	return m.searchContributions(organizationId, "", false, startDate, endDate, pageable)
+}
 
-	if endDate.IsZero() {
-		query = `SELECT count(*) OVER(), id,receipt_no,total,organization_id,contribution_date,
-						contributor,break_down,created_at,modified_at 
-				FROM funds
-				where organization_id = $1 AND contribution_date = $2
-				ORDER BY created_at DESC LIMIT $3 OFFSET $4;`
-	} else {
-		query = `SELECT count(*) OVER(), id,receipt_no,total,organization_id,contribution_date,
-						contributor,break_down,created_at,modified_at 
-				FROM funds
-				where organization_id = $1 AND contribution_date BETWEEN $2 AND $3
-				ORDER BY created_at DESC LIMIT $4 OFFSET $5;`
-	}
+func (m *FundsModel) searchContributions(organizationId int, searchString string, exact bool, startDate, endDate time.Time,
+	pageable utils.Pageable) ([]*models.Fund, utils.PageInfo, error) {
+	conditions, args := contributionFilters(organizationId, searchString, exact, startDate, endDate)
 
-	var rows *sql.Rows
-	var err error
+	args = append(args, pageable.Size, pageable.OffSet)
 
-	if endDate.IsZero() {
-		rows, err = m.DB.Query(query, 1, startDate, pageable.Size, pageable.OffSet)
-	} else {
-		rows, err = m.DB.Query(query, 1, startDate, endDate, pageable.Size, pageable.OffSet)
-	}
+	query := fmt.Sprintf(`SELECT count(*) OVER(), id,receipt_no,total,organization_id,contribution_date,
+						contributor,payment_method,break_down,created_at,modified_at
+				FROM funds
+				WHERE %s
+				ORDER BY created_at DESC LIMIT $%d OFFSET $%d;`, strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	rows, err := m.DB.Query(query, args...)
 
 	if err != nil {
 		return nil, utils.PageInfo{}, err
@@ -347,82 +728,315 @@ func (m *FundsModel) SearchByDateRange(startDate, endDate time.Time, pageable ut
 	return contributions, pageInfo, nil
 }
 
-func (m *FundsModel) GetSummary(startDate, endDate time.Time, organizationId int) ([]byte, error) {
-	var stmt string
+// QueryContributions opens a query over the contributions matching a search, newest
+// first, with the same filters as FullTextSearch and SearchByDateRange. Rows are read
+// from the connection as the cursor advances; the cursor must be closed.
+func (m *FundsModel) QueryContributions(organizationId int, searchString string, exact bool, startDate, endDate time.Time) (*FundRows, error) {
+	conditions, args := contributionFilters(organizationId, searchString, exact, startDate, endDate)
 
-	if !startDate.IsZero() && endDate.IsZero() {
-		stmt = `SELECT key as name, sum(value::jsonb::text::numeric) as value,contribution_date
-			from funds, jsonb_each(funds.break_down)
-			where organization_id = $1 AND contribution_date = $2 
-			group by contribution_date,key
-			order by contribution_date;`
-	} else if !startDate.IsZero() && !endDate.IsZero() {
-		stmt = `SELECT key as name, sum(value::jsonb::text::numeric) as value,contribution_date
-			from funds, jsonb_each(funds.break_down)
-			where organization_id = $1 AND contribution_date BETWEEN $2 AND $3
-			group by contribution_date,key
-			order by contribution_date;`
-	} else {
-		return nil, fmt.Errorf("start date and end date cannot be both empty")
+	query := `SELECT id,receipt_no,total,organization_id,contribution_date,contributor,payment_method,break_down,created_at,modified_at
+				FROM funds
+				WHERE ` + strings.Join(conditions, " AND ") + `
+				ORDER BY created_at DESC, id DESC;`
+
+	rows, err := m.DB.Query(query, args...)
+
+	if err != nil {
+		return nil, err
 	}
 
-	var rows *sql.Rows
-	var err error
+	return &FundRows{rows: rows}, nil
+}
+
+// contributionFilters builds the conditions of a search over the contributions of an
+// organization. Names and receipt numbers match as prefixes of their words, or
+// anywhere in them when exact is set. A start date without an end date matches that
+// day only.
+func contributionFilters(organizationId int, searchString string, exact bool, startDate, endDate time.Time) ([]string, []any) {
+	conditions := []string{"organization_id = $1"}
+	args := []any{organizationId}
+
+	if strings.TrimSpace(searchString) != "" {
+		args = append(args, searchString)
+
+		if exact {
+			conditions = append(conditions, fmt.Sprintf("(contributor ILIKE '%%' || $%d || '%%' OR receipt_no ILIKE '%%' || $%d || '%%')",
+				len(args), len(args)))
+		} else {
+			terms := []string{}
+
+			for _, token := range strings.Fields(searchString) {
+				terms = append(terms, token+":*")
+			}
+
+			args[len(args)-1] = strings.Join(terms, " | ")
+			conditions = append(conditions, fmt.Sprintf("(to_tsvector(contributor || ' ' || receipt_no) @@ to_tsquery($%d))", len(args)))
+		}
+	}
+
+	if !startDate.IsZero() && !endDate.IsZero() {
+		args = append(args, startDate, endDate)
+		conditions = append(conditions, fmt.Sprintf("contribution_date BETWEEN $%d AND $%d", len(args)-1, len(args)))
+	} else if !startDate.IsZero() {
+		args = append(args, startDate)
+		conditions = append(conditions, fmt.Sprintf("contribution_date = $%d", len(args)))
+	}
+
+	return conditions, args
+}
+
+// FundRows is an open query over contributions. Only the current row is held.
+type FundRows struct {
+	rows *sql.Rows
+	fund *models.Fund
+	err  error
+}
+
+func (r *FundRows) Next() bool {
+	if r.err != nil || !r.rows.Next() {
+		return false
+	}
+
+	fund := &models.Fund{}
+	var breakDown []byte
+
+	r.err = r.rows.Scan(&fund.ID, &fund.ReceiptNo, &fund.Total, &fund.OrganizationId, &fund.Date, &fund.Contributor,
+		&fund.PaymentMethod, &breakDown, &fund.Audit.CreatedAt, &fund.Audit.ModifiedAt)
+
+	if r.err == nil {
+		r.err = json.Unmarshal(breakDown, &fund.BreakDown)
+	}
+
+	if r.err != nil {
+		return false
+	}
+
+	r.fund = fund
+
+	return true
+}
+
+func (r *FundRows) Fund() *models.Fund {
+	return r.fund
+}
+
+func (r *FundRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return r.rows.Err()
+}
+
+func (r *FundRows) Close() error {
+	return r.rows.Close()
+}
+
+// GetCashStatement builds the treasurer's cash statement for the period: fund
+// balances brought forward, receipts per sabbath and category, disbursements,
+// transfers between funds and the balances carried forward. A zero end date
+// reports on the start date only.
+func (m *FundsModel) GetCashStatement(startDate, endDate time.Time, organizationId int) (*models.CashStatement, error) {
+	if startDate.IsZero() {
+		return nil, fmt.Errorf("start date cannot be empty")
+	}
 
 	if endDate.IsZero() {
-		rows, err = m.DB.Query(stmt, organizationId, startDate)
-	} else {
-		rows, err = m.DB.Query(stmt, organizationId, startDate, endDate)
+		endDate = startDate
+	}
+
+	statement := &models.CashStatement{
+		From:          startDate,
+		To:            endDate,
+		Receipts:      []*models.CashStatementRow{},
+		TotalReceipts: make(map[string]float64),
+		Closing:       make(map[string]float64),
+	}
+
+	incomeBroughtForward, err := m.sumByCategory(`SELECT key, sum(value::jsonb::text::numeric)
+				FROM funds, jsonb_each(funds.break_down)
+				WHERE organization_id = $1 AND contribution_date < $2
+				GROUP BY key;`, organizationId, startDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	paymentsBroughtForward, err := m.sumByCategory(`SELECT category, sum(amount) FROM expenditures
+				WHERE organization_id = $1 AND expenditure_date < $2
+				GROUP BY category;`, organizationId, startDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transfersBroughtForward, err := m.sumByCategory(fundTransfersQuery+` AND e.entry_date < $2 GROUP BY a.category;`,
+		organizationId, startDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statement.Payments, err = m.sumByCategory(`SELECT category, sum(amount) FROM expenditures
+				WHERE organization_id = $1 AND expenditure_date BETWEEN $2 AND $3
+				GROUP BY category;`, organizationId, startDate, endDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statement.Transfers, err = m.sumByCategory(fundTransfersQuery+` AND e.entry_date BETWEEN $2 AND $3 GROUP BY a.category;`,
+		organizationId, startDate, endDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statement.Opening = make(map[string]float64)
+
+	for category, amount := range incomeBroughtForward {
+		statement.Opening[category] += amount
+	}
+
+	for category, amount := range paymentsBroughtForward {
+		statement.Opening[category] -= amount
+	}
+
+	for category, amount := range transfersBroughtForward {
+		statement.Opening[category] += amount
 	}
 
+	stmt := `SELECT key as name, sum(value::jsonb::text::numeric) as value,contribution_date
+			from funds, jsonb_each(funds.break_down)
+			where organization_id = $1 AND contribution_date BETWEEN $2 AND $3
+			group by contribution_date,key
+			order by contribution_date;`
+
+	rows, err := m.DB.Query(stmt, organizationId, startDate, endDate)
+
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	summary := make(map[string][]models.MonthlySummations, 0)
-	data := []*models.MonthlySummations{}
+	var current *models.CashStatementRow
 
 	for rows.Next() {
-		row := &models.MonthlySummations{}
+		var category, date string
+		var total float64
 
-		err := rows.Scan(&row.Category, &row.Total, &row.Date)
+		err := rows.Scan(&category, &total, &date)
 
 		if err != nil {
 			return nil, err
 		}
 
-		data = append(data, row)
+		date = strings.Split(date, "T")[0]
+
+		if current == nil || current.Date != date {
+			current = &models.CashStatementRow{Date: date, Amounts: make(map[string]float64)}
+			statement.Receipts = append(statement.Receipts, current)
+		}
+
+		current.Amounts[category] += total
+		current.Total += total
+		statement.TotalReceipts[category] += total
 	}
 
-	for _, row := range data {
-		if value, ok := summary[row.Date]; ok {
-			value = append(value, models.MonthlySummations{
-				Category: row.Category,
-				Date:     row.Date,
-				Total:    row.Total,
-			})
-			summary[row.Date] = value
-		} else {
-			list := []models.MonthlySummations{}
-			list = append(list, models.MonthlySummations{
-				Category: row.Category,
-				Date:     row.Date,
-				Total:    row.Total,
-			})
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
-			summary[row.Date] = list
+	statement.Disbursements, err = m.getDisbursements(organizationId, startDate, endDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make(map[string]bool)
+
+	for _, values := range []map[string]float64{statement.Opening, statement.TotalReceipts, statement.Payments, statement.Transfers} {
+		for category := range values {
+			categories[category] = true
 		}
 	}
 
-	file, err := m.GenerateExcelSummaryFile(summary)
+	statement.Categories = make([]string, 0, len(categories))
+
+	for category := range categories {
+		statement.Categories = append(statement.Categories, category)
+		statement.Closing[category] = statement.Opening[category] + statement.TotalReceipts[category] -
+			statement.Payments[category] + statement.Transfers[category]
+	}
+
+	slices.Sort(statement.Categories)
+
+	return statement, nil
+}
+
+// fundTransfersQuery sums the movements on fund accounts, i.e. money moved between
+// funds through ledger transfers
+const fundTransfersQuery = `SELECT a.category, sum(l.credit - l.debit)
+				FROM journal_lines l
+				JOIN journal_entries e ON e.id = l.journal_entry_id
+				JOIN accounts a ON a.id = l.account_id
+				WHERE e.organization_id = $1 AND a.account_type = 'FUND' AND a.category IS NOT NULL`
+
+func (m *FundsModel) sumByCategory(stmt string, args ...any) (map[string]float64, error) {
+	rows, err := m.DB.Query(stmt, args...)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return file, nil
+	defer rows.Close()
+
+	totals := make(map[string]float64)
+
+	for rows.Next() {
+		var category string
+		var total float64
+
+		if err := rows.Scan(&category, &total); err != nil {
+			return nil, err
+		}
+
+		totals[category] += total
+	}
+
+	return totals, rows.Err()
+}
+
+func (m *FundsModel) getDisbursements(organizationId int, startDate, endDate time.Time) ([]*models.Expenditure, error) {
+	stmt := `SELECT id,payee,amount,category,payment_method,voucher_no,coalesce(description,''),expenditure_date
+				FROM expenditures WHERE organization_id = $1 AND expenditure_date BETWEEN $2 AND $3
+				ORDER BY expenditure_date, id;`
+
+	rows, err := m.DB.Query(stmt, organizationId, startDate, endDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	disbursements := []*models.Expenditure{}
+
+	for rows.Next() {
+		row := &models.Expenditure{OrganizationId: organizationId}
+
+		err := rows.Scan(&row.ID, &row.Payee, &row.Amount, &row.Category, &row.PaymentMethod, &row.VoucherNo,
+			&row.Description, &row.Date)
+
+		if err != nil {
+			return nil, err
+		}
+
+		disbursements = append(disbursements, row)
+	}
+
+	return disbursements, rows.Err()
 }
 
 func (m *FundsModel) GetMonthlyStatistics(year, month, organizationId int) ([]*models.MonthlyStats, error) {
@@ -501,27 +1115,13 @@ func (m *FundsModel) GetMonthlyVariance(targetCategories []string) ([]*models.Va
 
 		if variance != nil {
 
-			var percentage float64
-
-			if variance.PreviousTotal == 0 {
-				percentage = 100.0
-			} else {
-				percentage = ((variance.Total - variance.PreviousTotal) / math.Abs(variance.PreviousTotal)) * 100.0
-			}
-
-			direction := 0
-
-			if percentage > 0 {
-				direction = 1
-			} else if percentage < 0 {
-				direction = -1
-			}
+			percentage, direction := calculateVariance(variance.Total, variance.PreviousTotal)
 
 			vars := models.Variance{
 				Category:     variance.Category,
 				CurrentValue: variance.Total,
-				Percentage:   float32(percentage),
-				Direction:    int8(direction),
+				Percentage:   percentage,
+				Direction:    direction,
 			}
 
 			statistics = append(statistics, &vars)
@@ -604,24 +1204,6 @@ func (m *FundsModel) GetCategories() []string {
 	return categories
 }
 
-func (m *FundsModel) SaveFileHash(hash, filename string, organizationId int) error {
-	stmt := `INSERT INTO imports(hash,filename,organization_id) VALUES ($1,$2,$3);`
-
-	result, err := m.DB.Exec(stmt, hash, filename, organizationId)
-
-	if err != nil {
-		return err
-	}
-
-	_, err = result.RowsAffected()
-
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func makeCategoriesUnique(newCategories *[]string, existingCategories []string) []string {
 	categoriesToSave := make([]string, 0)
 
@@ -643,7 +1225,7 @@ func mapSqlRowsToModel(rows *sql.Rows, pageable utils.Pageable) ([]*models.Fund,
 	for rows.Next() {
 		row := &models.Fund{}
 		err := rows.Scan(&totalRecords, &row.ID, &row.ReceiptNo, &row.Total, &row.OrganizationId, &row.Date, &row.Contributor,
-			&jsonb, &row.Audit.CreatedAt, &row.Audit.ModifiedAt)
+			&row.PaymentMethod, &jsonb, &row.Audit.CreatedAt, &row.Audit.ModifiedAt)
 
 		if err != nil {
 			return nil, utils.PageInfo{}
@@ -669,6 +1251,28 @@ func mapSqlRowsToModel(rows *sql.Rows, pageable utils.Pageable) ([]*models.Fund,
 	return contributions, pageInfo
 }
 
+// calculateVariance returns the percentage change of current against the baseline
+// together with its direction (1 up, -1 down, 0 unchanged)
+func calculateVariance(current, baseline float64) (float32, int8) {
+	var percentage float64
+
+	if baseline == 0 {
+		percentage = 100.0
+	} else {
+		percentage = ((current - baseline) / math.Abs(baseline)) * 100.0
+	}
+
+	var direction int8
+
+	if percentage > 0 {
+		direction = 1
+	} else if percentage < 0 {
+		direction = -1
+	}
+
+	return float32(percentage), direction
+}
+
 func getTargetCategory(target string, data []*models.StatisticalVariance) *models.StatisticalVariance {
 	for _, stat := range data {
 		if strings.EqualFold(target, stat.Category) {
@@ -688,42 +1292,3 @@ func (app *FundsModel) ValidateTotalAndBreakDown(total float64, breakDown map[st
 
 	return sum == total
 }
-
-func (m *FundsModel) GenerateExcelFile(contributions []*models.Fund) ([]byte, error) {
-
-	categories := m.GetCategories()
-
-	excelFile, err := m.ExcelExporter.GenerateExcelFile(contributions, categories)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return excelFile, nil
-}
-
-func (m *FundsModel) GeneratePdfFile(contributions []*models.Fund, startDate, endDate time.Time) ([]byte, error) {
-	categories := m.GetCategories()
-
-	pdfFile, err := m.PdfExporter.GeneratePdfFile(contributions, categories, startDate, endDate)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return pdfFile, nil
-}
-
-func (m *FundsModel) GenerateExcelSummaryFile(data map[string][]models.MonthlySummations) ([]byte, error) {
-	categories := m.GetCategories()
-
-	excelFile, err := m.ExcelExporter.GenerateExcelSummary(
-		data,
-		categories)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return excelFile, nil
-}
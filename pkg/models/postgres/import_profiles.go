@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+	"github.com/lib/pq"
+)
+
+// ImportProfileModel stores the workbook layouts used by an organization
+type ImportProfileModel struct {
+	DB     *sql.DB
+	Logger *utils.CLogger
+}
+
+const importProfileColumns = `id,organization_id,name,header_row,name_column,receipt_column,total_column,
+	category_start_column,coalesce(category_end_column,''),date_source,coalesce(date_column,''),
+	coalesce(to_char(fixed_date,'YYYY-MM-DD'),''),date_formats,skip_sheets,totals_label,totals_action`
+
+// SaveProfile creates a profile or replaces the organization's profile with the same name
+func (m *ImportProfileModel) SaveProfile(currentUser *models.User, profile *imports.Profile) (int, error) {
+	stmt := `INSERT INTO import_profiles(organization_id,name,header_row,name_column,receipt_column,total_column,
+				category_start_column,category_end_column,date_source,date_column,fixed_date,date_formats,skip_sheets,
+				totals_label,totals_action,created_by)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+				ON CONFLICT (organization_id,name) DO UPDATE SET
+				header_row = EXCLUDED.header_row, name_column = EXCLUDED.name_column, receipt_column = EXCLUDED.receipt_column,
+				total_column = EXCLUDED.total_column, category_start_column = EXCLUDED.category_start_column,
+				category_end_column = EXCLUDED.category_end_column, date_source = EXCLUDED.date_source,
+				date_column = EXCLUDED.date_column, fixed_date = EXCLUDED.fixed_date, date_formats = EXCLUDED.date_formats,
+				skip_sheets = EXCLUDED.skip_sheets, totals_label = EXCLUDED.totals_label,
+				totals_action = EXCLUDED.totals_action, modified_at = now(), modified_by = EXCLUDED.created_by
+				RETURNING id;`
+
+	skipSheets := profile.SkipSheets
+
+	if skipSheets == nil {
+		skipSheets = []string{}
+	}
+
+	if profile.TotalsLabel == "" {
+		profile.TotalsLabel = imports.DefaultTotalsLabel
+	}
+
+	if profile.TotalsAction == "" {
+		profile.TotalsAction = imports.TotalsWarn
+	}
+
+	var id int
+
+	err := m.DB.QueryRow(stmt, currentUser.OrganizationId, profile.Name, profile.HeaderRow,
+		strings.ToUpper(profile.NameColumn), strings.ToUpper(profile.ReceiptColumn), strings.ToUpper(profile.TotalColumn),
+		strings.ToUpper(profile.CategoryStart), nullableString(strings.ToUpper(profile.CategoryEnd)), profile.DateSource,
+		nullableString(strings.ToUpper(profile.DateColumn)), nullableString(profile.FixedDate),
+		pq.Array(profile.DateFormats), pq.Array(skipSheets), profile.TotalsLabel, profile.TotalsAction,
+		currentUser.ID).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *ImportProfileModel) GetProfiles(organizationId int) ([]*imports.Profile, error) {
+	stmt := `SELECT ` + importProfileColumns + ` FROM import_profiles WHERE organization_id = $1 ORDER BY name;`
+
+	rows, err := m.DB.Query(stmt, organizationId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	profiles := []*imports.Profile{}
+
+	for rows.Next() {
+		profile, err := scanImportProfile(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+func (m *ImportProfileModel) GetProfile(organizationId, id int) (*imports.Profile, error) {
+	stmt := `SELECT ` + importProfileColumns + ` FROM import_profiles WHERE organization_id = $1 AND id = $2;`
+
+	profile, err := scanImportProfile(m.DB.QueryRow(stmt, organizationId, id))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, data.ErrorNoRecords
+		}
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func scanImportProfile(row interface{ Scan(...any) error }) (*imports.Profile, error) {
+	profile := &imports.Profile{}
+
+	err := row.Scan(&profile.ID, &profile.OrganizationId, &profile.Name, &profile.HeaderRow, &profile.NameColumn,
+		&profile.ReceiptColumn, &profile.TotalColumn, &profile.CategoryStart, &profile.CategoryEnd, &profile.DateSource,
+		&profile.DateColumn, &profile.FixedDate, pq.Array(&profile.DateFormats), pq.Array(&profile.SkipSheets),
+		&profile.TotalsLabel, &profile.TotalsAction)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,565 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/imports/importers"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrImportNotReplaceable = errors.New("only a succeeded import can be replaced")
+	ErrSameFile             = errors.New("the file is the same as the current version of the import")
+	ErrReplacementStale     = errors.New("the import has changed since the replacement was previewed, preview it again")
+)
+
+const versionColumns = `id,import_id,coalesce(version,0),filename,coalesce(file_hash,''),coalesce(file_size,0),coalesce(profile_id,0),
+	status,rows_added,rows_removed,rows_changed,created_at,coalesce(created_by,''),applied_at,coalesce(applied_by,'')`
+
+// GetVersions returns the version history of an import, newest first. An import that
+// has never been replaced has a single version, its original file.
+func (m *ImportJobModel) GetVersions(organizationId, importId int) ([]*models.ImportVersion, error) {
+	job, err := m.GetImport(organizationId, importId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `SELECT ` + versionColumns + ` FROM import_versions WHERE import_id = $1 AND version IS NOT NULL
+				ORDER BY version DESC;`
+
+	rows, err := m.DB.Query(stmt, importId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	versions := []*models.ImportVersion{}
+
+	for rows.Next() {
+		version := &models.ImportVersion{}
+
+		err := rows.Scan(&version.ID, &version.ImportId, &version.Version, &version.FileName, &version.FileHash,
+			&version.FileSize, &version.ProfileId, &version.Status, &version.RowsAdded, &version.RowsRemoved,
+			&version.RowsChanged, &version.CreatedAt, &version.CreatedBy, &version.AppliedAt, &version.AppliedBy)
+
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, version)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		versions = append(versions, &models.ImportVersion{
+			ImportId:  job.ID,
+			Version:   job.Version,
+			FileName:  job.FileName,
+			FileHash:  job.Hash,
+			ProfileId: job.ProfileId,
+			Status:    data.VersionCurrent,
+			RowsAdded: job.RowsInserted,
+			CreatedAt: job.Audit.CreatedAt,
+			AppliedAt: job.FinishedAt,
+		})
+	}
+
+	return versions, nil
+}
+
+// OpenVersionFile opens the file of one version of an import
+func (m *ImportJobModel) OpenVersionFile(organizationId, importId, version int) (string, imports.File, int64, error) {
+	stmt := `SELECT v.filename,coalesce(v.file_path,'')
+				FROM import_versions v JOIN imports i ON i.id = v.import_id
+				WHERE i.organization_id = $1 AND v.import_id = $2 AND v.version = $3;`
+
+	var fileName, path string
+
+	err := m.DB.QueryRow(stmt, organizationId, importId, version).Scan(&fileName, &path)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		// an import that has never been replaced only has the file it was created with
+		job, err := m.GetImport(organizationId, importId)
+
+		if err != nil {
+			return "", nil, 0, err
+		}
+
+		if job.Version != version {
+			return "", nil, 0, data.ErrorNoRecords
+		}
+
+		return m.OpenFile(organizationId, importId)
+	}
+
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	if path == "" {
+		return "", nil, 0, data.ErrorNoRecords
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil, 0, data.ErrorNoRecords
+		}
+		return "", nil, 0, err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+		return "", nil, 0, err
+	}
+
+	return fileName, file, info.Size(), nil
+}
+
+// GetPendingVersion returns a previewed replacement that has not been applied yet.
+// Previews expire after a day.
+func (m *ImportJobModel) GetPendingVersion(organizationId, importId int, token string) (*models.ImportVersion, error) {
+	stmt := `SELECT ` + versionColumns + ` FROM import_versions v
+				WHERE import_id = $1 AND preview_token = $2 AND status = $3 AND created_at > now() - interval '1 day'
+				AND EXISTS (SELECT 1 FROM imports i WHERE i.id = v.import_id AND i.organization_id = $4);`
+
+	version := &models.ImportVersion{}
+
+	err := m.DB.QueryRow(stmt, importId, token, data.VersionPending, organizationId).Scan(&version.ID, &version.ImportId,
+		&version.Version, &version.FileName, &version.FileHash, &version.FileSize, &version.ProfileId, &version.Status,
+		&version.RowsAdded, &version.RowsRemoved, &version.RowsChanged, &version.CreatedAt, &version.CreatedBy,
+		&version.AppliedAt, &version.AppliedBy)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, data.ErrorNoRecords
+		}
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// PreviewReplacement compares a corrected file with the contributions saved by an
+// earlier import and keeps the file so the delta can be applied with the preview token
+func (m *FundsModel) PreviewReplacement(currentUser *models.User, importId int, upload *imports.Upload,
+	profile *imports.Profile) (*imports.Replacement, error) {
+	job, err := m.Imports.GetImport(currentUser.OrganizationId, importId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status != data.ImportSucceeded {
+		return nil, ErrImportNotReplaceable
+	}
+
+	if upload.Hash == job.Hash {
+		return nil, ErrSameFile
+	}
+
+	file, err := upload.Open()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	rows, categories, progress, err := readReplacement(file, upload.Size, upload.Name, profile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := m.savedRows(context.Background(), m.DB, currentUser.OrganizationId, importId, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	diff := imports.Compare(saved, rows)
+
+	digest, err := diffDigest(diff)
+
+	if err != nil {
+		return nil, err
+	}
+
+	replacement := &imports.Replacement{
+		ImportId:      importId,
+		FileName:      upload.Name,
+		RowsRead:      progress.RowsRead,
+		NewCategories: makeCategoriesUnique(&categories, m.GetCategories()),
+		Discrepancies: append([]imports.Discrepancy{}, progress.Discrepancies...),
+		Errors:        append([]imports.RowError{}, progress.Errors...),
+		Diff:          *diff,
+	}
+
+	slices.Sort(replacement.NewCategories)
+
+	replacement.AlreadyImported, err = m.Imports.HashExists(upload.Hash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := randomString(32)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `INSERT INTO import_versions(import_id,filename,file_path,file_hash,file_size,profile_id,status,preview_token,
+				diff_digest,rows_added,rows_removed,rows_changed,created_by) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13);`
+
+	var profileId int
+
+	if profile != nil {
+		profileId = profile.ID
+	}
+
+	_, err = m.DB.Exec(stmt, importId, upload.Name, upload.Path, upload.Hash, upload.Size, nullableInt(profileId),
+		data.VersionPending, token, digest, len(diff.Added), len(diff.Removed), len(diff.Changed), currentUser.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	replacement.Token = token
+
+	return replacement, nil
+}
+
+// ApplyReplacement applies a previewed replacement in one transaction. Removed
+// contributions are reversed in the ledger and deleted, changed ones are updated and
+// added ones saved against the import. The file is read and compared again, and the
+// replacement is refused if the delta is no longer the one previewed or, when the
+// profile's totals action is FAIL, if its totals rows disagree with the sheet. The
+// file it replaces is kept in the version history.
+func (m *FundsModel) ApplyReplacement(currentUser *models.User, version *models.ImportVersion,
+	profile *imports.Profile) (*models.ImportVersion, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var status string
+	var current int
+
+	err = tx.QueryRowContext(ctx, `SELECT status, version FROM imports WHERE id = $1 AND organization_id = $2 FOR UPDATE;`,
+		version.ImportId, currentUser.OrganizationId).Scan(&status, &current)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, data.ErrorNoRecords
+		}
+		return nil, err
+	}
+
+	if status != data.ImportSucceeded {
+		return nil, ErrImportNotReplaceable
+	}
+
+	var path, digest string
+
+	stmt := `SELECT file_path, diff_digest FROM import_versions WHERE id = $1 AND status = $2 FOR UPDATE;`
+
+	err = tx.QueryRowContext(ctx, stmt, version.ID, data.VersionPending).Scan(&path, &digest)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, data.ErrorNoRecords
+		}
+		return nil, err
+	}
+
+	upload := &imports.Upload{Name: version.FileName, Path: path, Hash: version.FileHash, Size: version.FileSize}
+
+	file, err := upload.Open()
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, data.ErrorNoRecords
+		}
+		return nil, err
+	}
+
+	defer file.Close()
+
+	rows, categories, progress, err := readReplacement(file, upload.Size, upload.Name, profile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(progress.Discrepancies) > 0 && profile != nil && profile.TotalsAction == imports.TotalsFail {
+		return nil, fmt.Errorf("%w: %d totals differ, see discrepancies", ErrTotalsMismatch, len(progress.Discrepancies))
+	}
+
+	saved, err := m.savedRows(ctx, tx, currentUser.OrganizationId, version.ImportId, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	diff := imports.Compare(saved, rows)
+
+	applied, err := diffDigest(diff)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if applied != digest {
+		return nil, ErrReplacementStale
+	}
+
+	if err = m.checkReplacementPeriods(ctx, tx, currentUser.OrganizationId, diff); err != nil {
+		return nil, err
+	}
+
+	removed := make([]int, 0, len(diff.Removed))
+
+	for _, row := range diff.Removed {
+		if err = m.Ledger.ReverseSource(tx, ctx, currentUser, data.SourceContribution, row.ContributionId); err != nil {
+			return nil, err
+		}
+
+		removed = append(removed, row.ContributionId)
+	}
+
+	if len(removed) > 0 {
+		_, err = tx.ExecContext(ctx, `DELETE FROM funds WHERE id = ANY($1) AND organization_id = $2;`, pq.Array(removed),
+			currentUser.OrganizationId)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, change := range diff.Changed {
+		err = m.updateContribution(tx, ctx, currentUser, change.Before.ContributionId, &models.UpdateFund{
+			Contributor: change.After.Contributor,
+			Date:        change.After.Date,
+			Total:       change.After.Total,
+			BreakDown:   change.After.BreakDown,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for batch := range slices.Chunk(diff.Added, importBatchSize) {
+		funds := make([]models.Fund, 0, len(batch))
+
+		for _, row := range batch {
+			funds = append(funds, models.Fund{
+				BreakDown:      row.BreakDown,
+				Total:          row.Total,
+				ReceiptNo:      row.ReceiptNo,
+				OrganizationId: currentUser.OrganizationId,
+				Date:           row.Date,
+				Contributor:    row.Contributor,
+				ImportId:       version.ImportId,
+			})
+		}
+
+		if _, err = m.insert(tx, ctx, currentUser, funds); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = m.SaveCategories(tx, ctx, categories); err != nil {
+		return nil, err
+	}
+
+	// the file the import was created with becomes its first version
+	stmt = `INSERT INTO import_versions(import_id,version,filename,file_path,file_hash,file_size,profile_id,status,
+				rows_added,created_at,created_by,applied_at)
+				SELECT id,version,filename,file_path,file_hash,file_size,profile_id,$1,rows_inserted,
+				created_at,created_by,finished_at FROM imports i
+				WHERE id = $2 AND NOT EXISTS (SELECT 1 FROM import_versions v WHERE v.import_id = i.id AND v.version = i.version);`
+
+	if _, err = tx.ExecContext(ctx, stmt, data.VersionSuperseded, version.ImportId); err != nil {
+		return nil, err
+	}
+
+	stmt = `UPDATE import_versions SET status = $1 WHERE import_id = $2 AND status = $3;`
+
+	if _, err = tx.ExecContext(ctx, stmt, data.VersionSuperseded, version.ImportId, data.VersionCurrent); err != nil {
+		return nil, err
+	}
+
+	version.Version = current + 1
+	version.Status = data.VersionCurrent
+	version.RowsAdded, version.RowsRemoved, version.RowsChanged = len(diff.Added), len(diff.Removed), len(diff.Changed)
+	version.AppliedBy = fmt.Sprint(currentUser.ID)
+
+	stmt = `UPDATE import_versions SET version = $1, status = $2, preview_token = NULL, rows_added = $3, rows_removed = $4,
+				rows_changed = $5, applied_at = now(), applied_by = $6 WHERE id = $7 RETURNING applied_at;`
+
+	err = tx.QueryRowContext(ctx, stmt, version.Version, version.Status, version.RowsAdded, version.RowsRemoved,
+		version.RowsChanged, version.AppliedBy, version.ID).Scan(&version.AppliedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt = `UPDATE imports SET version = $1, filename = $2, file_path = $3, file_hash = $4, file_size = $5, hash = $4,
+				modified_at = now() WHERE id = $6;`
+
+	_, err = tx.ExecContext(ctx, stmt, version.Version, upload.Name, upload.Path, upload.Hash, upload.Size, version.ImportId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	m.Logger.InfoLog.Printf("Applied version %d of import %d: %d added, %d removed, %d changed", version.Version,
+		version.ImportId, version.RowsAdded, version.RowsRemoved, version.RowsChanged)
+
+	return version, nil
+}
+
+// checkReplacementPeriods refuses a delta that touches a closed period, on either the
+// old or the new date of a contribution
+func (m *FundsModel) checkReplacementPeriods(ctx context.Context, tx *sql.Tx, organizationId int, diff *imports.Diff) error {
+	dates := make([]string, 0)
+
+	for _, row := range diff.Added {
+		dates = append(dates, row.Date)
+	}
+
+	for _, row := range diff.Removed {
+		dates = append(dates, row.Date)
+	}
+
+	for _, change := range diff.Changed {
+		dates = append(dates, change.Before.Date, change.After.Date)
+	}
+
+	slices.Sort(dates)
+
+	for _, date := range slices.Compact(dates) {
+		closed, err := m.Ledger.IsClosed(ctx, tx, organizationId, date)
+
+		if err != nil {
+			return err
+		}
+
+		if closed {
+			return fmt.Errorf("%w: %s", ErrPeriodClosed, date)
+		}
+	}
+
+	return nil
+}
+
+// savedRows loads the contributions saved by an import, locking them when they are
+// about to be replaced
+func (m *FundsModel) savedRows(ctx context.Context, q queryer, organizationId, importId int, lock bool) ([]imports.DiffRow, error) {
+	stmt := `SELECT id, receipt_no, contributor, contribution_date, total, break_down FROM funds
+				WHERE import_id = $1 AND organization_id = $2 ORDER BY id`
+
+	if lock {
+		stmt += ` FOR UPDATE`
+	}
+
+	rows, err := q.QueryContext(ctx, stmt+`;`, importId, organizationId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	saved := make([]imports.DiffRow, 0)
+
+	for rows.Next() {
+		var row imports.DiffRow
+		var date time.Time
+		var breakDown []byte
+
+		if err := rows.Scan(&row.ContributionId, &row.ReceiptNo, &row.Contributor, &date, &row.Total, &breakDown); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(breakDown, &row.BreakDown); err != nil {
+			return nil, err
+		}
+
+		row.Date = date.Format("2006-01-02")
+		saved = append(saved, row)
+	}
+
+	return saved, rows.Err()
+}
+
+// readReplacement reads every row of a replacement file
+func readReplacement(file imports.File, size int64, fileName string, profile *imports.Profile) ([]imports.DiffRow, []string,
+	*imports.Progress, error) {
+	importer, _, err := importers.Detect(file, size, fileName, profile)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	rows, categories, err := imports.ReadAll(importer, file, size)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	diffRows := make([]imports.DiffRow, 0, len(rows))
+
+	for _, row := range rows {
+		diffRows = append(diffRows, imports.NewDiffRow(row))
+	}
+
+	return diffRows, categories, importer.Progress(), nil
+}
+
+// diffDigest identifies a delta so that the one applied can be checked against the
+// one previewed
+func diffDigest(diff *imports.Diff) (string, error) {
+	diffJs, err := json.Marshal(diff)
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(diffJs)
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,347 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/imports"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+)
+
+var ErrImportNotSucceeded = errors.New("only a succeeded import can be rolled back")
+
+var ErrImportUpdatedRows = errors.New("an import that updated existing contributions cannot be rolled back")
+
+// ImportJobModel tracks uploaded files from the moment they are queued until their
+// rows have been saved or the import has failed
+type ImportJobModel struct {
+	DB     *sql.DB
+	Logger *utils.CLogger
+}
+
+const importColumns = `id,coalesce(hash,''),filename,organization_id,coalesce(profile_id,0),duplicate_action,status,rows_read,
+	rows_inserted,rows_updated,rows_skipped,errors,duplicates,discrepancies,started_at,finished_at,rows_rolled_back,rolled_back_at,version,created_at,modified_at`
+
+// CreateImport queues a new import of a spooled upload. The unique hash rejects a
+// file that has already been uploaded.
+func (m *ImportJobModel) CreateImport(currentUser *models.User, job *models.ImportJob, upload *imports.Upload) (int, error) {
+	stmt := `INSERT INTO imports(hash,filename,organization_id,profile_id,duplicate_action,status,file_path,file_hash,file_size,created_by)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id;`
+
+	job.OrganizationId = currentUser.OrganizationId
+	job.Status = data.ImportQueued
+	job.Hash = upload.Hash
+
+	err := m.DB.QueryRow(stmt, job.Hash, job.FileName, job.OrganizationId, nullableInt(job.ProfileId), job.DuplicateAction,
+		job.Status, upload.Path, upload.Hash, upload.Size, currentUser.ID).Scan(&job.ID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return job.ID, nil
+}
+
+// CreatePreview keeps an uploaded file for its preview to be confirmed. The hash is only
+// claimed on confirmation so the same file can be previewed more than once.
+func (m *ImportJobModel) CreatePreview(currentUser *models.User, job *models.ImportJob, upload *imports.Upload) (int, string, error) {
+	stmt := `INSERT INTO imports(filename,organization_id,profile_id,duplicate_action,status,preview_token,file_path,file_hash,
+				file_size,created_by) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id;`
+
+	token, err := randomString(32)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	var id int
+
+	err = m.DB.QueryRow(stmt, job.FileName, currentUser.OrganizationId, nullableInt(job.ProfileId), job.DuplicateAction,
+		data.ImportPreview, token, upload.Path, upload.Hash, upload.Size, currentUser.ID).Scan(&id)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	return id, token, nil
+}
+
+// ConfirmPreview queues a previewed file for import. Previews expire after a day.
+func (m *ImportJobModel) ConfirmPreview(currentUser *models.User, token string) (*models.ImportJob, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	job := &models.ImportJob{OrganizationId: currentUser.OrganizationId, Status: data.ImportQueued}
+
+	stmt := `SELECT id,filename,coalesce(profile_id,0),duplicate_action,file_hash
+				FROM imports
+				WHERE preview_token = $1 AND organization_id = $2 AND status = $3
+				AND created_at > now() - interval '1 day' FOR UPDATE;`
+
+	err = tx.QueryRowContext(ctx, stmt, token, currentUser.OrganizationId, data.ImportPreview).Scan(&job.ID, &job.FileName,
+		&job.ProfileId, &job.DuplicateAction, &job.Hash)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, data.ErrorNoRecords
+		}
+		return nil, err
+	}
+
+	stmt = `UPDATE imports SET hash = $1, status = $2, preview_token = NULL, modified_at = now() WHERE id = $3;`
+
+	_, err = tx.ExecContext(ctx, stmt, job.Hash, data.ImportQueued, job.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// HashExists reports whether a file with the hash has already been imported
+func (m *ImportJobModel) HashExists(hash string) (bool, error) {
+	var exists bool
+
+	err := m.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM imports WHERE hash = $1);`, hash).Scan(&exists)
+
+	return exists, err
+}
+
+func (m *ImportJobModel) StartImport(id int) error {
+	stmt := `UPDATE imports SET status = $1, started_at = now(), modified_at = now() WHERE id = $2;`
+
+	_, err := m.DB.Exec(stmt, data.ImportRunning, id)
+
+	return err
+}
+
+// CompleteImport records the rows read, saved and skipped by a successful import
+func (m *ImportJobModel) CompleteImport(id int, progress *imports.Progress, rowsInserted, rowsUpdated int) error {
+	stmt := `UPDATE imports SET status = $1, rows_read = $2, rows_inserted = $3, rows_updated = $4, rows_skipped = $5,
+				errors = $6, duplicates = $7, discrepancies = $8, finished_at = now(), modified_at = now() WHERE id = $9;`
+
+	progressJs, err := marshalProgress(progress, nil)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Exec(stmt, data.ImportSucceeded, progress.RowsRead, rowsInserted, rowsUpdated,
+		progress.RowsRead-rowsInserted-rowsUpdated, progressJs.errors, progressJs.duplicates, progressJs.discrepancies, id)
+
+	return err
+}
+
+// FailImport records why an import failed and releases its hash so that the same
+// file can be uploaded again. An import that has already finished is left as it is,
+// so failing it a second time keeps the errors recorded the first time.
+func (m *ImportJobModel) FailImport(id int, progress *imports.Progress, cause error) error {
+	stmt := `UPDATE imports SET status = $1, hash = NULL, rows_read = $2, rows_inserted = 0, rows_updated = 0, rows_skipped = $2,
+				errors = $3, duplicates = $4, discrepancies = $5, finished_at = now(), modified_at = now()
+				WHERE id = $6 AND status IN ($7, $8);`
+
+	progressJs, err := marshalProgress(progress, cause)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Exec(stmt, data.ImportFailed, progress.RowsRead, progressJs.errors, progressJs.duplicates,
+		progressJs.discrepancies, id, data.ImportQueued, data.ImportRunning)
+
+	return err
+}
+
+func (m *ImportJobModel) GetImports(organizationId int, pageable utils.Pageable) ([]*models.ImportJob, utils.PageInfo, error) {
+	stmt := `SELECT count(*) OVER(), ` + importColumns + ` FROM imports WHERE organization_id = $1
+				ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3;`
+
+	rows, err := m.DB.Query(stmt, organizationId, pageable.Size, pageable.OffSet)
+
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	defer rows.Close()
+
+	jobs := []*models.ImportJob{}
+	totalRecords := 0
+
+	for rows.Next() {
+		job, err := scanImportJob(rows, &totalRecords)
+
+		if err != nil {
+			return nil, utils.PageInfo{}, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	pageInfo := utils.PageInfo{
+		CurrentPage: pageable.Page,
+		Size:        pageable.Size,
+		TotalItems:  totalRecords,
+		FirstPage:   0,
+		LastPage:    int(math.Floor(float64(totalRecords) / float64(pageable.Size))),
+	}
+
+	return jobs, pageInfo, nil
+}
+
+func (m *ImportJobModel) GetImport(organizationId, id int) (*models.ImportJob, error) {
+	stmt := `SELECT 0, ` + importColumns + ` FROM imports WHERE organization_id = $1 AND id = $2;`
+
+	rows, err := m.DB.Query(stmt, organizationId, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, data.ErrorNoRecords
+	}
+
+	var total int
+
+	return scanImportJob(rows, &total)
+}
+
+// OpenFile opens the file uploaded for an import and returns its name and size
+func (m *ImportJobModel) OpenFile(organizationId, id int) (string, imports.File, int64, error) {
+	stmt := `SELECT filename,file_path FROM imports WHERE organization_id = $1 AND id = $2 AND file_path IS NOT NULL;`
+
+	var fileName, path string
+
+	err := m.DB.QueryRow(stmt, organizationId, id).Scan(&fileName, &path)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, 0, data.ErrorNoRecords
+		}
+		return "", nil, 0, err
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil, 0, data.ErrorNoRecords
+		}
+		return "", nil, 0, err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+		return "", nil, 0, err
+	}
+
+	return fileName, file, info.Size(), nil
+}
+
+func scanImportJob(rows *sql.Rows, totalRecords *int) (*models.ImportJob, error) {
+	job := &models.ImportJob{}
+	var rowErrors, duplicates, discrepancies []byte
+
+	err := rows.Scan(totalRecords, &job.ID, &job.Hash, &job.FileName, &job.OrganizationId, &job.ProfileId, &job.DuplicateAction,
+		&job.Status, &job.RowsRead, &job.RowsInserted, &job.RowsUpdated, &job.RowsSkipped, &rowErrors, &duplicates,
+		&discrepancies, &job.StartedAt, &job.FinishedAt, &job.RowsRolledBack, &job.RolledBackAt, &job.Version, &job.Audit.CreatedAt, &job.Audit.ModifiedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(rowErrors, &job.Errors); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(duplicates, &job.Duplicates); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(discrepancies, &job.Discrepancies); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+type progressJson struct {
+	errors        string
+	duplicates    string
+	discrepancies string
+}
+
+// marshalProgress converts the row errors, with the cause of a failure appended, the
+// duplicates and the totals discrepancies of an import to json
+func marshalProgress(progress *imports.Progress, cause error) (*progressJson, error) {
+	rowErrors := progress.Errors
+
+	if rowErrors == nil {
+		rowErrors = []imports.RowError{}
+	}
+
+	if cause != nil {
+		rowErrors = append(rowErrors, imports.RowError{Message: cause.Error()})
+	}
+
+	duplicates := progress.Duplicates
+
+	if duplicates == nil {
+		duplicates = []imports.Duplicate{}
+	}
+
+	discrepancies := progress.Discrepancies
+
+	if discrepancies == nil {
+		discrepancies = []imports.Discrepancy{}
+	}
+
+	errorsJs, err := json.Marshal(rowErrors)
+
+	if err != nil {
+		return nil, err
+	}
+
+	duplicatesJs, err := json.Marshal(duplicates)
+
+	if err != nil {
+		return nil, err
+	}
+
+	discrepanciesJs, err := json.Marshal(discrepancies)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &progressJson{errors: string(errorsJs), duplicates: string(duplicatesJs), discrepancies: string(discrepanciesJs)}, nil
+}
@@ -0,0 +1,290 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/mailer"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+)
+
+var ErrJobNotDead = errors.New("only a dead job can be retried")
+
+// JobModel stores background jobs. Workers claim a job for a visibility timeout and
+// the job becomes available again if that lock is not extended in time.
+type JobModel struct {
+	DB     *sql.DB
+	Logger *utils.CLogger
+}
+
+const jobColumns = `id,kind,coalesce(organization_id,0),payload,status,attempts,max_attempts,run_at,coalesce(last_error,''),
+	result,started_at,finished_at,created_at,modified_at,coalesce(created_by,'')`
+
+// defaultMaxAttempts is the number of times a job is run before it is declared dead
+const defaultMaxAttempts = 5
+
+// Enqueue adds a job for the workers. The user is nil for jobs that do not belong to
+// an organization, such as password reset emails.
+func (m *JobModel) Enqueue(currentUser *models.User, kind string, payload any) (int, error) {
+	stmt := `INSERT INTO jobs(kind,organization_id,payload,max_attempts,created_by) VALUES ($1,$2,$3,$4,$5) RETURNING id;`
+
+	payloadJs, err := json.Marshal(payload)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var organizationId, createdBy any
+
+	if currentUser != nil {
+		organizationId = nullableInt(currentUser.OrganizationId)
+		createdBy = fmt.Sprint(currentUser.ID)
+	}
+
+	var id int
+
+	err = m.DB.QueryRow(stmt, kind, organizationId, string(payloadJs), defaultMaxAttempts, createdBy).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// EnqueueMail queues an email rendered from one of the mailer templates, in the theme
+// of the organization when it is known and in the default one for zero, and in the
+// language of locale. The payload is emptied once the job has finished.
+func (m *JobModel) EnqueueMail(organizationId int, recipient, locale, templateFile string, templateData any) error {
+	_, err := m.Enqueue(nil, data.JobMail, mailer.Message{OrganizationId: organizationId, Recipient: recipient,
+		Locale: locale, Template: templateFile, Data: templateData})
+
+	return err
+}
+
+// Claim locks the next due job for the worker. Running jobs whose lock has expired are
+// claimed again as their worker is presumed gone. A nil job means nothing is due.
+func (m *JobModel) Claim(worker string, visibility time.Duration) (*models.Job, error) {
+	stmt := `UPDATE jobs SET status = $1, attempts = attempts + 1, locked_by = $2,
+				locked_until = now() + $3 * interval '1 millisecond', started_at = coalesce(started_at, now()), modified_at = now()
+				WHERE id = (SELECT id FROM jobs
+					WHERE (status = $4 AND run_at <= now()) OR (status = $1 AND locked_until < now())
+					ORDER BY run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED)
+				RETURNING 0, ` + jobColumns + `;`
+
+	rows, err := m.DB.Query(stmt, data.JobRunning, worker, visibility.Milliseconds(), data.JobQueued)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var total int
+
+	return scanJob(rows, &total)
+}
+
+// Extend keeps the worker's lock on a job that is still running. It returns false
+// when the lock has been lost to another worker.
+func (m *JobModel) Extend(id int, worker string, visibility time.Duration) (bool, error) {
+	stmt := `UPDATE jobs SET locked_until = now() + $1 * interval '1 millisecond'
+				WHERE id = $2 AND locked_by = $3 AND status = $4;`
+
+	return m.updateLocked(stmt, visibility.Milliseconds(), id, worker, data.JobRunning)
+}
+
+// purgedPayload empties the payload of a finished mail job, as it carries one time
+// codes and reset links that must not outlive the email
+const purgedPayload = `payload = CASE WHEN kind = '` + data.JobMail + `' THEN '{}'::jsonb ELSE payload END`
+
+// Complete records the result of a job
+func (m *JobModel) Complete(id int, worker string, result json.RawMessage) error {
+	stmt := `UPDATE jobs SET status = $1, result = $2, last_error = NULL,
+				locked_by = NULL, locked_until = NULL, finished_at = now(), modified_at = now(), ` + purgedPayload + `
+				WHERE id = $3 AND locked_by = $4;`
+
+	var resultJs any
+
+	if result != nil {
+		resultJs = string(result)
+	}
+
+	_, err := m.updateLocked(stmt, data.JobSucceeded, resultJs, id, worker)
+
+	return err
+}
+
+// Retry puts a failed job back on the queue to run again after the delay
+func (m *JobModel) Retry(id int, worker string, cause error, delay time.Duration) error {
+	stmt := `UPDATE jobs SET status = $1, last_error = $2, run_at = now() + $3 * interval '1 millisecond',
+				locked_by = NULL, locked_until = NULL, modified_at = now()
+				WHERE id = $4 AND locked_by = $5;`
+
+	_, err := m.updateLocked(stmt, data.JobQueued, cause.Error(), delay.Milliseconds(), id, worker)
+
+	return err
+}
+
+// Bury moves a job that cannot succeed to the dead letter state, where it stays until
+// it is retried by hand
+func (m *JobModel) Bury(id int, worker string, cause error) error {
+	stmt := `UPDATE jobs SET status = $1, last_error = $2, locked_by = NULL, locked_until = NULL,
+				finished_at = now(), modified_at = now(), ` + purgedPayload + `
+				WHERE id = $3 AND locked_by = $4;`
+
+	_, err := m.updateLocked(stmt, data.JobDead, cause.Error(), id, worker)
+
+	return err
+}
+
+// Release hands a job that was interrupted by a shutdown back to the queue without
+// counting the attempt
+func (m *JobModel) Release(id int, worker string) error {
+	stmt := `UPDATE jobs SET status = $1, attempts = greatest(attempts - 1, 0), locked_by = NULL, locked_until = NULL,
+				modified_at = now()
+				WHERE id = $2 AND locked_by = $3;`
+
+	_, err := m.updateLocked(stmt, data.JobQueued, id, worker)
+
+	return err
+}
+
+// Requeue runs a dead job again with a fresh set of attempts
+func (m *JobModel) Requeue(organizationId, id int) error {
+	stmt := `UPDATE jobs SET status = $1, attempts = 0, run_at = now(), finished_at = NULL, modified_at = now()
+				WHERE id = $2 AND organization_id = $3 AND status = $4;`
+
+	result, err := m.DB.Exec(stmt, data.JobQueued, id, organizationId, data.JobDead)
+
+	if err != nil {
+		return err
+	}
+
+	rowAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowAffected == 0 {
+		if _, err = m.GetJob(organizationId, id); err != nil {
+			return err
+		}
+		return ErrJobNotDead
+	}
+
+	return nil
+}
+
+func (m *JobModel) GetJobs(organizationId int, pageable utils.Pageable) ([]*models.Job, utils.PageInfo, error) {
+	stmt := `SELECT count(*) OVER(), ` + jobColumns + ` FROM jobs WHERE organization_id = $1
+				ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3;`
+
+	rows, err := m.DB.Query(stmt, organizationId, pageable.Size, pageable.OffSet)
+
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	defer rows.Close()
+
+	jobs := []*models.Job{}
+	totalRecords := 0
+
+	for rows.Next() {
+		job, err := scanJob(rows, &totalRecords)
+
+		if err != nil {
+			return nil, utils.PageInfo{}, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	pageInfo := utils.PageInfo{
+		CurrentPage: pageable.Page,
+		Size:        pageable.Size,
+		TotalItems:  totalRecords,
+		FirstPage:   0,
+		LastPage:    int(math.Floor(float64(totalRecords) / float64(pageable.Size))),
+	}
+
+	return jobs, pageInfo, nil
+}
+
+func (m *JobModel) GetJob(organizationId, id int) (*models.Job, error) {
+	stmt := `SELECT 0, ` + jobColumns + ` FROM jobs WHERE organization_id = $1 AND id = $2;`
+
+	rows, err := m.DB.Query(stmt, organizationId, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, data.ErrorNoRecords
+	}
+
+	var total int
+
+	return scanJob(rows, &total)
+}
+
+// updateLocked runs an update guarded by the worker's lock and reports whether the
+// job was still held
+func (m *JobModel) updateLocked(stmt string, args ...any) (bool, error) {
+	result, err := m.DB.Exec(stmt, args...)
+
+	if err != nil {
+		return false, err
+	}
+
+	rowAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return rowAffected > 0, nil
+}
+
+func scanJob(rows *sql.Rows, totalRecords *int) (*models.Job, error) {
+	job := &models.Job{}
+	var payload, result []byte
+
+	err := rows.Scan(totalRecords, &job.ID, &job.Kind, &job.OrganizationId, &payload, &job.Status, &job.Attempts,
+		&job.MaxAttempts, &job.RunAt, &job.LastError, &result, &job.StartedAt,
+		&job.FinishedAt, &job.Audit.CreatedAt, &job.Audit.ModifiedAt, &job.Audit.CreatedBy)
+
+	if err != nil {
+		return nil, err
+	}
+
+	job.Payload = payload
+
+	if result != nil {
+		job.Result = result
+	}
+
+	return job, nil
+}
@@ -0,0 +1,882 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrUnbalancedEntry = errors.New("journal entry debits and credits do not balance")
+	ErrAlreadyReversed = errors.New("journal entry has already been reversed")
+	ErrMixedTransfer   = errors.New("transfers must be between two asset accounts or between two fund accounts")
+	ErrPeriodClosed    = errors.New("the period has been closed")
+)
+
+const unallocated = data.UnallocatedCategory
+
+type LedgerModel struct {
+	DB     *sql.DB
+	Logger *utils.CLogger
+}
+
+// Post writes a journal entry and its lines inside tx. The entry is rejected unless
+// its debits and credits balance to the cent. An entry that totals zero, e.g. a
+// zero amount contribution, has nothing to post and is skipped with id 0.
+func (m *LedgerModel) Post(tx *sql.Tx, ctx context.Context, currentUser *models.User, entry *models.JournalEntry) (int, error) {
+	amount, err := balance(entry.Lines)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if amount == 0 {
+		return 0, nil
+	}
+
+	closed, err := m.IsClosed(ctx, tx, entry.OrganizationId, entry.Date)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if closed {
+		return 0, ErrPeriodClosed
+	}
+
+	stmt := `INSERT INTO journal_entries(organization_id,entry_date,description,source_type,source_id,reverses_id,created_by)
+				VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id;`
+
+	var id int
+
+	err = tx.QueryRowContext(ctx, stmt, entry.OrganizationId, entry.Date, entry.Description, entry.SourceType,
+		nullableInt(entry.SourceId), nullableInt(entry.ReversesId), currentUser.ID).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	values := make([]string, 0, len(entry.Lines))
+	args := []any{id}
+
+	for _, line := range entry.Lines {
+		if toCents(line.Debit) == 0 && toCents(line.Credit) == 0 {
+			continue
+		}
+
+		args = append(args, line.AccountId, line.Debit, line.Credit)
+		values = append(values, fmt.Sprintf("($1,$%d,$%d,$%d)", len(args)-2, len(args)-1, len(args)))
+	}
+
+	stmt = `INSERT INTO journal_lines(journal_entry_id,account_id,debit,credit) VALUES ` + strings.Join(values, ",") + `;`
+
+	_, err = tx.ExecContext(ctx, stmt, args...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	entry.ID = id
+
+	return id, nil
+}
+
+// PostContribution debits cash on hand, or bank for contributions not received in
+// cash, with the contribution total and credits the income account of every
+// category in the break down
+func (m *LedgerModel) PostContribution(tx *sql.Tx, ctx context.Context, currentUser *models.User, fund *models.Fund) (int, error) {
+	receivedIn, err := m.accountByCode(tx, ctx, fund.OrganizationId, contributionAccount(fund.PaymentMethod))
+
+	if err != nil {
+		return 0, err
+	}
+
+	allocations, total := allocate(fund.Total, fund.BreakDown)
+
+	lines := []models.JournalLine{{AccountId: receivedIn, Debit: float64(total) / 100}}
+
+	for _, allocation := range allocations {
+		accountId, err := m.categoryAccount(tx, ctx, fund.OrganizationId, data.AccountTypeIncome, allocation.category)
+
+		if err != nil {
+			return 0, err
+		}
+
+		lines = append(lines, creditLine(accountId, float64(allocation.cents)/100))
+	}
+
+	entry := &models.JournalEntry{
+		OrganizationId: fund.OrganizationId,
+		Date:           fund.Date,
+		Description:    fmt.Sprintf("Contribution receipt %s from %s", fund.ReceiptNo, strings.ToUpper(fund.Contributor)),
+		SourceType:     data.SourceContribution,
+		SourceId:       fund.ID,
+		Lines:          lines,
+	}
+
+	return m.Post(tx, ctx, currentUser, entry)
+}
+
+// contributionAccount is the code of the account a contribution is received into.
+// Contributions saved before payment methods were recorded were received in cash.
+func contributionAccount(paymentMethod string) string {
+	if paymentMethod == "" || paymentMethod == "CASH" {
+		return data.AccountCodeCash
+	}
+
+	return data.AccountCodeBank
+}
+
+// allocation is the part of a contribution credited to one category, in cents
+type allocation struct {
+	category string
+	cents    int64
+}
+
+// allocate splits a contribution between its categories in cents. The total and
+// every amount are rounded once and whatever the rounded amounts leave over, or
+// overshoot, goes to the unallocated category, so that the credits always add up to
+// the total returned.
+func allocate(total float64, breakDown map[string]float64) ([]allocation, int64) {
+	totalCents := toCents(total)
+	allocations := make([]allocation, 0, len(breakDown)+1)
+
+	var allocated int64
+
+	for _, category := range sortedKeys(breakDown) {
+		cents := toCents(breakDown[category])
+
+		if cents == 0 {
+			continue
+		}
+
+		allocations = append(allocations, allocation{category: category, cents: cents})
+		allocated += cents
+	}
+
+	if remainder := totalCents - allocated; remainder != 0 {
+		allocations = append(allocations, allocation{category: unallocated, cents: remainder})
+	}
+
+	return allocations, totalCents
+}
+
+// PostExpenditure debits the expense account of the category charged and credits
+// cash or bank depending on the payment method
+func (m *LedgerModel) PostExpenditure(tx *sql.Tx, ctx context.Context, currentUser *models.User, expenditure *models.Expenditure) (int, error) {
+	code := data.AccountCodeBank
+
+	if expenditure.PaymentMethod == "CASH" {
+		code = data.AccountCodeCash
+	}
+
+	paidFrom, err := m.accountByCode(tx, ctx, expenditure.OrganizationId, code)
+
+	if err != nil {
+		return 0, err
+	}
+
+	expenseAccount, err := m.categoryAccount(tx, ctx, expenditure.OrganizationId, data.AccountTypeExpense, expenditure.Category)
+
+	if err != nil {
+		return 0, err
+	}
+
+	entry := &models.JournalEntry{
+		OrganizationId: expenditure.OrganizationId,
+		Date:           expenditure.Date,
+		Description:    fmt.Sprintf("Payment voucher %s to %s", expenditure.VoucherNo, strings.ToUpper(expenditure.Payee)),
+		SourceType:     data.SourceExpenditure,
+		SourceId:       expenditure.ID,
+		Lines: []models.JournalLine{
+			{AccountId: expenseAccount, Debit: expenditure.Amount},
+			{AccountId: paidFrom, Credit: expenditure.Amount},
+		},
+	}
+
+	return m.Post(tx, ctx, currentUser, entry)
+}
+
+// PostTransfer moves an amount between two accounts of the same organization,
+// e.g. cash banked or money moved between funds
+func (m *LedgerModel) PostTransfer(currentUser *models.User, transfer *models.Transfer) (int, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	var owned, creditNormal int
+
+	err = tx.QueryRowContext(ctx, `SELECT count(*), count(*) FILTER (WHERE account_type IN ($4,$5,$6))
+				FROM accounts WHERE organization_id = $1 AND id IN ($2,$3);`,
+		currentUser.OrganizationId, transfer.FromAccountId, transfer.ToAccountId,
+		data.AccountTypeFund, data.AccountTypeIncome, data.AccountTypeLiability).Scan(&owned, &creditNormal)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if owned != 2 {
+		return 0, data.ErrorNoRecords
+	}
+
+	if creditNormal == 1 {
+		return 0, ErrMixedTransfer
+	}
+
+	description := transfer.Description
+
+	if description == "" {
+		description = "Transfer between accounts"
+	}
+
+	entry := &models.JournalEntry{
+		OrganizationId: currentUser.OrganizationId,
+		Date:           transfer.Date,
+		Description:    description,
+		SourceType:     data.SourceTransfer,
+		Lines: []models.JournalLine{
+			{AccountId: transfer.ToAccountId, Debit: transfer.Amount},
+			{AccountId: transfer.FromAccountId, Credit: transfer.Amount},
+		},
+	}
+
+	// funds carry credit balances, so moving money into a fund credits it
+	if creditNormal == 2 {
+		entry.Lines = []models.JournalLine{
+			{AccountId: transfer.FromAccountId, Debit: transfer.Amount},
+			{AccountId: transfer.ToAccountId, Credit: transfer.Amount},
+		}
+	}
+
+	id, err := m.Post(tx, ctx, currentUser, entry)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Reverse posts a mirror image of an entry. An empty date dates the reversal on
+// the same day as the original entry.
+func (m *LedgerModel) Reverse(tx *sql.Tx, ctx context.Context, currentUser *models.User, entryId int, date string) (int, error) {
+	entries, err := m.loadEntries(ctx, tx, `WHERE e.id = $1 AND e.organization_id = $2`, entryId, currentUser.OrganizationId)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(entries) == 0 {
+		return 0, data.ErrorNoRecords
+	}
+
+	original := entries[0]
+
+	var reversed bool
+
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM journal_entries WHERE reverses_id = $1);`, entryId).Scan(&reversed)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if reversed {
+		return 0, ErrAlreadyReversed
+	}
+
+	if date == "" {
+		date = strings.Split(original.Date, "T")[0]
+	}
+
+	lines := make([]models.JournalLine, 0, len(original.Lines))
+
+	for _, line := range original.Lines {
+		lines = append(lines, models.JournalLine{AccountId: line.AccountId, Debit: line.Credit, Credit: line.Debit})
+	}
+
+	entry := &models.JournalEntry{
+		OrganizationId: original.OrganizationId,
+		Date:           date,
+		Description:    fmt.Sprintf("Reversal of entry #%d: %s", original.ID, original.Description),
+		SourceType:     data.SourceReversal,
+		SourceId:       original.SourceId,
+		ReversesId:     original.ID,
+		Lines:          lines,
+	}
+
+	return m.Post(tx, ctx, currentUser, entry)
+}
+
+// ReverseSource reverses the current entry posted for a contribution or an
+// expenditure, if there is one, before the record is posted again
+func (m *LedgerModel) ReverseSource(tx *sql.Tx, ctx context.Context, currentUser *models.User, sourceType string, sourceId int) error {
+	stmt := `SELECT e.id FROM journal_entries e WHERE e.source_type = $1 AND e.source_id = $2 AND e.organization_id = $3
+				AND NOT EXISTS (SELECT 1 FROM journal_entries r WHERE r.reverses_id = e.id)
+				ORDER BY e.id DESC;`
+
+	rows, err := tx.QueryContext(ctx, stmt, sourceType, sourceId, currentUser.OrganizationId)
+
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int, 0)
+
+	for rows.Next() {
+		var id int
+
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+
+	rows.Close()
+
+	for _, id := range ids {
+		_, err := m.Reverse(tx, ctx, currentUser, id, "")
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReverseEntry reverses a single entry on request of a user
+func (m *LedgerModel) ReverseEntry(currentUser *models.User, entryId int, date string) (int, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	id, err := m.Reverse(tx, ctx, currentUser, entryId, date)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// PostUnposted posts journal entries for contributions and expenditures recorded
+// before the ledger existed
+func (m *LedgerModel) PostUnposted(currentUser *models.User) (int, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	stmt := `SELECT f.id,f.receipt_no,f.total,f.organization_id,f.contribution_date,f.contributor,f.payment_method,f.break_down
+				FROM funds f WHERE f.organization_id = $1 AND NOT EXISTS
+				(SELECT 1 FROM journal_entries e WHERE e.source_type = $2 AND e.source_id = f.id)
+				ORDER BY f.id;`
+
+	rows, err := tx.QueryContext(ctx, stmt, currentUser.OrganizationId, data.SourceContribution)
+
+	if err != nil {
+		return 0, err
+	}
+
+	funds := make([]*models.Fund, 0)
+
+	for rows.Next() {
+		fund := &models.Fund{}
+		var breakDown []byte
+		var date time.Time
+
+		err := rows.Scan(&fund.ID, &fund.ReceiptNo, &fund.Total, &fund.OrganizationId, &date, &fund.Contributor, &fund.PaymentMethod, &breakDown)
+
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		fund.Date = date.Format("2006-01-02")
+
+		if err := json.Unmarshal(breakDown, &fund.BreakDown); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		funds = append(funds, fund)
+	}
+
+	rows.Close()
+
+	for _, fund := range funds {
+		if _, err := m.PostContribution(tx, ctx, currentUser, fund); err != nil {
+			return 0, fmt.Errorf("contribution %d: %w", fund.ID, err)
+		}
+	}
+
+	stmt = `SELECT x.id,x.payee,x.amount,x.category,x.payment_method,x.voucher_no,x.organization_id,x.expenditure_date
+				FROM expenditures x WHERE x.organization_id = $1 AND NOT EXISTS
+				(SELECT 1 FROM journal_entries e WHERE e.source_type = $2 AND e.source_id = x.id)
+				ORDER BY x.id;`
+
+	rows, err = tx.QueryContext(ctx, stmt, currentUser.OrganizationId, data.SourceExpenditure)
+
+	if err != nil {
+		return 0, err
+	}
+
+	expenditures := make([]*models.Expenditure, 0)
+
+	for rows.Next() {
+		expenditure := &models.Expenditure{}
+		var date time.Time
+
+		err := rows.Scan(&expenditure.ID, &expenditure.Payee, &expenditure.Amount, &expenditure.Category,
+			&expenditure.PaymentMethod, &expenditure.VoucherNo, &expenditure.OrganizationId, &date)
+
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		expenditure.Date = date.Format("2006-01-02")
+		expenditures = append(expenditures, expenditure)
+	}
+
+	rows.Close()
+
+	for _, expenditure := range expenditures {
+		if _, err := m.PostExpenditure(tx, ctx, currentUser, expenditure); err != nil {
+			return 0, fmt.Errorf("expenditure %d: %w", expenditure.ID, err)
+		}
+	}
+
+	return len(funds) + len(expenditures), tx.Commit()
+}
+
+func (m *LedgerModel) CreateAccount(currentUser *models.User, account *models.Account) (int, error) {
+	stmt := `INSERT INTO accounts(organization_id,code,name,account_type,category,created_by)
+				VALUES ($1,$2,$3,$4,$5,$6) RETURNING id;`
+
+	var id int
+
+	err := m.DB.QueryRow(stmt, currentUser.OrganizationId, account.Code, account.Name, account.Type,
+		nullableString(account.Category), currentUser.ID).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *LedgerModel) GetAccounts(organizationId int) ([]*models.Account, error) {
+	stmt := `SELECT id,organization_id,code,name,account_type,coalesce(category,''),created_at,modified_at
+				FROM accounts WHERE organization_id = $1 ORDER BY code;`
+
+	rows, err := m.DB.Query(stmt, organizationId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	accounts := []*models.Account{}
+
+	for rows.Next() {
+		account := &models.Account{}
+
+		err := rows.Scan(&account.ID, &account.OrganizationId, &account.Code, &account.Name, &account.Type,
+			&account.Category, &account.Audit.CreatedAt, &account.Audit.ModifiedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// GetEntries pages through the journal for a period, optionally only the entries
+// touching accountId
+func (m *LedgerModel) GetEntries(organizationId int, startDate, endDate time.Time, accountId int,
+	pageable utils.Pageable) ([]*models.JournalEntry, utils.PageInfo, error) {
+
+	ctx := context.Background()
+
+	conditions := []string{"e.organization_id = $1"}
+	args := []any{organizationId}
+
+	if !startDate.IsZero() {
+		args = append(args, startDate)
+		conditions = append(conditions, fmt.Sprintf("e.entry_date >= $%d", len(args)))
+	}
+
+	if !endDate.IsZero() {
+		args = append(args, endDate)
+		conditions = append(conditions, fmt.Sprintf("e.entry_date <= $%d", len(args)))
+	}
+
+	if accountId > 0 {
+		args = append(args, accountId)
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM journal_lines l WHERE l.journal_entry_id = e.id AND l.account_id = $%d)", len(args)))
+	}
+
+	var totalRecords int
+
+	err := m.DB.QueryRowContext(ctx, `SELECT count(*) FROM journal_entries e WHERE `+strings.Join(conditions, " AND ")+`;`,
+		args...).Scan(&totalRecords)
+
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	args = append(args, pageable.Size, pageable.OffSet)
+
+	where := fmt.Sprintf(`WHERE e.id IN (SELECT e.id FROM journal_entries e WHERE %s
+				ORDER BY e.entry_date, e.id LIMIT $%d OFFSET $%d)`, strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	entries, err := m.loadEntries(ctx, m.DB, where, args...)
+
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	pageInfo := utils.PageInfo{
+		CurrentPage: pageable.Page,
+		Size:        pageable.Size,
+		TotalItems:  totalRecords,
+		FirstPage:   0,
+		LastPage:    int(math.Floor(float64(totalRecords) / float64(pageable.Size))),
+	}
+
+	return entries, pageInfo, nil
+}
+
+// CheckIntegrity lists every journal entry whose debits and credits differ
+func (m *LedgerModel) CheckIntegrity(organizationId int) ([]*models.LedgerImbalance, error) {
+	stmt := `SELECT e.id, coalesce(sum(l.debit),0), coalesce(sum(l.credit),0)
+				FROM journal_entries e LEFT JOIN journal_lines l ON l.journal_entry_id = e.id
+				WHERE e.organization_id = $1
+				GROUP BY e.id
+				HAVING coalesce(sum(l.debit),0) <> coalesce(sum(l.credit),0) OR count(l.id) = 0
+				ORDER BY e.id;`
+
+	rows, err := m.DB.Query(stmt, organizationId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	imbalances := []*models.LedgerImbalance{}
+
+	for rows.Next() {
+		row := &models.LedgerImbalance{}
+
+		if err := rows.Scan(&row.EntryId, &row.Debit, &row.Credit); err != nil {
+			return nil, err
+		}
+
+		imbalances = append(imbalances, row)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return imbalances, nil
+}
+
+// ClosePeriod closes the books up to and including periodEnd. Nothing dated in a
+// closed period can be posted or reversed.
+func (m *LedgerModel) ClosePeriod(currentUser *models.User, periodEnd string) (int, error) {
+	stmt := `INSERT INTO closed_periods(organization_id,period_end,created_by) VALUES ($1,$2,$3)
+				ON CONFLICT (organization_id,period_end) DO UPDATE SET period_end = EXCLUDED.period_end RETURNING id;`
+
+	var id int
+
+	err := m.DB.QueryRow(stmt, currentUser.OrganizationId, periodEnd, currentUser.ID).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *LedgerModel) GetClosedPeriods(organizationId int) ([]*models.ClosedPeriod, error) {
+	stmt := `SELECT id,organization_id,period_end,created_at FROM closed_periods WHERE organization_id = $1
+				ORDER BY period_end DESC;`
+
+	rows, err := m.DB.Query(stmt, organizationId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	periods := []*models.ClosedPeriod{}
+
+	for rows.Next() {
+		period := &models.ClosedPeriod{}
+
+		err := rows.Scan(&period.ID, &period.OrganizationId, &period.PeriodEnd, &period.Audit.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		periods = append(periods, period)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return periods, nil
+}
+
+// IsClosed reports whether date falls in a closed period of the organization
+func (m *LedgerModel) IsClosed(ctx context.Context, q queryer, organizationId int, date string) (bool, error) {
+	rows, err := q.QueryContext(ctx, `SELECT EXISTS(SELECT 1 FROM closed_periods WHERE organization_id = $1 AND period_end >= $2::date);`,
+		organizationId, date)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+
+	var closed bool
+
+	if rows.Next() {
+		if err = rows.Scan(&closed); err != nil {
+			return false, err
+		}
+	}
+
+	return closed, rows.Err()
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// loadEntries reads the entries matching where together with their lines
+func (m *LedgerModel) loadEntries(ctx context.Context, q queryer, where string, args ...any) ([]*models.JournalEntry, error) {
+	stmt := `SELECT e.id,e.organization_id,e.entry_date,e.description,e.source_type,coalesce(e.source_id,0),
+				coalesce(e.reverses_id,0),e.created_at,e.modified_at FROM journal_entries e ` + where + ` ORDER BY e.entry_date, e.id;`
+
+	rows, err := q.QueryContext(ctx, stmt, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*models.JournalEntry{}
+	index := make(map[int]*models.JournalEntry)
+	ids := make([]int64, 0)
+
+	for rows.Next() {
+		entry := &models.JournalEntry{Lines: []models.JournalLine{}}
+
+		err := rows.Scan(&entry.ID, &entry.OrganizationId, &entry.Date, &entry.Description, &entry.SourceType,
+			&entry.SourceId, &entry.ReversesId, &entry.Audit.CreatedAt, &entry.Audit.ModifiedAt)
+
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+		index[entry.ID] = entry
+		ids = append(ids, int64(entry.ID))
+	}
+
+	rows.Close()
+
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	stmt = `SELECT l.journal_entry_id,l.account_id,a.code,a.name,l.debit,l.credit
+				FROM journal_lines l JOIN accounts a ON a.id = l.account_id
+				WHERE l.journal_entry_id = ANY($1) ORDER BY l.id;`
+
+	rows, err = q.QueryContext(ctx, stmt, pq.Array(ids))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryId int
+		line := models.JournalLine{}
+
+		err := rows.Scan(&entryId, &line.AccountId, &line.AccountCode, &line.AccountName, &line.Debit, &line.Credit)
+
+		if err != nil {
+			return nil, err
+		}
+
+		index[entryId].Lines = append(index[entryId].Lines, line)
+	}
+
+	return entries, rows.Err()
+}
+
+func (m *LedgerModel) accountByCode(tx *sql.Tx, ctx context.Context, organizationId int, code string) (int, error) {
+	var id int
+
+	err := tx.QueryRowContext(ctx, `SELECT id FROM accounts WHERE organization_id = $1 AND code = $2;`,
+		organizationId, code).Scan(&id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("account %s is missing from the chart of accounts", code)
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// categoryAccount finds the income or expense account of a category, opening a new
+// one with the next free code when the category has not been posted before. The
+// lookup and the insert are one statement, and a category opened by a concurrent
+// transaction is taken over through the conflict on (organization_id, account_type,
+// category). Two transactions opening different categories at once may still pick
+// the same code; the later one fails and is retried like any other error.
+func (m *LedgerModel) categoryAccount(tx *sql.Tx, ctx context.Context, organizationId int, accountType, category string) (int, error) {
+	base := 4000
+	if accountType == data.AccountTypeExpense {
+		base = 5000
+	}
+
+	// codes are unique across every account type of the organization, so the next
+	// free code in the type's thousand is checked against the whole chart. No code
+	// is looked for when the account already exists.
+	stmt := `WITH existing AS (
+					SELECT id FROM accounts WHERE organization_id = $1 AND account_type = $4 AND category = $3
+				), opened AS (
+					INSERT INTO accounts(organization_id,code,name,account_type,category)
+					SELECT $1, candidate::text, $3, $4, $3 FROM generate_series($2 + 1, $2 + 999) AS candidate
+					WHERE NOT EXISTS (SELECT 1 FROM existing)
+						AND NOT EXISTS (SELECT 1 FROM accounts WHERE organization_id = $1 AND code = candidate::text)
+					ORDER BY candidate LIMIT 1
+					ON CONFLICT (organization_id, account_type, category) DO UPDATE SET modified_at = accounts.modified_at
+					RETURNING id
+				)
+				SELECT id FROM existing UNION ALL SELECT id FROM opened;`
+
+	var id int
+
+	err := tx.QueryRowContext(ctx, stmt, organizationId, base, category, accountType).Scan(&id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("no free %s account code between %d and %d", accountType, base+1, base+999)
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// balance checks that the debits of an entry's lines equal their credits to the cent
+// and returns that amount in cents
+func balance(lines []models.JournalLine) (int64, error) {
+	var debit, credit int64
+
+	for _, line := range lines {
+		if line.Debit < 0 || line.Credit < 0 {
+			return 0, fmt.Errorf("journal line amounts must not be negative")
+		}
+
+		debit += toCents(line.Debit)
+		credit += toCents(line.Credit)
+	}
+
+	if debit != credit {
+		return 0, ErrUnbalancedEntry
+	}
+
+	return debit, nil
+}
+
+func creditLine(accountId int, amount float64) models.JournalLine {
+	if amount < 0 {
+		return models.JournalLine{AccountId: accountId, Debit: -amount}
+	}
+
+	return models.JournalLine{AccountId: accountId, Credit: amount}
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func nullableInt(value int) any {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
+
+func nullableString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/models"
+)
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     float64
+		breakDown map[string]float64
+		want      []allocation
+		wantTotal int64
+	}{
+		{
+			name:      "categories add up",
+			total:     1500,
+			breakDown: map[string]float64{"TITHE": 1000, "OFFERING": 500},
+			want:      []allocation{{category: "OFFERING", cents: 50000}, {category: "TITHE", cents: 100000}},
+			wantTotal: 150000,
+		},
+		{
+			name:      "floats that only add up once rounded",
+			total:     0.3,
+			breakDown: map[string]float64{"A": 0.1, "B": 0.2},
+			want:      []allocation{{category: "A", cents: 10}, {category: "B", cents: 20}},
+			wantTotal: 30,
+		},
+		{
+			name:      "fractions of a cent",
+			total:     100.004,
+			breakDown: map[string]float64{"A": 33.335, "B": 33.335, "C": 33.334},
+			want: []allocation{{category: "A", cents: 3334}, {category: "B", cents: 3334}, {category: "C", cents: 3333},
+				{category: unallocated, cents: -1}},
+			wantTotal: 10000,
+		},
+		{
+			name:      "remainder to unallocated",
+			total:     1000.5,
+			breakDown: map[string]float64{"TITHE": 900},
+			want:      []allocation{{category: "TITHE", cents: 90000}, {category: unallocated, cents: 10050}},
+			wantTotal: 100050,
+		},
+		{
+			name:      "no break down",
+			total:     250,
+			want:      []allocation{{category: unallocated, cents: 25000}},
+			wantTotal: 25000,
+		},
+		{
+			name:      "zero amounts are left out",
+			total:     100,
+			breakDown: map[string]float64{"TITHE": 100, "BUILDING": 0, "CAMP": 0.004},
+			want:      []allocation{{category: "TITHE", cents: 10000}},
+			wantTotal: 10000,
+		},
+		{
+			name:      "break down over the total",
+			total:     100,
+			breakDown: map[string]float64{"TITHE": 120},
+			want:      []allocation{{category: "TITHE", cents: 12000}, {category: unallocated, cents: -2000}},
+			wantTotal: 10000,
+		},
+		{
+			name:      "zero total",
+			breakDown: map[string]float64{},
+			want:      []allocation{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total := allocate(tt.total, tt.breakDown)
+
+			if !reflect.DeepEqual(got, tt.want) || total != tt.wantTotal {
+				t.Fatalf("allocate(%v, %v) = %+v, %d, want %+v, %d", tt.total, tt.breakDown, got, total, tt.want, tt.wantTotal)
+			}
+
+			lines := []models.JournalLine{{AccountId: 1, Debit: float64(total) / 100}}
+
+			for i, allocation := range got {
+				lines = append(lines, creditLine(i+2, float64(allocation.cents)/100))
+			}
+
+			if _, err := balance(lines); err != nil {
+				t.Fatalf("posted lines do not balance: %v", err)
+			}
+		})
+	}
+}
+
+func TestBalance(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []models.JournalLine
+		want    int64
+		wantErr error
+	}{
+		{
+			name:  "balanced",
+			lines: []models.JournalLine{{Debit: 1500}, {Credit: 1000}, {Credit: 500}},
+			want:  150000,
+		},
+		{
+			name:  "balanced once rounded to the cent",
+			lines: []models.JournalLine{{Debit: 0.3}, {Credit: 0.1}, {Credit: 0.2}},
+			want:  30,
+		},
+		{
+			name:  "debit and credit on one line",
+			lines: []models.JournalLine{{Debit: 100, Credit: 40}, {Credit: 60}},
+			want:  10000,
+		},
+		{
+			name:  "empty",
+			lines: []models.JournalLine{},
+		},
+		{
+			name:    "off by a cent",
+			lines:   []models.JournalLine{{Debit: 100}, {Credit: 99.99}},
+			wantErr: ErrUnbalancedEntry,
+		},
+		{
+			name:    "negative amount",
+			lines:   []models.JournalLine{{Debit: -100}, {Credit: -100}},
+			wantErr: errors.New("journal line amounts must not be negative"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := balance(tt.lines)
+
+			if (err == nil) != (tt.wantErr == nil) || (err != nil && err.Error() != tt.wantErr.Error()) {
+				t.Fatalf("balance() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("balance() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreditLine(t *testing.T) {
+	if got := creditLine(7, 250); got != (models.JournalLine{AccountId: 7, Credit: 250}) {
+		t.Fatalf("creditLine(7, 250) = %+v", got)
+	}
+
+	if got := creditLine(7, -0.01); got != (models.JournalLine{AccountId: 7, Debit: 0.01}) {
+		t.Fatalf("creditLine(7, -0.01) = %+v", got)
+	}
+}
+
+func TestContributionAccount(t *testing.T) {
+	tests := []struct {
+		paymentMethod string
+		want          string
+	}{
+		{paymentMethod: "", want: data.AccountCodeCash},
+		{paymentMethod: "CASH", want: data.AccountCodeCash},
+		{paymentMethod: "MPESA", want: data.AccountCodeBank},
+		{paymentMethod: "CHEQUE", want: data.AccountCodeBank},
+		{paymentMethod: "BANK TRANSFER", want: data.AccountCodeBank},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.paymentMethod, func(t *testing.T) {
+			if got := contributionAccount(tt.paymentMethod); got != tt.want {
+				t.Fatalf("contributionAccount(%q) = %s, want %s", tt.paymentMethod, got, tt.want)
+			}
+		})
+	}
+}
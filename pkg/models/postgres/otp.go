@@ -7,7 +7,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/VaudKK/CAS/pkg/mailer"
 	"github.com/VaudKK/CAS/pkg/models"
 	"github.com/VaudKK/CAS/utils"
 	"github.com/google/uuid"
@@ -15,7 +14,7 @@ import (
 
 type OtpModel struct {
 	DB     *sql.DB
-	Mailer *mailer.Mailer
+	Jobs   *JobModel
 	User   *UserModel
 	Logger *utils.CLogger
 }
@@ -46,7 +45,9 @@ func (m *OtpModel) SendOtp(subject, mode string) (*models.Otp, error) {
 	emailData.Otp = otp
 
 	//send mail
-	err = m.Mailer.Send(subject, "user_otp.tmpl", emailData)
+	organizationId, locale := m.User.mailSettings(subject)
+
+	err = m.Jobs.EnqueueMail(organizationId, subject, locale, "user_otp.tmpl", emailData)
 
 	if err != nil {
 		return nil, err
@@ -102,10 +103,12 @@ func (m *OtpModel) VerifyOtp(otp, sessionId string) (bool, error) {
 	}
 
 	//send account review email
-	err = m.Mailer.Send(data.subject, "account_review.tmpl", nil)
+	organizationId, locale := m.User.mailSettings(data.subject)
+
+	err = m.Jobs.EnqueueMail(organizationId, data.subject, locale, "account_review.tmpl", nil)
 
 	if err != nil {
-		m.Logger.ErrorLog.Printf("Error while sending review email: %v", err)
+		m.Logger.ErrorLog.Printf("Error while queueing review email: %v", err)
 	}
 
 	return true, nil
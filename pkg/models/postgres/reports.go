@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+)
+
+// ReportModel keeps track of reports generated in the background. The files
+// themselves are in storage under the report's storage key.
+type ReportModel struct {
+	DB     *sql.DB
+	Logger *utils.CLogger
+}
+
+const reportColumns = `id,organization_id,type,format,parameters,status,coalesce(job_id,0),coalesce(storage_key,''),
+	coalesce(file_name,''),coalesce(content_type,''),coalesce(size,0),coalesce(last_error,''),expires_at,completed_at,
+	created_at,modified_at,coalesce(created_by,'')`
+
+func (m *ReportModel) Create(currentUser *models.User, report *models.Report) (int, error) {
+	stmt := `INSERT INTO reports(organization_id,type,format,parameters,status,created_by) VALUES ($1,$2,$3,$4,$5,$6) RETURNING id;`
+
+	parameters, err := json.Marshal(report.Parameters)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+
+	err = m.DB.QueryRow(stmt, currentUser.OrganizationId, report.Type, report.Format, string(parameters), data.ReportQueued,
+		fmt.Sprint(currentUser.ID)).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// SetJob links a report to the job generating it
+func (m *ReportModel) SetJob(id, jobId int) error {
+	_, err := m.DB.Exec(`UPDATE reports SET job_id = $1, modified_at = now() WHERE id = $2;`, jobId, id)
+
+	return err
+}
+
+// Start marks a report as being generated. A failed report starts again when its dead
+// job is retried. It returns false when the report is no longer waiting for its file,
+// such as after an earlier attempt succeeded.
+func (m *ReportModel) Start(id int) (bool, error) {
+	stmt := `UPDATE reports SET status = $1, modified_at = now() WHERE id = $2 AND status IN ($1, $3, $4);`
+
+	result, err := m.DB.Exec(stmt, data.ReportRunning, id, data.ReportQueued, data.ReportFailed)
+
+	if err != nil {
+		return false, err
+	}
+
+	rowAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return rowAffected > 0, nil
+}
+
+// Complete records the stored file of a report and when it expires
+func (m *ReportModel) Complete(id int, storageKey, fileName, contentType string, size int64, expiresAt time.Time) error {
+	stmt := `UPDATE reports SET status = $1, storage_key = $2, file_name = $3, content_type = $4, size = $5,
+				expires_at = $6, last_error = NULL, completed_at = now(), modified_at = now()
+				WHERE id = $7;`
+
+	_, err := m.DB.Exec(stmt, data.ReportReady, storageKey, fileName, contentType, size, expiresAt, id)
+
+	return err
+}
+
+func (m *ReportModel) Fail(id int, cause error) error {
+	stmt := `UPDATE reports SET status = $1, last_error = $2, completed_at = now(), modified_at = now() WHERE id = $3;`
+
+	_, err := m.DB.Exec(stmt, data.ReportFailed, cause.Error(), id)
+
+	return err
+}
+
+// RecordError keeps the error of a failed attempt that will be retried
+func (m *ReportModel) RecordError(id int, cause error) error {
+	_, err := m.DB.Exec(`UPDATE reports SET last_error = $1, modified_at = now() WHERE id = $2;`, cause.Error(), id)
+
+	return err
+}
+
+func (m *ReportModel) GetReports(organizationId int, pageable utils.Pageable) ([]*models.Report, utils.PageInfo, error) {
+	stmt := `SELECT count(*) OVER(), ` + reportColumns + ` FROM reports WHERE organization_id = $1
+				ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3;`
+
+	reports, totalRecords, err := m.queryReports(stmt, organizationId, pageable.Size, pageable.OffSet)
+
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	pageInfo := utils.PageInfo{
+		CurrentPage: pageable.Page,
+		Size:        pageable.Size,
+		TotalItems:  totalRecords,
+		FirstPage:   0,
+		LastPage:    int(math.Floor(float64(totalRecords) / float64(pageable.Size))),
+	}
+
+	return reports, pageInfo, nil
+}
+
+func (m *ReportModel) GetReport(organizationId, id int) (*models.Report, error) {
+	stmt := `SELECT 0, ` + reportColumns + ` FROM reports WHERE organization_id = $1 AND id = $2;`
+
+	return m.getReport(stmt, organizationId, id)
+}
+
+// GetReportByID finds a report in any organization, for the workers and for signed
+// download links which are not tied to a logged in user
+func (m *ReportModel) GetReportByID(id int) (*models.Report, error) {
+	stmt := `SELECT 0, ` + reportColumns + ` FROM reports WHERE id = $1;`
+
+	return m.getReport(stmt, id)
+}
+
+// Expired returns up to limit ready reports whose files have passed their expiry
+func (m *ReportModel) Expired(limit int) ([]*models.Report, error) {
+	stmt := `SELECT 0, ` + reportColumns + ` FROM reports WHERE status = $1 AND expires_at < now()
+				ORDER BY expires_at LIMIT $2;`
+
+	reports, _, err := m.queryReports(stmt, data.ReportReady, limit)
+
+	return reports, err
+}
+
+// MarkExpired records that the file of a report has been removed from storage
+func (m *ReportModel) MarkExpired(id int) error {
+	stmt := `UPDATE reports SET status = $1, storage_key = NULL, modified_at = now() WHERE id = $2 AND status = $3;`
+
+	_, err := m.DB.Exec(stmt, data.ReportExpired, id, data.ReportReady)
+
+	return err
+}
+
+func (m *ReportModel) getReport(stmt string, args ...any) (*models.Report, error) {
+	reports, _, err := m.queryReports(stmt, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reports) == 0 {
+		return nil, data.ErrorNoRecords
+	}
+
+	return reports[0], nil
+}
+
+func (m *ReportModel) queryReports(stmt string, args ...any) ([]*models.Report, int, error) {
+	rows, err := m.DB.Query(stmt, args...)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	reports := []*models.Report{}
+	totalRecords := 0
+
+	for rows.Next() {
+		report := &models.Report{}
+		var parameters []byte
+
+		err = rows.Scan(&totalRecords, &report.ID, &report.OrganizationId, &report.Type, &report.Format, &parameters,
+			&report.Status, &report.JobId, &report.StorageKey, &report.FileName, &report.ContentType, &report.Size,
+			&report.LastError, &report.ExpiresAt, &report.CompletedAt, &report.Audit.CreatedAt, &report.Audit.ModifiedAt,
+			&report.Audit.CreatedBy)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if err = json.Unmarshal(parameters, &report.Parameters); err != nil {
+			return nil, 0, err
+		}
+
+		reports = append(reports, report)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return reports, totalRecords, nil
+}
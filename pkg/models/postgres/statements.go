@@ -0,0 +1,330 @@
+package postgres
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+)
+
+// StatementModel builds financial statements from the ledger
+type StatementModel struct {
+	DB     *sql.DB
+	Logger *utils.CLogger
+}
+
+type accountMovement struct {
+	Code     string
+	Name     string
+	Type     string
+	Category string
+	Debit    float64
+	Credit   float64
+}
+
+// beginningOfBooks is used as the start date for balances carried from inception
+var beginningOfBooks = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// NewStatementPeriod returns the period from start to end together with the same
+// period a year earlier for the comparative columns
+func NewStatementPeriod(startDate, endDate time.Time, comparative bool) models.StatementPeriod {
+	period := models.StatementPeriod{From: startDate, To: endDate}
+
+	if comparative {
+		period.PriorFrom = startDate.AddDate(-1, 0, 0)
+		period.PriorTo = endDate.AddDate(-1, 0, 0)
+	}
+
+	return period
+}
+
+// GetTrialBalance lists the balance of every account as at the end of the period
+func (m *StatementModel) GetTrialBalance(organizationId int, period models.StatementPeriod) (*models.TrialBalance, error) {
+	current, err := m.accountMovements(organizationId, beginningOfBooks, period.To)
+
+	if err != nil {
+		return nil, err
+	}
+
+	prior := map[int]*accountMovement{}
+
+	if !period.PriorTo.IsZero() {
+		prior, err = m.accountMovements(organizationId, beginningOfBooks, period.PriorTo)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &models.TrialBalance{Period: period, Lines: []*models.TrialBalanceLine{}}
+
+	for _, id := range sortedAccountIds(current) {
+		account := current[id]
+
+		line := &models.TrialBalanceLine{Code: account.Code, Name: account.Name, Type: account.Type}
+		line.Debit, line.Credit = splitBalance(account.Debit - account.Credit)
+
+		if previous, ok := prior[id]; ok {
+			line.PriorDebit, line.PriorCredit = splitBalance(previous.Debit - previous.Credit)
+		}
+
+		if line.Debit == 0 && line.Credit == 0 && line.PriorDebit == 0 && line.PriorCredit == 0 {
+			continue
+		}
+
+		report.Lines = append(report.Lines, line)
+		report.TotalDebit += line.Debit
+		report.TotalCredit += line.Credit
+		report.PriorTotalDebit += line.PriorDebit
+		report.PriorTotalCredit += line.PriorCredit
+	}
+
+	return report, nil
+}
+
+// GetIncomeStatement reports income and expenditure per account for the period
+func (m *StatementModel) GetIncomeStatement(organizationId int, period models.StatementPeriod) (*models.IncomeStatement, error) {
+	current, err := m.accountMovements(organizationId, period.From, period.To)
+
+	if err != nil {
+		return nil, err
+	}
+
+	prior := map[int]*accountMovement{}
+
+	if !period.PriorTo.IsZero() {
+		prior, err = m.accountMovements(organizationId, period.PriorFrom, period.PriorTo)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &models.IncomeStatement{
+		Period:      period,
+		Income:      []*models.StatementLine{},
+		Expenditure: []*models.StatementLine{},
+	}
+
+	for _, id := range sortedAccountIds(current) {
+		account := current[id]
+		previous, hasPrior := prior[id]
+
+		if !hasPrior {
+			previous = &accountMovement{}
+		}
+
+		switch account.Type {
+		case data.AccountTypeIncome:
+			line := &models.StatementLine{Code: account.Code, Name: account.Name,
+				Amount: account.Credit - account.Debit, PriorAmount: previous.Credit - previous.Debit}
+
+			if line.Amount != 0 || line.PriorAmount != 0 {
+				report.Income = append(report.Income, line)
+			}
+
+			report.TotalIncome += line.Amount
+			report.PriorTotalIncome += line.PriorAmount
+		case data.AccountTypeExpense:
+			line := &models.StatementLine{Code: account.Code, Name: account.Name,
+				Amount: account.Debit - account.Credit, PriorAmount: previous.Debit - previous.Credit}
+
+			if line.Amount != 0 || line.PriorAmount != 0 {
+				report.Expenditure = append(report.Expenditure, line)
+			}
+
+			report.TotalExpenditure += line.Amount
+			report.PriorTotalExpenditure += line.PriorAmount
+		}
+	}
+
+	report.Surplus = report.TotalIncome - report.TotalExpenditure
+	report.PriorSurplus = report.PriorTotalIncome - report.PriorTotalExpenditure
+
+	return report, nil
+}
+
+// GetFundBalanceSheet reports the opening balance, receipts, payments, transfers and
+// closing balance of every fund (category) for the period
+func (m *StatementModel) GetFundBalanceSheet(organizationId int, period models.StatementPeriod) (*models.FundBalanceSheet, error) {
+	opening, err := m.accountMovements(organizationId, beginningOfBooks, period.From.AddDate(0, 0, -1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	movements, err := m.accountMovements(organizationId, period.From, period.To)
+
+	if err != nil {
+		return nil, err
+	}
+
+	prior := map[int]*accountMovement{}
+
+	if !period.PriorTo.IsZero() {
+		prior, err = m.accountMovements(organizationId, beginningOfBooks, period.PriorTo)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	funds := make(map[string]*models.FundBalanceLine)
+
+	fundLine := func(account *accountMovement) *models.FundBalanceLine {
+		name := account.Category
+
+		if name == "" {
+			name = account.Name
+		}
+
+		line, ok := funds[name]
+
+		if !ok {
+			line = &models.FundBalanceLine{Fund: name}
+			funds[name] = line
+		}
+
+		return line
+	}
+
+	for id, account := range movements {
+		if !isFundAccount(account) {
+			continue
+		}
+
+		line := fundLine(account)
+		line.Opening += fundBalance(opening[id])
+
+		switch account.Type {
+		case data.AccountTypeIncome:
+			line.Receipts += account.Credit - account.Debit
+		case data.AccountTypeExpense:
+			line.Payments += account.Debit - account.Credit
+		case data.AccountTypeFund:
+			line.Transfers += account.Credit - account.Debit
+		}
+
+		line.PriorClosing += fundBalance(prior[id])
+	}
+
+	report := &models.FundBalanceSheet{Period: period, Lines: []*models.FundBalanceLine{}}
+
+	names := make([]string, 0, len(funds))
+
+	for name := range funds {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		line := funds[name]
+		line.Closing = line.Opening + line.Receipts - line.Payments + line.Transfers
+
+		if line.Opening == 0 && line.Closing == 0 && line.Receipts == 0 && line.Payments == 0 && line.PriorClosing == 0 {
+			continue
+		}
+
+		report.Lines = append(report.Lines, line)
+		report.Totals.Opening += line.Opening
+		report.Totals.Receipts += line.Receipts
+		report.Totals.Payments += line.Payments
+		report.Totals.Transfers += line.Transfers
+		report.Totals.Closing += line.Closing
+		report.Totals.PriorClosing += line.PriorClosing
+	}
+
+	report.Totals.Fund = "Total"
+
+	return report, nil
+}
+
+// accountMovements totals the debits and credits of every account for entries
+// dated between startDate and endDate, inclusive
+func (m *StatementModel) accountMovements(organizationId int, startDate, endDate time.Time) (map[int]*accountMovement, error) {
+	stmt := `SELECT a.id,a.code,a.name,a.account_type,coalesce(a.category,''),
+				coalesce(sum(l.debit),0),coalesce(sum(l.credit),0)
+				FROM accounts a
+				LEFT JOIN (journal_lines l JOIN journal_entries e ON e.id = l.journal_entry_id AND e.entry_date BETWEEN $2 AND $3)
+				ON l.account_id = a.id
+				WHERE a.organization_id = $1
+				GROUP BY a.id;`
+
+	rows, err := m.DB.Query(stmt, organizationId, startDate, endDate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	movements := make(map[int]*accountMovement)
+
+	for rows.Next() {
+		var id int
+		movement := &accountMovement{}
+
+		err := rows.Scan(&id, &movement.Code, &movement.Name, &movement.Type, &movement.Category, &movement.Debit, &movement.Credit)
+
+		if err != nil {
+			return nil, err
+		}
+
+		movements[id] = movement
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+func isFundAccount(account *accountMovement) bool {
+	return account.Type == data.AccountTypeIncome || account.Type == data.AccountTypeExpense ||
+		(account.Type == data.AccountTypeFund && account.Category != "")
+}
+
+// fundBalance is the contribution of an account to the balance of its fund
+func fundBalance(account *accountMovement) float64 {
+	if account == nil {
+		return 0
+	}
+
+	if account.Type == data.AccountTypeExpense {
+		return -(account.Debit - account.Credit)
+	}
+
+	return account.Credit - account.Debit
+}
+
+// splitBalance places a net balance in the debit or credit column
+func splitBalance(balance float64) (float64, float64) {
+	if balance >= 0 {
+		return balance, 0
+	}
+	return 0, -balance
+}
+
+func sortedAccountIds(accounts map[int]*accountMovement) []int {
+	ids := make([]int, 0, len(accounts))
+
+	for id := range accounts {
+		ids = append(ids, id)
+	}
+
+	slices.SortFunc(ids, func(a, b int) int {
+		if accounts[a].Code < accounts[b].Code {
+			return -1
+		} else if accounts[a].Code > accounts[b].Code {
+			return 1
+		}
+		return 0
+	})
+
+	return ids
+}
@@ -0,0 +1,397 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/VaudKK/CAS/pkg/data"
+	"github.com/VaudKK/CAS/pkg/models"
+	"github.com/VaudKK/CAS/utils"
+	"github.com/lib/pq"
+)
+
+var ErrDeliveryNotFailed = errors.New("only a failed delivery can be retried")
+
+// SubscriptionModel stores the reports an organization has sent on a schedule and the
+// log of each time one was sent
+type SubscriptionModel struct {
+	DB     *sql.DB
+	Logger *utils.CLogger
+}
+
+const subscriptionColumns = `id,organization_id,name,report_type,period,format,recipients,cron,charts,active,
+	next_run_at,last_run_at,created_at,modified_at,coalesce(created_by,''),coalesce(modified_by,'')`
+
+const deliveryColumns = `id,subscription_id,organization_id,scheduled_for,to_char(period_from,'YYYY-MM-DD'),
+	to_char(period_to,'YYYY-MM-DD'),status,attempts,coalesce(job_id,0),recipients,coalesce(file_name,''),
+	coalesce(last_error,''),sent_at,created_at,coalesce(created_by,'')`
+
+// Create saves a subscription that next falls due at nextRunAt
+func (m *SubscriptionModel) Create(currentUser *models.User, subscription *models.ReportSubscription,
+	nextRunAt time.Time) (int, error) {
+	stmt := `INSERT INTO report_subscriptions(organization_id,name,report_type,period,format,recipients,cron,charts,
+				active,next_run_at,created_by)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) RETURNING id;`
+
+	var id int
+
+	err := m.DB.QueryRow(stmt, currentUser.OrganizationId, subscription.Name, subscription.ReportType,
+		subscription.Period, subscription.Format, pq.Array(subscription.Recipients), subscription.Cron,
+		pq.Array(chartNames(subscription.Charts)), subscription.Active, nextRunAt, fmt.Sprint(currentUser.ID)).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Update replaces a subscription of the user's organization. The next run is worked
+// out again as the cron expression may have changed.
+func (m *SubscriptionModel) Update(currentUser *models.User, id int, subscription *models.ReportSubscription,
+	nextRunAt time.Time) error {
+	stmt := `UPDATE report_subscriptions SET name = $1, report_type = $2, period = $3, format = $4, recipients = $5,
+				cron = $6, charts = $7, active = $8, next_run_at = $9, modified_at = now(), modified_by = $10
+				WHERE organization_id = $11 AND id = $12;`
+
+	result, err := m.DB.Exec(stmt, subscription.Name, subscription.ReportType, subscription.Period, subscription.Format,
+		pq.Array(subscription.Recipients), subscription.Cron, pq.Array(chartNames(subscription.Charts)), subscription.Active,
+		nextRunAt, fmt.Sprint(currentUser.ID), currentUser.OrganizationId, id)
+
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// Delete removes a subscription along with its delivery log
+func (m *SubscriptionModel) Delete(organizationId, id int) error {
+	result, err := m.DB.Exec(`DELETE FROM report_subscriptions WHERE organization_id = $1 AND id = $2;`,
+		organizationId, id)
+
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+func (m *SubscriptionModel) GetSubscriptions(organizationId int) ([]*models.ReportSubscription, error) {
+	stmt := `SELECT ` + subscriptionColumns + ` FROM report_subscriptions WHERE organization_id = $1 ORDER BY name, id;`
+
+	return m.querySubscriptions(stmt, organizationId)
+}
+
+func (m *SubscriptionModel) GetSubscription(organizationId, id int) (*models.ReportSubscription, error) {
+	stmt := `SELECT ` + subscriptionColumns + ` FROM report_subscriptions WHERE organization_id = $1 AND id = $2;`
+
+	return m.getSubscription(stmt, organizationId, id)
+}
+
+// GetSubscriptionByID finds a subscription in any organization, for the scheduler and
+// the workers
+func (m *SubscriptionModel) GetSubscriptionByID(id int) (*models.ReportSubscription, error) {
+	stmt := `SELECT ` + subscriptionColumns + ` FROM report_subscriptions WHERE id = $1;`
+
+	return m.getSubscription(stmt, id)
+}
+
+// Due returns up to limit active subscriptions whose next run is not after now
+func (m *SubscriptionModel) Due(now time.Time, limit int) ([]*models.ReportSubscription, error) {
+	stmt := `SELECT ` + subscriptionColumns + ` FROM report_subscriptions WHERE active AND next_run_at <= $1
+				ORDER BY next_run_at LIMIT $2;`
+
+	return m.querySubscriptions(stmt, now, limit)
+}
+
+// Advance moves a subscription that fell due at scheduledFor on to its next run. It
+// returns false when the run was already taken, so that a report is sent once even
+// with several servers checking the schedule. A zero next run stops the subscription
+// from falling due again.
+func (m *SubscriptionModel) Advance(id int, scheduledFor, nextRunAt time.Time) (bool, error) {
+	stmt := `UPDATE report_subscriptions SET next_run_at = $1, last_run_at = $2 WHERE id = $3 AND next_run_at = $2;`
+
+	var next any
+
+	if !nextRunAt.IsZero() {
+		next = nextRunAt
+	}
+
+	result, err := m.DB.Exec(stmt, next, scheduledFor, id)
+
+	if err != nil {
+		return false, err
+	}
+
+	rowAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return rowAffected > 0, nil
+}
+
+// CreateDelivery logs a report of a subscription waiting to be sent for the period
+// from to to. createdBy is empty for reports sent on schedule. It returns 0 when the
+// subscription already has a delivery scheduled for the same time.
+func (m *SubscriptionModel) CreateDelivery(subscription *models.ReportSubscription, scheduledFor, from, to time.Time,
+	createdBy string) (int, error) {
+	stmt := `INSERT INTO report_deliveries(subscription_id,organization_id,scheduled_for,period_from,period_to,status,
+				recipients,created_by)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+				ON CONFLICT (subscription_id,scheduled_for) DO NOTHING RETURNING id;`
+
+	var id int
+
+	err := m.DB.QueryRow(stmt, subscription.ID, subscription.OrganizationId, scheduledFor, from.Format("2006-01-02"),
+		to.Format("2006-01-02"), data.DeliveryPending, pq.Array(subscription.Recipients),
+		nullableString(createdBy)).Scan(&id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// SetDeliveryJob links a delivery to the job sending it
+func (m *SubscriptionModel) SetDeliveryJob(id, jobId int) error {
+	_, err := m.DB.Exec(`UPDATE report_deliveries SET job_id = $1, modified_at = now() WHERE id = $2;`, jobId, id)
+
+	return err
+}
+
+// StartDelivery counts an attempt at sending a delivery. It returns false when the
+// delivery is no longer waiting to be sent, such as after an earlier attempt
+// succeeded.
+func (m *SubscriptionModel) StartDelivery(id int) (bool, error) {
+	stmt := `UPDATE report_deliveries SET attempts = attempts + 1, modified_at = now()
+				WHERE id = $1 AND status = $2;`
+
+	result, err := m.DB.Exec(stmt, id, data.DeliveryPending)
+
+	if err != nil {
+		return false, err
+	}
+
+	rowAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return rowAffected > 0, nil
+}
+
+// CompleteDelivery records that the report was sent as the named attachment
+func (m *SubscriptionModel) CompleteDelivery(id int, fileName string) error {
+	stmt := `UPDATE report_deliveries SET status = $1, file_name = $2, last_error = NULL, sent_at = now(),
+				modified_at = now() WHERE id = $3;`
+
+	_, err := m.DB.Exec(stmt, data.DeliverySent, fileName, id)
+
+	return err
+}
+
+func (m *SubscriptionModel) FailDelivery(id int, cause error) error {
+	stmt := `UPDATE report_deliveries SET status = $1, last_error = $2, modified_at = now() WHERE id = $3;`
+
+	_, err := m.DB.Exec(stmt, data.DeliveryFailed, cause.Error(), id)
+
+	return err
+}
+
+// RecordDeliveryError keeps the error of a failed attempt that will be retried
+func (m *SubscriptionModel) RecordDeliveryError(id int, cause error) error {
+	_, err := m.DB.Exec(`UPDATE report_deliveries SET last_error = $1, modified_at = now() WHERE id = $2;`,
+		cause.Error(), id)
+
+	return err
+}
+
+// RetryDelivery puts a failed delivery of the organization back to waiting to be sent
+func (m *SubscriptionModel) RetryDelivery(organizationId, id int) (*models.ReportDelivery, error) {
+	delivery, err := m.getDelivery(`SELECT 0, `+deliveryColumns+` FROM report_deliveries
+				WHERE organization_id = $1 AND id = $2;`, organizationId, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `UPDATE report_deliveries SET status = $1, modified_at = now() WHERE id = $2 AND status = $3;`
+
+	result, err := m.DB.Exec(stmt, data.DeliveryPending, id, data.DeliveryFailed)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = expectRow(result); err != nil {
+		if errors.Is(err, data.ErrorNoRecords) {
+			return nil, ErrDeliveryNotFailed
+		}
+		return nil, err
+	}
+
+	delivery.Status = data.DeliveryPending
+
+	return delivery, nil
+}
+
+// GetDelivery finds a delivery in any organization, for the workers
+func (m *SubscriptionModel) GetDelivery(id int) (*models.ReportDelivery, error) {
+	return m.getDelivery(`SELECT 0, `+deliveryColumns+` FROM report_deliveries WHERE id = $1;`, id)
+}
+
+// GetDeliveries pages through the delivery log of a subscription, newest first
+func (m *SubscriptionModel) GetDeliveries(organizationId, subscriptionId int,
+	pageable utils.Pageable) ([]*models.ReportDelivery, utils.PageInfo, error) {
+	stmt := `SELECT count(*) OVER(), ` + deliveryColumns + ` FROM report_deliveries
+				WHERE organization_id = $1 AND subscription_id = $2
+				ORDER BY scheduled_for DESC, id DESC LIMIT $3 OFFSET $4;`
+
+	deliveries, totalRecords, err := m.queryDeliveries(stmt, organizationId, subscriptionId, pageable.Size,
+		pageable.OffSet)
+
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	pageInfo := utils.PageInfo{
+		CurrentPage: pageable.Page,
+		Size:        pageable.Size,
+		TotalItems:  totalRecords,
+		FirstPage:   0,
+		LastPage:    int(math.Floor(float64(totalRecords) / float64(pageable.Size))),
+	}
+
+	return deliveries, pageInfo, nil
+}
+
+func (m *SubscriptionModel) getSubscription(stmt string, args ...any) (*models.ReportSubscription, error) {
+	subscriptions, err := m.querySubscriptions(stmt, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(subscriptions) == 0 {
+		return nil, data.ErrorNoRecords
+	}
+
+	return subscriptions[0], nil
+}
+
+func (m *SubscriptionModel) querySubscriptions(stmt string, args ...any) ([]*models.ReportSubscription, error) {
+	rows, err := m.DB.Query(stmt, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	subscriptions := []*models.ReportSubscription{}
+
+	for rows.Next() {
+		subscription := &models.ReportSubscription{}
+
+		err = rows.Scan(&subscription.ID, &subscription.OrganizationId, &subscription.Name, &subscription.ReportType,
+			&subscription.Period, &subscription.Format, pq.Array(&subscription.Recipients), &subscription.Cron,
+			pq.Array(&subscription.Charts), &subscription.Active, &subscription.NextRunAt, &subscription.LastRunAt,
+			&subscription.Audit.CreatedAt, &subscription.Audit.ModifiedAt, &subscription.Audit.CreatedBy,
+			&subscription.Audit.ModifiedBy)
+
+		if err != nil {
+			return nil, err
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (m *SubscriptionModel) getDelivery(stmt string, args ...any) (*models.ReportDelivery, error) {
+	deliveries, _, err := m.queryDeliveries(stmt, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deliveries) == 0 {
+		return nil, data.ErrorNoRecords
+	}
+
+	return deliveries[0], nil
+}
+
+func (m *SubscriptionModel) queryDeliveries(stmt string, args ...any) ([]*models.ReportDelivery, int, error) {
+	rows, err := m.DB.Query(stmt, args...)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	deliveries := []*models.ReportDelivery{}
+	totalRecords := 0
+
+	for rows.Next() {
+		delivery := &models.ReportDelivery{}
+
+		err = rows.Scan(&totalRecords, &delivery.ID, &delivery.SubscriptionId, &delivery.OrganizationId,
+			&delivery.ScheduledFor, &delivery.PeriodFrom, &delivery.PeriodTo, &delivery.Status, &delivery.Attempts,
+			&delivery.JobId, pq.Array(&delivery.Recipients), &delivery.FileName, &delivery.LastError, &delivery.SentAt,
+			&delivery.CreatedAt, &delivery.CreatedBy)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, totalRecords, nil
+}
+
+// chartNames keeps an empty chart list out of the database as NULL
+func chartNames(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+
+	return names
+}
+
+// expectRow turns an update or delete that matched nothing into data.ErrorNoRecords
+func expectRow(result sql.Result) error {
+	rowAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowAffected == 0 {
+		return data.ErrorNoRecords
+	}
+
+	return nil
+}
@@ -3,20 +3,20 @@ package postgres
 import (
 	"crypto/rand"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
 	"time"
 
 	"github.com/VaudKK/CAS/pkg/data"
-	"github.com/VaudKK/CAS/pkg/mailer"
 	"github.com/VaudKK/CAS/pkg/models"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserModel struct {
-	DB     *sql.DB
-	Mailer *mailer.Mailer
+	DB   *sql.DB
+	Jobs *JobModel
 }
 
 func (m *UserModel) CreateUser(user *models.User) (int, error) {
@@ -84,7 +84,8 @@ func (m *UserModel) VerifyUser(email string) error {
 }
 
 func (m *UserModel) GetUserByEmail(email string) (*models.User, error) {
-	stmt := `SELECT id, username FROM users WHERE email = $1`
+	stmt := `SELECT u.id, u.username, u.organization_id, coalesce(u.locale, o.locale, 'en') FROM users u
+	LEFT JOIN organizations o ON o.id = u.organization_id WHERE u.email = $1`
 
 	row, err := m.DB.Query(stmt, email)
 
@@ -97,7 +98,7 @@ func (m *UserModel) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
 
 	if row.Next() {
-		err = row.Scan(&user.ID, &user.UserName)
+		err = row.Scan(&user.ID, &user.UserName, &user.OrganizationId, &user.Locale)
 		if err != nil {
 			return nil, err
 		}
@@ -108,7 +109,8 @@ func (m *UserModel) GetUserByEmail(email string) (*models.User, error) {
 }
 
 func (m *UserModel) GetUserID(id int) (*models.User, error) {
-	stmt := `SELECT id, username, email, verified, active FROM users WHERE id = $1`
+	stmt := `SELECT u.id, u.username, u.email, u.organization_id, u.verified, u.active, coalesce(u.locale, o.locale, 'en')
+	FROM users u LEFT JOIN organizations o ON o.id = u.organization_id WHERE u.id = $1`
 
 	row, err := m.DB.Query(stmt, id)
 
@@ -121,7 +123,8 @@ func (m *UserModel) GetUserID(id int) (*models.User, error) {
 	var user models.User
 
 	if row.Next() {
-		err = row.Scan(&user.ID, &user.UserName, &user.Email, &user.Verified, &user.Active)
+		err = row.Scan(&user.ID, &user.UserName, &user.Email, &user.OrganizationId, &user.Verified, &user.Active,
+			&user.Locale)
 		if err != nil {
 			return nil, err
 		}
@@ -131,8 +134,70 @@ func (m *UserModel) GetUserID(id int) (*models.User, error) {
 	}
 }
 
+// SetLocale chooses the language of a user's reports and emails. An empty locale
+// goes back to that of their organization.
+func (m *UserModel) SetLocale(userId int, locale string) error {
+	stmt := `UPDATE users SET locale = nullif($1, '') WHERE id = $2`
+
+	result, err := m.DB.Exec(stmt, locale, userId)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return data.ErrorNoRecords
+	}
+
+	return nil
+}
+
+// SetOrganizationLocale chooses the language of an organization, used by its users
+// who have not chosen one and for the reports it is sent
+func (m *UserModel) SetOrganizationLocale(organizationId int, locale string) error {
+	stmt := `UPDATE organizations SET locale = $1, modified_at = now() WHERE id = $2`
+
+	result, err := m.DB.Exec(stmt, locale, organizationId)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return data.ErrorNoRecords
+	}
+
+	return nil
+}
+
+func (m *UserModel) OrganizationLocale(organizationId int) (string, error) {
+	stmt := `SELECT locale FROM organizations WHERE id = $1`
+
+	var locale string
+
+	err := m.DB.QueryRow(stmt, organizationId).Scan(&locale)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", data.ErrorNoRecords
+	}
+
+	return locale, err
+}
+
 func (m *UserModel) SendResetLink(email string) error {
-	_, err := m.GetUserByEmail(strings.TrimSpace(email))
+	user, err := m.GetUserByEmail(strings.TrimSpace(email))
 
 	if err != nil {
 		return err
@@ -154,7 +219,7 @@ func (m *UserModel) SendResetLink(email string) error {
 	resetData.ResetLink = "http://localhost:3000/auth/reset?token=" + resetToken
 
 	//send mail
-	_ = m.Mailer.Send(strings.TrimSpace(email), "user_reset_link.tmpl", resetData)
+	_ = m.Jobs.EnqueueMail(user.OrganizationId, strings.TrimSpace(email), user.Locale, "user_reset_link.tmpl", resetData)
 
 	return nil
 }
@@ -217,11 +282,25 @@ func (m *UserModel) ChangePassword(resetToken, newPassword string) error {
 	}
 
 	//send mail
-	_ = m.Mailer.Send(strings.TrimSpace(response.email), "password_change.tmpl", nil)
+	organizationId, locale := m.mailSettings(response.email)
+
+	_ = m.Jobs.EnqueueMail(organizationId, strings.TrimSpace(response.email), locale, "password_change.tmpl", nil)
 
 	return tx.Commit()
 }
 
+// mailSettings are the organization and locale emails to a user are sent in, zero and
+// the default locale when the address is not a user's
+func (m *UserModel) mailSettings(email string) (int, string) {
+	user, err := m.GetUserByEmail(strings.TrimSpace(email))
+
+	if err != nil {
+		return 0, ""
+	}
+
+	return user.OrganizationId, user.Locale
+}
+
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
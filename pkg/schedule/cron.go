@@ -0,0 +1,231 @@
+// Package schedule reads cron expressions and works out when they next fall due.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression of five fields: minute, hour, day of month,
+// month and day of week. Each field is a bit set of the values it matches.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// when both day fields are restricted a day matching either of them is due, as
+	// in Vixie cron
+	domAny, dowAny bool
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug",
+		"sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse reads a cron expression such as "0 7 1 * *" or "@monthly". Fields take
+// "*", single values, ranges such as "1-5", lists such as "1,15" and steps such as
+// "*/15" or "8-18/2". Months and days of the week can be named by their first three
+// letters, and both 0 and 7 are Sunday.
+func Parse(expression string) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+
+	if spec, ok := descriptors[strings.ToLower(expression)]; ok {
+		expression = spec
+	}
+
+	parts := strings.Fields(expression)
+
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression must have %d fields, found %d", len(fields), len(parts))
+	}
+
+	bits := make([]uint64, len(fields))
+
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+
+		if err != nil {
+			return nil, err
+		}
+
+		bits[i] = set
+	}
+
+	s := &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*" || strings.HasPrefix(parts[2], "*/"),
+		dowAny: parts[4] == "*" || strings.HasPrefix(parts[4], "*/"),
+	}
+
+	// Sunday is kept as 0
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	return s, nil
+}
+
+func parseField(part string, f field) (uint64, error) {
+	var set uint64
+
+	for _, item := range strings.Split(part, ",") {
+		if item == "" {
+			return 0, fmt.Errorf("%s has an empty list item", f.name)
+		}
+
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("%s step %q must be a positive number", f.name, stepPart)
+			}
+		}
+
+		low, high := f.min, f.max
+
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+
+			if low, err = f.value(lowPart); err != nil {
+				return 0, err
+			}
+
+			high = low
+
+			if isRange {
+				if high, err = f.value(highPart); err != nil {
+					return 0, err
+				}
+
+				if high < low {
+					return 0, fmt.Errorf("%s range %q ends before it starts", f.name, rangePart)
+				}
+			} else if hasStep {
+				// "5/15" runs from 5 to the end of the field
+				high = f.max
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+
+	return set, nil
+}
+
+func (f field) value(text string) (int, error) {
+	for i, name := range f.names {
+		if name != "" && strings.EqualFold(text, name) {
+			return i, nil
+		}
+	}
+
+	value, err := strconv.Atoi(text)
+
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("%s %q must be between %d and %d", f.name, text, f.min, f.max)
+	}
+
+	return value, nil
+}
+
+// ErrNeverDue is returned for expressions that match no date, such as the 30th of
+// February
+var ErrNeverDue = errors.New("cron expression never falls due")
+
+// searchYears bounds the search for the next time. Every valid day of the month falls
+// on every day of the week within a handful of years.
+const searchYears = 8
+
+// Next returns the first time after t that the schedule falls due, in t's location.
+// It returns the zero time when the schedule never falls due.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + searchYears
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// Validate parses an expression and checks that it falls due at some point
+func Validate(expression string) error {
+	s, err := Parse(expression)
+
+	if err != nil {
+		return err
+	}
+
+	if s.Next(time.Now()).IsZero() {
+		return ErrNeverDue
+	}
+
+	return nil
+}
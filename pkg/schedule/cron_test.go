@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{name: "every minute", expression: "* * * * *"},
+		{name: "descriptor", expression: "@monthly"},
+		{name: "upper case descriptor", expression: "@WEEKLY"},
+		{name: "lists ranges and steps", expression: "0,30 8-18/2 1,15 * mon-fri"},
+		{name: "named months", expression: "0 0 1 jan,jul *"},
+		{name: "sunday as seven", expression: "0 0 * * 7"},
+		{name: "too few fields", expression: "0 0 * *", wantErr: true},
+		{name: "too many fields", expression: "0 0 * * * *", wantErr: true},
+		{name: "minute out of range", expression: "60 * * * *", wantErr: true},
+		{name: "day of month zero", expression: "0 0 0 * *", wantErr: true},
+		{name: "range ends before it starts", expression: "0 18-8 * * *", wantErr: true},
+		{name: "zero step", expression: "*/0 * * * *", wantErr: true},
+		{name: "empty list item", expression: "0,,30 * * * *", wantErr: true},
+		{name: "unknown name", expression: "0 0 * * funday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.expression)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, want error %v", tt.expression, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return parsed
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		from       string
+		want       string
+	}{
+		{name: "first of the month", expression: "0 7 1 * *", from: "2024-01-15 10:00", want: "2024-02-01 07:00"},
+		{name: "strictly after from", expression: "0 7 1 * *", from: "2024-02-01 07:00", want: "2024-03-01 07:00"},
+		{name: "seconds are dropped", expression: "*/15 * * * *", from: "2024-01-15 10:07", want: "2024-01-15 10:15"},
+		{name: "step from a value", expression: "5/20 * * * *", from: "2024-01-15 10:26", want: "2024-01-15 10:45"},
+		{name: "rolls over the year", expression: "@yearly", from: "2024-12-31 23:59", want: "2025-01-01 00:00"},
+		{name: "sunday as seven", expression: "0 8 * * 7", from: "2024-09-02 00:00", want: "2024-09-08 08:00"},
+		{name: "weekdays only", expression: "0 9 * * mon-fri", from: "2024-09-06 10:00", want: "2024-09-09 09:00"},
+		{name: "day of month or week, week first", expression: "0 9 13 * fri", from: "2024-10-01 00:00", want: "2024-10-04 09:00"},
+		{name: "day of month or week, month first", expression: "0 9 13 * fri", from: "2024-11-09 00:00", want: "2024-11-13 09:00"},
+		{name: "day of month with any weekday", expression: "0 0 13 * *", from: "2024-10-01 00:00", want: "2024-10-13 00:00"},
+		{name: "day of month with a weekday step", expression: "0 0 13 * */1", from: "2024-10-01 00:00", want: "2024-10-13 00:00"},
+		{name: "leap day", expression: "0 0 29 feb *", from: "2025-03-01 00:00", want: "2028-02-29 00:00"},
+		{name: "thirty first skips short months", expression: "0 0 31 * *", from: "2024-04-01 00:00", want: "2024-05-31 00:00"},
+		{name: "thirtieth of february", expression: "0 0 30 2 *", from: "2024-01-01 00:00", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expression)
+
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expression, err)
+			}
+
+			got := s.Next(at(tt.from))
+
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Fatalf("Next(%s) = %s, want never", tt.from, got.Format("2006-01-02 15:04"))
+				}
+				return
+			}
+
+			if want := at(tt.want); !got.Equal(want) {
+				t.Fatalf("Next(%s) = %s, want %s", tt.from, got.Format("2006-01-02 15:04"), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextKeepsLocation(t *testing.T) {
+	nairobi := time.FixedZone("EAT", 3*60*60)
+
+	s, err := Parse("0 7 * * *")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.Next(time.Date(2024, 1, 15, 8, 0, 0, 0, nairobi))
+
+	if want := time.Date(2024, 1, 16, 7, 0, 0, 0, nairobi); !got.Equal(want) || got.Location() != nairobi {
+		t.Fatalf("Next = %s, want %s", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       error
+	}{
+		{expression: "0 7 1 * *"},
+		{expression: "0 0 30 2 *", want: ErrNeverDue},
+		{expression: "0 0 31 4,6,9,11 *", want: ErrNeverDue},
+		{expression: "0 0 30 2 mon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			if err := Validate(tt.expression); !errors.Is(err, tt.want) {
+				t.Fatalf("Validate(%q) = %v, want %v", tt.expression, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound         = errors.New("file not found")
+	ErrInvalidKey       = errors.New("invalid storage key")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrLinkExpired      = errors.New("link has expired")
+)
+
+// Storage keeps generated files under keys such as reports/12/trial-balance.pdf
+type Storage interface {
+	// Put stores everything read from r under the key and returns its size. A file
+	// is only visible under the key once it has been written completely.
+	Put(key string, r io.Reader) (int64, error)
+	Open(key string) (File, error)
+	Delete(key string) error
+}
+
+// File is a stored file opened for reading
+type File interface {
+	io.ReadSeekCloser
+}
+
+// Local stores files in a directory on the local disk
+type Local struct {
+	Dir string
+}
+
+func (l *Local) Put(key string, r io.Reader) (int64, error) {
+	path, err := l.path(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := io.Copy(tmp, r)
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+
+	return size, nil
+}
+
+func (l *Local) Open(key string) (File, error) {
+	path, err := l.path(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return file, err
+}
+
+// Delete removes the file under the key. Deleting a file that is already gone is
+// not an error.
+func (l *Local) Delete(key string) error {
+	path, err := l.path(key)
+
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// path maps a key into the directory, refusing keys that would leave it
+func (l *Local) path(key string) (string, error) {
+	if key == "" || !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+// Signer signs links so that a file can be downloaded without logging in until the
+// link expires
+type Signer struct {
+	Key []byte
+}
+
+// Sign returns the expires and signature query values for a path
+func (s *Signer) Sign(path string, expires time.Time) string {
+	unix := strconv.FormatInt(expires.Unix(), 10)
+
+	return "expires=" + unix + "&signature=" + s.signature(path, unix)
+}
+
+// Verify checks the expires and signature query values of a signed link
+func (s *Signer) Verify(path, expires, signature string) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+
+	if err != nil {
+		return fmt.Errorf("invalid expiry: %w", err)
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.signature(path, expires))) {
+		return ErrInvalidSignature
+	}
+
+	if time.Now().Unix() > unix {
+		return ErrLinkExpired
+	}
+
+	return nil
+}
+
+func (s *Signer) signature(path, expires string) string {
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write([]byte(strings.Join([]string{path, expires}, "\n")))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
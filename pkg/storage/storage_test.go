@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignerVerify(t *testing.T) {
+	signer := &Signer{Key: []byte("download-key")}
+	path := "/v1/reports/12/download"
+
+	valid, err := url.ParseQuery(signer.Sign(path, time.Now().Add(time.Hour)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := url.ParseQuery(signer.Sign(path, time.Now().Add(-time.Minute)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	later := strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10)
+	otherKey, err := url.ParseQuery((&Signer{Key: []byte("other-key")}).Sign(path, time.Now().Add(time.Hour)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := []byte(valid.Get("signature"))
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name      string
+		path      string
+		expires   string
+		signature string
+		wantErr   error
+	}{
+		{name: "valid link", path: path, expires: valid.Get("expires"), signature: valid.Get("signature")},
+		{name: "expired link", path: path, expires: expired.Get("expires"), signature: expired.Get("signature"), wantErr: ErrLinkExpired},
+		{name: "tampered signature", path: path, expires: valid.Get("expires"), signature: string(tampered), wantErr: ErrInvalidSignature},
+		{name: "extended expiry", path: path, expires: later, signature: valid.Get("signature"), wantErr: ErrInvalidSignature},
+		{name: "expired link with a later expiry", path: path, expires: later, signature: expired.Get("signature"), wantErr: ErrInvalidSignature},
+		{name: "other report", path: "/v1/reports/13/download", expires: valid.Get("expires"), signature: valid.Get("signature"), wantErr: ErrInvalidSignature},
+		{name: "signed with another key", path: path, expires: otherKey.Get("expires"), signature: otherKey.Get("signature"), wantErr: ErrInvalidSignature},
+		{name: "missing signature", path: path, expires: valid.Get("expires"), wantErr: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := signer.Verify(tt.path, tt.expires, tt.signature); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignerVerifyInvalidExpiry(t *testing.T) {
+	signer := &Signer{Key: []byte("download-key")}
+
+	for _, expires := range []string{"", "tomorrow", "1.5"} {
+		t.Run(expires, func(t *testing.T) {
+			if err := signer.Verify("/v1/reports/12/download", expires, "signature"); err == nil {
+				t.Fatalf("Verify with expires %q succeeded", expires)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package theme finds the files an organization uses in place of the fonts, logo and
+// mail templates built into the binary.
+package theme
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Dir is a theme directory with a subdirectory per organization, named by its id:
+//
+//	<dir>/<organization id>/fonts/Roboto-Regular.ttf
+//	<dir>/<organization id>/fonts/Roboto-Bold.ttf
+//	<dir>/<organization id>/logo.png
+//	<dir>/<organization id>/templates/<template>.tmpl
+//
+// Any file a theme leaves out is taken from the defaults. An empty Dir overrides
+// nothing.
+type Dir string
+
+// FS is the theme of an organization, or nil when it has none
+func (d Dir) FS(organizationId int) fs.FS {
+	if d == "" || organizationId <= 0 {
+		return nil
+	}
+
+	path := filepath.Join(string(d), strconv.Itoa(organizationId))
+
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return nil
+	}
+
+	return os.DirFS(path)
+}
+
+// Organizations lists the organizations that have a theme
+func (d Dir) Organizations() ([]int, error) {
+	if d == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(string(d))
+
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	organizations := make([]int, 0, len(entries))
+
+	for _, entry := range entries {
+		if id, err := strconv.Atoi(entry.Name()); err == nil && id > 0 && entry.IsDir() {
+			organizations = append(organizations, id)
+		}
+	}
+
+	return organizations, nil
+}
+
+// ReadFile reads name from the organization's theme, or defaultName from defaults
+// when the organization has no theme or its theme leaves the file out
+func (d Dir) ReadFile(organizationId int, name string, defaults fs.FS, defaultName string) ([]byte, error) {
+	if theme := d.FS(organizationId); theme != nil {
+		data, err := fs.ReadFile(theme, name)
+
+		if err == nil {
+			return data, nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return fs.ReadFile(defaults, defaultName)
+}